@@ -1,6 +1,6 @@
 --- core stuff
 -- :name version
-demo8
+demo15
 -- :name init
 CREATE SCHEMA ib0
 
@@ -40,6 +40,7 @@ CREATE TABLE ib0.gposts (
 	date_sent TIMESTAMP  WITH TIME ZONE,
 	date_recv TIMESTAMP  WITH TIME ZONE,
 	sage      BOOLEAN    NOT NULL        DEFAULT FALSE,
+	expires   TIMESTAMP  WITH TIME ZONE, -- parsed Expires header, if any
 
 	-- attachment count
 	f_count INTEGER NOT NULL             DEFAULT 0,
@@ -66,6 +67,11 @@ CREATE TABLE ib0.gposts (
 	PRIMARY KEY (g_p_id),
 	UNIQUE      (msgid)
 )
+-- :next
+-- expiry
+CREATE INDEX
+	ON ib0.gposts (expires)
+	WHERE expires IS NOT NULL
 
 
 -- :next
@@ -113,6 +119,8 @@ CREATE TABLE ib0.boards (
 	thread_opts      JSONB, -- options common for all threads. stuff like bump/file limits
 	attrib           JSONB, -- board attributes
 
+	moderated BOOLEAN  DEFAULT FALSE  NOT NULL, -- posts need approval of mod
+
 
 	PRIMARY KEY (b_id),
 	UNIQUE      (b_name)
@@ -140,6 +148,8 @@ CREATE TABLE ib0.threads (
 
 	bump      TIMESTAMP  WITH TIME ZONE  NOT NULL, -- last bump time. decides position in pages/catalog
 	t_order   BIGINT                     NOT NULL, -- order within board its in
+	-- order number within archive (newest thread goes last, so don't need updating of old indexes), NULL if not archived
+	t_arch_order BIGINT,
 	skip_over BOOLEAN                    NOT NULL, -- if true, do not include in overboard
 	p_count   BIGINT                     NOT NULL DEFAULT 0, -- post count (including OP)
 	f_count   BIGINT                     NOT NULL DEFAULT 0, -- sum of posts' (including OP) f_count
@@ -162,6 +172,7 @@ CREATE TABLE ib0.threads (
 CREATE INDEX
 	ON ib0.threads (
 		b_id   ASC,
+		COALESCE((thread_opts ->> 'sticky')::BOOLEAN, FALSE) DESC,
 		bump   DESC,
 		b_t_id ASC
 	)
@@ -175,6 +186,15 @@ CREATE INDEX
 	)
 	WHERE
 		skip_over IS NOT TRUE
+-- :next
+-- for board archive
+CREATE INDEX
+	ON ib0.threads (
+		b_id         ASC,
+		t_arch_order ASC
+	)
+	WHERE
+		t_arch_order IS NOT NULL
 
 
 -- :next
@@ -472,3 +492,115 @@ CREATE INDEX
 CREATE INDEX
 	ON ib0.banlist (b_name,msgid)
 	WHERE b_name IS NOT NULL
+
+
+-- :next
+-- audit log of executed mod commands.
+-- deliberately not linked to posts, so it stays after ctl msg is gone
+CREATE TABLE ib0.modlog (
+	ml_id     BIGINT GENERATED ALWAYS AS IDENTITY,
+	date_exec TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+	mod_pubkey TEXT  COLLATE "C", -- NULL if done by local admin
+	msgid      TEXT  COLLATE "C", -- ctl msg which issued command (if any)
+	b_name     TEXT  COLLATE "C", -- board command was scoped to, NULL if global
+
+	cmd     TEXT                NOT NULL,
+	targets TEXT  COLLATE "C"   ARRAY,    -- msgids, pubkeys and such
+	applied BOOLEAN             NOT NULL, -- false if rejected
+	reason  TEXT                NOT NULL DEFAULT '', -- why rejected, or reason given by mod
+
+
+	PRIMARY KEY (ml_id)
+)
+-- :next
+CREATE INDEX
+	ON ib0.modlog (b_name,ml_id)
+	WHERE b_name IS NOT NULL
+-- :next
+CREATE INDEX
+	ON ib0.modlog (mod_pubkey,ml_id)
+	WHERE mod_pubkey IS NOT NULL
+
+
+-- :next
+-- posts to moderated boards awaiting approval.
+-- post is kept in the form it would be inserted in,
+-- its files are already in place and are counted as used.
+CREATE TABLE ib0.modqueue (
+	mq_id     BIGINT GENERATED ALWAYS AS IDENTITY,
+	date_recv TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+	b_id   INTEGER NOT NULL, -- board post is for
+	b_t_id BIGINT,           -- thread if reply, NULL if new thread
+
+	msgid TEXT  COLLATE "C"  NOT NULL, -- Message-ID of post
+	pinfo JSONB              NOT NULL, -- post itself
+
+
+	PRIMARY KEY (mq_id),
+	UNIQUE      (msgid),
+
+	FOREIGN KEY (b_id)
+		REFERENCES ib0.boards
+		ON DELETE CASCADE,
+	FOREIGN KEY (b_id,b_t_id)
+		REFERENCES ib0.threads
+		ON DELETE CASCADE -- thread is gone, reply has nowhere to go
+)
+-- :next
+CREATE INDEX
+	ON ib0.modqueue (b_id,mq_id)
+-- :next
+-- files of queued posts. triggers keep them in files GC counters
+CREATE TABLE ib0.modqueue_files (
+	mq_id BIGINT               NOT NULL,
+	fname TEXT    COLLATE "C"  NOT NULL,
+	thumb TEXT    COLLATE "C"  NOT NULL, -- '' if none
+
+
+	FOREIGN KEY (mq_id)
+		REFERENCES ib0.modqueue
+		ON DELETE CASCADE
+)
+-- :next
+CREATE INDEX
+	ON ib0.modqueue_files (mq_id)
+
+
+-- :next
+-- control messages already acted upon, so they can't be replayed.
+-- they aren't stored in gposts, so this is the only trace of them
+CREATE TABLE ib0.control_history (
+	ch_id     BIGINT GENERATED ALWAYS AS IDENTITY,
+	date_recv TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+	msgid TEXT  COLLATE "C", -- Message-ID, if it had one
+	sig   TEXT  COLLATE "C", -- signature, if it was signed
+	-- whole message, kept only for ones posted here so that we push them
+	article TEXT,
+
+
+	PRIMARY KEY (ch_id)
+)
+-- :next
+CREATE UNIQUE INDEX
+	ON ib0.control_history (msgid)
+	WHERE msgid IS NOT NULL
+-- :next
+CREATE UNIQUE INDEX
+	ON ib0.control_history (sig)
+	WHERE sig IS NOT NULL
+-- :next
+CREATE INDEX
+	ON ib0.control_history (date_recv)
+
+
+-- :next
+-- server-side secrets, generated when first needed
+CREATE TABLE ib0.secrets (
+	s_name TEXT  COLLATE "C" NOT NULL,
+	s_data BYTEA NOT NULL,
+
+	PRIMARY KEY (s_name)
+)
@@ -6,6 +6,8 @@ CREATE TABLE ib0.puller_list (
 	sname    TEXT    COLLATE "C"  NOT NULL,
 	-- nonce, used to clean dead server trackings
 	last_use BIGINT               NOT NULL,
+	-- pull rules puller_rejects were made under
+	rules    TEXT,
 
 	PRIMARY KEY (sid),
 	UNIQUE (sname)
@@ -68,3 +70,22 @@ CREATE INDEX
 -- :next
 CREATE INDEX
 	ON ib0.puller_group_track (bid)
+
+-- :next
+-- articles pull filter rejected (per-server), not to be fetched again
+CREATE TABLE ib0.puller_rejects (
+	sid      BIGINT                    NOT NULL,
+	msgid    TEXT  COLLATE "C"         NOT NULL,
+	date_rej TIMESTAMP  WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	reason   TEXT                      NOT NULL,
+
+
+	PRIMARY KEY (sid,msgid),
+
+	FOREIGN KEY (sid)
+		REFERENCES ib0.puller_list
+		ON DELETE CASCADE
+)
+-- :next
+CREATE INDEX
+	ON ib0.puller_rejects (date_rej)
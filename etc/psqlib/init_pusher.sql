@@ -0,0 +1,50 @@
+-- :name init_pusher
+
+-- :next
+CREATE TABLE ib0.pusher_list (
+	sid      BIGINT  GENERATED ALWAYS AS IDENTITY,
+	sname    TEXT    COLLATE "C"  NOT NULL,
+	-- nonce, used to clean dead peer trackings
+	last_use BIGINT               NOT NULL,
+
+	PRIMARY KEY (sid),
+	UNIQUE (sname)
+)
+-- :next
+CREATE INDEX ON ib0.pusher_list (last_use)
+
+-- :next
+CREATE TABLE ib0.pusher_group_track (
+	sid      BIGINT  NOT NULL,
+	bid      INTEGER NOT NULL,
+	-- max board post id already offered to peer
+	last_max BIGINT  NOT NULL,
+
+
+	PRIMARY KEY (sid,bid),
+
+	FOREIGN KEY (sid)
+		REFERENCES ib0.pusher_list
+		ON DELETE CASCADE,
+	FOREIGN KEY (bid)
+		REFERENCES ib0.boards
+		ON DELETE CASCADE
+)
+-- :next
+CREATE INDEX
+	ON ib0.pusher_group_track (bid)
+
+-- :next
+-- control messages posted here are pushed as if they were separate group
+CREATE TABLE ib0.pusher_control_track (
+	sid      BIGINT  NOT NULL,
+	-- max control_history id already offered to peer
+	last_max BIGINT  NOT NULL,
+
+
+	PRIMARY KEY (sid),
+
+	FOREIGN KEY (sid)
+		REFERENCES ib0.pusher_list
+		ON DELETE CASCADE
+)
@@ -145,7 +145,8 @@ BEGIN
 				ib0.banlist exibl
 			ON
 				delbl.msgid = exibl.msgid AND
-					delbl.b_name = exibl.b_name
+					-- global bans have NULL b_name
+					delbl.b_name IS NOT DISTINCT FROM exibl.b_name
 			GROUP BY
 				delbl.msgid,
 				delbl.b_name
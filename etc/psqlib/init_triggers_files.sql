@@ -155,3 +155,23 @@ ON ib0.files_uniq_thumb
 FOR EACH ROW
 WHEN (NEW.cnt <= 0)
 EXECUTE PROCEDURE ib0.files_uniq_thumb_update_delete()
+
+
+
+
+-- :next
+-- files of queued posts use the same counters
+CREATE TRIGGER after_insert
+AFTER INSERT
+ON ib0.modqueue_files
+REFERENCING NEW TABLE AS newrows
+FOR EACH STATEMENT
+EXECUTE PROCEDURE ib0.files_after_insert()
+
+-- :next
+CREATE TRIGGER after_delete
+AFTER DELETE
+ON ib0.modqueue_files
+REFERENCING OLD TABLE AS oldrows
+FOR EACH STATEMENT
+EXECUTE PROCEDURE ib0.files_after_delete()
@@ -161,6 +161,380 @@ VALUES
 		$4
 	)
 
+-- :name mod_bban_by_msgid
+-- input: {msgid} {b_id} {b_p_id} {reason} {board name}
+-- per-board ban. board may not exist yet
+INSERT INTO
+	ib0.banlist
+	(
+		msgid,
+		b_id,
+		b_p_id,
+		ban_info,
+		b_name
+	)
+VALUES
+	(
+		$1,
+		$2,
+		$3,
+		$4,
+		$5
+	)
+
+-- :name mod_bdelete_by_msgid
+-- input: {msgid} {board name}
+-- deletes post only from specified board, keeping placeholder if needed
+WITH
+	dbp AS (
+		DELETE FROM
+			ib0.bposts AS zbp
+		USING
+			ib0.boards AS xb
+		WHERE
+			zbp.b_id = xb.b_id AND
+				xb.b_name = $2 AND
+				zbp.msgid = $1 AND
+				zbp.date_recv IS NOT NULL
+		RETURNING
+			zbp.b_id,
+			zbp.msgid,
+			zbp.has_ph,
+			zbp.ph_ban,
+			zbp.ph_banpriv
+	)
+INSERT INTO
+	ib0.bposts
+	(
+		b_id,
+		msgid,
+		has_ph,
+		ph_ban,
+		ph_banpriv
+	)
+SELECT
+	b_id,
+	msgid,
+	has_ph,
+	ph_ban,
+	ph_banpriv
+FROM
+	dbp
+WHERE
+	has_ph IS TRUE
+
+-- :name mod_unban_by_msgid
+-- input: {msgid} {board name, NULL for global ban}
+-- trigger recalculates what's left
+DELETE FROM
+	ib0.banlist
+WHERE
+	msgid = $1 AND b_name IS NOT DISTINCT FROM $2
+RETURNING
+	ban_id
+
+-- :name mod_delete_board_posts
+-- input: {board name}
+-- triggers take care of threads bookkeeping and orphaned gposts
+DELETE FROM
+	ib0.bposts AS xbp
+USING
+	ib0.boards AS xb
+WHERE
+	xb.b_name = $1 AND xbp.b_id = xb.b_id
+
+-- :name mod_delete_board
+-- input: {board name}
+-- posts must be deleted already
+WITH
+	xb AS (
+		SELECT
+			b_id
+		FROM
+			ib0.boards
+		WHERE
+			b_name = $1
+	),
+	dt AS (
+		DELETE FROM
+			ib0.threads AS xt
+		USING
+			xb
+		WHERE
+			xt.b_id = xb.b_id
+	)
+DELETE FROM
+	ib0.boards AS xb2
+USING
+	xb
+WHERE
+	xb2.b_id = xb.b_id
+RETURNING
+	xb2.b_id
+
+-- :name mod_cancel_lock_by_msgid
+-- input: {core msgid}
+-- output: {Cancel-Lock header values as JSON array, or NULL}
+SELECT
+	headers -> 'Cancel-Lock'
+FROM
+	ib0.gposts
+WHERE
+	msgid = $1 AND date_recv IS NOT NULL
+FOR UPDATE
+
+-- :name mod_boards_by_msgid
+-- input: {core msgid}
+-- output: {board name}
+-- boards cancel would delete article from
+SELECT
+	xb.b_name
+FROM
+	ib0.bposts AS xbp
+JOIN
+	ib0.boards AS xb
+ON
+	xbp.b_id = xb.b_id
+WHERE
+	xbp.msgid = $1 AND xbp.date_recv IS NOT NULL
+FOR UPDATE OF
+	xbp
+
+-- :name mod_control_history_seen
+-- input: {core msgid or NULL} {signature or NULL}
+SELECT
+	1
+FROM
+	ib0.control_history
+WHERE
+	msgid = $1 OR sig = $2
+LIMIT
+	1
+
+-- :name mod_control_history_add
+-- input: {core msgid or NULL} {signature or NULL} {article or NULL}
+INSERT INTO
+	ib0.control_history (msgid,sig,article)
+VALUES
+	($1,$2,$3)
+ON CONFLICT
+	DO NOTHING
+
+-- :name mod_control_history_prune
+-- input: {cutoff date}
+DELETE FROM
+	ib0.control_history
+WHERE
+	date_recv < $1
+
+-- :name mod_puller_rejects_prune
+-- input: {cutoff date}
+DELETE FROM
+	ib0.puller_rejects
+WHERE
+	date_rej < $1
+
+-- :name mod_expire_find_expires
+-- input: {current time, limit}
+-- output: {core msgid}
+-- expires is parsed from Expires header at insertion
+SELECT
+	msgid
+FROM
+	ib0.gposts
+WHERE
+	expires <= $1 AND date_recv IS NOT NULL
+ORDER BY
+	expires ASC
+LIMIT
+	$2
+
+-- :name mod_expire_find_threads
+-- input: {limit}
+-- output: {b_id, b_t_id, board name, thread name}
+-- threads which fell off last page, went over thread limit
+-- or weren't bumped for longer than max_age of thread_opts (in seconds)
+WITH
+	xt AS (
+		SELECT
+			zt.b_id,
+			zt.b_t_id,
+			zt.b_t_name,
+			zt.bump,
+			COALESCE(
+				(zt.thread_opts ->> 'max_age')::BIGINT,
+				(zb.thread_opts ->> 'max_age')::BIGINT,
+				0) AS max_age,
+			ROW_NUMBER() OVER (
+				PARTITION BY
+					zt.b_id
+				ORDER BY
+					COALESCE((zt.thread_opts ->> 'sticky')::BOOLEAN, FALSE) DESC,
+					zt.bump   DESC,
+					zt.b_t_id ASC
+			) AS t_pos
+		FROM
+			ib0.threads AS zt
+		JOIN
+			ib0.boards AS zb
+		ON
+			zt.b_id = zb.b_id
+	)
+SELECT
+	xt.b_id,
+	xt.b_t_id,
+	xb.b_name,
+	xt.b_t_name
+FROM
+	xt
+JOIN
+	ib0.boards AS xb
+ON
+	xt.b_id = xb.b_id
+WHERE
+	(
+		xb.threads_per_page > 0 AND xb.max_pages > 0 AND
+			xt.t_pos > xb.threads_per_page::BIGINT * xb.max_pages
+	) OR
+	(
+		xb.cfg_t_thread_limit > 0 AND
+			xt.t_pos > xb.cfg_t_thread_limit
+	) OR
+	(
+		xt.max_age > 0 AND
+			xt.bump < NOW() - xt.max_age * INTERVAL '1 second'
+	)
+ORDER BY
+	xt.bump ASC
+LIMIT
+	$1
+
+-- :name mod_archive_threads
+-- input: {limit}
+-- output: {board name, thread name}
+-- archives threads which fell off active pages.
+-- they're numbered after already archived ones, oldest first
+WITH
+	xt AS (
+		SELECT
+			zt.b_id,
+			zt.b_t_id,
+			zt.b_t_name,
+			zt.bump,
+			zt.t_arch_order,
+			ROW_NUMBER() OVER (
+				PARTITION BY
+					zt.b_id
+				ORDER BY
+					COALESCE((zt.thread_opts ->> 'sticky')::BOOLEAN, FALSE) DESC,
+					zt.bump   DESC,
+					zt.b_t_id ASC
+			) AS t_pos
+		FROM
+			ib0.threads AS zt
+	),
+	xa AS (
+		SELECT
+			xt.b_id,
+			xt.b_t_id,
+			xb.b_name,
+			xt.b_t_name,
+			ROW_NUMBER() OVER (
+				PARTITION BY
+					xt.b_id
+				ORDER BY
+					xt.bump   ASC,
+					xt.b_t_id DESC
+			) AS a_pos
+		FROM
+			xt
+		JOIN
+			ib0.boards AS xb
+		ON
+			xt.b_id = xb.b_id
+		WHERE
+			xt.t_arch_order IS NULL AND
+				xb.threads_per_page > 0 AND xb.max_active_pages > 0 AND
+				xt.t_pos > xb.threads_per_page::BIGINT * xb.max_active_pages
+		ORDER BY
+			xt.bump   ASC,
+			xt.b_t_id DESC
+		LIMIT
+			$1
+	),
+	xo AS (
+		SELECT
+			zt.b_id,
+			MAX(zt.t_arch_order) AS last_order
+		FROM
+			ib0.threads AS zt
+		JOIN
+			(
+				SELECT DISTINCT
+					b_id
+				FROM
+					xa
+			) AS zb
+		ON
+			zt.b_id = zb.b_id
+		WHERE
+			zt.t_arch_order IS NOT NULL
+		GROUP BY
+			zt.b_id
+	)
+UPDATE
+	ib0.threads AS ut
+SET
+	t_arch_order = COALESCE(xo.last_order,0) + xa.a_pos
+FROM
+	xa
+LEFT JOIN
+	xo
+ON
+	xa.b_id = xo.b_id
+WHERE
+	(ut.b_id,ut.b_t_id) = (xa.b_id,xa.b_t_id)
+RETURNING
+	xa.b_name,
+	xa.b_t_name
+
+-- :name mod_delete_thread
+-- input: {b_id, b_t_id}
+-- posts go together with thread by cascade, triggers do the rest
+DELETE FROM
+	ib0.threads
+WHERE
+	b_id = $1 AND b_t_id = $2
+
+-- :name mod_set_thread_opt
+-- input: {msgid} {option name} {option value} {any board} {board names}
+-- output: {board name, thread name}
+-- sets boolean thread option of threads containing specified post.
+-- only threads of specified boards are touched, unless any board is true
+UPDATE
+	ib0.threads AS ut
+SET
+	thread_opts =
+		COALESCE(ut.thread_opts, '{}'::JSONB) ||
+			jsonb_build_object($2::TEXT, $3::BOOLEAN)
+FROM
+	ib0.gposts AS xp
+JOIN
+	ib0.bposts AS xbp
+ON
+	xp.g_p_id = xbp.g_p_id
+JOIN
+	ib0.boards AS xb
+ON
+	xbp.b_id = xb.b_id
+WHERE
+	xp.msgid = $1 AND
+		(ut.b_id,ut.b_t_id) = (xbp.b_id,xbp.b_t_id) AND
+		($4 OR xb.b_name = ANY($5::TEXT[]))
+RETURNING
+	xb.b_name,
+	ut.b_t_name
+
 -- :name mod_bname_topts_by_tid
 -- returns boardname and thread opts
 SELECT
@@ -218,6 +592,100 @@ WHERE
 	b_id = $1 AND b_t_id = $2
 
 
+-- :name mod_log_cmd
+-- input: {mod_id} {ctl msgid} {board name} {cmd} {targets} {applied} {reason}
+-- mod_id is NULL for local admin actions
+INSERT INTO
+	ib0.modlog
+	(
+		mod_pubkey,
+		msgid,
+		b_name,
+		cmd,
+		targets,
+		applied,
+		reason
+	)
+VALUES
+	(
+		(SELECT mod_pubkey FROM ib0.modlist WHERE mod_id = $1),
+		$2,
+		$3,
+		$4,
+		$5,
+		$6,
+		$7
+	)
+
+
+-- :name mod_modqueue_add
+-- input: {b_id} {b_t_id or NULL} {msgid} {pinfo} {fnames} {thumbs}
+-- files are counted as used while post sits in queue.
+-- already queued msgid is left alone.
+WITH
+	mq AS (
+		INSERT INTO
+			ib0.modqueue
+			(
+				b_id,
+				b_t_id,
+				msgid,
+				pinfo
+			)
+		VALUES
+			(
+				$1,
+				$2,
+				$3,
+				$4
+			)
+		ON CONFLICT (msgid) DO NOTHING
+		RETURNING
+			mq_id
+	)
+INSERT INTO
+	ib0.modqueue_files
+	(
+		mq_id,
+		fname,
+		thumb
+	)
+SELECT
+	mq.mq_id,
+	x.fname,
+	x.thumb
+FROM
+	mq
+CROSS JOIN
+	UNNEST($5::TEXT[],$6::TEXT[]) AS x (fname,thumb)
+
+-- :name mod_modqueue_get
+-- input: {msgid}
+-- locks queued post for approval or rejection
+SELECT
+	mq.mq_id,
+	mq.b_id,
+	xb.b_name,
+	mq.b_t_id,
+	mq.pinfo
+FROM
+	ib0.modqueue AS mq
+JOIN
+	ib0.boards AS xb
+USING
+	(b_id)
+WHERE
+	mq.msgid = $1
+FOR UPDATE OF
+	mq
+
+-- :name mod_modqueue_del
+-- input: {mq_id}
+-- files go away together with queue entry (if not used by anything else)
+DELETE FROM
+	ib0.modqueue
+WHERE
+	mq_id = $1
 -- :name mod_set_mod_priv
 -- args: <pubkey> <capabilities> <delpriv>
 INSERT INTO
@@ -189,3 +189,113 @@ SELECT
 	*
 FROM
 	things
+
+
+
+
+
+-- :name mod_joblist_files_deleted_get
+SELECT
+	d_id,
+	fname
+FROM
+	ib0.files_deleted
+ORDER BY
+	d_id ASC
+LIMIT
+	$1
+FOR UPDATE
+SKIP LOCKED
+
+-- :name mod_joblist_files_deleted_lock
+-- input: {fname}
+-- output: {cnt}
+-- lock counter so that nobody could start using file while we're deleting it
+SELECT
+	cnt
+FROM
+	ib0.files_uniq_fname
+WHERE
+	fname = $1
+FOR UPDATE
+
+-- :name mod_joblist_files_deleted_hold
+-- input: {fname}
+-- output: {cnt}, nothing if someone else got there first
+-- if counter is already gone, hold placeholder so that we still have lock
+INSERT INTO
+	ib0.files_uniq_fname (fname,cnt)
+VALUES
+	($1,0)
+ON CONFLICT
+	DO NOTHING
+RETURNING
+	cnt
+
+-- :name mod_joblist_files_deleted_del
+-- input: {d_id, fname}
+WITH
+	du AS (
+		DELETE FROM
+			ib0.files_uniq_fname
+		WHERE
+			fname = $2 AND cnt <= 0
+	)
+DELETE FROM
+	ib0.files_deleted
+WHERE
+	d_id = $1
+
+
+
+
+-- :name mod_joblist_fthumbs_deleted_get
+SELECT
+	d_id,
+	fname,
+	thumb
+FROM
+	ib0.fthumbs_deleted
+ORDER BY
+	d_id ASC
+LIMIT
+	$1
+FOR UPDATE
+SKIP LOCKED
+
+-- :name mod_joblist_fthumbs_deleted_lock
+-- input: {fname, thumb}
+-- output: {cnt}
+SELECT
+	cnt
+FROM
+	ib0.files_uniq_thumb
+WHERE
+	fname = $1 AND thumb = $2
+FOR UPDATE
+
+-- :name mod_joblist_fthumbs_deleted_hold
+-- input: {fname, thumb}
+-- output: {cnt}, nothing if someone else got there first
+INSERT INTO
+	ib0.files_uniq_thumb (fname,thumb,cnt)
+VALUES
+	($1,$2,0)
+ON CONFLICT
+	DO NOTHING
+RETURNING
+	cnt
+
+-- :name mod_joblist_fthumbs_deleted_del
+-- input: {d_id, fname, thumb}
+WITH
+	du AS (
+		DELETE FROM
+			ib0.files_uniq_thumb
+		WHERE
+			fname = $2 AND thumb = $3 AND cnt <= 0
+	)
+DELETE FROM
+	ib0.fthumbs_deleted
+WHERE
+	d_id = $1
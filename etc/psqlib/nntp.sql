@@ -1,12 +1,22 @@
 -- :name nntp_article_exists_or_banned_by_msgid
 -- input: cmsgid
 -- output: 1
+-- processed control messages count as existing
 SELECT
 	1
 FROM
 	ib0.gposts
 WHERE
 	msgid = $1
+UNION ALL
+SELECT
+	1
+FROM
+	ib0.control_history
+WHERE
+	msgid = $1
+LIMIT
+	1
 
 -- :name nntp_article_valid_by_msgid
 -- input: cmsgid
@@ -39,6 +49,24 @@ ORDER BY
 LIMIT
 	1
 
+-- :name nntp_article_groups_by_msgid
+-- input: msgid
+-- output: newsgroups article is in
+SELECT
+	xb.newsgroup
+FROM
+	ib0.gposts AS xp
+JOIN
+	ib0.bposts AS xbp
+USING
+	(g_p_id)
+JOIN
+	ib0.boards AS xb
+USING
+	(b_id)
+WHERE
+	xp.msgid = $1 AND xb.newsgroup IS NOT NULL
+
 -- :name nntp_article_msgid_by_num
 -- input: bid bpid
 SELECT
@@ -244,7 +272,8 @@ ORDER BY
 SELECT
 	xb.newsgroup,
 	MIN(xbp.b_p_id) AS lo,
-	MAX(xbp.b_p_id) AS hi
+	MAX(xbp.b_p_id) AS hi,
+	xb.moderated
 FROM
 	ib0.boards AS xb
 LEFT JOIN
@@ -265,7 +294,8 @@ ORDER BY
 SELECT
 	xb.newsgroup,
 	MIN(xbp.b_p_id),
-	MAX(xbp.b_p_id)
+	MAX(xbp.b_p_id),
+	xb.moderated
 FROM
 	ib0.boards AS xb
 LEFT JOIN
@@ -282,7 +312,70 @@ ORDER BY
 SELECT
 	xb.newsgroup,
 	MIN(xbp.b_p_id),
-	MAX(xbp.b_p_id)
+	MAX(xbp.b_p_id),
+	xb.moderated
+FROM
+	ib0.boards AS xb
+LEFT JOIN
+	ib0.bposts AS xbp
+USING
+	(b_id)
+WHERE
+	xb.newsgroup = $1
+GROUP BY
+	xb.b_id
+
+
+
+-- :name nntp_listactivetimes_all
+SELECT
+	xb.newsgroup,
+	xb.badded
+FROM
+	ib0.boards AS xb
+WHERE
+	xb.newsgroup IS NOT NULL
+ORDER BY
+	xb.newsgroup COLLATE "und-x-icu"
+
+-- :name nntp_listactivetimes_one
+-- input: {board name}
+SELECT
+	xb.newsgroup,
+	xb.badded
+FROM
+	ib0.boards AS xb
+WHERE
+	xb.newsgroup = $1
+
+-- :name nntp_listcounts_all
+SELECT
+	xb.newsgroup,
+	MIN(xbp.b_p_id),
+	MAX(xbp.b_p_id),
+	COUNT(xbp.b_p_id),
+	xb.moderated
+FROM
+	ib0.boards AS xb
+LEFT JOIN
+	ib0.bposts AS xbp
+USING
+	(b_id)
+WHERE
+	xb.newsgroup IS NOT NULL
+GROUP BY
+	xb.b_id
+ORDER BY
+	xb.newsgroup COLLATE "und-x-icu"
+
+-- :name nntp_listcounts_one
+-- input: {board name}
+SELECT
+	xb.newsgroup,
+	MIN(xbp.b_p_id),
+	MAX(xbp.b_p_id),
+	COUNT(xbp.b_p_id),
+	xb.moderated
 FROM
 	ib0.boards AS xb
 LEFT JOIN
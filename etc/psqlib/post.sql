@@ -368,3 +368,43 @@ WITH
 {{ .post_template_newreply_ubp_mb }},
 {{ .post_template_common_uf_many }}
 {{ .post_template_common_result }}
+
+
+
+-- :name post_set_expires
+-- input: {g_p_id, parsed Expires date}
+UPDATE
+	ib0.gposts
+SET
+	expires = $2
+WHERE
+	g_p_id = $1
+
+
+
+-- :name post_superseded_info
+-- input: {core msgid of superseded post, board name}
+-- output: {trip, b_id, b_t_id, is OP, core msgid of thread OP, date_sent}
+SELECT
+	xp.trip,
+	xbp.b_id,
+	xbp.b_t_id,
+	xbp.b_p_id = xbp.b_t_id,
+	xtp.msgid,
+	xbp.date_sent
+FROM
+	ib0.gposts AS xp
+JOIN
+	ib0.bposts AS xbp
+ON
+	xp.g_p_id = xbp.g_p_id
+JOIN
+	ib0.boards AS xb
+ON
+	xbp.b_id = xb.b_id
+JOIN
+	ib0.bposts AS xtp
+ON
+	xbp.b_id = xtp.b_id AND xbp.b_t_id = xtp.b_p_id
+WHERE
+	xp.msgid = $1 AND xb.b_name = $2
@@ -17,6 +17,8 @@ func main() {
 	// initialize flags
 	dbconnstr := flag.String("dbstr", "", "postgresql connection string")
 	banstr := flag.String("ban", "", "ban reason (if unset, won't ban)")
+	board := flag.String("board", "", "board to act in (if unset, act globally)")
+	unban := flag.Bool("unban", false, "lift bans instead of deleting")
 
 	flag.Parse()
 
@@ -50,5 +52,9 @@ func main() {
 	}
 
 	args := flag.Args()
-	dbib.DemoDeleteOrBanByMsgID(args, *banstr)
+	if *unban {
+		dbib.DemoUnbanByMsgID(args, *board)
+	} else {
+		dbib.DemoBanByMsgID(args, *board, *banstr)
+	}
 }
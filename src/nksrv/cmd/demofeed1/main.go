@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"nksrv/lib/app/base/ctlmsg"
+	"nksrv/lib/app/base/psql"
+	"nksrv/lib/app/demo/democonfigs"
+	"nksrv/lib/app/demo/demohelper"
+	"nksrv/lib/app/psqlib"
+	"nksrv/lib/nntp"
+	"nksrv/lib/nntp/nntpfeedcfg"
+	"nksrv/lib/nntp/nntppostlockmgr"
+	"nksrv/lib/thumbnailer/extthm"
+	"nksrv/lib/utils/logx"
+	. "nksrv/lib/utils/logx"
+	fl "nksrv/lib/utils/logx/filelogger"
+)
+
+type feedDB struct {
+	dbib *psqlib.PSQLIB
+}
+
+func (f feedDB) NewPullerDB(name string) (nntp.PullerDatabase, error) {
+	return f.dbib.NewPullerDB(name, "", false)
+}
+
+func (f feedDB) NewPusherDB(name string) (nntp.PusherDatabase, error) {
+	return f.dbib.NewPusherDB(name)
+}
+
+func main() {
+	var err error
+	// initialize flags
+	dbconnstr := flag.String("dbstr", "", "postgresql connection string")
+	feedcfg := flag.String("feedcfg", "", "feed configuration file (TOML)")
+	thumbext := flag.Bool("extthm", false, "use extthm")
+	nodename := flag.String("nodename", "nekochan", "node name. must be non-empty")
+	pathaliases := flag.String("pathaliases", "", "comma-separated other path identities of this node")
+	ctltrust := flag.String("ctltrust", "", "control messages trust list file (TOML), control messages aren't processed if not set")
+	ngp := flag.String("ngp", "*", "new group policy: which groups can be automatically added?")
+	expiry := flag.Duration("expiry", psqlib.DefaultExpiryConfig.Interval, "how often to run expiry of old content, 0 disables it")
+	archive := flag.Duration("archive", psqlib.DefaultArchiveConfig.Interval, "how often to archive threads which fell off active pages, besides after bumps. 0 disables archiving")
+	archreplies := flag.Bool("archreplies", false, "accept NNTP replies to archived threads")
+	locktimeout := flag.Duration("locktimeout", nntppostlockmgr.DefaultConfig.LockTimeout, "how long article transfer may take before other peers are allowed to send it")
+	lockwait := flag.Duration("lockwait", nntppostlockmgr.DefaultConfig.WaitTimeout, "how long TAKETHIS waits for other transfer of the same article before asking peer to retry later")
+
+	flag.Parse()
+
+	// logger
+	lgr, err := fl.NewFileLogger(os.Stderr, DEBUG, fl.ColorAuto)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fl.NewFileLogger error: %v\n", err)
+		os.Exit(1)
+	}
+	mlg := NewLogToX(lgr, "main")
+
+	fcfg, err := nntpfeedcfg.ParseCfgFile(*feedcfg)
+	if err != nil {
+		mlg.LogPrintln(CRITICAL, "failed loading feed config:", err)
+		return
+	}
+
+	err = demohelper.LoadMIMEDB()
+	if err != nil {
+		mlg.LogPrintln(logx.CRITICAL, "LoadMIMEDB err:", err)
+		return
+	}
+
+	psqlcfg := psql.DefaultConfig
+
+	psqlcfg.Logger = lgr
+	psqlcfg.ConnStr = *dbconnstr
+
+	db, err := psql.OpenAndPrepare(psqlcfg)
+	if err != nil {
+		mlg.LogPrintln(logx.CRITICAL, "psql.OpenAndPrepare error:", err)
+		return
+	}
+	defer db.Close()
+
+	psqlibcfg := democonfigs.CfgPSQLIB
+	psqlibcfg.DB = &db
+	psqlibcfg.Logger = &lgr
+	psqlibcfg.NodeName = *nodename
+	if *pathaliases != "" {
+		psqlibcfg.PathAliases = strings.Split(*pathaliases, ",")
+	}
+	if *ctltrust != "" {
+		psqlibcfg.ControlTrust, err = ctlmsg.LoadTrustFile(*ctltrust)
+		if err != nil {
+			mlg.LogPrintln(CRITICAL, "failed loading control trust list:", err)
+			return
+		}
+	}
+	psqlibcfg.NGPGlobal = *ngp
+	psqlibcfg.ArchivedNNTPReplies = *archreplies
+	plcfg := nntppostlockmgr.DefaultConfig
+	plcfg.LockTimeout = *locktimeout
+	plcfg.WaitTimeout = *lockwait
+	psqlibcfg.PostLocks = &plcfg
+	if *thumbext {
+		psqlibcfg.TBuilder = extthm.DefaultConfig
+	}
+
+	dbib, err := psqlib.NewInitAndPrepare(psqlibcfg)
+	if err != nil {
+		mlg.LogPrintln(CRITICAL, "psqlib.NewInitAndPrepare error:", err)
+		return
+	}
+
+	pn := nntp.NewPushNotifier()
+	dbib.PushNotifier = pn
+
+	feed := nntpfeedcfg.NewFeed(fcfg, dbib, feedDB{dbib}, pn, lgr)
+	err = feed.Start()
+	if err != nil {
+		mlg.LogPrintln(CRITICAL, "feed.Start error:", err)
+		return
+	}
+	dbib.ClearPullerDBs()
+	dbib.ClearPusherDBs()
+
+	if *expiry > 0 {
+		ecfg := psqlib.DefaultExpiryConfig
+		ecfg.Interval = *expiry
+		stopExpiry, e := dbib.StartExpiry(ecfg)
+		if e != nil {
+			mlg.LogPrintln(CRITICAL, "dbib.StartExpiry error:", e)
+			feed.Close()
+			return
+		}
+		defer stopExpiry()
+	}
+
+	if *archive > 0 {
+		acfg := psqlib.DefaultArchiveConfig
+		acfg.Interval = *archive
+		stopArchiver, e := dbib.StartArchiver(acfg)
+		if e != nil {
+			mlg.LogPrintln(CRITICAL, "dbib.StartArchiver error:", e)
+			feed.Close()
+			return
+		}
+		defer stopArchiver()
+	}
+
+	// reload on SIGHUP, stats on SIGUSR1, graceful shutdown on SIGTERM
+	sigc := make(chan os.Signal, 2)
+	signal.Notify(sigc,
+		os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1)
+	for {
+		s := <-sigc
+		switch s {
+		case syscall.SIGHUP:
+			mlg.LogPrint(NOTICE, "reloading feed config")
+			ncfg, e := nntpfeedcfg.ParseCfgFile(*feedcfg)
+			if e != nil {
+				mlg.LogPrintln(ERROR, "failed loading feed config:", e)
+				continue
+			}
+			e = feed.Reload(ncfg)
+			if e != nil {
+				mlg.LogPrintln(ERROR, "failed applying feed config:", e)
+				continue
+			}
+			dbib.ClearPullerDBs()
+			dbib.ClearPusherDBs()
+			mlg.LogPrint(NOTICE, "feed config reloaded")
+		case syscall.SIGUSR1:
+			for name, st := range feed.Stats() {
+				mlg.LogPrintf(NOTICE,
+					"server %q: conns=%d rejected=%d ratelimited=%d "+
+						"byprefix=%v bypeer=%v",
+					name, st.Conns, st.RejectedConns, st.RateLimited,
+					st.ConnsByPrefix, st.ConnsByPeer)
+			}
+			for name, st := range feed.PullStates() {
+				mlg.LogPrintf(NOTICE,
+					"peer %q: running=%v lastok=%v failures=%d "+
+						"lasterr=%q next=%v",
+					name, st.Running, st.LastSuccess, st.Failures,
+					st.LastError, st.NextAttempt)
+			}
+		case os.Interrupt, syscall.SIGTERM:
+			signal.Reset(os.Interrupt, syscall.SIGTERM)
+			fmt.Fprintf(os.Stderr, "killing server\n")
+			feed.Close()
+			return
+		}
+	}
+}
@@ -25,6 +25,8 @@ func main() {
 	thumbext := flag.Bool("extthm", false, "use extthm")
 	nodename := flag.String("nodename", "nekochan", "node name. must be non-empty")
 	ngp := flag.String("ngp", "*", "new group policy: which groups can be automatically added?")
+	interval := flag.Duration("interval", nntp.DefaultPullSchedulerCfg.Interval, "wait between pulls")
+	maxbackoff := flag.Duration("maxbackoff", nntp.DefaultPullSchedulerCfg.MaxBackoff, "max wait after failed pulls")
 
 	flag.Parse()
 
@@ -99,6 +101,13 @@ func main() {
 	}
 	dbib.ClearPullerDBs()
 
+	scfg := nntp.PullSchedulerCfg{
+		Interval:   *interval,
+		MaxBackoff: *maxbackoff,
+	}
+	sched := nntp.NewPullScheduler(lgr)
+	defer sched.Close()
+
 	j := 0
 	for i := 0; i+1 < len(args); i += 2 {
 		addr := args[i+1]
@@ -109,18 +118,24 @@ func main() {
 			return
 		}
 
-		go func(x int) {
-			mlg.LogPrintf(
-				NOTICE, "starting nntp puller no. %d with proto(%s) host(%s)", x, proto, host)
-			pullers[x].Run(d, proto, host)
-			mlg.LogPrintf(NOTICE, "nntp puller no. %d terminated", x)
-		}(j)
+		mlg.LogPrintf(
+			NOTICE, "starting nntp puller no. %d with proto(%s) host(%s)", j, proto, host)
+		e = sched.Add(args[i], pullers[j], d, proto, host, scfg)
+		if e != nil {
+			mlg.LogPrintf(CRITICAL, "puller %d: %v", j, e)
+			return
+		}
 
 		j++
 	}
 
-	// XXX
+	// report peer states periodically
 	for {
-		time.Sleep(30 * time.Second)
+		time.Sleep(5 * time.Minute)
+		for name, st := range sched.States() {
+			mlg.LogPrintf(INFO,
+				"peer %q: lastok=%v failures=%d lasterr=%q next=%v",
+				name, st.LastSuccess, st.Failures, st.LastError, st.NextAttempt)
+		}
 	}
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"nksrv/lib/app/base/psql"
+	"nksrv/lib/app/demo/democonfigs"
+	"nksrv/lib/app/demo/demohelper"
+	"nksrv/lib/app/psqlib"
+	"nksrv/lib/nntp"
+	"nksrv/lib/utils/logx"
+	. "nksrv/lib/utils/logx"
+	fl "nksrv/lib/utils/logx/filelogger"
+	"nksrv/lib/utils/xdialer"
+)
+
+func main() {
+	var err error
+	// initialize flags
+	dbconnstr := flag.String("dbstr", "", "postgresql connection string")
+	nodename := flag.String("nodename", "nekochan", "node name. must be non-empty")
+	workers := flag.Int("workers", 1, "number of connections per peer")
+
+	flag.Parse()
+
+	// logger
+	lgr, err := fl.NewFileLogger(os.Stderr, DEBUG, fl.ColorAuto)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fl.NewFileLogger error: %v\n", err)
+		return
+	}
+	mlg := NewLogToX(lgr, "main")
+
+	err = demohelper.LoadMIMEDB()
+	if err != nil {
+		mlg.LogPrintln(logx.CRITICAL, "LoadMIMEDB err:", err)
+		return
+	}
+
+	psqlcfg := psql.DefaultConfig
+
+	psqlcfg.Logger = lgr
+	psqlcfg.ConnStr = *dbconnstr
+
+	db, err := psql.OpenAndPrepare(psqlcfg)
+	if err != nil {
+		mlg.LogPrintln(logx.CRITICAL, "psql.OpenAndPrepare error:", err)
+		return
+	}
+	defer db.Close()
+
+	psqlibcfg := democonfigs.CfgPSQLIB
+	psqlibcfg.DB = &db
+	psqlibcfg.Logger = &lgr
+	psqlibcfg.NodeName = *nodename
+
+	dbib, err := psqlib.NewInitAndPrepare(psqlibcfg)
+	if err != nil {
+		mlg.LogPrintln(CRITICAL, "psqlib.NewInitAndPrepare error:", err)
+		return
+	}
+
+	args := flag.Args()
+	if len(args) < 2 {
+		mlg.LogPrintln(CRITICAL, "no push hosts specified")
+		return
+	}
+	if len(args)%2 != 0 {
+		mlg.LogPrintln(CRITICAL, "odd argument count")
+		return
+	}
+
+	if *workers <= 0 {
+		mlg.LogPrintln(CRITICAL, "invalid workers count")
+		return
+	}
+
+	var pushers []*nntp.NNTPPusher
+	for i := 0; i+1 < len(args); i += 2 {
+		key := args[i]
+
+		dbpusher, err := dbib.NewPusherDB(key)
+		if err != nil {
+			mlg.LogPrintln(CRITICAL, "dbib.NewPusherDB failed:", err)
+			return
+		}
+		for w := 0; w < *workers; w++ {
+			pusher := nntp.NewNNTPPusher(dbpusher, lgr, w, *workers)
+			pushers = append(pushers, pusher)
+		}
+	}
+	dbib.ClearPusherDBs()
+
+	j := 0
+	for i := 0; i+1 < len(args); i += 2 {
+		addr := args[i+1]
+
+		d, proto, host, e := xdialer.XDial(addr)
+		if e != nil {
+			mlg.LogPrintf(CRITICAL, "dial %d fail: %v", i/2, e)
+			return
+		}
+
+		for w := 0; w < *workers; w++ {
+			go func(x int) {
+				mlg.LogPrintf(
+					NOTICE, "starting nntp pusher no. %d with proto(%s) host(%s)", x, proto, host)
+				pushers[x].Run(d, proto, host)
+				mlg.LogPrintf(NOTICE, "nntp pusher no. %d terminated", x)
+			}(j)
+
+			j++
+		}
+	}
+
+	// XXX
+	for {
+		time.Sleep(30 * time.Second)
+	}
+}
@@ -93,6 +93,19 @@ func NewAPIRouter(cfg Cfg) http.Handler {
 				cfg.Renderer.ServeThreadCatalog(w, r, b)
 			})))
 
+	h_bcontent.Handle("/archive/pages/{{n:[0-9]+}}", false,
+		handler.NewMethod().Handle("GET", http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				b := r.Context().Value("b").(string)
+				sn := r.Context().Value("n").(string)
+				n, e := strconv.ParseUint(sn, 10, 32)
+				if e != nil {
+					httpErrorBadRequest(w, r)
+					return
+				}
+				cfg.Renderer.ServeThreadArchive(w, r, b, uint32(n))
+			})))
+
 	h_threads := handler.NewMethod().Handle("GET", http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			b := r.Context().Value("b").(string)
@@ -122,6 +135,100 @@ func NewAPIRouter(cfg Cfg) http.Handler {
 	}
 	h_bcontent.Handle("/threads/{{t}}", false, h_threads)
 
+	if cfg.WebPostProvider != nil {
+		h_bcontent.Handle("/queue", false,
+			handler.NewMethod().Handle("GET", http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					b := r.Context().Value("b").(string)
+
+					var q ib0.IBModQueue
+					e := cfg.WebPostProvider.IBGetModQueue(w, r, &q, b)
+					if e != nil {
+						e, code := ib0.UnpackWebPostError(e)
+						http.Error(w, e.Error(), code)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(&q)
+				})))
+
+		h_bcontent.Handle("/queue/{{m}}", false,
+			handler.NewMethod().
+				Handle("POST", http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						b := r.Context().Value("b").(string)
+						m := r.Context().Value("m").(string)
+
+						e := cfg.WebPostProvider.IBApproveQueuedPost(w, r, b, m)
+						if e != nil {
+							e, code := ib0.UnpackWebPostError(e)
+							http.Error(w, e.Error(), code)
+							return
+						}
+
+						http.Error(w, "approved", 200)
+					})).
+				Handle("DELETE", http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						b := r.Context().Value("b").(string)
+						m := r.Context().Value("m").(string)
+
+						e := cfg.WebPostProvider.IBRejectQueuedPost(w, r, b, m)
+						if e != nil {
+							e, code := ib0.UnpackWebPostError(e)
+							http.Error(w, e.Error(), code)
+							return
+						}
+
+						http.Error(w, "rejected", 200)
+					})))
+
+		h_bcontent.Handle("/posts/{{p}}", false,
+			handler.NewMethod().Handle("DELETE", http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					b := r.Context().Value("b").(string)
+					p := r.Context().Value("p").(string)
+
+					ct, _, e :=
+						mime.ParseMediaType(r.Header.Get("Content-Type"))
+					if e != nil {
+						http.Error(
+							w, fmt.Sprintf("failed to parse content type: %v", e),
+							http.StatusBadRequest)
+						return
+					}
+					if ct != "application/json" {
+						http.Error(w, "bad Content-Type", http.StatusBadRequest)
+						return
+					}
+
+					jd := json.NewDecoder(r.Body)
+					cancelinfo := struct {
+						Password string `json:"password"`
+						Key      string `json:"key"`
+					}{}
+					e = jd.Decode(&cancelinfo)
+					if e != nil {
+						http.Error(
+							w, fmt.Sprintf("failed to parse content: %v", e),
+							http.StatusBadRequest)
+						return
+					}
+
+					var code int
+					e = cfg.WebPostProvider.IBCancelPost(
+						w, r, b, p, cancelinfo.Password, cancelinfo.Key)
+					if e != nil {
+						e, code = ib0.UnpackWebPostError(e)
+						http.Error(w, e.Error(), code)
+						return
+					}
+
+					http.Error(w, "deleted", 200)
+				})))
+	}
+
 	if cfg.WebPostProvider != nil {
 		h_bcontent.Handle("/", false,
 			handler.NewMethod().Handle("POST", http.HandlerFunc(
@@ -271,6 +378,24 @@ func NewAPIRouter(cfg Cfg) http.Handler {
 	h.Handle("/overboard", true,
 		handler.NewMethod().Handle("GET", h_overboard))
 
+	h_modlog := handler.NewRegexPath()
+
+	h_modlog.Handle("/pages/{{n:[0-9]+}}", false, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			sn := r.Context().Value("n").(string)
+			n, e := strconv.ParseUint(sn, 10, 32)
+			if e != nil {
+				httpErrorBadRequest(w, r)
+				return
+			}
+			q := r.URL.Query()
+			cfg.Renderer.ServeModLogPage(
+				w, r, q.Get("board"), q.Get("mod"), uint32(n))
+		}))
+
+	h.Handle("/modlog", true,
+		handler.NewMethod().Handle("GET", h_modlog))
+
 	/*
 		if cfg.Auth != nil {
 			h.Handle("/auth/login", false, http.HandlerFunc(
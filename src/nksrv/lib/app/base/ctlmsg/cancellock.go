@@ -0,0 +1,105 @@
+package ctlmsg
+
+// Cancel-Lock and Cancel-Key as specified in {RFC 8315}
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"hash"
+	"strings"
+)
+
+// hash algorithms we can verify with.
+// sha1 is only there for compatibility, we don't generate it
+var cancelHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha224": sha256.New224,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+const cancelHash = "sha256"
+
+// MakeCancelKey derives Cancel-Key value for article from secret
+// {RFC 8315 4}. fmsgid is full Message-ID, with angle brackets.
+func MakeCancelKey(secret []byte, fmsgid string) string {
+	m := hmac.New(sha256.New, secret)
+	_, _ = m.Write([]byte(fmsgid))
+	return cancelHash + ":" + base64.StdEncoding.EncodeToString(m.Sum(nil))
+}
+
+// PasswordCancelSecret makes secret for MakeCancelKey out of poster password.
+// It's keyed with server secret, so that Cancel-Lock, which is published,
+// can't be used to brute-force password offline.
+func PasswordCancelSecret(serverSecret []byte, password string) []byte {
+	m := hmac.New(sha256.New, serverSecret)
+	_, _ = m.Write([]byte(password))
+	return m.Sum(nil)
+}
+
+func splitCancelEntry(s string) (scheme, val string, ok bool) {
+	i := strings.IndexByte(s, ':')
+	if i <= 0 || i == len(s)-1 {
+		return
+	}
+	return strings.ToLower(s[:i]), s[i+1:], true
+}
+
+func cancelLockFor(scheme, kval string) (string, bool) {
+	hf := cancelHashes[scheme]
+	if hf == nil {
+		return "", false
+	}
+	h := hf()
+	_, _ = h.Write([]byte(kval))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), true
+}
+
+// MakeCancelLock makes Cancel-Lock value matching Cancel-Key value.
+// It returns empty string if key is malformed.
+func MakeCancelLock(ckey string) string {
+	scheme, kval, ok := splitCancelEntry(ckey)
+	if !ok {
+		return ""
+	}
+	lval, ok := cancelLockFor(scheme, kval)
+	if !ok {
+		return ""
+	}
+	return scheme + ":" + lval
+}
+
+// VerifyCancelKey tells whether any of Cancel-Key header values
+// matches any of Cancel-Lock header values.
+// Each header value may have multiple space-separated entries.
+func VerifyCancelKey(locks, keys []string) bool {
+	for _, kh := range keys {
+		for _, k := range strings.Fields(kh) {
+			scheme, kval, ok := splitCancelEntry(k)
+			if !ok {
+				continue
+			}
+			want, ok := cancelLockFor(scheme, kval)
+			if !ok {
+				continue
+			}
+			for _, lh := range locks {
+				for _, l := range strings.Fields(lh) {
+					ls, lval, ok := splitCancelEntry(l)
+					if ok && ls == scheme &&
+						subtle.ConstantTimeCompare(
+							[]byte(lval), []byte(want)) == 1 {
+
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
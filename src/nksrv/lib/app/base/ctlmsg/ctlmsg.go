@@ -0,0 +1,207 @@
+package ctlmsg
+
+// control messages as specified in {RFC 5537} section 5
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"nksrv/lib/nntp"
+	mm "nksrv/lib/utils/minimail"
+	au "nksrv/lib/utils/text/asciiutils"
+)
+
+const (
+	VerbCancel      = "cancel"
+	VerbNewGroup    = "newgroup"
+	VerbRmGroup     = "rmgroup"
+	VerbCheckGroups = "checkgroups"
+)
+
+var ErrUnsupported = errors.New("unsupported control message")
+
+// Control is parsed value of Control header.
+type Control struct {
+	Verb string // lowercase
+	Args []string
+}
+
+func validGroup(s string) bool {
+	return nntp.ValidGroupSlice([]byte(s))
+}
+
+// Parse parses Control header value.
+// It returns ErrUnsupported for verbs we don't process.
+func Parse(s string) (c Control, err error) {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		err = errors.New("empty control message")
+		return
+	}
+	c.Verb = strings.ToLower(f[0])
+	c.Args = f[1:]
+
+	nargs := func(min, max int) error {
+		if len(c.Args) < min || len(c.Args) > max {
+			return fmt.Errorf("%s: wrong number of arguments", c.Verb)
+		}
+		return nil
+	}
+
+	switch c.Verb {
+	case VerbCancel:
+		if err = nargs(1, 1); err != nil {
+			return
+		}
+		if !mm.ValidMessageIDStr(mm.TFullMsgIDStr(c.Args[0])) {
+			err = fmt.Errorf("cancel: invalid Message-ID %q", c.Args[0])
+			return
+		}
+	case VerbNewGroup:
+		if err = nargs(1, 2); err != nil {
+			return
+		}
+		if !validGroup(c.Args[0]) {
+			err = fmt.Errorf("newgroup: invalid group %q", c.Args[0])
+			return
+		}
+		if len(c.Args) > 1 && !strings.EqualFold(c.Args[1], "moderated") {
+			err = fmt.Errorf("newgroup: unknown argument %q", c.Args[1])
+			return
+		}
+	case VerbRmGroup:
+		if err = nargs(1, 1); err != nil {
+			return
+		}
+		if !validGroup(c.Args[0]) {
+			err = fmt.Errorf("rmgroup: invalid group %q", c.Args[0])
+			return
+		}
+	case VerbCheckGroups:
+		// checkgroups [<chkscope>] [#<chksernr>]
+		if err = nargs(0, 2); err != nil {
+			return
+		}
+		for i, a := range c.Args {
+			if a[0] == '#' {
+				if i != len(c.Args)-1 || !isDigits(a[1:]) {
+					err = fmt.Errorf("checkgroups: invalid serial %q", a)
+					return
+				}
+			} else if i != 0 || !validScope(a) {
+				err = fmt.Errorf("checkgroups: invalid scope %q", a)
+				return
+			}
+		}
+	default:
+		err = ErrUnsupported
+	}
+	return
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// scope is comma-separated list of hierarchies, possibly negated
+func validScope(s string) bool {
+	for _, h := range strings.Split(s, ",") {
+		h = strings.TrimPrefix(h, "!")
+		if !validGroup(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// Group returns group control message is about,
+// or empty string if it's not about single group.
+func (c Control) Group() string {
+	switch c.Verb {
+	case VerbNewGroup, VerbRmGroup:
+		return c.Args[0]
+	}
+	return ""
+}
+
+// Moderated tells whether newgroup asks for moderated group.
+func (c Control) Moderated() bool {
+	return c.Verb == VerbNewGroup && len(c.Args) > 1
+}
+
+// Target returns Message-ID cancel message refers to.
+func (c Control) Target() mm.TFullMsgIDStr {
+	if c.Verb != VerbCancel {
+		return ""
+	}
+	return mm.TFullMsgIDStr(c.Args[0])
+}
+
+// InScope tells whether group is covered by checkgroups scope.
+// Without scope all groups are covered.
+func (c Control) InScope(group string) bool {
+	if c.Verb != VerbCheckGroups ||
+		len(c.Args) == 0 || c.Args[0][0] == '#' {
+
+		return true
+	}
+	res := false
+	for _, h := range strings.Split(c.Args[0], ",") {
+		neg := strings.HasPrefix(h, "!")
+		if neg {
+			h = h[1:]
+		}
+		if group == h ||
+			(strings.HasPrefix(group, h) && group[len(h)] == '.') {
+
+			res = !neg
+		}
+	}
+	return res
+}
+
+// GroupInfo is entry of newsgroups list,
+// as found in checkgroups and newgroup bodies.
+type GroupInfo struct {
+	Name        string
+	Description string
+}
+
+// ParseGroupList reads lines consisting of group name and description.
+// Lines not starting with valid group name and
+// introductory lines ending with colon are skipped,
+// so it can be used on newgroup bodies too.
+func ParseGroupList(r io.Reader) (l []GroupInfo, err error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasSuffix(au.TrimWSString(line), ":") {
+			continue
+		}
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			i = len(line)
+		}
+		name := line[:i]
+		if !validGroup(name) {
+			continue
+		}
+		l = append(l, GroupInfo{
+			Name:        name,
+			Description: au.TrimWSString(line[i:]),
+		})
+	}
+	err = s.Err()
+	return
+}
@@ -0,0 +1,172 @@
+package ctlmsg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{
+		"cancel <abc@example.org>",
+		"Cancel   <abc@example.org> ",
+		"newgroup alt.test",
+		"newgroup alt.test Moderated",
+		"rmgroup alt.test",
+		"checkgroups",
+		"checkgroups alt,!alt.binaries #1234",
+		"checkgroups #1",
+	} {
+		if _, err := Parse(s); err != nil {
+			t.Errorf("Parse(%q) err: %v", s, err)
+		}
+	}
+	for _, s := range []string{
+		"",
+		"cancel",
+		"cancel abc@example.org",
+		"newgroup",
+		"newgroup alt.test unmoderated",
+		"rmgroup alt.test extra",
+		"checkgroups #12a",
+		"checkgroups #1 alt",
+	} {
+		if _, err := Parse(s); err == nil || err == ErrUnsupported {
+			t.Errorf("Parse(%q) expected error", s)
+		}
+	}
+	if _, err := Parse("sendsys"); err != ErrUnsupported {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+
+	c, _ := Parse("checkgroups alt,!alt.binaries")
+	for g, exp := range map[string]bool{
+		"alt":              true,
+		"alt.test":         true,
+		"alt.binaries.foo": false,
+		"altx.test":        false,
+		"comp.lang":        false,
+	} {
+		if c.InScope(g) != exp {
+			t.Errorf("InScope(%q) expected %v", g, exp)
+		}
+	}
+}
+
+func TestParseGroupList(t *testing.T) {
+	l, err := ParseGroupList(strings.NewReader(
+		"For your newsgroups file:\n" +
+			"alt.test\tTesting stuff.\n" +
+			"alt.misc  Misc (Moderated)\n" +
+			"alt.nodesc\n"))
+	if err != nil {
+		t.Fatalf("ParseGroupList err: %v", err)
+	}
+	exp := []GroupInfo{
+		{"alt.test", "Testing stuff."},
+		{"alt.misc", "Misc (Moderated)"},
+		{"alt.nodesc", ""},
+	}
+	if !reflect.DeepEqual(l, exp) {
+		t.Errorf("got %q, expected %q", l, exp)
+	}
+}
+
+func TestTrustList(t *testing.T) {
+	const key = "3b6a27bcceb6a42d62a3a8d02a6f0d73653215771de243a63ac048a18b59da29"
+	tl, err := MakeTrustList([]TrustEntry{
+		{Key: key, Groups: "*,!local.*"},
+		{Peer: "peer1", Verbs: "cancel"},
+	})
+	if err != nil {
+		t.Fatalf("MakeTrustList err: %v", err)
+	}
+	if !tl.Enabled() {
+		t.Errorf("expected enabled")
+	}
+	upkey := strings.ToUpper(key)
+	for _, tc := range []struct {
+		verb, group, key, peer string
+		exp                    bool
+	}{
+		{VerbNewGroup, "alt.test", upkey, "", true},
+		{VerbNewGroup, "local.test", upkey, "", false},
+		{VerbCancel, "local.test", "", "peer1", true},
+		{VerbRmGroup, "alt.test", "", "peer1", false},
+		{VerbCancel, "alt.test", "", "peer2", false},
+		{VerbCancel, "alt.test", "", "", false},
+	} {
+		if tl.Allowed(tc.verb, tc.group, tc.key, tc.peer) != tc.exp {
+			t.Errorf("Allowed(%+v) expected %v", tc, tc.exp)
+		}
+	}
+
+	for _, l := range [][]TrustEntry{
+		{{}},
+		{{Key: key, Peer: "x"}},
+		{{Key: "abcd"}},
+		{{Peer: "x", Verbs: "sendsys"}},
+		{{Peer: "x", Groups: "a["}},
+	} {
+		if _, err = MakeTrustList(l); err == nil {
+			t.Errorf("MakeTrustList(%+v) expected error", l)
+		}
+	}
+	var zero TrustList
+	if zero.Enabled() {
+		t.Errorf("zero TrustList should be disabled")
+	}
+}
+
+func TestCancelLock(t *testing.T) {
+	// example from {RFC 8315 3.1}
+	if !VerifyCancelKey(
+		[]string{"sha256:s/pmK/3grrz++29ce2/mQydzJuc7iqHn1nqcJiQTPMc="},
+		[]string{"sha256:qv1VXHYiCGjkX/N1nhfYKcAeUn8bCVhrWhoKuBSnpMA="}) {
+
+		t.Errorf("RFC 8315 example didn't verify")
+	}
+
+	key := MakeCancelKey([]byte("secret"), "<abc@example.org>")
+	if key != MakeCancelKey([]byte("secret"), "<abc@example.org>") {
+		t.Errorf("MakeCancelKey not deterministic")
+	}
+	if key == MakeCancelKey([]byte("secret"), "<abd@example.org>") {
+		t.Errorf("MakeCancelKey doesn't depend on Message-ID")
+	}
+	lock := MakeCancelLock(key)
+	if lock == "" {
+		t.Fatalf("MakeCancelLock(%q) failed", key)
+	}
+	if !VerifyCancelKey([]string{"sha1:AAAA " + lock}, []string{key}) {
+		t.Errorf("own lock didn't verify")
+	}
+	other := MakeCancelKey([]byte("other"), "<abc@example.org>")
+	for _, tc := range [][2][]string{
+		{{lock}, {other}},
+		{{lock}, {""}},
+		{{lock}, {"sha512:" + key[len("sha256:"):]}},
+		{{}, {key}},
+	} {
+		if VerifyCancelKey(tc[0], tc[1]) {
+			t.Errorf("VerifyCancelKey(%q, %q) expected false", tc[0], tc[1])
+		}
+	}
+	if MakeCancelLock("md5:abc") != "" || MakeCancelLock("sha256:") != "" {
+		t.Errorf("MakeCancelLock accepted bad key")
+	}
+
+	pwkey := MakeCancelKey(
+		PasswordCancelSecret([]byte("server"), "secret"), "<abc@example.org>")
+	if pwkey == key {
+		t.Errorf("password key same as unkeyed one")
+	}
+	if pwkey != MakeCancelKey(
+		PasswordCancelSecret([]byte("server"), "secret"), "<abc@example.org>") {
+		t.Errorf("password key not deterministic")
+	}
+	if pwkey == MakeCancelKey(
+		PasswordCancelSecret([]byte("server2"), "secret"), "<abc@example.org>") {
+		t.Errorf("password key doesn't depend on server secret")
+	}
+}
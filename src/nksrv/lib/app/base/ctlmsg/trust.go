@@ -0,0 +1,131 @@
+package ctlmsg
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/crypto/ed25519"
+
+	"nksrv/lib/nntp"
+)
+
+// TrustEntry describes who may issue which control messages.
+// Exactly one of Key and Peer must be set.
+type TrustEntry struct {
+	Key    string `toml:"key"`    // hex-encoded ed25519 public key
+	Peer   string `toml:"peer"`   // name of feed peer which sent message
+	Verbs  string `toml:"verbs"`  // comma-separated, all if empty
+	Groups string `toml:"groups"` // wildmat of affected groups, all if empty
+}
+
+type trustEnt struct {
+	key    string
+	peer   string
+	verbs  []string
+	groups nntp.Wildmat
+}
+
+// TrustList decides whether control messages should be acted upon.
+// Zero value trusts nobody and means control messages aren't processed.
+type TrustList struct {
+	ents []trustEnt
+}
+
+func MakeTrustList(l []TrustEntry) (tl TrustList, err error) {
+	for i, e := range l {
+		var te trustEnt
+		if (e.Key == "") == (e.Peer == "") {
+			err = fmt.Errorf("trust entry %d: exactly one of key and peer must be set", i)
+			return
+		}
+		if e.Key != "" {
+			k, ee := hex.DecodeString(e.Key)
+			if ee != nil || len(k) != ed25519.PublicKeySize {
+				err = fmt.Errorf("trust entry %d: invalid ed25519 key %q", i, e.Key)
+				return
+			}
+			// same form as signature verifier gives
+			te.key = fmt.Sprintf("%X", k)
+		}
+		te.peer = e.Peer
+		if e.Verbs != "" {
+			for _, v := range strings.Split(e.Verbs, ",") {
+				v = strings.ToLower(strings.TrimSpace(v))
+				switch v {
+				case VerbCancel, VerbNewGroup, VerbRmGroup, VerbCheckGroups:
+				default:
+					err = fmt.Errorf("trust entry %d: unsupported verb %q", i, v)
+					return
+				}
+				te.verbs = append(te.verbs, v)
+			}
+		}
+		if e.Groups != "" {
+			if !nntp.ValidWildmatStr(e.Groups) {
+				err = fmt.Errorf("trust entry %d: invalid groups wildmat %q", i, e.Groups)
+				return
+			}
+			te.groups = nntp.CompileWildmatStr(e.Groups)
+		}
+		tl.ents = append(tl.ents, te)
+	}
+	return
+}
+
+type trustFile struct {
+	Trust []TrustEntry `toml:"trust"`
+}
+
+// LoadTrustFile reads TOML file consisting of [[trust]] entries.
+func LoadTrustFile(fn string) ([]TrustEntry, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var tf trustFile
+	if _, err = toml.Decode(string(b), &tf); err != nil {
+		return nil, err
+	}
+	return tf.Trust, nil
+}
+
+// Enabled tells whether control messages should be processed at all.
+func (tl TrustList) Enabled() bool {
+	return len(tl.ents) != 0
+}
+
+// Allowed tells whether control message with verb affecting group
+// is allowed when signed by key (hex, may be empty) or sent by peer
+// (may be empty).
+func (tl TrustList) Allowed(verb, group, key, peer string) bool {
+	for i := range tl.ents {
+		e := &tl.ents[i]
+		if e.key != "" {
+			if key == "" || !strings.EqualFold(e.key, key) {
+				continue
+			}
+		} else if peer == "" || e.peer != peer {
+			continue
+		}
+		if e.verbs != nil && !hasVerb(e.verbs, verb) {
+			continue
+		}
+		if e.groups != nil && !e.groups.CheckString(group) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func hasVerb(l []string, v string) bool {
+	for _, x := range l {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
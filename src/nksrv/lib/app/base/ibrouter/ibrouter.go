@@ -174,6 +174,26 @@ func NewIBRouter(cfg Cfg) (http.Handler, *IBRouterCtl) {
 					ServeOverboardCatalog(w, r)
 			}))
 
+		h_get_modlog := handler.NewRegexPath()
+		h_get.Handle("/_modlog", true, h_get_modlog)
+		h_get_modlog.Handle("/{{pn:[0-9]*}}", false,
+			http.HandlerFunc(func(
+				w http.ResponseWriter, r *http.Request) {
+
+				pn := r.Context().Value("pn").(string)
+				ok, pni := handlePageNum(w, r, pn)
+				if !ok {
+					return
+				}
+
+				q := r.URL.Query()
+				b, m := q.Get("board"), q.Get("mod")
+				log.LogPrintf(DEBUG, "modlog-page %q %q %d", b, m, pni)
+
+				c.GetHTMLRenderer().
+					ServeModLogPage(w, r, b, m, pni)
+			}))
+
 		h_getr := handler.NewRegexPath()
 		h_get.Fallback(h_getr)
 
@@ -199,6 +219,19 @@ func NewIBRouter(cfg Cfg) (http.Handler, *IBRouterCtl) {
 				log.LogPrintf(DEBUG, "board-catalog %q", b)
 				c.GetHTMLRenderer().ServeThreadCatalog(w, r, b)
 			}))
+		h_getr_archive := handler.NewRegexPath()
+		h_getr_board.Handle("/archive", true, h_getr_archive)
+		h_getr_archive.Handle("/{{pn:[0-9]*}}", false, http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				b := r.Context().Value("b").(string)
+				pn := r.Context().Value("pn").(string)
+				ok, pni := handlePageNum(w, r, pn)
+				if !ok {
+					return
+				}
+				log.LogPrintf(DEBUG, "board-archive %q %d", b, pni)
+				c.GetHTMLRenderer().ServeThreadArchive(w, r, b, pni)
+			}))
 
 		h_getr_board.Handle("/thread/{{t}}(?:/[^/]*)?", false,
 			http.HandlerFunc(func(
@@ -289,6 +322,43 @@ func NewIBRouter(cfg Cfg) (http.Handler, *IBRouterCtl) {
 				}
 			}))
 		h.Handle("/_post/post", false, h_post)
+
+		// author-initiated deletion, small urlencoded form
+		h_delete := handler.NewMethod()
+		h_delete.Handle("POST", http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				err := r.ParseForm()
+				if err != nil {
+					http.Error(w,
+						fmt.Sprintf("error parsing form: %v", err),
+						http.StatusBadRequest)
+					return
+				}
+				board := r.PostForm.Get("board")
+				post := r.PostForm.Get("post")
+				if board == "" || post == "" {
+					http.Error(w,
+						"invalid form params",
+						http.StatusBadRequest)
+					return
+				}
+
+				log.LogPrintf(DEBUG, "post-delete b:%q p:%q", board, post)
+
+				err = cfg.WebPostProvider.IBCancelPost(
+					w, r, board, post,
+					r.PostForm.Get(ib0.IBWebFormTextPassword),
+					r.PostForm.Get(ib0.IBWebFormTextCancelKey))
+				if err != nil {
+					var code int
+					err, code = ib0.UnpackWebPostError(err)
+					http.Error(w, err.Error(), code)
+					return
+				}
+
+				http.Error(w, "deleted", http.StatusOK)
+			}))
+		h.Handle("/_post/delete", false, h_delete)
 	}
 
 	if cfg.WebCaptcha != nil {
@@ -345,6 +345,23 @@ func (IBProviderDemo) IBGetThreadCatalog(
 	return nil, 0
 }
 
+func (IBProviderDemo) IBGetThreadArchive(
+	archive *webib0.IBThreadArchive, board string, num uint32) (error, int) {
+
+	if board != "test" {
+		return errors.New("board does not exist"), http.StatusNotFound
+	}
+	if num > 0 {
+		return errors.New("page does not exist"), http.StatusNotFound
+	}
+	// nothing got archived in demo
+	*archive = webib0.IBThreadArchive{
+		Board:     testThreadCatalog.Board,
+		Available: 1,
+	}
+	return nil, 0
+}
+
 func (IBProviderDemo) IBGetOverboardCatalog(
 	page *webib0.IBOverboardCatalog) (error, int) {
 
@@ -364,3 +381,14 @@ func (IBProviderDemo) IBGetThread(
 	*thread = testThread
 	return nil, 0
 }
+
+func (IBProviderDemo) IBGetModLogPage(
+	page *webib0.IBModLogPage, board, mod string, num uint32) (error, int) {
+
+	if num > 0 {
+		return errors.New("page does not exist"), http.StatusNotFound
+	}
+	// demo has no mods
+	*page = webib0.IBModLogPage{Board: board, Mod: mod}
+	return nil, 0
+}
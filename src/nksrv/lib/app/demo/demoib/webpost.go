@@ -35,6 +35,7 @@ var textFieldFunc = form.FieldsCheckFunc([]string{
 	ib0.IBWebFormTextName,
 	ib0.IBWebFormTextMessage,
 	ib0.IBWebFormTextOptions,
+	ib0.IBWebFormTextPassword,
 })
 
 // FIXME: this probably in future should go thru some sort of abstractation
@@ -229,4 +230,46 @@ func (IBProviderDemo) IBDeletePost(
 	return
 }
 
+func (IBProviderDemo) IBCancelPost(
+	w http.ResponseWriter, r *http.Request,
+	board, post, password, ckey string) (err error) {
+
+	if board != "test" {
+		return webNotFound(errors.New("board not found"))
+	}
+	if len(post) < 4 || post[:4] != "0123" {
+		return webNotFound(errors.New("post not found"))
+	}
+	if password != "test" && ckey != "test" {
+		return &ib0.WebPostError{
+			Err: errors.New("wrong key"), Code: http.StatusForbidden}
+	}
+	return
+}
+
+func (IBProviderDemo) IBGetModQueue(
+	w http.ResponseWriter, r *http.Request, q *ib0.IBModQueue, board string) (
+	err error) {
+
+	if board != "" && board != "test" {
+		return webNotFound(errors.New("board not found"))
+	}
+	q.Board = board
+	return
+}
+
+func (IBProviderDemo) IBApproveQueuedPost(
+	w http.ResponseWriter, r *http.Request, board, msgid string) (
+	err error) {
+
+	return webNotFound(errors.New("no such post in mod queue"))
+}
+
+func (IBProviderDemo) IBRejectQueuedPost(
+	w http.ResponseWriter, r *http.Request, board, msgid string) (
+	err error) {
+
+	return webNotFound(errors.New("no such post in mod queue"))
+}
+
 var _ ib0.IBWebPostProvider = IBProviderDemo{}
@@ -0,0 +1,20 @@
+package psqlib
+
+import (
+	"nksrv/lib/app/psqlib/internal/pimod"
+)
+
+type ArchiveConfig = pimod.ArchiveConfig
+
+var DefaultArchiveConfig = pimod.DefaultArchiveConfig
+
+// StartArchiver starts background job which archives threads
+// which fell off active pages of boards.
+func (sp *PSQLIB) StartArchiver(cfg ArchiveConfig) (stop func(), err error) {
+	j := pimod.NewArchiveJob(&sp.PSQLIB, cfg)
+	err = j.Start()
+	if err != nil {
+		return
+	}
+	return j.Stop, nil
+}
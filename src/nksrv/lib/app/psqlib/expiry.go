@@ -0,0 +1,20 @@
+package psqlib
+
+import (
+	"nksrv/lib/app/psqlib/internal/pimod"
+)
+
+type ExpiryConfig = pimod.ExpiryConfig
+
+var DefaultExpiryConfig = pimod.DefaultExpiryConfig
+
+// StartExpiry starts background job which deletes expired articles
+// and threads, and removes files nothing references anymore.
+func (sp *PSQLIB) StartExpiry(cfg ExpiryConfig) (stop func(), err error) {
+	j := pimod.NewExpiryJob(&sp.PSQLIB, cfg)
+	err = j.Start()
+	if err != nil {
+		return
+	}
+	return j.Stop, nil
+}
@@ -5,4 +5,8 @@ import "errors"
 var (
 	ErrNoSuchBoard  = errors.New("board does not exist")
 	ErrNoSuchThread = errors.New("thread does not exist")
+	ErrNoSuchPost   = errors.New("post does not exist")
+
+	ErrThreadArchived = errors.New("thread is archived")
+	ErrThreadLocked   = errors.New("thread is locked")
 )
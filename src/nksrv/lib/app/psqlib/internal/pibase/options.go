@@ -1,7 +1,9 @@
 package pibase
 
 type ThreadOptions struct {
-	///Locked     bool   `json:"locked,omitempty"`     // do not allow non-mod posts? or any posts at all??
+	Locked   bool `json:"locked,omitempty"`   // do not accept any replies
+	Sticky   bool `json:"sticky,omitempty"`   // keep on top of board pages and catalog
+	Autosage bool `json:"autosage,omitempty"` // do not bump anymore
 	///PostLimit  uint32 `json:"post_limit,omitempty"` // do not bump after thread has this much posts. is this behavior good?
 	BumpLimit uint32 `json:"bump_limit,omitempty"` // do not bump after thread has this much (non-sage or sage, doesn't matter) posts
 	FileLimit uint32 `json:"file_limit,omitempty"`
@@ -9,6 +11,7 @@ type ThreadOptions struct {
 	// 8ch does not count OP images, and counts every post with files (files aren't distinctly counted, just whether post has file or not)
 	// from sum-of-all-files-sizes limit perspective this makes sense I guess
 	// current stuff won't count files individually and won't count OP
+	MaxAge uint32 `json:"max_age,omitempty"` // expire thread if it wasn't bumped for this much seconds. 0 - never
 }
 
 var DefaultThreadOptions = ThreadOptions{
@@ -3,14 +3,19 @@ package pibase
 // psql imageboard module
 
 import (
+	crand "crypto/rand"
 	"database/sql"
+	"io"
 
 	"golang.org/x/crypto/ed25519"
 
 	"nksrv/lib/app/base/altthumber"
+	"nksrv/lib/app/base/ctlmsg"
 	"nksrv/lib/app/base/psql"
 	"nksrv/lib/app/base/webcaptcha"
 	"nksrv/lib/mail/form"
+	"nksrv/lib/nntp"
+	"nksrv/lib/nntp/nntppostlockmgr"
 	"nksrv/lib/thumbnailer"
 	"nksrv/lib/utils/fs/cacheengine"
 	"nksrv/lib/utils/fs/fstore"
@@ -45,17 +50,34 @@ type PSQLIB struct {
 	TextPostParamFunc func(string) bool
 
 	Instance           string
+	PathIdentities     []string // Instance and its aliases, for loop detection
 	MaxArticleBodySize int64
 	WebCaptcha         *webcaptcha.WebCaptcha
 	WebFrontendKey     ed25519.PrivateKey
+	// Cancel-Keys derived from poster passwords are keyed with it,
+	// so that published Cancel-Lock can't be used to guess password
+	CancelSecret []byte
 
 	NGPGlobal    pigpolicy.NewGroupPolicy
 	NGPAnyPuller pigpolicy.NewGroupPolicy
 	NGPAnyServer pigpolicy.NewGroupPolicy
 
+	// who may issue control messages, processing is disabled if empty
+	ControlTrust ctlmsg.TrustList
+
+	// whether to accept replies to archived threads over NNTP
+	ArchivedNNTPReplies bool
+
 	StPrep [stMax]*sql.Stmt
 
 	PullerNonce int64
+	PusherNonce int64
+
+	// informs outgoing feeds about new articles, may be nil
+	PushNotifier *nntp.PushNotifier
+
+	// prevents concurrent transfers of the same article
+	PostLocks *nntppostlockmgr.PostLockMgr
 
 	NoFileSync bool
 }
@@ -67,10 +89,40 @@ func (sp *PSQLIB) Prepare() (err error) {
 	if err != nil {
 		return
 	}
+	err = sp.loadCancelSecret()
+	if err != nil {
+		return
+	}
+
+	return
+}
 
+// loadCancelSecret loads secret for Cancel-Keys,
+// making new one if database doesn't have it yet.
+func (sp *PSQLIB) loadCancelSecret() (err error) {
+	secret := make([]byte, 32)
+	if _, err = io.ReadFull(crand.Reader, secret); err != nil {
+		return
+	}
+	err = sp.StPrep[St_web_cancel_secret].QueryRow(secret).
+		Scan(&sp.CancelSecret)
+	if err != nil {
+		return sp.SQLError("cancel secret query", err)
+	}
 	return
 }
 
+// ControlPushGroup is name of pseudo group
+// control messages posted here are pushed in
+const ControlPushGroup = "control"
+
+// NotifyPush wakes up pushers after article got committed
+func (sp *PSQLIB) NotifyPush(msgid nntp.TCoreMsgIDStr, groups ...string) {
+	if sp.PushNotifier != nil {
+		sp.PushNotifier.NotifyArticle(msgid, groups)
+	}
+}
+
 func (sp *PSQLIB) Close() error {
 	return sp.closeStatements()
 }
@@ -18,6 +18,7 @@ const (
 
 	St_nntp_article_num_by_msgid
 	St_nntp_article_msgid_by_num
+	St_nntp_article_groups_by_msgid
 
 	St_nntp_article_get_gpid
 
@@ -35,6 +36,10 @@ const (
 
 	St_nntp_listactive_all
 	St_nntp_listactive_one
+	St_nntp_listactivetimes_all
+	St_nntp_listactivetimes_one
+	St_nntp_listcounts_all
+	St_nntp_listcounts_one
 
 	St_nntp_over_msgid
 	St_nntp_over_range
@@ -56,12 +61,19 @@ const (
 	St_web_thread_list_page
 	St_web_overboard_page
 	St_web_thread_catalog
+	St_web_thread_archive
 	St_web_overboard_catalog
 	St_web_thread
+	St_web_modlog
+	St_web_modqueue
 
 	St_web_prepost_newthread
 	St_web_prepost_newpost
 
+	St_web_post_msgid_by_pname
+
+	St_web_cancel_secret
+
 	// post
 
 	St_post_newthread_sb_nf
@@ -78,6 +90,9 @@ const (
 	St_post_newreply_sb_mf
 	St_post_newreply_mb_mf
 
+	St_post_set_expires
+	St_post_superseded_info
+
 	// various modification
 
 	St_mod_ref_write
@@ -87,9 +102,29 @@ const (
 	St_mod_autoregister_mod
 	St_mod_delete_by_msgid
 	St_mod_ban_by_msgid
+	St_mod_bban_by_msgid
+	St_mod_bdelete_by_msgid
+	St_mod_unban_by_msgid
+	St_mod_delete_board_posts
+	St_mod_delete_board
+	St_mod_cancel_lock_by_msgid
+	St_mod_boards_by_msgid
+	St_mod_control_history_seen
+	St_mod_control_history_add
+	St_mod_control_history_prune
+	St_mod_puller_rejects_prune
+	St_mod_expire_find_expires
+	St_mod_expire_find_threads
+	St_mod_archive_threads
+	St_mod_delete_thread
+	St_mod_set_thread_opt
 	St_mod_bname_topts_by_tid
 	St_mod_refresh_bump_by_tid
 
+	St_mod_log_cmd
+	St_mod_modqueue_add
+	St_mod_modqueue_get
+	St_mod_modqueue_del
 	St_mod_set_mod_priv
 	St_mod_set_mod_priv_group
 	St_mod_unset_mod
@@ -114,6 +149,16 @@ const (
 
 	St_mod_joblist_refs_recalc_get
 
+	St_mod_joblist_files_deleted_get
+	St_mod_joblist_files_deleted_lock
+	St_mod_joblist_files_deleted_hold
+	St_mod_joblist_files_deleted_del
+
+	St_mod_joblist_fthumbs_deleted_get
+	St_mod_joblist_fthumbs_deleted_lock
+	St_mod_joblist_fthumbs_deleted_hold
+	St_mod_joblist_fthumbs_deleted_del
+
 	// puller specific
 
 	St_puller_get_last_newnews
@@ -124,6 +169,21 @@ const (
 	St_puller_set_group_id
 	St_puller_unset_group_id
 	St_puller_load_temp_groups
+	St_puller_reject_add
+	St_puller_reject_check
+	St_puller_set_rules
+
+	// pusher specific
+
+	St_pusher_register
+	St_pusher_clear_unused
+	St_pusher_get_groups
+	St_pusher_set_group_id
+	St_pusher_list_articles
+	St_pusher_get_control
+	St_pusher_set_control_id
+	St_pusher_list_control
+	St_pusher_get_control_article
 
 	stMax
 )
@@ -145,6 +205,7 @@ var stNames = [stMax]StReference{
 
 	{"nntp", "nntp_article_num_by_msgid"},
 	{"nntp", "nntp_article_msgid_by_num"},
+	{"nntp", "nntp_article_groups_by_msgid"},
 
 	{"nntp", "nntp_article_get_gpid"},
 
@@ -162,6 +223,10 @@ var stNames = [stMax]StReference{
 
 	{"nntp", "nntp_listactive_all"},
 	{"nntp", "nntp_listactive_one"},
+	{"nntp", "nntp_listactivetimes_all"},
+	{"nntp", "nntp_listactivetimes_one"},
+	{"nntp", "nntp_listcounts_all"},
+	{"nntp", "nntp_listcounts_one"},
 
 	{"nntp", "nntp_over_msgid"},
 	{"nntp", "nntp_over_range"},
@@ -183,12 +248,19 @@ var stNames = [stMax]StReference{
 	{"web", "web_thread_list_page"},
 	{"web", "web_overboard_page"},
 	{"web", "web_thread_catalog"},
+	{"web", "web_thread_archive"},
 	{"web", "web_overboard_catalog"},
 	{"web", "web_thread"},
+	{"web", "web_modlog"},
+	{"web", "web_modqueue"},
 
 	{"web", "web_prepost_newthread"},
 	{"web", "web_prepost_newpost"},
 
+	{"web", "web_post_msgid_by_pname"},
+
+	{"web", "web_cancel_secret"},
+
 	// post stuff
 
 	{"post", "post_newthread_sb_nf"},
@@ -205,6 +277,9 @@ var stNames = [stMax]StReference{
 	{"post", "post_newreply_sb_mf"},
 	{"post", "post_newreply_mb_mf"},
 
+	{"post", "post_set_expires"},
+	{"post", "post_superseded_info"},
+
 	// database-modification
 
 	{"mod", "mod_ref_write"},
@@ -214,9 +289,29 @@ var stNames = [stMax]StReference{
 	{"mod", "mod_autoregister_mod"},
 	{"mod", "mod_delete_by_msgid"},
 	{"mod", "mod_ban_by_msgid"},
+	{"mod", "mod_bban_by_msgid"},
+	{"mod", "mod_bdelete_by_msgid"},
+	{"mod", "mod_unban_by_msgid"},
+	{"mod", "mod_delete_board_posts"},
+	{"mod", "mod_delete_board"},
+	{"mod", "mod_cancel_lock_by_msgid"},
+	{"mod", "mod_boards_by_msgid"},
+	{"mod", "mod_control_history_seen"},
+	{"mod", "mod_control_history_add"},
+	{"mod", "mod_control_history_prune"},
+	{"mod", "mod_puller_rejects_prune"},
+	{"mod", "mod_expire_find_expires"},
+	{"mod", "mod_expire_find_threads"},
+	{"mod", "mod_archive_threads"},
+	{"mod", "mod_delete_thread"},
+	{"mod", "mod_set_thread_opt"},
 	{"mod", "mod_bname_topts_by_tid"},
 	{"mod", "mod_refresh_bump_by_tid"},
 
+	{"mod", "mod_log_cmd"},
+	{"mod", "mod_modqueue_add"},
+	{"mod", "mod_modqueue_get"},
+	{"mod", "mod_modqueue_del"},
 	{"mod", "mod_set_mod_priv"},
 	{"mod", "mod_set_mod_priv_group"},
 	{"mod", "mod_unset_mod"},
@@ -241,6 +336,16 @@ var stNames = [stMax]StReference{
 
 	{"mod_joblist", "mod_joblist_refs_recalc_get"},
 
+	{"mod_joblist", "mod_joblist_files_deleted_get"},
+	{"mod_joblist", "mod_joblist_files_deleted_lock"},
+	{"mod_joblist", "mod_joblist_files_deleted_hold"},
+	{"mod_joblist", "mod_joblist_files_deleted_del"},
+
+	{"mod_joblist", "mod_joblist_fthumbs_deleted_get"},
+	{"mod_joblist", "mod_joblist_fthumbs_deleted_lock"},
+	{"mod_joblist", "mod_joblist_fthumbs_deleted_hold"},
+	{"mod_joblist", "mod_joblist_fthumbs_deleted_del"},
+
 	// puller-related
 
 	{"puller", "puller_get_last_newnews"},
@@ -251,6 +356,21 @@ var stNames = [stMax]StReference{
 	{"puller", "puller_set_group_id"},
 	{"puller", "puller_unset_group_id"},
 	{"puller", "puller_load_temp_groups"},
+	{"puller", "puller_reject_add"},
+	{"puller", "puller_reject_check"},
+	{"puller", "puller_set_rules"},
+
+	// pusher-related
+
+	{"pusher", "pusher_register"},
+	{"pusher", "pusher_clear_unused"},
+	{"pusher", "pusher_get_groups"},
+	{"pusher", "pusher_set_group_id"},
+	{"pusher", "pusher_list_articles"},
+	{"pusher", "pusher_get_control"},
+	{"pusher", "pusher_set_control_id"},
+	{"pusher", "pusher_list_control"},
+	{"pusher", "pusher_get_control_article"},
 }
 
 func LoadStatements() {
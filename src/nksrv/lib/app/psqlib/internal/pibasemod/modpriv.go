@@ -14,10 +14,10 @@ const (
 	Cap_DelPost
 	Cap_DelBoardPost
 	Cap_DelBoard
-	_
-	_
-	_
-	_
+	Cap_LockThread
+	Cap_StickyThread
+	Cap_AutosageThread
+	Cap_ApprovePost
 	_
 	_
 	_
@@ -17,6 +17,11 @@ var (
 	//ErrDuplicateArticle      = errors.New("article with this ID already exists")
 	ErrEmptyMsg       = errors.New("posting empty messages isn't allowed")
 	ErrInvalidOptions = errors.New("invalid options")
+
+	ErrSupersededNotFound    = errors.New("post to supersede not found in this board")
+	ErrSupersededOtherThread = errors.New("superseding post must be reply in same thread")
+
+	ErrApprovedUnsigned = errors.New("approving post requires signing it")
 )
 
 func ErrTooLongMessage(limit uint32) error {
@@ -12,6 +12,7 @@ func MakePostParamFunc(c *webcaptcha.WebCaptcha) func(string) bool {
 		ib0.IBWebFormTextName,
 		ib0.IBWebFormTextMessage,
 		ib0.IBWebFormTextOptions,
+		ib0.IBWebFormTextPassword,
 	}
 	if c != nil {
 		tfields = append(tfields, c.TextFields()...)
@@ -0,0 +1,142 @@
+package pimod
+
+import (
+	"database/sql"
+	"time"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	. "nksrv/lib/utils/logx"
+)
+
+// archiving of threads which fell off active pages.
+// it's independent of expiry, as boards may have archive
+// without having anything expire
+
+// archiveThreads archives up to limit threads which fell off active pages.
+// Archived threads don't get bumped and don't accept replies from web.
+func archiveThreads(sp *pibase.PSQLIB, limit int) (n int, err error) {
+	rows, err := sp.StPrep[pibase.St_mod_archive_threads].Query(limit)
+	if err != nil {
+		return 0, sp.SQLError("archive threads query", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bname sql.NullString
+		var tname string
+		err = rows.Scan(&bname, &tname)
+		if err != nil {
+			return 0, sp.SQLError("archive threads rows scan", err)
+		}
+		sp.Log.LogPrintf(INFO, "archived thread %s/%s", bname.String, tname)
+		n++
+	}
+	if err = rows.Err(); err != nil {
+		return 0, sp.SQLError("archive threads rows it", err)
+	}
+	return
+}
+
+type ArchiveConfig struct {
+	Interval  time.Duration // how often to look for threads to archive
+	Delay     time.Duration // how long to wait after bump before looking
+	BatchSize int           // how much to archive in single query
+}
+
+var DefaultArchiveConfig = ArchiveConfig{
+	Interval:  10 * time.Minute,
+	Delay:     5 * time.Second,
+	BatchSize: 100,
+}
+
+// ArchiveJob archives threads in background.
+// It's woken up by thread bumps, as that's what pushes
+// other threads off active pages, and runs periodically
+// to catch up with board settings changes.
+type ArchiveJob struct {
+	sp   *pibase.PSQLIB
+	cfg  ArchiveConfig
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewArchiveJob(sp *pibase.PSQLIB, cfg ArchiveConfig) *ArchiveJob {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultArchiveConfig.Interval
+	}
+	if cfg.Delay < 0 {
+		cfg.Delay = 0
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultArchiveConfig.BatchSize
+	}
+	return &ArchiveJob{
+		sp:   sp,
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+func (j *ArchiveJob) notify(string, bool) {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (j *ArchiveJob) Start() (err error) {
+	if err = j.sp.DB.Listen("ib0_threads_bumped", j.notify); err != nil {
+		return
+	}
+	go j.run()
+	return
+}
+
+// Stop stops job and waits for it to finish.
+func (j *ArchiveJob) Stop() {
+	close(j.stop)
+	<-j.done
+}
+
+func (j *ArchiveJob) run() {
+	defer close(j.done)
+
+	t := time.NewTicker(j.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		for {
+			n, err := archiveThreads(j.sp, j.cfg.BatchSize)
+			if err != nil {
+				j.sp.Log.LogPrintf(ERROR, "archiving failed: %v", err)
+				break
+			}
+			if n < j.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-j.stop:
+			return
+		case <-t.C:
+		case <-j.wake:
+			// let burst of posts settle, so that we don't
+			// look through threads after every single one
+			d := time.NewTimer(j.cfg.Delay)
+			select {
+			case <-j.stop:
+				d.Stop()
+				return
+			case <-d.C:
+			}
+			select {
+			case <-j.wake:
+			default:
+			}
+		}
+	}
+}
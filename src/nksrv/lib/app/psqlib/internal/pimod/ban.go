@@ -0,0 +1,347 @@
+package pimod
+
+import (
+	"database/sql"
+	"strings"
+
+	"nksrv/lib/app/mailib"
+	. "nksrv/lib/utils/logx"
+	mm "nksrv/lib/utils/minimail"
+
+	. "nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibasemod"
+	. "nksrv/lib/app/psqlib/internal/pibasenntp"
+)
+
+// board-scoped bans and deletions, and lifting of bans.
+// board bans are stored in banlist with b_name set,
+// triggers take care of placeholders and deletion of banned posts.
+
+// canModGlobal tells whether mod can ban or delete in any board.
+func canModGlobal(modCC pibasemod.ModCombinedCaps) bool {
+	return modCC.ModCap.Cap&pibasemod.Cap_DelPost != 0
+}
+
+// canModBoard tells whether mod can ban or delete in specified board.
+func canModBoard(modCC pibasemod.ModCombinedCaps, board string) bool {
+	const mask = pibasemod.Cap_DelPost | pibasemod.Cap_DelBoardPost
+	return modCC.ModCap.Cap&mask != 0 || modCC.ModBoardCap[board].Cap&mask != 0
+}
+
+// modCmdMsgID parses msgid argument of mod command.
+// Mod msg isn't allowed to act on itself or post it replies to.
+// If msgid isn't acceptable, rejection reason is returned.
+func modCmdMsgID(
+	arg string, selfid, ref TCoreMsgIDStr) (TCoreMsgIDStr, string) {
+
+	fmsgids := TFullMsgIDStr(arg)
+	if !mm.ValidMessageIDStr(fmsgids) {
+		return "", rejBadMsgID
+	}
+	cmsgids := cutMsgID(fmsgids)
+	if cmsgids == selfid || cmsgids == ref {
+		return "", rejSelfRef
+	}
+	return cmsgids, ""
+}
+
+func modCmdReason(args []string, pi mailib.PostInfo) string {
+	if len(args) != 0 {
+		return strings.Join(args, " ")
+	}
+	return pi.MI.Title
+}
+
+func (mc *modCtx) BoardBanByMsgID(
+	cmsgids TCoreMsgIDStr, board string,
+	banbid TBoardID, banbpid TPostID, reason string,
+) (
+	err error,
+) {
+
+	bidn := sql.NullInt64{
+		Int64: int64(banbid),
+		Valid: banbid != 0 && banbpid != 0,
+	}
+	bpidn := sql.NullInt64{
+		Int64: int64(banbpid),
+		Valid: banbid != 0 && banbpid != 0,
+	}
+
+	mc.sp.Log.LogPrintf(
+		DEBUG, "BAN ARTICLE <%s> IN %q (reason: %q) start",
+		cmsgids, board, reason)
+	banst := mc.tx.Stmt(mc.sp.StPrep[St_mod_bban_by_msgid])
+	_, err = banst.Exec(string(cmsgids), bidn, bpidn, reason, board)
+	if err != nil {
+		err = mc.sp.SQLError("board ban by msgid query", err)
+		return
+	}
+
+	mc.sp.Log.LogPrintf(
+		DEBUG, "BAN ARTICLE <%s> IN %q processing", cmsgids, board)
+	err = mc.postDelete()
+	mc.sp.Log.LogPrintf(DEBUG, "BAN ARTICLE <%s> IN %q end", cmsgids, board)
+	return
+}
+
+func (mc *modCtx) boardDeleteByMsgID(
+	cmsgids TCoreMsgIDStr, board string) (err error) {
+
+	mc.sp.Log.LogPrintf(
+		DEBUG, "DELET ARTICLE <%s> IN %q start", cmsgids, board)
+	delst := mc.tx.Stmt(mc.sp.StPrep[St_mod_bdelete_by_msgid])
+	_, err = delst.Exec(string(cmsgids), board)
+	if err != nil {
+		err = mc.sp.SQLError("board delete by msgid query", err)
+		return
+	}
+
+	mc.sp.Log.LogPrintf(
+		DEBUG, "DELET ARTICLE <%s> IN %q processing", cmsgids, board)
+	err = mc.postDelete()
+	mc.sp.Log.LogPrintf(
+		DEBUG, "DELET ARTICLE <%s> IN %q end", cmsgids, board)
+	return
+}
+
+// unbanByMsgID lifts bans of msgid.
+// If board is empty, global bans are lifted.
+func (mc *modCtx) unbanByMsgID(
+	cmsgids TCoreMsgIDStr, board string) (n int, err error) {
+
+	bname := sql.NullString{String: board, Valid: board != ""}
+
+	st := mc.tx.Stmt(mc.sp.StPrep[St_mod_unban_by_msgid])
+	rows, err := st.Query(string(cmsgids), bname)
+	if err != nil {
+		err = mc.sp.SQLError("unban by msgid query", err)
+		return
+	}
+	for rows.Next() {
+		n++
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		err = mc.sp.SQLError("unban by msgid rows it", err)
+		return
+	}
+	rows.Close()
+
+	mc.sp.Log.LogPrintf(
+		DEBUG, "UNBAN ARTICLE <%s> IN %q: %d bans lifted", cmsgids, board, n)
+	return
+}
+
+// ModCmdBan bans msgid globally.
+// syntax: ban <msgid> [reason]
+func ModCmdBan(
+	mc *modCtx, modCC pibasemod.ModCombinedCaps,
+	bid TBoardID, bpid TPostID, pi mailib.PostInfo,
+	selfid, ref TCoreMsgIDStr,
+	args []string,
+) (
+	res modCmdResult, err error,
+) {
+
+	if len(args) == 0 {
+		return rejectModCmd(rejNoArgs, "")
+	}
+	if !canModGlobal(modCC) {
+		return rejectModCmd(rejNoCap, "", args[0])
+	}
+	cmsgids, rej := modCmdMsgID(args[0], selfid, ref)
+	if rej != "" {
+		return rejectModCmd(rej, "", args[0])
+	}
+
+	res = modCmdResult{
+		targets: args[:1],
+		applied: true,
+		reason:  modCmdReason(args[1:], pi),
+	}
+	err = mc.BanByMsgID(cmsgids, bid, bpid, res.reason)
+	return
+}
+
+// ModCmdBBan bans msgid in single board.
+// syntax: bban <board> <msgid> [reason]
+func ModCmdBBan(
+	mc *modCtx, modCC pibasemod.ModCombinedCaps,
+	bid TBoardID, bpid TPostID, pi mailib.PostInfo,
+	selfid, ref TCoreMsgIDStr,
+	args []string,
+) (
+	res modCmdResult, err error,
+) {
+
+	if len(args) < 2 {
+		return rejectModCmd(rejNoArgs, "")
+	}
+	if !canModBoard(modCC, args[0]) {
+		return rejectModCmd(rejNoCap, args[0], args[1])
+	}
+	cmsgids, rej := modCmdMsgID(args[1], selfid, ref)
+	if rej != "" {
+		return rejectModCmd(rej, args[0], args[1])
+	}
+
+	res = modCmdResult{
+		board:   args[0],
+		targets: args[1:2],
+		applied: true,
+		reason:  modCmdReason(args[2:], pi),
+	}
+	err = mc.BoardBanByMsgID(cmsgids, args[0], bid, bpid, res.reason)
+	return
+}
+
+// ModCmdBDelete deletes msgid from single board, without banning it.
+// syntax: bdelete <board> <msgid>
+func ModCmdBDelete(
+	mc *modCtx, modCC pibasemod.ModCombinedCaps,
+	selfid, ref TCoreMsgIDStr,
+	args []string,
+) (
+	res modCmdResult, err error,
+) {
+
+	if len(args) < 2 {
+		return rejectModCmd(rejNoArgs, "")
+	}
+	if !canModBoard(modCC, args[0]) {
+		return rejectModCmd(rejNoCap, args[0], args[1])
+	}
+	cmsgids, rej := modCmdMsgID(args[1], selfid, ref)
+	if rej != "" {
+		return rejectModCmd(rej, args[0], args[1])
+	}
+
+	res = modCmdResult{board: args[0], targets: args[1:2], applied: true}
+	err = mc.boardDeleteByMsgID(cmsgids, args[0])
+	return
+}
+
+// ModCmdUnban lifts global ban.
+// syntax: unban <msgid>
+func ModCmdUnban(
+	mc *modCtx, modCC pibasemod.ModCombinedCaps,
+	args []string,
+) (
+	res modCmdResult, err error,
+) {
+
+	if len(args) == 0 {
+		return rejectModCmd(rejNoArgs, "")
+	}
+	fmsgids := TFullMsgIDStr(args[0])
+	if !mm.ValidMessageIDStr(fmsgids) {
+		return rejectModCmd(rejBadMsgID, "", args[0])
+	}
+	if !canModGlobal(modCC) {
+		return rejectModCmd(rejNoCap, "", args[0])
+	}
+
+	res = modCmdResult{targets: args[:1], applied: true}
+	_, err = mc.unbanByMsgID(cutMsgID(fmsgids), "")
+	return
+}
+
+// ModCmdBUnban lifts ban of msgid in single board.
+// syntax: bunban <board> <msgid>
+func ModCmdBUnban(
+	mc *modCtx, modCC pibasemod.ModCombinedCaps,
+	args []string,
+) (
+	res modCmdResult, err error,
+) {
+
+	if len(args) < 2 {
+		return rejectModCmd(rejNoArgs, "")
+	}
+	fmsgids := TFullMsgIDStr(args[1])
+	if !mm.ValidMessageIDStr(fmsgids) {
+		return rejectModCmd(rejBadMsgID, args[0], args[1])
+	}
+	if !canModBoard(modCC, args[0]) {
+		return rejectModCmd(rejNoCap, args[0], args[1])
+	}
+
+	res = modCmdResult{board: args[0], targets: args[1:2], applied: true}
+	_, err = mc.unbanByMsgID(cutMsgID(fmsgids), args[0])
+	return
+}
+
+// BanMsgIDs bans msgids globally or, if board isn't empty, in board.
+// If reason is empty, msgids are just deleted instead.
+// Meant for CLI tools.
+func BanMsgIDs(
+	sp *PSQLIB, msgids []TFullMsgIDStr, board, reason string) error {
+
+	return withModTx(sp, func(mc *modCtx) (err error) {
+		for _, s := range msgids {
+			cmsgids := cutMsgID(s)
+			sp.Log.LogPrintf(INFO, "deleting <%s> (board %q)", cmsgids, board)
+			var cmd string
+			switch {
+			case board == "" && reason == "":
+				cmd = "delete"
+				err = mc.deleteByMsgID(cmsgids)
+			case board == "":
+				cmd = "ban"
+				err = mc.BanByMsgID(cmsgids, 0, 0, reason)
+			case reason == "":
+				cmd = "bdelete"
+				err = mc.boardDeleteByMsgID(cmsgids, board)
+			default:
+				cmd = "bban"
+				err = mc.BoardBanByMsgID(cmsgids, board, 0, 0, reason)
+			}
+			if err != nil {
+				return
+			}
+			err = mc.logModCmd(0, "", cmd, modCmdResult{
+				board:   board,
+				targets: []string{string(s)},
+				applied: true,
+				reason:  reason,
+			})
+			if err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
+// UnbanMsgIDs lifts global or, if board isn't empty, board bans of msgids.
+// Meant for CLI tools.
+func UnbanMsgIDs(
+	sp *PSQLIB, msgids []TFullMsgIDStr, board string) error {
+
+	return withModTx(sp, func(mc *modCtx) (err error) {
+		for _, s := range msgids {
+			cmsgids := cutMsgID(s)
+			var n int
+			n, err = mc.unbanByMsgID(cmsgids, board)
+			if err != nil {
+				return
+			}
+			sp.Log.LogPrintf(
+				INFO, "unbanned <%s> (board %q): %d bans", cmsgids, board, n)
+			cmd := "unban"
+			if board != "" {
+				cmd = "bunban"
+			}
+			err = mc.logModCmd(0, "", cmd, modCmdResult{
+				board:   board,
+				targets: []string{string(s)},
+				applied: true,
+			})
+			if err != nil {
+				return
+			}
+		}
+		return
+	})
+}
@@ -0,0 +1,208 @@
+package pimod
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"nksrv/lib/app/base/ctlmsg"
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibasenntp"
+	"nksrv/lib/mail"
+	. "nksrv/lib/utils/logx"
+)
+
+// actions requested by netnews control messages {RFC 5537}
+
+func withModTx(sp *pibase.PSQLIB, f func(mc *modCtx) error) (err error) {
+	tx, err := sp.DB.DB.Begin()
+	if err != nil {
+		return sp.SQLError("tx begin", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	mc := &modCtx{sp: sp, tx: tx}
+	if err = mc.makeDelTables(); err != nil {
+		return
+	}
+	if err = f(mc); err != nil {
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		err = sp.SQLError("tx commit", err)
+		return
+	}
+	mc.notifyApproved()
+	return
+}
+
+// MaxControlAge is how old control message may be to be acted upon.
+const MaxControlAge = 14 * 24 * time.Hour
+
+// processed control messages are remembered for longer than they're
+// accepted, so that they can't be replayed once forgotten
+const controlHistoryKeep = MaxControlAge + 24*time.Hour
+
+// ControlSeen tells whether control message with such Message-ID
+// or signature was already processed.
+func ControlSeen(
+	sp *pibase.PSQLIB, cmsgid pibasenntp.TCoreMsgIDStr, sig string) (
+	bool, error) {
+
+	var dummy int
+	err := sp.StPrep[pibase.St_mod_control_history_seen].QueryRow(
+		sql.NullString{String: string(cmsgid), Valid: cmsgid != ""},
+		sql.NullString{String: sig, Valid: sig != ""}).Scan(&dummy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, sp.SQLError("control history query scan", err)
+	}
+	return true, nil
+}
+
+// RecordControl remembers processed control message.
+// Empty cmsgid or sig aren't recorded.
+// article is kept for pushers if it's not nil.
+func RecordControl(
+	sp *pibase.PSQLIB, cmsgid pibasenntp.TCoreMsgIDStr, sig string,
+	article []byte) error {
+
+	_, err := sp.StPrep[pibase.St_mod_control_history_add].Exec(
+		sql.NullString{String: string(cmsgid), Valid: cmsgid != ""},
+		sql.NullString{String: sig, Valid: sig != ""},
+		sql.NullString{String: string(article), Valid: article != nil})
+	if err != nil {
+		return sp.SQLError("control history insert", err)
+	}
+	return nil
+}
+
+func pruneControlHistory(sp *pibase.PSQLIB, now time.Time) error {
+	_, err := sp.StPrep[pibase.St_mod_control_history_prune].
+		Exec(now.Add(-controlHistoryKeep))
+	if err != nil {
+		return sp.SQLError("control history prune", err)
+	}
+	return nil
+}
+
+var ErrCancelNotAllowed = errors.New("not allowed to cancel in article's boards")
+
+// CancelByMsgID deletes article, as asked by cancel control message,
+// if allowed permits every board article is in.
+// It returns pibase.ErrNoSuchPost if there's no such article.
+func CancelByMsgID(
+	sp *pibase.PSQLIB, cmsgid pibasenntp.TCoreMsgIDStr,
+	allowed func(board string) bool) error {
+
+	return withModTx(sp, func(mc *modCtx) (err error) {
+
+		st := mc.tx.Stmt(sp.StPrep[pibase.St_mod_boards_by_msgid])
+		rows, err := st.Query(string(cmsgid))
+		if err != nil {
+			return sp.SQLError("boards by msgid query", err)
+		}
+		var boards []string
+		for rows.Next() {
+			var b string
+			if err = rows.Scan(&b); err != nil {
+				rows.Close()
+				return sp.SQLError("boards by msgid query rows scan", err)
+			}
+			boards = append(boards, b)
+		}
+		if err = rows.Err(); err != nil {
+			return sp.SQLError("boards by msgid query rows iteration", err)
+		}
+
+		if len(boards) == 0 {
+			return pibase.ErrNoSuchPost
+		}
+		for _, b := range boards {
+			if !allowed(b) {
+				return ErrCancelNotAllowed
+			}
+		}
+
+		return mc.deleteByMsgID(cmsgid)
+	})
+}
+
+// DeleteBoard deletes board together with all its posts,
+// as asked by rmgroup control message.
+// It returns pibase.ErrNoSuchBoard if there's no such board.
+func DeleteBoard(sp *pibase.PSQLIB, board string) error {
+	return withModTx(sp, func(mc *modCtx) (err error) {
+
+		sp.Log.LogPrintf(DEBUG, "DELET BOARD %q start", board)
+
+		st := mc.tx.Stmt(sp.StPrep[pibase.St_mod_delete_board_posts])
+		if _, err = st.Exec(board); err != nil {
+			return sp.SQLError("delete board posts query", err)
+		}
+		if err = mc.postDelete(); err != nil {
+			return
+		}
+
+		var bid int64
+		st = mc.tx.Stmt(sp.StPrep[pibase.St_mod_delete_board])
+		err = st.QueryRow(board).Scan(&bid)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return pibase.ErrNoSuchBoard
+			}
+			return sp.SQLError("delete board query", err)
+		}
+
+		sp.Log.LogPrintf(DEBUG, "DELET BOARD %q end", board)
+		return
+	})
+}
+
+var ErrCancelKeyMismatch = errors.New("Cancel-Key doesn't match Cancel-Lock")
+
+// CancelWithKey deletes article if any of Cancel-Key header values
+// matches its Cancel-Lock {RFC 8315}.
+// It returns pibase.ErrNoSuchPost if there's no such article.
+func CancelWithKey(
+	sp *pibase.PSQLIB, cmsgid pibasenntp.TCoreMsgIDStr, keys []string) error {
+
+	return withModTx(sp, func(mc *modCtx) (err error) {
+
+		var jlocks sql.NullString
+		st := mc.tx.Stmt(sp.StPrep[pibase.St_mod_cancel_lock_by_msgid])
+		err = st.QueryRow(string(cmsgid)).Scan(&jlocks)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return pibase.ErrNoSuchPost
+			}
+			return sp.SQLError("cancel lock query", err)
+		}
+
+		var hlocks mail.HeaderMapVals
+		if jlocks.Valid {
+			err = json.Unmarshal([]byte(jlocks.String), &hlocks)
+			if err != nil {
+				return fmt.Errorf("failed to unmarshal Cancel-Lock: %v", err)
+			}
+		}
+		locks := make([]string, len(hlocks))
+		for i := range hlocks {
+			locks[i] = hlocks[i].V
+		}
+		if !ctlmsg.VerifyCancelKey(locks, keys) {
+			return ErrCancelKeyMismatch
+		}
+
+		return mc.deleteByMsgID(cmsgid)
+	})
+}
@@ -0,0 +1,252 @@
+package pimod
+
+import (
+	"database/sql"
+	"time"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibasenntp"
+	. "nksrv/lib/utils/logx"
+)
+
+// expiry of old content:
+// articles whose Expires {RFC 5536 3.2.5} date has passed,
+// and threads which fell out of board limits or got too old
+
+type expThread struct {
+	bid   int32
+	btid  int64
+	bname string
+	tname string
+}
+
+// findExpiredArticles returns up to limit msgids of articles
+// whose Expires date, parsed when they were inserted, has passed.
+func findExpiredArticles(
+	sp *pibase.PSQLIB, now time.Time, limit int) (
+	msgids []pibasenntp.TCoreMsgIDStr, err error) {
+
+	rows, err := sp.StPrep[pibase.St_mod_expire_find_expires].
+		Query(now, limit)
+	if err != nil {
+		return nil, sp.SQLError("expires query", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var msgid string
+		err = rows.Scan(&msgid)
+		if err != nil {
+			return nil, sp.SQLError("expires rows scan", err)
+		}
+		msgids = append(msgids, pibasenntp.TCoreMsgIDStr(msgid))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, sp.SQLError("expires rows it", err)
+	}
+	return
+}
+
+func findExpiredThreads(
+	sp *pibase.PSQLIB, limit int) (threads []expThread, err error) {
+
+	rows, err := sp.StPrep[pibase.St_mod_expire_find_threads].Query(limit)
+	if err != nil {
+		return nil, sp.SQLError("expired threads query", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t expThread
+		var bname sql.NullString
+		err = rows.Scan(&t.bid, &t.btid, &bname, &t.tname)
+		if err != nil {
+			return nil, sp.SQLError("expired threads rows scan", err)
+		}
+		t.bname = bname.String
+		threads = append(threads, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, sp.SQLError("expired threads rows it", err)
+	}
+	return
+}
+
+// rejected articles are remembered for about as long as peers
+// are likely to still offer them
+const pullerRejectsKeep = 30 * 24 * time.Hour
+
+func prunePullerRejects(sp *pibase.PSQLIB, now time.Time) error {
+	_, err := sp.StPrep[pibase.St_mod_puller_rejects_prune].
+		Exec(now.Add(-pullerRejectsKeep))
+	if err != nil {
+		return sp.SQLError("puller rejects prune", err)
+	}
+	return nil
+}
+
+func (mc *modCtx) deleteThread(t expThread) (err error) {
+	mc.sp.Log.LogPrintf(
+		DEBUG, "DELET THREAD %s/%s start", t.bname, t.tname)
+	st := mc.tx.Stmt(mc.sp.StPrep[pibase.St_mod_delete_thread])
+	_, err = st.Exec(t.bid, t.btid)
+	if err != nil {
+		return mc.sp.SQLError("delete thread query", err)
+	}
+
+	mc.sp.Log.LogPrintf(
+		DEBUG, "DELET THREAD %s/%s processing", t.bname, t.tname)
+	err = mc.postDelete()
+	mc.sp.Log.LogPrintf(DEBUG, "DELET THREAD %s/%s end", t.bname, t.tname)
+	return
+}
+
+// ExpireOnce forgets old control messages and old pull rejects,
+// then deletes up to limit expired articles and up to limit
+// expired threads. Files are left for reaper.
+// It returns number of deleted things.
+func ExpireOnce(sp *pibase.PSQLIB, limit int) (n int, err error) {
+	now := time.Now()
+	if err = pruneControlHistory(sp, now); err != nil {
+		return
+	}
+	if err = prunePullerRejects(sp, now); err != nil {
+		return
+	}
+
+	msgids, err := findExpiredArticles(sp, now, limit)
+	if err != nil {
+		return
+	}
+	threads, err := findExpiredThreads(sp, limit)
+	if err != nil {
+		return
+	}
+	if len(msgids) == 0 && len(threads) == 0 {
+		return
+	}
+
+	err = withModTx(sp, func(mc *modCtx) (err error) {
+		for _, msgid := range msgids {
+			sp.Log.LogPrintf(INFO, "expiring article <%s>", msgid)
+			if err = mc.deleteByMsgID(msgid); err != nil {
+				return
+			}
+		}
+		for _, t := range threads {
+			sp.Log.LogPrintf(INFO, "expiring thread %s/%s", t.bname, t.tname)
+			if err = mc.deleteThread(t); err != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	n = len(msgids) + len(threads)
+	return
+}
+
+type ExpiryConfig struct {
+	Interval  time.Duration // how often to look for expired content
+	BatchSize int           // how much to process in single transaction
+}
+
+var DefaultExpiryConfig = ExpiryConfig{
+	Interval:  10 * time.Minute,
+	BatchSize: 100,
+}
+
+// ExpiryJob runs expiry and file reaping in background.
+// Reaper is additionally woken up by file deletion notifications.
+type ExpiryJob struct {
+	sp   *pibase.PSQLIB
+	cfg  ExpiryConfig
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewExpiryJob(sp *pibase.PSQLIB, cfg ExpiryConfig) *ExpiryJob {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultExpiryConfig.Interval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultExpiryConfig.BatchSize
+	}
+	return &ExpiryJob{
+		sp:   sp,
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+func (j *ExpiryJob) notify(string, bool) {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (j *ExpiryJob) Start() (err error) {
+	for _, n := range [...]string{"ib0_files_deleted", "ib0_fthumbs_deleted"} {
+		if err = j.sp.DB.Listen(n, j.notify); err != nil {
+			return
+		}
+	}
+	go j.run()
+	return
+}
+
+// Stop stops job and waits for it to finish.
+func (j *ExpiryJob) Stop() {
+	close(j.stop)
+	<-j.done
+}
+
+func (j *ExpiryJob) run() {
+	defer close(j.done)
+
+	t := time.NewTicker(j.cfg.Interval)
+	defer t.Stop()
+
+	expire := true
+	for {
+		if expire {
+			for {
+				n, err := ExpireOnce(j.sp, j.cfg.BatchSize)
+				if err != nil {
+					j.sp.Log.LogPrintf(ERROR, "expiry failed: %v", err)
+					break
+				}
+				if n < j.cfg.BatchSize {
+					break
+				}
+			}
+		}
+		// reap what expiry (or anything else) left behind
+		for {
+			n, err := ReapFilesOnce(j.sp, j.cfg.BatchSize)
+			if err != nil {
+				j.sp.Log.LogPrintf(ERROR, "file reaping failed: %v", err)
+				break
+			}
+			if n == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-j.stop:
+			return
+		case <-t.C:
+			expire = true
+		case <-j.wake:
+			expire = false
+		}
+	}
+}
@@ -9,7 +9,6 @@ import (
 
 	"nksrv/lib/app/mailib"
 	. "nksrv/lib/utils/logx"
-	mm "nksrv/lib/utils/minimail"
 	"nksrv/lib/utils/text/bufreader"
 
 	"nksrv/lib/app/psqlib/internal/pibase"
@@ -24,23 +23,20 @@ func ModCmdDelete(
 	selfid, ref pibasenntp.TCoreMsgIDStr,
 	cmd string, args []string,
 ) (
-	err error,
+	res modCmdResult, err error,
 ) {
 
 	if len(args) == 0 {
-		return
+		return rejectModCmd(rejNoArgs, "")
 	}
 
-	fmsgids := TFullMsgIDStr(args[0])
-	if !mm.ValidMessageIDStr(fmsgids) {
-		return
-	}
-	cmsgids := cutMsgID(fmsgids)
-	if cmsgids == selfid || cmsgids == ref {
-		return
+	cmsgids, rej := modCmdMsgID(args[0], selfid, ref)
+	if rej != "" {
+		return rejectModCmd(rej, "", args[0])
 	}
 
-	err = BanByMsgID(sp, tx, cmsgids, bid, bpid, pi.MI.Title)
+	res = modCmdResult{targets: args[:1], applied: true, reason: pi.MI.Title}
+	err = mc.BanByMsgID(cmsgids, bid, bpid, pi.MI.Title)
 	if err != nil {
 		return
 	}
@@ -113,22 +109,54 @@ func ExecModCmd(
 			unsafe_cmd := strings.ToLower(unsafe_fields[0])
 			unsafe_args := unsafe_fields[1:]
 
-			// TODO log commands we couldn't understand
+			var res modCmdResult
+			known := true
 			switch unsafe_cmd {
 			case "delete":
-				// TODO per-board stuff
-				// TODO TODO TODO
-				if modCC.ModCap.Cap&pibasemod.Cap_DelPost != 0 {
+				if canModGlobal(modCC) {
 					// global delete by msgid
-					err =
+					res, err =
 						ModCmdDelete(
 							mc,
 							gpid, bid, bpid, pi, selfid, ref,
 							unsafe_cmd, unsafe_args)
+				} else {
+					res, err = rejectModCmd(rejNoCap, "", unsafe_args...)
+				}
+			case "ban":
+				res, err = ModCmdBan(
+					mc, modCC, bid, bpid, pi, selfid, ref, unsafe_args)
+			case "bban":
+				res, err = ModCmdBBan(
+					mc, modCC, bid, bpid, pi, selfid, ref, unsafe_args)
+			case "bdelete":
+				res, err = ModCmdBDelete(mc, modCC, selfid, ref, unsafe_args)
+			case "unban":
+				res, err = ModCmdUnban(mc, modCC, unsafe_args)
+			case "bunban":
+				res, err = ModCmdBUnban(mc, modCC, unsafe_args)
+			case "lock", "unlock", "sticky", "unsticky",
+				"autosage", "unautosage":
+
+				res, err = ModCmdThreadOpt(
+					mc, modCC, selfid, unsafe_cmd, unsafe_args)
+			case "approve", "reject":
+				res, err = modCmdQueue(
+					mc, modCC, unsafe_cmd == "approve", unsafe_args)
+			default:
+				// just text around commands
+				known = false
 			}
 			if err != nil {
 				return
 			}
+
+			if known {
+				err = mc.logModCmd(modid, selfid, unsafe_cmd, res)
+				if err != nil {
+					return
+				}
+			}
 		}
 
 		// EOF
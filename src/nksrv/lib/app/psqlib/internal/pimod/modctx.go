@@ -14,4 +14,6 @@ type modCtx struct {
 	ModID       int64
 	DelInited   bool
 	DelOurModID bool
+
+	approved []queuedPost // posts to notify pushers about after commit
 }
@@ -0,0 +1,80 @@
+package pimod
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	. "nksrv/lib/utils/logx"
+
+	. "nksrv/lib/app/psqlib/internal/pibase"
+	. "nksrv/lib/app/psqlib/internal/pibasenntp"
+)
+
+// audit log of mod commands.
+// every known command we encounter gets recorded, whether it was applied
+// or not. lines which aren't commands are just text and aren't recorded.
+// log isn't linked to ctl msg so it survives its deletion.
+
+// why commands get rejected
+const (
+	rejUnknownCmd = "unknown command"
+	rejNoArgs     = "not enough arguments"
+	rejBadMsgID   = "invalid msgid"
+	rejNoCap      = "insufficient capabilities"
+	rejSelfRef    = "refers to itself"
+)
+
+type modCmdResult struct {
+	board   string   // board command was scoped to, empty if global
+	targets []string // msgids and such command acted upon
+	applied bool     // false if rejected
+	reason  string   // why rejected, or reason given by mod
+}
+
+func rejectModCmd(
+	reason, board string, targets ...string) (modCmdResult, error) {
+
+	return modCmdResult{board: board, targets: targets, reason: reason}, nil
+}
+
+// logModCmd records result of mod command.
+// modid of 0 means local admin or control message,
+// selfid is msgid of ctl msg, if any.
+func (mc *modCtx) logModCmd(
+	modid uint64, selfid TCoreMsgIDStr, cmd string, res modCmdResult) error {
+
+	mc.sp.Log.LogPrintf(DEBUG,
+		"MODLOG mod(%d) <%s> %s %q %q applied(%t) reason(%q)",
+		modid, selfid, cmd, res.board, res.targets, res.applied, res.reason)
+
+	st := mc.tx.Stmt(mc.sp.StPrep[St_mod_log_cmd])
+	_, err := st.Exec(
+		sql.NullInt64{Int64: int64(modid), Valid: modid != 0},
+		sql.NullString{String: string(selfid), Valid: selfid != ""},
+		sql.NullString{String: res.board, Valid: res.board != ""},
+		cmd,
+		pq.Array(res.targets),
+		res.applied,
+		res.reason)
+	if err != nil {
+		return mc.sp.SQLError("mod log query", err)
+	}
+	return nil
+}
+
+// LogControl records action requested by control message {RFC 5537}.
+// Reason is only set for rejected ones.
+func LogControl(
+	sp *PSQLIB, selfid TCoreMsgIDStr, cmd, board string, targets []string,
+	applied bool, reason string) error {
+
+	return withModTx(sp, func(mc *modCtx) error {
+		return mc.logModCmd(0, selfid, cmd, modCmdResult{
+			board:   board,
+			targets: targets,
+			applied: applied,
+			reason:  reason,
+		})
+	})
+}
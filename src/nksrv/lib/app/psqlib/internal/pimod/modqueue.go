@@ -0,0 +1,261 @@
+package pimod
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	xtypes "github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+
+	"nksrv/lib/app/ibref/ibrefsrnd"
+	"nksrv/lib/app/mailib"
+	"nksrv/lib/mail"
+	. "nksrv/lib/utils/logx"
+
+	. "nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibasemod"
+	. "nksrv/lib/app/psqlib/internal/pibasenntp"
+	"nksrv/lib/app/psqlib/internal/pipostbase"
+	"nksrv/lib/app/psqlib/internal/pipostbase/pipostinsert"
+	"nksrv/lib/app/psqlib/internal/pirefs"
+)
+
+// mod queue of moderated boards.
+// posts which weren't approved by mod wait there in the form they'd be
+// inserted in, until mod approves or rejects them.
+// their files are already in place, modqueue_files keeps them counted,
+// so rejected posts' files get reaped like files of deleted posts.
+
+var ErrNotQueued = errors.New("no such post in mod queue")
+
+// HasApproval tells whether post claims approval of key it was signed with,
+// by having it in Approved header {RFC 5536 3.2.1}.
+func HasApproval(H mail.HeaderMap, pubkeystr string) bool {
+	return pubkeystr != "" &&
+		strings.EqualFold(strings.TrimSpace(H.GetFirst("Approved")), pubkeystr)
+}
+
+// CanApprove tells whether mod can approve queued posts of specified board.
+func CanApprove(modCC pibasemod.ModCombinedCaps, board string) bool {
+	const mask = pibasemod.Cap_ApprovePost
+	return modCC.ModCap.Cap&mask != 0 || modCC.ModBoardCap[board].Cap&mask != 0
+}
+
+// EnqueuePost puts post into mod queue instead of inserting it.
+// tid is 0 for new thread.
+// Files must end up in their usual place before tx is committed.
+func EnqueuePost(
+	sp *PSQLIB, tx *sql.Tx,
+	bid TBoardID, tid TPostID, pi mailib.PostInfo) error {
+
+	fnames := make([]string, len(pi.FI))
+	thumbs := make([]string, len(pi.FI))
+	for i := range pi.FI {
+		fnames[i] = pi.FI[i].ID
+		thumbs[i] = pi.FI[i].ThumbField
+	}
+
+	sp.Log.LogPrintf(DEBUG, "MODQUEUE %s <%s>", pi.ID, pi.MessageID)
+
+	st := tx.Stmt(sp.StPrep[St_mod_modqueue_add])
+	_, err := st.Exec(
+		bid,
+		sql.NullInt64{Int64: int64(tid), Valid: tid != 0},
+		string(pi.MessageID),
+		pipostbase.MustMarshal(&pi),
+		pq.Array(fnames),
+		pq.Array(thumbs))
+	if err != nil {
+		return sp.SQLError("modqueue add query", err)
+	}
+	return nil
+}
+
+type queuedPost struct {
+	mqid  uint64
+	bid   TBoardID
+	board string
+	tid   TPostID // 0 if new thread
+	pi    mailib.PostInfo
+}
+
+// getQueued fetches and locks queued post.
+func (mc *modCtx) getQueued(cmsgids TCoreMsgIDStr) (q queuedPost, err error) {
+	var tid sql.NullInt64
+	var jpi xtypes.JSONText
+
+	st := mc.tx.Stmt(mc.sp.StPrep[St_mod_modqueue_get])
+	err = st.QueryRow(string(cmsgids)).
+		Scan(&q.mqid, &q.bid, &q.board, &tid, &jpi)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return q, ErrNotQueued
+		}
+		return q, mc.sp.SQLError("modqueue get query", err)
+	}
+	q.tid = TPostID(tid.Int64)
+
+	if err = jpi.Unmarshal(&q.pi); err != nil {
+		return q, fmt.Errorf("failed to unmarshal queued post: %v", err)
+	}
+	return
+}
+
+func (mc *modCtx) delQueued(mqid uint64) error {
+	st := mc.tx.Stmt(mc.sp.StPrep[St_mod_modqueue_del])
+	_, err := st.Exec(mqid)
+	if err != nil {
+		return mc.sp.SQLError("modqueue del query", err)
+	}
+	return nil
+}
+
+// approveQueued inserts queued post the same way it'd be if it didn't
+// need approval, and takes it out of queue.
+func (mc *modCtx) approveQueued(q queuedPost) (err error) {
+	var bpid TPostID
+	var duplicate bool
+
+	if q.tid == 0 {
+		_, bpid, duplicate, err = pipostinsert.InsertNewThread(
+			mc.sp, mc.tx, q.bid, q.pi, false, 0)
+	} else {
+		_, bpid, duplicate, err = pipostinsert.InsertNewReply(
+			mc.sp, mc.tx, nil,
+			pipostinsert.ReplyTargetInfo{BID: q.bid, TID: q.tid}, q.pi, 0)
+	}
+	if err != nil {
+		return
+	}
+	if duplicate {
+		return errors.New("approved post already exists")
+	}
+
+	srefs, irefs := ibrefsrnd.ParseReferences(q.pi.MI.Message)
+	prefs := mail.ExtractAllValidReferences(
+		nil, q.pi.H.GetFirst("In-Reply-To"))
+	err = pirefs.ProcessRefsAfterPost(
+		mc.sp, mc.tx,
+		srefs, irefs, prefs,
+		q.bid, q.tid, bpid,
+		q.pi.ID, q.board, q.pi.MessageID)
+	if err != nil {
+		return
+	}
+
+	// files are counted by post now, queue entry can go
+	if err = mc.delQueued(q.mqid); err != nil {
+		return
+	}
+
+	mc.approved = append(mc.approved, q)
+
+	mc.sp.Log.LogPrintf(INFO, "modqueue: approved <%s> in %s",
+		q.pi.MessageID, q.board)
+	return
+}
+
+// rejectQueued drops queued post together with its files.
+func (mc *modCtx) rejectQueued(q queuedPost) (err error) {
+	if err = mc.delQueued(q.mqid); err != nil {
+		return
+	}
+	mc.sp.Log.LogPrintf(INFO, "modqueue: rejected <%s> in %s",
+		q.pi.MessageID, q.board)
+	return
+}
+
+// notifyApproved wakes up pushers for posts approved in committed tx.
+func (mc *modCtx) notifyApproved() {
+	for _, q := range mc.approved {
+		mc.sp.NotifyPush(q.pi.MessageID, q.board)
+	}
+	mc.approved = nil
+}
+
+// modCmdQueue executes approve or reject command.
+// Argument is msgid of queued post.
+func modCmdQueue(
+	mc *modCtx, modCC pibasemod.ModCombinedCaps,
+	approve bool, args []string,
+) (
+	res modCmdResult, err error,
+) {
+
+	if len(args) == 0 {
+		return rejectModCmd(rejNoArgs, "")
+	}
+
+	cmsgids, rej := modCmdMsgID(args[0], "", "")
+	if rej != "" {
+		return rejectModCmd(rej, "", args[0])
+	}
+
+	q, err := mc.getQueued(cmsgids)
+	if err != nil {
+		if err == ErrNotQueued {
+			return rejectModCmd(ErrNotQueued.Error(), "", args[0])
+		}
+		return
+	}
+	if !CanApprove(modCC, q.board) {
+		return rejectModCmd(rejNoCap, q.board, args[0])
+	}
+
+	res = modCmdResult{board: q.board, targets: args[:1], applied: true}
+	if approve {
+		err = mc.approveQueued(q)
+	} else {
+		err = mc.rejectQueued(q)
+	}
+	return
+}
+
+// queueAction approves or rejects queued post on behalf of local admin.
+func queueAction(
+	sp *PSQLIB, board string, cmsgids TCoreMsgIDStr, approve bool) error {
+
+	cmd := "reject"
+	if approve {
+		cmd = "approve"
+	}
+
+	return withModTx(sp, func(mc *modCtx) (err error) {
+		q, err := mc.getQueued(cmsgids)
+		if err != nil {
+			return
+		}
+		if board != "" && q.board != board {
+			return ErrNotQueued
+		}
+
+		if approve {
+			err = mc.approveQueued(q)
+		} else {
+			err = mc.rejectQueued(q)
+		}
+		if err != nil {
+			return
+		}
+
+		return mc.logModCmd(0, "", cmd, modCmdResult{
+			board:   q.board,
+			targets: []string{"<" + string(cmsgids) + ">"},
+			applied: true,
+		})
+	})
+}
+
+// ApproveQueued inserts queued post, as local admin.
+// If board isn't empty, post must be queued for that board.
+// It returns ErrNotQueued if there's no such post in queue.
+func ApproveQueued(sp *PSQLIB, board string, cmsgids TCoreMsgIDStr) error {
+	return queueAction(sp, board, cmsgids, true)
+}
+
+// RejectQueued drops queued post, as local admin.
+func RejectQueued(sp *PSQLIB, board string, cmsgids TCoreMsgIDStr) error {
+	return queueAction(sp, board, cmsgids, false)
+}
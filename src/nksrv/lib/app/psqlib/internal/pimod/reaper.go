@@ -0,0 +1,195 @@
+package pimod
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	. "nksrv/lib/utils/logx"
+)
+
+// unreferenced files/thumbs reaper.
+// triggers put files whose reference counters dropped to zero
+// into files_deleted/fthumbs_deleted job tables.
+// we can only remove them from disk after that got committed,
+// and while holding lock on counter, so that nobody could start
+// using the same file again while we're removing it.
+
+// lockUnused locks counter row and tells whether it's unused.
+// If counter row is already gone, placeholder is inserted to hold lock on.
+func lockUnused(lst, hst *sql.Stmt, args ...interface{}) (bool, error) {
+	var cnt int64
+	err := lst.QueryRow(args...).Scan(&cnt)
+	if err == sql.ErrNoRows {
+		err = hst.QueryRow(args...).Scan(&cnt)
+		if err == sql.ErrNoRows {
+			// someone else just inserted it
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return cnt <= 0, nil
+}
+
+func removeFile(sp *pibase.PSQLIB, fn string) error {
+	err := os.Remove(fn)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	sp.Log.LogPrintf(DEBUG, "REAP %q", fn)
+	return nil
+}
+
+// thumbFiles returns names of files, relative to thumbnail dir,
+// which belong to thumb of file.
+// thumb is stored as "plan.ext", but thumbnailer may have also
+// produced extra files with other extensions for same plan.
+func thumbFiles(thmdir, fname, thumb string) []string {
+	base := fname + "."
+	if i := strings.LastIndexByte(thumb, '.'); i >= 0 {
+		base += thumb[:i+1]
+	}
+	fns := []string{fname + "." + thumb}
+	m, _ := filepath.Glob(thmdir + base + "*")
+	for _, x := range m {
+		x = x[len(thmdir):]
+		// files of other plans have extra dot
+		if x != fns[0] && strings.IndexByte(x[len(base):], '.') < 0 {
+			fns = append(fns, x)
+		}
+	}
+	return fns
+}
+
+func reapFilesTx(sp *pibase.PSQLIB, tx *sql.Tx, limit int) (n int, err error) {
+	srcdir := sp.Src.Main()
+
+	type dfile struct {
+		did   int64
+		fname string
+	}
+	var dfiles []dfile
+
+	rows, err := tx.Stmt(sp.StPrep[pibase.St_mod_joblist_files_deleted_get]).
+		Query(limit)
+	if err != nil {
+		return 0, sp.SQLError("files_deleted query", err)
+	}
+	for rows.Next() {
+		var f dfile
+		if err = rows.Scan(&f.did, &f.fname); err != nil {
+			rows.Close()
+			return 0, sp.SQLError("files_deleted rows scan", err)
+		}
+		dfiles = append(dfiles, f)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, sp.SQLError("files_deleted rows it", err)
+	}
+
+	lst := tx.Stmt(sp.StPrep[pibase.St_mod_joblist_files_deleted_lock])
+	hst := tx.Stmt(sp.StPrep[pibase.St_mod_joblist_files_deleted_hold])
+	dst := tx.Stmt(sp.StPrep[pibase.St_mod_joblist_files_deleted_del])
+	for _, f := range dfiles {
+		var unused bool
+		unused, err = lockUnused(lst, hst, f.fname)
+		if err != nil {
+			return 0, sp.SQLError("files_deleted lock", err)
+		}
+		if unused {
+			if err = removeFile(sp, srcdir+f.fname); err != nil {
+				return
+			}
+		}
+		if _, err = dst.Exec(f.did, f.fname); err != nil {
+			return 0, sp.SQLError("files_deleted del", err)
+		}
+	}
+
+	return len(dfiles), nil
+}
+
+func reapThumbsTx(sp *pibase.PSQLIB, tx *sql.Tx, limit int) (n int, err error) {
+	thmdir := sp.Thm.Main()
+
+	type dthumb struct {
+		did   int64
+		fname string
+		thumb string
+	}
+	var dthumbs []dthumb
+
+	rows, err := tx.Stmt(sp.StPrep[pibase.St_mod_joblist_fthumbs_deleted_get]).
+		Query(limit)
+	if err != nil {
+		return 0, sp.SQLError("fthumbs_deleted query", err)
+	}
+	for rows.Next() {
+		var t dthumb
+		if err = rows.Scan(&t.did, &t.fname, &t.thumb); err != nil {
+			rows.Close()
+			return 0, sp.SQLError("fthumbs_deleted rows scan", err)
+		}
+		dthumbs = append(dthumbs, t)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, sp.SQLError("fthumbs_deleted rows it", err)
+	}
+
+	lst := tx.Stmt(sp.StPrep[pibase.St_mod_joblist_fthumbs_deleted_lock])
+	hst := tx.Stmt(sp.StPrep[pibase.St_mod_joblist_fthumbs_deleted_hold])
+	dst := tx.Stmt(sp.StPrep[pibase.St_mod_joblist_fthumbs_deleted_del])
+	for _, t := range dthumbs {
+		var unused bool
+		unused, err = lockUnused(lst, hst, t.fname, t.thumb)
+		if err != nil {
+			return 0, sp.SQLError("fthumbs_deleted lock", err)
+		}
+		if unused {
+			for _, fn := range thumbFiles(thmdir, t.fname, t.thumb) {
+				if err = removeFile(sp, thmdir+fn); err != nil {
+					return
+				}
+			}
+		}
+		if _, err = dst.Exec(t.did, t.fname, t.thumb); err != nil {
+			return 0, sp.SQLError("fthumbs_deleted del", err)
+		}
+	}
+
+	return len(dthumbs), nil
+}
+
+// ReapFilesOnce removes up to limit unreferenced files
+// and up to limit unreferenced thumbnails.
+// It returns number of processed entries.
+func ReapFilesOnce(sp *pibase.PSQLIB, limit int) (n int, err error) {
+	for _, f := range [...]func(*pibase.PSQLIB, *sql.Tx, int) (int, error){
+		reapFilesTx, reapThumbsTx,
+	} {
+		var tx *sql.Tx
+		tx, err = sp.DB.DB.Begin()
+		if err != nil {
+			return n, sp.SQLError("tx begin", err)
+		}
+
+		var nn int
+		nn, err = f(sp, tx, limit)
+		if err == nil {
+			err = tx.Commit()
+			if err != nil {
+				err = sp.SQLError("tx commit", err)
+			}
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		n += nn
+	}
+	return
+}
@@ -0,0 +1,22 @@
+package pimod
+
+import (
+	"database/sql"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibasenntp"
+	. "nksrv/lib/utils/logx"
+)
+
+// DeleteSupersededInTx deletes post which is being replaced by new one
+// {RFC 5536 3.2.12}.
+// Deletion tables must be already made in tx.
+// Caller is responsible for checking authorship.
+func DeleteSupersededInTx(
+	sp *pibase.PSQLIB, tx *sql.Tx, cmsgid pibasenntp.TCoreMsgIDStr) error {
+
+	sp.Log.LogPrintf(DEBUG, "SUPERSEDE <%s>", cmsgid)
+
+	mc := &modCtx{sp: sp, tx: tx}
+	return mc.deleteByMsgID(cmsgid)
+}
@@ -0,0 +1,106 @@
+package pimod
+
+import (
+	"github.com/lib/pq"
+
+	. "nksrv/lib/utils/logx"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibasemod"
+	. "nksrv/lib/app/psqlib/internal/pibasenntp"
+)
+
+// thread state controlled by mod commands.
+// stored in thread_opts of threads, in the same form as pibase.ThreadOptions
+
+type threadOptCmd struct {
+	opt string
+	val bool
+	cap pibasemod.CapType
+}
+
+var threadOptCmds = map[string]threadOptCmd{
+	"lock":       {"locked", true, pibasemod.Cap_LockThread},
+	"unlock":     {"locked", false, pibasemod.Cap_LockThread},
+	"sticky":     {"sticky", true, pibasemod.Cap_StickyThread},
+	"unsticky":   {"sticky", false, pibasemod.Cap_StickyThread},
+	"autosage":   {"autosage", true, pibasemod.Cap_AutosageThread},
+	"unautosage": {"autosage", false, pibasemod.Cap_AutosageThread},
+}
+
+// setThreadOpt sets option of threads containing msgid.
+// It returns names of boards where threads were touched.
+func (mc *modCtx) setThreadOpt(
+	cmsgids TCoreMsgIDStr, opt string, val bool,
+	anyboard bool, boards []string) (touched []string, err error) {
+
+	st := mc.tx.Stmt(mc.sp.StPrep[pibase.St_mod_set_thread_opt])
+	rows, err := st.Query(
+		string(cmsgids), opt, val, anyboard, pq.Array(boards))
+	if err != nil {
+		return nil, mc.sp.SQLError("set thread opt query", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bname, tname string
+		if err = rows.Scan(&bname, &tname); err != nil {
+			return nil, mc.sp.SQLError("set thread opt rows scan", err)
+		}
+		mc.sp.Log.LogPrintf(INFO,
+			"thread %s/%s: set %s to %t", bname, tname, opt, val)
+		touched = append(touched, bname)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, mc.sp.SQLError("set thread opt rows it", err)
+	}
+	return
+}
+
+// ModCmdThreadOpt executes lock/unlock/sticky/unsticky/autosage/unautosage
+// command. Argument is msgid of any post of thread, usually OP.
+// Threads are touched only in boards where mod has relevant capability.
+func ModCmdThreadOpt(
+	mc *modCtx, modCC pibasemod.ModCombinedCaps,
+	selfid TCoreMsgIDStr,
+	cmd string, args []string,
+) (
+	res modCmdResult, err error,
+) {
+
+	tc, ok := threadOptCmds[cmd]
+	if !ok {
+		return rejectModCmd(rejUnknownCmd, "")
+	}
+	if len(args) == 0 {
+		return rejectModCmd(rejNoArgs, "")
+	}
+
+	cmsgids, rej := modCmdMsgID(args[0], selfid, "")
+	if rej != "" {
+		return rejectModCmd(rej, "", args[0])
+	}
+
+	anyboard := modCC.ModCap.Cap&tc.cap != 0
+	var boards []string
+	if !anyboard {
+		for b, c := range modCC.ModBoardCap {
+			if c.Cap&tc.cap != 0 {
+				boards = append(boards, b)
+			}
+		}
+		if len(boards) == 0 {
+			return rejectModCmd(rejNoCap, "", args[0])
+		}
+	}
+
+	touched, err := mc.setThreadOpt(cmsgids, tc.opt, tc.val, anyboard, boards)
+	if err != nil {
+		return
+	}
+	res = modCmdResult{targets: args[:1], applied: true}
+	if len(touched) == 1 {
+		res.board = touched[0]
+	}
+	return
+}
@@ -0,0 +1,30 @@
+package pipostinsert
+
+import (
+	"database/sql"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/mail"
+)
+
+// setExpires stores parsed Expires {RFC 5536 3.2.5} date of just inserted
+// post so that expiry doesn't need to look at headers of every post.
+// bogus Expires is as good as none.
+func setExpires(
+	sp *pibase.PSQLIB, tx *sql.Tx,
+	gpid pibase.TPostID, H mail.HeaderMap) error {
+
+	hexp := H.GetFirst("Expires")
+	if hexp == "" {
+		return nil
+	}
+	t, e := mail.ParseDateX(hexp, true)
+	if e != nil {
+		return nil
+	}
+	_, err := tx.Stmt(sp.StPrep[pibase.St_post_set_expires]).Exec(gpid, t)
+	if err != nil {
+		return sp.SQLError("set expires query", err)
+	}
+	return nil
+}
@@ -138,6 +138,11 @@ func InsertNewReply(
 		return
 	}
 
+	err = setExpires(sp, tx, gpid, pInfo.H)
+	if err != nil {
+		return
+	}
+
 	sp.Log.LogPrintf(DEBUG, "NEWPOST %s done", pInfo.ID)
 
 	// done
@@ -297,6 +302,13 @@ func InsertNewReplyMB(
 		return
 	}
 
+	// rows must be done before tx can be used for anything else
+	r.Close()
+	err = setExpires(sp, tx, gpid, pInfo.H)
+	if err != nil {
+		return
+	}
+
 	sp.Log.LogPrintf(DEBUG, "NEWPOST %s done", pInfo.ID)
 
 	// done
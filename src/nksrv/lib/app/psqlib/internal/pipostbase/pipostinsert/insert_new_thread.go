@@ -133,6 +133,11 @@ func InsertNewThread(
 		return
 	}
 
+	err = setExpires(sp, tx, gpid, pInfo.H)
+	if err != nil {
+		return
+	}
+
 	sp.Log.LogPrintf(logx.DEBUG, "NEWTHREAD %s done", pInfo.ID)
 
 	// done
@@ -289,6 +294,13 @@ func InsertNewThreadMB(
 		return
 	}
 
+	// rows must be done before tx can be used for anything else
+	r.Close()
+	err = setExpires(sp, tx, gpid, pInfo.H)
+	if err != nil {
+		return
+	}
+
 	sp.Log.LogPrintf(logx.DEBUG, "NEWTHREAD %s done", pInfo.ID)
 
 	// done
@@ -221,6 +221,20 @@ func setModCap(
 	sp.Log.LogPrintf(DEBUG,
 		"setmodpriv: %s priv changed", pubkeystr)
 
+	// record in mod log; privs are only set by local admin
+	lst := tx.Stmt(sp.StPrep[pibase.St_mod_log_cmd])
+	_, err = lst.Exec(
+		nil, nil,
+		sql.NullString{String: group, Valid: group != ""},
+		"setpriv",
+		pq.Array([]string{pubkeystr}),
+		true,
+		"cap "+m_cap.String()+" inherit "+mi_cap.String())
+	if err != nil {
+		err = sp.SQLError("st_mod_log_cmd exec", err)
+		return
+	}
+
 	return
 }
 
@@ -0,0 +1,80 @@
+package pipostbase
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibasenntp"
+)
+
+// Supersedes {RFC 5536 3.2.12}.
+// We only honour it if new post is signed by same key as old one,
+// as otherwise anyone could replace anyone else's posts.
+
+var (
+	ErrSupersedeNotAuthor = errors.New(
+		"superseding post must be signed by same key as superseded one")
+	ErrSupersedeOP = errors.New(
+		"superseding thread OP is not supported")
+)
+
+// SupersededInfo describes post which is about to be replaced.
+type SupersededInfo struct {
+	MsgID pibasenntp.TCoreMsgIDStr // superseded post
+	Trip  string                   // pubkey it was signed with, if any
+	BID   boardID                  // board it's in
+	TID   postID                   // thread it's in
+	IsOP  bool
+	Root  pibasenntp.TCoreMsgIDStr // OP of thread it's in
+	Date  time.Time                // its date, zero if unknown
+}
+
+// LookupSuperseded finds post in board which is to be superseded.
+// Not found post isn't error, found will be false then.
+func LookupSuperseded(
+	sp *pibase.PSQLIB, cmsgid pibasenntp.TCoreMsgIDStr, board string) (
+	si SupersededInfo, found bool, err error) {
+
+	st := sp.StPrep[pibase.St_post_superseded_info]
+	var date sql.NullTime
+	err = st.QueryRow(string(cmsgid), board).Scan(
+		&si.Trip, &si.BID, &si.TID, &si.IsOP, &si.Root, &date)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = nil
+			return
+		}
+		err = sp.SQLError("superseded info query", err)
+		return
+	}
+	si.MsgID = cmsgid
+	si.Date = date.Time
+	found = true
+	return
+}
+
+// SupersedeDate returns date replacement post should be stored with.
+// It takes over date of superseded post so that it stays where
+// superseded post was in thread instead of going to its end,
+// and doesn't bump thread either.
+func SupersedeDate(si SupersededInfo, date time.Time) time.Time {
+	if si.Date.IsZero() {
+		return date
+	}
+	return si.Date
+}
+
+// CheckSupersede decides whether post signed with pubkey (may be empty)
+// may replace superseded post.
+// Deleting OP would nuke whole thread, so we don't allow that.
+func CheckSupersede(si SupersededInfo, pubkey string) error {
+	if si.IsOP {
+		return ErrSupersedeOP
+	}
+	if si.Trip == "" || pubkey == "" || si.Trip != pubkey {
+		return ErrSupersedeNotAuthor
+	}
+	return nil
+}
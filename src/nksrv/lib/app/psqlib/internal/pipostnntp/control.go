@@ -0,0 +1,336 @@
+package pipostnntp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"nksrv/lib/app/base/ctlmsg"
+	"nksrv/lib/app/base/mailibsign"
+	"nksrv/lib/app/mailib"
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pimod"
+	"nksrv/lib/mail"
+	. "nksrv/lib/utils/logx"
+	au "nksrv/lib/utils/text/asciiutils"
+)
+
+// control messages are small, except maybe checkgroups
+const maxControlBodySize = 1 << 20
+
+// ErrControlNotAllowed is returned when sender of control message
+// has no authority over what it targets
+var ErrControlNotAllowed = errors.New("control message not authorized")
+var errControlSeen = errors.New("control message already processed")
+
+func connPeerName(cs *ConnState) string {
+	if cs == nil {
+		return ""
+	}
+	return cs.PeerName()
+}
+
+// signature can only be checked over body as it is
+func identityCTE(cte string) bool {
+	cte = strings.ToLower(au.TrimWSString(cte))
+	return cte == "" || cte == "7bit" || cte == "8bit" || cte == "binary"
+}
+
+// reads control message body and figures out who signed it, if anyone.
+// Signature only covers inner message, so key is only returned if
+// inner message carries the same Control and Date headers as outer one,
+// otherwise anyone could rewrap signed message with Control of their own,
+// or refresh Date of old one.
+// sig is signature key was verified with, it identifies message
+// no matter how it's rewrapped.
+func readControlBody(
+	H mail.HeaderMap, r io.Reader) (body []byte, key, sig string, err error) {
+
+	body, err = ioutil.ReadAll(io.LimitReader(r, maxControlBodySize+1))
+	if err != nil {
+		err = fmt.Errorf("failed reading body: %v", err)
+		return
+	}
+	if len(body) > maxControlBodySize {
+		err = fmt.Errorf(
+			"control message body too large, up to %d allowed",
+			maxControlBodySize)
+		return
+	}
+
+	act_t, act_par := mailib.ProcessContentType(H.GetFirst("Content-Type"))
+	eatinner := isInnerMessage(act_t, H)
+
+	ver, iow := mailibsign.PrepareVerifier(H, act_t, act_par, eatinner)
+	if ver != nil && identityCTE(H.GetFirst("Content-Transfer-Encoding")) {
+		_, _ = iow.Write(body)
+		key = ver.Verify(iow).PubKey
+	}
+
+	if eatinner {
+		// actual content is in inner message
+		mh, e := mail.ReadHeaders(
+			bytes.NewReader(body), mailib.DefaultHeaderSizeLimit)
+		if e == nil {
+			if key != "" &&
+				(!sameHeader(H, mh.H, "Control") ||
+					!sameHeader(H, mh.H, "Date")) {
+
+				key = ""
+			}
+			body, _ = ioutil.ReadAll(mh.B)
+			mh.Close()
+		} else {
+			key = ""
+		}
+	}
+
+	if key != "" {
+		sig = H.GetFirst("X-Signature-Ed25519-SHA512")
+		if sig == "" {
+			sig = H.GetFirst("X-Signature-Ed25519-BLAKE2b")
+		}
+		sig = strings.ToLower(au.TrimWSString(sig))
+	}
+	return
+}
+
+// whether both have single h header with exactly the same value.
+func sameHeader(outer, inner mail.HeaderMap, h string) bool {
+	return len(outer[h]) == 1 && len(inner[h]) == 1 &&
+		outer[h][0].V == inner[h][0].V
+}
+
+// adds board for newgroup and checkgroups.
+// existing board isn't error.
+func controlAddBoard(
+	sp *pibase.PSQLIB, group, desc string, moderated bool) (
+	err error, unexpected bool) {
+
+	if !shouldAutoAddNNTPPostGroup(sp, group) {
+		err = fmt.Errorf("newsgroup %q not allowed by policy", group)
+		return
+	}
+	bi := sp.IBDefaultBoardInfo()
+	bi.Name = group
+	bi.Description = desc
+	bi.Moderated = moderated
+	err, dup := addNewBoard(sp, bi)
+	if err != nil {
+		if dup {
+			sp.Log.LogPrintf(DEBUG, "control: newsgroup %q already exists", group)
+			return nil, false
+		}
+		return fmt.Errorf("addNewBoard error: %v", err), true
+	}
+	sp.Log.LogPrintf(NOTICE, "control: added newsgroup %q", group)
+	return
+}
+
+// controlArticle puts control message back together for storage
+func controlArticle(H mail.HeaderMap, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	err := mail.WriteMessageHeaderMap(&buf, H, true)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed writing control message headers: %v", err)
+	}
+	buf.WriteByte('\n')
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// acts upon control message {RFC 5537 5}.
+// Processed ones are remembered so that they can't be replayed.
+// Ones received from peers aren't propagated further,
+// but ones posted here are kept (if keep is set) and pushed to peers.
+// Authorization comes from ControlTrust, using either key message was
+// signed with or name of peer which sent it to us.
+func nntpHandleControl(
+	sp *pibase.PSQLIB, H mail.HeaderMap, r io.Reader,
+	info nntpParsedInfo, peer string, keep bool) (
+	err error, unexpected bool) {
+
+	c := info.Control
+
+	body, key, sig, err := readControlBody(H, r)
+	if err != nil {
+		return
+	}
+
+	// Message-ID is checked before we get there, but signed message
+	// could be rewrapped with different one
+	var cmsgid TCoreMsgIDStr
+	if info.FullMsgIDStr != "" {
+		cmsgid = cutMsgID(info.FullMsgIDStr)
+	}
+	seen, err := pimod.ControlSeen(sp, cmsgid, sig)
+	if err != nil {
+		unexpected = true
+		return
+	}
+	if seen {
+		err = errControlSeen
+		return
+	}
+	defer func() {
+		if err != nil {
+			return
+		}
+		var article []byte
+		if keep && cmsgid != "" {
+			article, err = controlArticle(H, body)
+			if err != nil {
+				unexpected = true
+				return
+			}
+		}
+		err = pimod.RecordControl(sp, cmsgid, sig, article)
+		if err != nil {
+			unexpected = true
+			return
+		}
+		if article != nil {
+			sp.NotifyPush(cmsgid, pibase.ControlPushGroup)
+		}
+	}()
+
+	sp.Log.LogPrintf(INFO,
+		"control: %s %q from %s (key=%q peer=%q)",
+		c.Verb, c.Args, info.FullMsgIDStr, key, peer)
+
+	allowed := func(group string) bool {
+		return sp.ControlTrust.Allowed(c.Verb, group, key, peer)
+	}
+
+	// goes to mod log like mod commands do, applied or rejected
+	defer func() {
+		if err != nil && err != ErrControlNotAllowed {
+			return
+		}
+		board, reason := "", ""
+		if c.Verb == ctlmsg.VerbNewGroup || c.Verb == ctlmsg.VerbRmGroup {
+			board = c.Group()
+		}
+		if err != nil {
+			reason = err.Error()
+		}
+		e := pimod.LogControl(
+			sp, cmsgid, c.Verb, board, c.Args, err == nil, reason)
+		if e != nil {
+			err, unexpected = e, true
+		}
+	}()
+
+	switch c.Verb {
+
+	case ctlmsg.VerbCancel:
+		target := c.Target()
+		if info.FullMsgIDStr != "" &&
+			cutMsgID(target) == cutMsgID(info.FullMsgIDStr) {
+
+			err = errors.New("cancel message cancelling itself")
+			return
+		}
+		// authority over every group target article is in,
+		// or anyone who knows key matching article's Cancel-Lock
+		err = pimod.CancelByMsgID(sp, cutMsgID(target), allowed)
+		if err == pimod.ErrCancelNotAllowed && len(H["Cancel-Key"]) != 0 {
+			keys := make([]string, len(H["Cancel-Key"]))
+			for i, v := range H["Cancel-Key"] {
+				keys[i] = v.V
+			}
+			err = pimod.CancelWithKey(sp, cutMsgID(target), keys)
+			if err == pimod.ErrCancelKeyMismatch {
+				err = ErrControlNotAllowed
+				return
+			}
+		}
+		if err == pimod.ErrCancelNotAllowed {
+			err = ErrControlNotAllowed
+			return
+		}
+		if err == pibase.ErrNoSuchPost {
+			sp.Log.LogPrintf(DEBUG,
+				"control: cancel target %s not found", target)
+			err = nil
+			return
+		}
+		if err != nil {
+			unexpected = true
+			return
+		}
+		sp.Log.LogPrintf(NOTICE, "control: cancelled %s", target)
+
+	case ctlmsg.VerbNewGroup:
+		group := c.Group()
+		if !allowed(group) {
+			err = ErrControlNotAllowed
+			return
+		}
+		desc := ""
+		l, _ := ctlmsg.ParseGroupList(bytes.NewReader(body))
+		for _, gi := range l {
+			if gi.Name == group {
+				desc = gi.Description
+				break
+			}
+		}
+		err, unexpected = controlAddBoard(sp, group, desc, c.Moderated())
+
+	case ctlmsg.VerbRmGroup:
+		group := c.Group()
+		if !allowed(group) {
+			err = ErrControlNotAllowed
+			return
+		}
+		err = pimod.DeleteBoard(sp, group)
+		if err != nil {
+			if err == pibase.ErrNoSuchBoard {
+				sp.Log.LogPrintf(DEBUG,
+					"control: newsgroup %q doesn't exist", group)
+				err = nil
+				return
+			}
+			unexpected = true
+			return
+		}
+		sp.Log.LogPrintf(NOTICE, "control: removed newsgroup %q", group)
+
+	case ctlmsg.VerbCheckGroups:
+		var l []ctlmsg.GroupInfo
+		l, err = ctlmsg.ParseGroupList(bytes.NewReader(body))
+		if err != nil {
+			err = fmt.Errorf("bad checkgroups body: %v", err)
+			return
+		}
+		// we only add missing groups.
+		// groups not listed are left alone, removal needs rmgroup
+		nallowed := 0
+		for _, gi := range l {
+			if !c.InScope(gi.Name) || !allowed(gi.Name) {
+				continue
+			}
+			nallowed++
+			e, u := controlAddBoard(sp, gi.Name, gi.Description, false)
+			if e != nil {
+				if u {
+					err, unexpected = e, u
+					return
+				}
+				sp.Log.LogPrintf(INFO, "control: checkgroups: %v", e)
+			}
+		}
+		if nallowed == 0 && len(l) != 0 {
+			err = ErrControlNotAllowed
+			return
+		}
+
+	default:
+		err = ctlmsg.ErrUnsupported
+	}
+	return
+}
@@ -63,7 +63,7 @@ func acceptArticleHead(
 	if !ins.isReply {
 
 		// new thread
-		q := `SELECT b_id,post_limits,newthread_limits
+		q := `SELECT b_id,post_limits,newthread_limits,moderated
 FROM ib0.boards
 WHERE b_name=$1`
 
@@ -71,7 +71,7 @@ WHERE b_name=$1`
 
 		nadd := 0
 		for {
-			err = sp.DB.DB.QueryRow(q, board).Scan(&ins.bid, &jbPL, &jbXL)
+			err = sp.DB.DB.QueryRow(q, board).Scan(&ins.bid, &jbPL, &jbXL, &ins.moderated)
 			if err != nil {
 				if err == sql.ErrNoRows {
 					if !shouldAutoAddNNTPPostGroup(sp, board) || nadd >= 20 {
@@ -119,7 +119,7 @@ WHERE b_name=$1`
 		q := `WITH
 	xb AS (
 		SELECT
-			b_id,post_limits,reply_limits,thread_opts
+			b_id,post_limits,reply_limits,thread_opts,moderated
 		FROM
 			ib0.boards
 		WHERE
@@ -130,12 +130,13 @@ WHERE b_name=$1`
 SELECT
 	xb.b_id,xb.post_limits,xb.reply_limits,
 	xtp.b_id,xtp.b_t_id,xtp.reply_limits,xb.thread_opts,xtp.thread_opts,
-	xtp.title,xtp.date_sent
+	xtp.title,xtp.date_sent,xtp.archived,xb.moderated
 FROM
 	xb
 FULL JOIN (
 	SELECT
-		xt.b_id,xt.b_t_id,xt.reply_limits,xt.thread_opts,xp.title,xp.date_sent
+		xt.b_id,xt.b_t_id,xt.reply_limits,xt.thread_opts,xp.title,xp.date_sent,
+		xt.t_arch_order IS NOT NULL AS archived
 	FROM
 		ib0.threads xt
 	JOIN
@@ -160,10 +161,12 @@ ON TRUE`
 		var xtid sql.NullInt64
 		var xsubject sql.NullString
 		var xreftime *time.Time
+		var xarchived sql.NullBool
+		var xmoderated sql.NullBool
 
 		err = sp.db.DB.QueryRow(q, board, string(mm.CutMessageIDStr(troot))).
 			Scan(&xbid, &jbPL, &jbXL, &xtbid, &xtid, &jtRL, &jbTO, &jtTO,
-				&xsubject, &xreftime)
+				&xsubject, &xreftime, &xarchived, &xmoderated)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				err = errNoSuchBoard
@@ -213,6 +216,11 @@ ON TRUE`
 			return
 		}
 
+		if xarchived.Bool && !sp.ArchivedNNTPReplies {
+			err = pibase.ErrThreadArchived
+			return
+		}
+
 		if xreftime.Unix() > pdate {
 			err = errors.New("post has date before post it refers to")
 			return
@@ -220,6 +228,7 @@ ON TRUE`
 
 		ins.tid = postID(xtid.Int64)
 		ins.refSubject = xsubject.String
+		ins.moderated = xmoderated.Bool
 
 		ins.postLimits = defaultReplySubmissionLimits
 
@@ -240,6 +249,11 @@ ON TRUE`
 		}
 
 		sp.applyInstanceThreadOptions(&ins.threadOpts, board)
+
+		if ins.threadOpts.Locked {
+			err = pibase.ErrThreadLocked
+			return
+		}
 	}
 
 	// apply instance-specific limit tweaks
@@ -306,7 +320,7 @@ func (sp *PSQLIB) HandlePost(
 
 	nntpAbortOnErr(w.ResSendArticleToBePosted())
 	r := ro.OpenReader()
-	err, unexpected := sp.netnewsHandleSubmissionDirectly(r, false)
+	err, unexpected := sp.netnewsHandleSubmissionDirectly(r, cs, false)
 	if err != nil {
 		if !unexpected {
 			err = w.ResPostingFailed(err)
@@ -321,8 +335,9 @@ func (sp *PSQLIB) HandlePost(
 	return true
 }
 
+// cs is nil if article doesn't come from client connection.
 func (sp *PSQLIB) netnewsHandleSubmissionDirectly(
-	r io.Reader, notrace bool) (
+	r io.Reader, cs *ConnState, notrace bool) (
 	err error, unexpected bool) {
 
 	lr := &io.LimitedReader{R: r, N: int64(math.MaxInt64)}
@@ -348,17 +363,33 @@ func (sp *PSQLIB) netnewsHandleSubmissionDirectly(
 	if err != nil {
 		return
 	}
+	if err = checkPostGroup(cs, info.Newsgroup); err != nil {
+		return
+	}
 
 	if info.FullMsgIDStr != "" {
+		// processed control messages count as existing too
 		err, unexpected = sp.ensureArticleDoesntExist(cutMsgID(info.FullMsgIDStr))
 		if err != nil {
 			return
 		}
 	}
 
+	if info.Control != nil {
+		return sp.nntpHandleControl(mh.H, mh.B, info, connPeerName(cs), true)
+	}
+
 	return sp.netnewsSubmitArticle(mh.B, mh.H, info)
 }
 
+// SubmitLocalArticle processes article made by us
+// the same way as one posted by client.
+func SubmitLocalArticle(
+	sp *pibase.PSQLIB, r io.Reader) (err error, unexpected bool) {
+
+	return sp.netnewsHandleSubmissionDirectly(r, nil, false)
+}
+
 // + iok: 335{ResSendArticleToBeTransferred} ifail: 435{ResTransferNotWanted[false]} 436{ResTransferFailed}
 // cok: 235{ResTransferSuccess} cfail: 436{ResTransferFailed} 437{ResTransferRejected}
 func (sp *PSQLIB) HandleIHave(
@@ -379,13 +410,24 @@ func (sp *PSQLIB) HandleIHave(
 		return false
 	}
 
+	if !sp.PostLocks.Lock(string(unsafe_sid), cs.PosterID(), cs) {
+		// someone else is sending it right now
+		nntpAbortOnErr(w.ResTransferFailed())
+		return true
+	}
+	failed := false
+	defer func() {
+		sp.PostLocks.Unlock(string(unsafe_sid), cs, failed)
+	}()
+
 	nntpAbortOnErr(w.ResSendArticleToBeTransferred())
 	r := ro.OpenReader()
 
 	info, newname, H, err, unexpected, _ :=
-		sp.handleIncoming(r, unsafe_sid, "", nntpIncomingDir, false)
+		sp.handleIncoming(r, cs, unsafe_sid, "", nntpIncomingDir, false)
 	if err != nil {
 		if !unexpected {
+			failed = true
 			err = w.ResTransferRejected(err)
 		} else {
 			err = w.ResInternalError(err)
@@ -395,7 +437,9 @@ func (sp *PSQLIB) HandleIHave(
 		return true
 	}
 
-	sp.nntpSendIncomingArticle(newname, H, info)
+	if info.Control == nil {
+		sp.nntpSendIncomingArticle(newname, H, info)
+	}
 
 	// we're done there, signal success
 	nntpAbortOnErr(w.ResTransferSuccess())
@@ -420,11 +464,17 @@ func (sp *PSQLIB) HandleCheck(
 		// article exists, false for default message
 		return false
 	}
+	if sp.PostLocks.Check(string(unsafe_sid), cs.PosterID(), cs) {
+		// other connection is transferring it, or poster misbehaves
+		nntpAbortOnErr(w.ResArticleWantLater(msgid))
+		return true
+	}
 	nntpAbortOnErr(w.ResArticleWanted(msgid))
 	return true
 }
 
 // + ok: 239{ResArticleTransferedOK} 439{ResArticleRejected[false]}
+// busy: 400{ResServiceUnavailable}
 func (sp *PSQLIB) HandleTakeThis(
 	w Responder, cs *ConnState, r nntp.ArticleReader, msgid TCoreMsgID) bool {
 
@@ -443,10 +493,35 @@ func (sp *PSQLIB) HandleTakeThis(
 		return false
 	}
 
+	if !sp.PostLocks.LockWait(string(unsafe_sid), cs.PosterID(), cs) {
+		// TAKETHIS can't be answered with 431, and 439 would make
+		// peer never offer it again, even if other transfer fails.
+		// drop connection so that peer retries later
+		_, _ = r.Discard(-1)
+		nntpAbortOnErr(w.ResServiceUnavailable(errArticleInProgress))
+		w.Abort()
+	}
+	failed := false
+	defer func() {
+		sp.PostLocks.Unlock(string(unsafe_sid), cs, failed)
+	}()
+
+	// other transfer we waited for may have got it
+	exists, err = sp.nntpCheckArticleExistsOrBanned(unsafe_sid)
+	if err != nil {
+		nntpAbortOnErr(w.ResInternalError(err))
+		_, _ = r.Discard(-1)
+		return true
+	}
+	if exists {
+		return false
+	}
+
 	info, newname, H, err, unexpected, _ :=
-		sp.handleIncoming(r, unsafe_sid, "", nntpIncomingDir, false)
+		sp.handleIncoming(r, cs, unsafe_sid, "", nntpIncomingDir, false)
 	if err != nil {
 		if !unexpected {
+			failed = true
 			err = w.ResArticleRejected(msgid, err)
 		} else {
 			err = w.ResInternalError(err)
@@ -456,22 +531,26 @@ func (sp *PSQLIB) HandleTakeThis(
 		return true
 	}
 
-	sp.nntpSendIncomingArticle(newname, H, info)
+	if info.Control == nil {
+		sp.nntpSendIncomingArticle(newname, H, info)
+	}
 
 	// we're done there, signal success
 	nntpAbortOnErr(w.ResArticleTransferedOK(msgid))
 	return true
 }
 
+// cs is nil if article doesn't come from client connection.
 func (sp *PSQLIB) handleIncoming(
-	r io.Reader, unsafe_sid TCoreMsgIDStr, expectgroup string, incdir string,
+	r io.Reader, cs *ConnState,
+	unsafe_sid TCoreMsgIDStr, expectgroup string, incdir string,
 	notrace bool) (
 	info nntpParsedInfo, newname string, H mail.HeaderMap,
 	err error, unexpected bool, wantroot TFullMsgIDStr) {
 
 	info, f, H, err, unexpected, wantroot :=
-		sp.handleIncomingIntoFile(r, unsafe_sid, expectgroup, notrace)
-	if err != nil {
+		sp.handleIncomingIntoFile(r, cs, unsafe_sid, expectgroup, notrace)
+	if err != nil || info.Control != nil {
 		return
 	}
 
@@ -496,7 +575,8 @@ func (sp *PSQLIB) handleIncoming(
 }
 
 func (sp *PSQLIB) handleIncomingIntoFile(
-	r io.Reader, unsafe_sid TCoreMsgIDStr, expectgroup string, notrace bool) (
+	r io.Reader, cs *ConnState,
+	unsafe_sid TCoreMsgIDStr, expectgroup string, notrace bool) (
 	info nntpParsedInfo, f *os.File, H mail.HeaderMap,
 	err error, unexpected bool, wantroot TFullMsgIDStr) {
 
@@ -510,9 +590,23 @@ func (sp *PSQLIB) handleIncomingIntoFile(
 
 	info, err, unexpected, wantroot =
 		sp.nntpDigestTransferHead(mh.H, unsafe_sid, expectgroup, false, notrace)
+	if cs != nil {
+		// even if rejected, it tells us how peer calls itself
+		cs.NotePeerPathID(info.PathSender)
+	}
 	if err != nil {
 		return
 	}
+	if err = checkPostGroup(cs, info.Newsgroup); err != nil {
+		return
+	}
+
+	if info.Control != nil {
+		// acted upon right away, nothing to store
+		err, unexpected =
+			sp.nntpHandleControl(mh.H, mh.B, info, connPeerName(cs), false)
+		return
+	}
 
 	f, err, unexpected = sp.netnewsCopyArticleToFile(mh.H, mh.B)
 	if err != nil {
@@ -524,6 +618,18 @@ func (sp *PSQLIB) handleIncomingIntoFile(
 }
 
 var errArticleAlreadyExists = errors.New("article with this Message-ID already exists")
+var errArticleInProgress = errors.New("article is being transferred by other connection")
+
+// checkPostGroup checks whether client is allowed to post to group.
+// Error doesn't tell whether group exists, so it's fine for hidden ones too.
+func checkPostGroup(cs *ConnState, group string) error {
+	if cs != nil &&
+		(!cs.CanPostGroup(group) || !cs.CanReadGroup(group)) {
+
+		return fmt.Errorf("posting to newsgroup %q not permitted", group)
+	}
+	return nil
+}
 
 func (sp *PSQLIB) ensureArticleDoesntExist(
 	msgid TCoreMsgIDStr) (err error, unexpected bool) {
@@ -7,6 +7,8 @@ import (
 	"os"
 
 	"nksrv/lib/app/ibref/ibrefsrnd"
+	"nksrv/lib/app/psqlib/internal/pimod"
+	"nksrv/lib/app/psqlib/internal/pipostbase"
 	"nksrv/lib/mail"
 	fu "nksrv/lib/utils/fs/fileutil"
 	. "nksrv/lib/utils/logx"
@@ -46,6 +48,13 @@ func (ctx *postNNTPContext) netnewsSubmitArticle(
 		return
 	}
 
+	if ctx.info.Superseded != nil {
+		err = pipostbase.CheckSupersede(*ctx.info.Superseded, ctx.pi.MI.Trip)
+		if err != nil {
+			return
+		}
+	}
+
 	// before starting transaction, ensure stmt for postinsert is ready
 	// otherwise deadlock is v possible
 	var gstmt *sql.Stmt
@@ -80,6 +89,15 @@ func (ctx *postNNTPContext) netnewsSubmitArticle(
 		return
 	}
 
+	if ctx.info.Superseded != nil {
+		err = pimod.DeleteSupersededInTx(ctx.sp, tx, ctx.info.Superseded.MsgID)
+		if err != nil {
+			unexpected = true
+			return
+		}
+		ctx.pi.Date = pipostbase.SupersedeDate(*ctx.info.Superseded, ctx.pi.Date)
+	}
+
 	isctlgrp := ctx.info.Newsgroup == "ctl"
 
 	var modid uint64
@@ -100,73 +118,95 @@ func (ctx *postNNTPContext) netnewsSubmitArticle(
 		sp.log.LogPrintf(DEBUG, "REGMOD %s done", ctx.pubkeystr)
 	}
 
-	var gpid, bpid postID
-	var duplicate bool
-	// perform insert
-	if !info.isReply {
-		sp.log.LogPrint(DEBUG, "inserting newthread post data to database")
-		gpid, bpid, duplicate, err =
-			sp.insertNewThread(tx, gstmt, info.bid, pi, isctlgrp, modid)
-	} else {
-		sp.log.LogPrint(DEBUG, "inserting reply post data to database")
-		gpid, bpid, duplicate, err =
-			sp.insertNewReply(
-				tx, gstmt,
-				replyTargetInfo{info.bid, info.tid},
-				pi, modid)
-	}
-	if err != nil {
-		err = fmt.Errorf("post insertion failed: %v", err)
-		unexpected = true
-		return
-	}
-	if duplicate {
-		err = errDuplicateArticle
-		return
+	// moderated board takes post only if mod approved it
+	queued := ctx.info.moderated && !isctlgrp
+	if queued && pimod.HasApproval(ctx.H, ctx.pubkeystr) {
+		var apCC ModCombinedCaps
+		_, _, apCC, err = ctx.sp.registeredMod(tx, ctx.pubkeystr)
+		if err != nil {
+			unexpected = true
+			return
+		}
+		queued = !pimod.CanApprove(apCC, ctx.info.Newsgroup)
 	}
 
-	// execute mod cmd
-	if hascap {
-
-		var cref TCoreMsgIDStr
-		if info.FRef != "" {
-			cref = cutMsgID(info.FRef)
+	if queued {
+		ctx.sp.log.LogPrint(DEBUG, "putting post data to mod queue")
+		err = pimod.EnqueuePost(
+			ctx.sp, tx, ctx.info.bid, ctx.info.tid, ctx.pi)
+		if err != nil {
+			unexpected = true
+			return
+		}
+	} else {
+		var gpid, bpid postID
+		var duplicate bool
+		// perform insert
+		if !info.isReply {
+			sp.log.LogPrint(DEBUG, "inserting newthread post data to database")
+			gpid, bpid, duplicate, err =
+				sp.insertNewThread(tx, gstmt, info.bid, pi, isctlgrp, modid)
+		} else {
+			sp.log.LogPrint(DEBUG, "inserting reply post data to database")
+			gpid, bpid, duplicate, err =
+				sp.insertNewReply(
+					tx, gstmt,
+					replyTargetInfo{info.bid, info.tid},
+					pi, modid)
 		}
+		if err != nil {
+			err = fmt.Errorf("post insertion failed: %v", err)
+			unexpected = true
+			return
+		}
+		if duplicate {
+			err = errDuplicateArticle
+			return
+		}
+
+		// execute mod cmd
+		if hascap {
+
+			var cref TCoreMsgIDStr
+			if info.FRef != "" {
+				cref = cutMsgID(info.FRef)
+			}
 
-		// msgid deletion state
-		var delmsgids delMsgIDState
-		defer func() { sp.cleanDeletedMsgIDs(delmsgids) }()
+			// msgid deletion state
+			var delmsgids delMsgIDState
+			defer func() { sp.cleanDeletedMsgIDs(delmsgids) }()
 
-		sp.log.LogPrintf(DEBUG, "EXECMOD %s start", pi.MessageID)
+			sp.log.LogPrintf(DEBUG, "EXECMOD %s start", pi.MessageID)
 
-		// we should execute it
-		delmsgids, _, err, _ = sp.execModCmd(
-			tx, gpid, info.bid, bpid,
-			modid, modCC,
-			pi, tmpfns, pi.MessageID,
-			cref, delmsgids, delModIDState{})
+			// we should execute it
+			delmsgids, _, err, _ = sp.execModCmd(
+				tx, gpid, info.bid, bpid,
+				modid, modCC,
+				pi, tmpfns, pi.MessageID,
+				cref, delmsgids, delModIDState{})
+			if err != nil {
+				unexpected = true
+				return
+			}
+
+			sp.log.LogPrintf(DEBUG, "EXECMOD %s done", pi.MessageID)
+		}
+
+		// parse msg itself
+		srefs, irefs := ibrefsrnd.ParseReferences(pi.MI.Message)
+		// In-Reply-To helps
+		prefs :=
+			mail.ExtractAllValidReferences(nil, H.GetFirst("In-Reply-To"))
+		// do processing
+		err = sp.processRefsAfterPost(
+			tx,
+			srefs, irefs, prefs,
+			info.bid, info.tid, bpid,
+			pi.ID, info.Newsgroup, pi.MessageID)
 		if err != nil {
 			unexpected = true
 			return
 		}
-
-		sp.log.LogPrintf(DEBUG, "EXECMOD %s done", pi.MessageID)
-	}
-
-	// parse msg itself
-	srefs, irefs := ibrefsrnd.ParseReferences(pi.MI.Message)
-	// In-Reply-To helps
-	prefs :=
-		mail.ExtractAllValidReferences(nil, H.GetFirst("In-Reply-To"))
-	// do processing
-	err = sp.processRefsAfterPost(
-		tx,
-		srefs, irefs, prefs,
-		info.bid, info.tid, bpid,
-		pi.ID, info.Newsgroup, pi.MessageID)
-	if err != nil {
-		unexpected = true
-		return
 	}
 
 	// move files
@@ -226,5 +266,7 @@ func (ctx *postNNTPContext) netnewsSubmitArticle(
 	}
 	sp.log.LogPrintf(DEBUG, "nntppost commit done")
 
+	sp.NotifyPush(pi.MessageID, info.Newsgroup)
+
 	return
 }
@@ -1,7 +1,9 @@
 package pipostnntp
 
 import (
+	"nksrv/lib/app/base/ctlmsg"
 	"nksrv/lib/app/mailib"
+	"nksrv/lib/app/psqlib/internal/pipostbase"
 	"nksrv/lib/mail"
 )
 
@@ -12,12 +14,22 @@ type insertSqlInfo struct {
 	bid        boardID
 	isReply    bool
 	refSubject string
+	moderated  bool // board needs posts to be approved
 }
 
 type nntpParsedInfo struct {
 	insertSqlInfo
 	mailib.ParsedMessageInfo
 	FRef TFullMsgIDStr
+
+	PathSender string // identity of node which sent us article, if known
+
+	// set if article is control message we should act upon
+	// instead of storing
+	Control *ctlmsg.Control
+
+	// set if article replaces earlier post
+	Superseded *pipostbase.SupersededInfo
 }
 
 type postNNTPContext struct {
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"time"
 
+	"nksrv/lib/app/base/ctlmsg"
 	"nksrv/lib/app/mailib"
 	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pimod"
+	"nksrv/lib/app/psqlib/internal/pipostbase"
 	"nksrv/lib/mail"
 	"nksrv/lib/nntp"
 	"nksrv/lib/utils/date"
@@ -39,6 +42,17 @@ func nntpDigestTransferHead(
 		}
 	}
 
+	if !post {
+		// loop detection {RFC 5537 3.3}
+		hpath := H["Path"][0].V
+		info.PathSender = nntp.PathSender(hpath)
+		if nntp.PathHasAny(nntp.PathIdentities(hpath), sp.PathIdentities) {
+			err = fmt.Errorf("article already passed through us (Path %q)",
+				au.TrimWSString(hpath))
+			return
+		}
+	}
+
 	// delete garbage
 	delete(H, "Relay-Version")
 	delete(H, "Date-Received")
@@ -115,7 +129,8 @@ func nntpDigestTransferHead(
 
 	// Date
 	nowtimeu := date.NowTimeUnix()
-	if len(H["Date"]) != 0 {
+	hadDate := len(H["Date"]) != 0
+	if hadDate {
 		hdate := H.GetFirst("Date")
 		// NOTE: incase POST we try to parse in more strict way
 		// limiting syntax of non-POST stuff would hurt propagation
@@ -142,6 +157,62 @@ func nntpDigestTransferHead(
 		info.PostedDate = nowtimeu
 	}
 
+	// control messages don't get stored, so they don't need board.
+	// cancels with Cancel-Key {RFC 8315} are processed even without
+	// any trusted sources configured
+	if hctl := H.GetOneOrNone("Control"); hctl != "" {
+		c, e := ctlmsg.Parse(hctl)
+		if sp.ControlTrust.Enabled() ||
+			(e == nil && c.Verb == ctlmsg.VerbCancel && H.Has("Cancel-Key")) {
+
+			if e != nil {
+				err = fmt.Errorf("bad Control header: %v", e)
+				return
+			}
+			// old ones could be replays of messages we no longer remember
+			if !hadDate && !post {
+				err = errors.New("control message without Date")
+				return
+			}
+			if info.PostedDate < nowtimeu-int64(pimod.MaxControlAge/time.Second) {
+				err = errors.New("control message is too old")
+				return
+			}
+			info.Control = &c
+			return
+		}
+	}
+
+	// Supersedes {RFC 5536 3.2.12}
+	// authorship can only be checked once we've verified body signature
+	if hsup := H.GetOneOrNone("Supersedes"); hsup != "" {
+		sid := TFullMsgIDStr(au.TrimWSString(hsup))
+		if !validMsgID(sid) {
+			err = fmt.Errorf("invalid Supersedes Message-ID %q", sid)
+			return
+		}
+		if sid == info.FullMsgIDStr {
+			err = errors.New("article superseding itself")
+			return
+		}
+		var si pipostbase.SupersededInfo
+		var found bool
+		si, found, err = pipostbase.LookupSuperseded(sp, cutMsgID(sid), hgroup)
+		if err != nil {
+			unexpected = true
+			return
+		}
+		if found {
+			if si.IsOP {
+				err = pipostbase.ErrSupersedeOP
+				return
+			}
+			// take place of superseded post in its thread
+			info.FRef = TFullMsgIDStr("<" + string(si.Root) + ">")
+			info.Superseded = &si
+		}
+	}
+
 	// actual DB check on group and refered article
 	var wr bool
 	info.insertSqlInfo, err, unexpected, wr =
@@ -13,6 +13,8 @@ var hdrNNTPPostRestrict = [...]headerRestriction{
 	{"Newsgroups", false},
 	{"Path", true},
 	{"Subject", true}, // more lax than {RFC 5536} (no subject is much better than "none")
+	{"Control", true},
+	{"Supersedes", true},
 
 	// {RFC 5322}
 	{"Sender", true},
@@ -37,6 +39,8 @@ var hdrNNTPTransferRestrict = [...]headerRestriction{
 	{"Newsgroups", false},
 	{"Path", false},
 	{"Subject", true}, // more lax than {RFC 5536} (no subject is much better than "none")
+	{"Control", true},
+	{"Supersedes", true},
 
 	// {RFC 5322}
 	{"Sender", true},
@@ -0,0 +1,114 @@
+package pipostweb
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"nksrv/lib/app/base/ctlmsg"
+	"nksrv/lib/app/mailib"
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibasenntp"
+	"nksrv/lib/app/psqlib/internal/pimod"
+	"nksrv/lib/app/psqlib/internal/pipostnntp"
+	ib0 "nksrv/lib/app/webib0"
+	"nksrv/lib/mail"
+	"nksrv/lib/utils/date"
+	. "nksrv/lib/utils/logx"
+)
+
+var errWrongCancelKey = errors.New("wrong password or key")
+
+// CancelPost deletes post on behalf of its author.
+// Either password post was made with, which we derive key from,
+// or ckey, Cancel-Key returned when posting, must be specified.
+// If board is federated, cancel message is posted so that peers
+// would delete post too.
+func CancelPost(
+	sp *pibase.PSQLIB, board, post, password, ckey string) (err error) {
+
+	if password == "" && ckey == "" {
+		return &ib0.WebPostError{
+			Err: errWrongCancelKey, Code: http.StatusForbidden}
+	}
+
+	var msgid string
+	var newsgroup sql.NullString
+	err = sp.StPrep[pibase.St_web_post_msgid_by_pname].
+		QueryRow(board, post).Scan(&msgid, &newsgroup)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &ib0.WebPostError{
+				Err: pibase.ErrNoSuchPost, Code: http.StatusNotFound}
+		}
+		return sp.SQLError("post msgid query", err)
+	}
+
+	fmsgid := fmt.Sprintf("<%s>", msgid)
+	if ckey == "" {
+		ckey = ctlmsg.MakeCancelKey(
+			ctlmsg.PasswordCancelSecret(sp.CancelSecret, password), fmsgid)
+	}
+
+	if !newsgroup.Valid {
+		// nobody else has it, so no need to tell anyone
+		err = pimod.CancelWithKey(
+			sp, pibasenntp.TCoreMsgIDStr(msgid), []string{ckey})
+	} else {
+		var unexpected bool
+		err, unexpected = postCancel(sp, newsgroup.String, fmsgid, ckey)
+		if err != nil && !unexpected &&
+			err != pipostnntp.ErrControlNotAllowed {
+
+			return &ib0.WebPostError{Err: err, Code: http.StatusBadRequest}
+		}
+	}
+	if err != nil {
+		if err == pimod.ErrCancelKeyMismatch ||
+			err == pipostnntp.ErrControlNotAllowed {
+
+			return &ib0.WebPostError{
+				Err: errWrongCancelKey, Code: http.StatusForbidden}
+		}
+		if err == pibase.ErrNoSuchPost {
+			return &ib0.WebPostError{Err: err, Code: http.StatusNotFound}
+		}
+		return
+	}
+
+	sp.Log.LogPrintf(INFO, "post %s cancelled by author", fmsgid)
+	return
+}
+
+// postCancel makes cancel message {RFC 5537 5.3} and processes it
+// the same way as one posted over NNTP, so that it's pushed to peers.
+// It's authorized by Cancel-Key only, as poster has no other authority.
+func postCancel(
+	sp *pibase.PSQLIB, newsgroup, fmsgid, ckey string) (
+	err error, unexpected bool) {
+
+	tu := date.NowTimeUnix()
+	H := mail.HeaderMap{
+		"Message-ID": mail.OneHeaderVal(
+			string(mailib.NewRandomMessageID(tu, sp.Instance))),
+		"From": mail.OneHeaderVal(
+			mail.FormatAddress("", "poster@"+sp.Instance)),
+		"Newsgroups": mail.OneHeaderVal(newsgroup),
+		"Date":       mail.OneHeaderVal(mail.FormatDate(time.Unix(tu, 0))),
+		"Subject":    mail.OneHeaderVal("cmsg cancel " + fmsgid),
+		"Control":    mail.OneHeaderVal("cancel " + fmsgid),
+		"Cancel-Key": mail.OneHeaderVal(ckey),
+	}
+
+	var buf bytes.Buffer
+	err = mail.WriteMessageHeaderMap(&buf, H, false)
+	if err != nil {
+		return fmt.Errorf("failed writing cancel message: %v", err), true
+	}
+	buf.WriteString("\ncancelled by author\n")
+
+	return pipostnntp.SubmitLocalArticle(sp, &buf)
+}
@@ -0,0 +1,36 @@
+package pipostweb
+
+import (
+	"errors"
+	"net/http"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibasenntp"
+	"nksrv/lib/app/psqlib/internal/pimod"
+	ib0 "nksrv/lib/app/webib0"
+	mm "nksrv/lib/utils/minimail"
+)
+
+var errInvalidMsgID = errors.New("invalid msgid")
+
+// QueueAction approves or rejects post queued for board.
+// msgid is without angle brackets, as in IBPostedInfo.
+func QueueAction(
+	sp *pibase.PSQLIB, board, msgid string, approve bool) (err error) {
+
+	if !mm.ValidMessageIDStr(mm.TFullMsgIDStr("<" + msgid + ">")) {
+		return &ib0.WebPostError{
+			Err: errInvalidMsgID, Code: http.StatusBadRequest}
+	}
+
+	cmsgid := pibasenntp.TCoreMsgIDStr(msgid)
+	if approve {
+		err = pimod.ApproveQueued(sp, board, cmsgid)
+	} else {
+		err = pimod.RejectQueued(sp, board, cmsgid)
+	}
+	if err == pimod.ErrNotQueued {
+		return &ib0.WebPostError{Err: err, Code: http.StatusNotFound}
+	}
+	return
+}
@@ -47,6 +47,10 @@ type postWebContext struct {
 	irefs     []ibrefSrnd.Index
 
 	msgfn string // full filename of inner msg (if doing primitive signing)
+
+	superseded *pipostbase.SupersededInfo // post we're replacing, if any
+	cancelKey  string                     // matches Cancel-Lock of post
+	queued     bool                       // post went to mod queue
 }
 
 type wp_dbinfo struct {
@@ -55,4 +59,7 @@ type wp_dbinfo struct {
 	ref        sql.NullString             // if replying, referenced msgid
 	postLimits pibaseweb.SubmissionLimits // post limits applying for this transaction
 	opdate     pq.NullTime                // date of OP for validity checking
+	archived   sql.NullBool               // if replying, whether thread is archived
+	locked     sql.NullBool               // if replying, whether thread is locked
+	moderated  bool                       // whether board needs posts to be approved
 }
@@ -1,15 +1,20 @@
 package pipostweb
 
 import (
+	crand "crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 
 	"golang.org/x/crypto/ed25519"
 
+	"nksrv/lib/app/base/ctlmsg"
 	"nksrv/lib/app/ibref/ibrefsrnd"
 	"nksrv/lib/app/mailib"
+	"nksrv/lib/app/psqlib/internal/pibaseweb"
+	"nksrv/lib/app/psqlib/internal/pipostbase"
 	"nksrv/lib/mail"
 	"nksrv/lib/thumbnailer"
 	"nksrv/lib/utils/date"
@@ -180,11 +185,31 @@ func (sp *PSQLIB) wp_act_process(ctx *postWebContext) (err error) {
 		return
 	}
 
+	// Approved, naming key post was signed with {RFC 5536 3.2.1}
+	if ctx.postOpts.approved {
+		if ctx.pubkeystr == "" {
+			err = badWebRequest(pibaseweb.ErrApprovedUnsigned)
+			return
+		}
+		ctx.pInfo.H["Approved"] = mail.OneHeaderVal(ctx.pubkeystr)
+	}
+
+	// Supersedes, only allowed for our own signed posts
+	if ctx.postOpts.supersedes != "" {
+		err = ctx.wp_supersedes()
+		if err != nil {
+			return
+		}
+	}
+
 	if fmsgids == "" {
 		// lets think of Message-ID there
 		fmsgids = mailib.NewRandomMessageID(tu, sp.instance)
 	}
 
+	// Cancel-Lock {RFC 8315}, so that poster could delete post later
+	ctx.wp_cancellock(fmsgids)
+
 	// frontend sign
 	if sp.webFrontendKey != nil {
 		ctx.pInfo.H["X-Frontend-PubKey"] =
@@ -220,3 +245,49 @@ func (sp *PSQLIB) wp_act_process(ctx *postWebContext) (err error) {
 
 	return
 }
+
+// checks whether post can supersede one specified in options.
+// it must be reply in same thread, signed by same key.
+func (ctx *postWebContext) wp_supersedes() (err error) {
+	si, found, err := pipostbase.LookupSuperseded(
+		ctx.sp, cutMsgID(ctx.postOpts.supersedes), ctx.board)
+	if err != nil {
+		return
+	}
+	if !found {
+		err = badWebRequest(pibaseweb.ErrSupersededNotFound)
+		return
+	}
+	if err = pipostbase.CheckSupersede(si, ctx.pubkeystr); err != nil {
+		err = badWebRequest(err)
+		return
+	}
+	if !ctx.isReply || si.TID != postID(ctx.tid.Int64) {
+		err = badWebRequest(pibaseweb.ErrSupersededOtherThread)
+		return
+	}
+	ctx.superseded = &si
+	ctx.pInfo.H["Supersedes"] =
+		mail.OneHeaderVal(string(ctx.postOpts.supersedes))
+	return
+}
+
+// makes Cancel-Lock from password, or from random secret if there's none.
+// Password is keyed with server secret first, as Cancel-Lock is public.
+// Cancel-Key is returned to poster.
+func (ctx *postWebContext) wp_cancellock(fmsgids TFullMsgIDStr) {
+	var secret []byte
+	if ctx.xf.password != "" {
+		secret = ctlmsg.PasswordCancelSecret(
+			ctx.sp.CancelSecret, ctx.xf.password)
+	} else {
+		secret = make([]byte, 32)
+		_, err := io.ReadFull(crand.Reader, secret)
+		if err != nil {
+			panic(err)
+		}
+	}
+	ctx.cancelKey = ctlmsg.MakeCancelKey(secret, string(fmsgids))
+	ctx.pInfo.H["Cancel-Lock"] =
+		mail.OneHeaderVal(ctlmsg.MakeCancelLock(ctx.cancelKey))
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 
+	"nksrv/lib/app/psqlib/internal/pimod"
+	"nksrv/lib/app/psqlib/internal/pipostbase"
 	. "nksrv/lib/utils/logx"
 )
 
@@ -30,6 +32,17 @@ func (ctx *postWebContext) wp_insertsql(tx *sql.Tx) (err error) {
 		return
 	}
 
+	if ctx.superseded != nil {
+		ct := ctx.traceStart("delete superseded %s", ctx.superseded.MsgID)
+		err = pimod.DeleteSupersededInTx(ctx.sp, tx, ctx.superseded.MsgID)
+		ct.Done()
+		if err != nil {
+			return
+		}
+		// article itself keeps its own Date header
+		ctx.pInfo.Date = pipostbase.SupersedeDate(*ctx.superseded, ctx.pInfo.Date)
+	}
+
 	var rmi regModInfo
 	if ctx.isctlgrp && ctx.pubkeystr != "" {
 		rmi, err = ctx.wp_registered_mod(tx)
@@ -38,6 +51,30 @@ func (ctx *postWebContext) wp_insertsql(tx *sql.Tx) (err error) {
 		}
 	}
 
+	// moderated board takes post only if mod approved it
+	ctx.queued = ctx.moderated && !ctx.isctlgrp
+	if ctx.queued && pimod.HasApproval(ctx.pInfo.H, ctx.pubkeystr) {
+		var armi regModInfo
+		armi, err = ctx.wp_registered_mod(tx)
+		if err != nil {
+			return
+		}
+		ctx.queued = !pimod.CanApprove(armi.ModCombinedCaps, ctx.board)
+	}
+	if ctx.queued {
+		ct := ctx.traceStart("put post data to mod queue")
+		err = pimod.EnqueuePost(
+			ctx.sp, tx, ctx.bid, postID(ctx.tid.Int64), ctx.pInfo)
+		ct.Done()
+		if err != nil {
+			return
+		}
+
+		// we've inserted file infos, so do P->A
+		ctx.wp_act_fpp_bc_spawn_PA()
+		return
+	}
+
 	var gpid, bpid postID
 	var duplicate bool
 	// perform insert
@@ -197,6 +234,7 @@ func (ctx *postWebContext) wp_act_commit() (err error) {
 
 	if err == nil {
 		ctx.log.LogPrintf(DEBUG, "wp_act_commit finished loop without error")
+		ctx.sp.NotifyPush(ctx.pInfo.MessageID, ctx.board)
 	} else {
 		ctx.log.LogPrintf(DEBUG, "wp_act_commit finished loop with error: %v", err)
 	}
@@ -1,22 +1,41 @@
 package pipostweb
 
-import "strings"
+import (
+	"strings"
+
+	mm "nksrv/lib/utils/minimail"
+)
 
 type PostOptions struct {
-	sage    bool
-	nolimit bool
+	sage     bool
+	nolimit  bool
+	approved bool // signing mod approves post for moderated board
+
+	supersedes TFullMsgIDStr // post to replace, if any
 }
 
 func parsePostOptions(opts string) (ok bool, popts PostOptions) {
 	sopts := strings.Split(opts, ",")
 	for i, sopt := range sopts {
 		topt := strings.ToLower(strings.TrimSpace(sopt))
-		switch topt {
-		case "sage":
+		switch {
+		case topt == "sage":
 			popts.sage = true
-		case "nolimit":
+		case topt == "nolimit":
 			popts.nolimit = true
-		case "":
+		case topt == "approved":
+			popts.approved = true
+		case strings.HasPrefix(topt, "supersedes="):
+			// Message-ID is case-sensitive so take it from original
+			sid := strings.TrimSpace(sopt)[len("supersedes="):]
+			if sid != "" && sid[0] != '<' {
+				sid = "<" + sid + ">"
+			}
+			if popts.supersedes != "" || !mm.ValidMessageIDStr(mm.TFullMsgIDStr(sid)) {
+				return
+			}
+			popts.supersedes = TFullMsgIDStr(sid)
+		case topt == "":
 			if i != len(sopts)-1 {
 				return
 			}
@@ -56,6 +56,8 @@ type webInputFields struct {
 	name    string
 	message string
 	options string
+
+	password string // for Cancel-Lock, optional
 }
 
 func (sp *PSQLIB) processTextFields(
@@ -66,12 +68,14 @@ func (sp *PSQLIB) processTextFields(
 	fnname := ib0.IBWebFormTextName
 	fnmessage := ib0.IBWebFormTextMessage
 	fnoptions := ib0.IBWebFormTextOptions
+	fnpassword := ib0.IBWebFormTextPassword
 
 	// check field counts
 	if len(f.Values[fntitle]) > 1 ||
 		len(f.Values[fnname]) != 1 ||
 		len(f.Values[fnmessage]) != 1 ||
-		len(f.Values[fnoptions]) > 1 {
+		len(f.Values[fnoptions]) > 1 ||
+		len(f.Values[fnpassword]) > 1 {
 
 		err = errInvalidSubmission
 		return
@@ -86,6 +90,9 @@ func (sp *PSQLIB) processTextFields(
 	if len(f.Values[fnoptions]) != 0 {
 		xf.options = f.Values[fnoptions][0]
 	}
+	if len(f.Values[fnpassword]) != 0 {
+		xf.password = f.Values[fnpassword][0]
+	}
 
 	// print
 	sp.log.LogPrintf(
@@ -97,7 +104,8 @@ func (sp *PSQLIB) processTextFields(
 	if !utf8.ValidString(xf.title) ||
 		!utf8.ValidString(xf.name) ||
 		!utf8.ValidString(xf.message) ||
-		!utf8.ValidString(xf.options) {
+		!utf8.ValidString(xf.options) ||
+		!utf8.ValidString(xf.password) {
 
 		err = errBadSubmissionEncoding
 		return
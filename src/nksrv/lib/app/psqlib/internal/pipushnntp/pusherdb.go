@@ -0,0 +1,217 @@
+package pipushnntp
+
+import (
+	"database/sql"
+	"io"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pireadnntp"
+	"nksrv/lib/nntp"
+	"nksrv/lib/utils/date"
+)
+
+type (
+	boardID = pibase.TBoardID
+	postID  = pibase.TPostID
+)
+
+// ID of pseudo group control messages posted here are pushed in
+type controlGroupID struct{}
+
+type PusherDB struct {
+	sp *pibase.PSQLIB
+	id int64
+}
+
+var _ nntp.PusherDatabase = (*PusherDB)(nil)
+
+func (s *PusherDB) GetPushGroups() (list []nntp.PushGroup, err error) {
+	rows, err := s.sp.StPrep[pibase.St_pusher_get_groups].Query(s.id)
+	if err != nil {
+		return nil, s.sp.SQLError("pusher_get_groups query", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bid boardID
+		var g nntp.PushGroup
+		var last sql.NullInt64
+
+		err = rows.Scan(&bid, &g.Group, &g.Max, &last)
+		if err != nil {
+			return nil, s.sp.SQLError("pusher_get_groups query rows scan", err)
+		}
+		g.ID = bid
+		g.Last = uint64(last.Int64)
+		g.Tracked = last.Valid
+
+		list = append(list, g)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, s.sp.SQLError("pusher_get_groups query rows iteration", err)
+	}
+
+	g, err := s.getControlGroup()
+	if err != nil {
+		return nil, err
+	}
+	list = append(list, g)
+
+	return
+}
+
+func (s *PusherDB) getControlGroup() (g nntp.PushGroup, err error) {
+	var max, last sql.NullInt64
+	err = s.sp.StPrep[pibase.St_pusher_get_control].
+		QueryRow(s.id).Scan(&max, &last)
+	if err != nil {
+		return g, s.sp.SQLError("pusher_get_control query scan", err)
+	}
+	g.ID = controlGroupID{}
+	g.Group = pibase.ControlPushGroup
+	g.Max = uint64(max.Int64)
+	g.Last = uint64(last.Int64)
+	g.Tracked = last.Valid
+	return
+}
+
+func (s *PusherDB) UpdatePushGroupID(g *nntp.PushGroup, id uint64) error {
+	if _, ok := g.ID.(controlGroupID); ok {
+		_, e := s.sp.StPrep[pibase.St_pusher_set_control_id].Exec(s.id, id)
+		if e != nil {
+			return s.sp.SQLError("pusher_set_control_id query execution", e)
+		}
+		return nil
+	}
+
+	_, e := s.sp.StPrep[pibase.St_pusher_set_group_id].
+		Exec(s.id, g.ID.(boardID), id)
+	if e != nil {
+		return s.sp.SQLError("pusher_set_group_id query execution", e)
+	}
+	return nil
+}
+
+func (s *PusherDB) ListPushArticles(
+	g *nntp.PushGroup, after uint64, limit int) (
+	list []nntp.PushArticle, err error) {
+
+	if _, ok := g.ID.(controlGroupID); ok {
+		return s.listControl(after, limit)
+	}
+
+	rows, err := s.sp.StPrep[pibase.St_pusher_list_articles].
+		Query(g.ID.(boardID), after, limit)
+	if err != nil {
+		return nil, s.sp.SQLError("pusher_list_articles query", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a nntp.PushArticle
+		var bpid postID
+
+		err = rows.Scan(&bpid, &a.MsgID, &a.Path)
+		if err != nil {
+			return nil, s.sp.SQLError("pusher_list_articles query rows scan", err)
+		}
+		a.Num = uint64(bpid)
+
+		list = append(list, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, s.sp.SQLError("pusher_list_articles query rows iteration", err)
+	}
+	return
+}
+
+// control messages we posted don't have Path entries of peers
+func (s *PusherDB) listControl(
+	after uint64, limit int) (list []nntp.PushArticle, err error) {
+
+	rows, err := s.sp.StPrep[pibase.St_pusher_list_control].
+		Query(after, limit)
+	if err != nil {
+		return nil, s.sp.SQLError("pusher_list_control query", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a nntp.PushArticle
+		var chid int64
+
+		err = rows.Scan(&chid, &a.MsgID)
+		if err != nil {
+			return nil, s.sp.SQLError("pusher_list_control query rows scan", err)
+		}
+		a.Num = uint64(chid)
+
+		list = append(list, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, s.sp.SQLError("pusher_list_control query rows iteration", err)
+	}
+	return
+}
+
+func (s *PusherDB) WriteArticle(
+	msgid nntp.TCoreMsgIDStr, begin func() (io.Writer, error)) (
+	exists bool, err error) {
+
+	exists, err = pireadnntp.CopyArticleForPush(s.sp, msgid, begin)
+	if exists || err != nil {
+		return
+	}
+
+	// maybe it's control message
+	var article string
+	err = s.sp.StPrep[pibase.St_pusher_get_control_article].
+		QueryRow(string(msgid)).Scan(&article)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, s.sp.SQLError("pusher_get_control_article query scan", err)
+	}
+
+	w, err := begin()
+	if err != nil {
+		return true, err
+	}
+	_, err = io.WriteString(w, article)
+	return true, err
+}
+
+func getPusherNonce(sp *pibase.PSQLIB) int64 {
+	// not to be used in multithreaded context
+	if sp.PusherNonce == 0 {
+		sp.PusherNonce = date.NowTimeUnixMilli()
+		if sp.PusherNonce == 0 {
+			sp.PusherNonce = 1
+		}
+	}
+	return sp.PusherNonce
+}
+
+// NewPusherDB registers peer and returns its push progress tracker
+func NewPusherDB(sp *pibase.PSQLIB, name string) (*PusherDB, error) {
+	nonce := getPusherNonce(sp)
+	db := &PusherDB{sp: sp}
+	e := sp.StPrep[pibase.St_pusher_register].
+		QueryRow(name, nonce).
+		Scan(&db.id)
+	if e != nil {
+		return nil, sp.SQLError("pusher_register query scan", e)
+	}
+	return db, nil
+}
+
+// ClearPusherDBs removes tracking of peers not registered since startup
+func ClearPusherDBs(sp *pibase.PSQLIB) error {
+	nonce := getPusherNonce(sp)
+	_, e := sp.StPrep[pibase.St_pusher_clear_unused].Exec(nonce)
+	if e != nil {
+		return sp.SQLError("pusher_clear_unused query execution", e)
+	}
+	return nil
+}
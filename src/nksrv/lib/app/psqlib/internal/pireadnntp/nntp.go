@@ -109,6 +109,48 @@ func handleNNTPGetError(sp *pibase.PSQLIB, w Responder, nc nntpCopyer, e error)
 	return true
 }
 
+// canReadAnyGroup tells whether article in bnames groups is visible to cs.
+func canReadAnyGroup(cs *ConnState, bnames []string) bool {
+	for _, bname := range bnames {
+		if cs.CanReadGroup(bname) {
+			return true
+		}
+	}
+	return false
+}
+
+// canReadMsgID is like canReadAnyGroup but looks up groups by msgid.
+// Lookup is skipped if cs isn't limited.
+func canReadMsgID(
+	sp *pibase.PSQLIB, cs *ConnState, msgid TCoreMsgIDStr) (bool, error) {
+
+	if cs.ReadGroups == nil {
+		return true, nil
+	}
+
+	rows, err := sp.StPrep[pibase.St_nntp_article_groups_by_msgid].
+		Query(string(msgid))
+	if err != nil {
+		return false, sp.SQLError("article groups query", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bname string
+		err = rows.Scan(&bname)
+		if err != nil {
+			return false, sp.SQLError("article groups query rows scan", err)
+		}
+		if cs.CanReadGroup(bname) {
+			return true, nil
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return false, sp.SQLError("article groups query rows iteration", err)
+	}
+	return false, nil
+}
+
 func GetArticleCommonByMsgID(
 	sp *pibase.PSQLIB, nc nntpCopyer, w Responder, cs *ConnState,
 	msgid TCoreMsgID) bool {
@@ -341,7 +383,8 @@ func emptyWildmat(w []byte) bool {
 }
 
 func ListNewNews(
-	sp *pibase.PSQLIB, aw AbstractResponder, wildmat []byte, qt time.Time) {
+	sp *pibase.PSQLIB, aw AbstractResponder, cs *ConnState,
+	wildmat []byte, qt time.Time) {
 
 	var rows *sql.Rows
 	var err error
@@ -351,7 +394,16 @@ func ListNewNews(
 	wmany := swildmat == "*"
 	wmgrp := !wmany && nntp.ValidGroupSlice(wildmat)
 
-	if wmany || wmgrp {
+	if wmgrp && !cs.CanReadGroup(swildmat) {
+		// hidden group, nothing to show
+		dw, err = aw.OpenDotWriter()
+		nntpAbortOnErr(err)
+		nntpAbortOnErr(dw.Close())
+		return
+	}
+
+	// if some groups are hidden, we need group names to filter them out
+	if (wmany && cs.ReadGroups == nil) || wmgrp {
 		if wmany {
 			rows, err = sp.StPrep[pibase.St_nntp_newnews_all].Query(qt)
 		} else {
@@ -384,7 +436,10 @@ func ListNewNews(
 	} else {
 		// TODO maybe we should use SQL LIKE to implement filtering?
 		// that would be a little bit complicated, though
-		wm := nntp.CompileWildmat(wildmat)
+		var wm nntp.Wildmat
+		if !wmany {
+			wm = nntp.CompileWildmat(wildmat)
+		}
 
 		rows, err = sp.StPrep[pibase.St_nntp_newnews_all_group].Query(qt)
 		if err != nil {
@@ -416,7 +471,9 @@ func ListNewNews(
 				continue
 			}
 
-			if wm.CheckBytes(bname) {
+			if (wm == nil || wm.CheckBytes(bname)) &&
+				cs.CanReadGroup(unsafeBytesToStr(bname)) {
+
 				last_msgid = msgid
 				fmt.Fprintf(dw, "<%s>\n", msgid)
 			}
@@ -432,7 +489,18 @@ func ListNewNews(
 	nntpAbortOnErr(dw.Close())
 }
 
-func ListNewGroups(sp *pibase.PSQLIB, aw AbstractResponder, qt time.Time) {
+// groupStatus returns LIST ACTIVE status of group.
+// moderated groups are "m", posting to them goes thru mod queue.
+func groupStatus(moderated bool) byte {
+	if moderated {
+		return 'm'
+	}
+	return 'y'
+}
+
+func ListNewGroups(
+	sp *pibase.PSQLIB, aw AbstractResponder, cs *ConnState, qt time.Time) {
+
 	// name hiwm lowm status
 	// for now lets use status of "y"
 	// TODO put something else in status when needed
@@ -454,20 +522,26 @@ func ListNewGroups(sp *pibase.PSQLIB, aw AbstractResponder, qt time.Time) {
 	for rows.Next() {
 		var bname []byte
 		var lo, hi sql.NullInt64
+		var moderated bool
 
-		err = rows.Scan(&bname, &lo, &hi)
+		err = rows.Scan(&bname, &lo, &hi, &moderated)
 		if err != nil {
 			_ = sp.SQLError("newgroups query rows scan", err)
 			aw.Abort()
 			return
 		}
 
+		if !cs.CanReadGroup(unsafeBytesToStr(bname)) {
+			continue
+		}
+
 		if uint64(hi.Int64) < uint64(lo.Int64) {
 			hi = lo // paranoia
 		}
 
-		_, err = fmt.Fprintf(dw, "%s %d %d y\n",
-			bname, uint64(hi.Int64), uint64(lo.Int64))
+		_, err = fmt.Fprintf(dw, "%s %d %d %c\n",
+			bname, uint64(hi.Int64), uint64(lo.Int64),
+			groupStatus(moderated))
 		nntpAbortOnErr(err)
 	}
 	if err = rows.Err(); err != nil {
@@ -479,7 +553,9 @@ func ListNewGroups(sp *pibase.PSQLIB, aw AbstractResponder, qt time.Time) {
 	nntpAbortOnErr(dw.Close())
 }
 
-func ListActiveGroups(sp *pibase.PSQLIB, aw AbstractResponder, wildmat []byte) {
+func ListActiveGroups(
+	sp *pibase.PSQLIB, aw AbstractResponder, cs *ConnState, wildmat []byte) {
+
 	// name hiwm lowm status
 	// for now lets use status of "y"
 	// TODO put something else in status when needed
@@ -518,15 +594,18 @@ func ListActiveGroups(sp *pibase.PSQLIB, aw AbstractResponder, wildmat []byte) {
 	for rows.Next() {
 		var bname []byte
 		var lo, hi sql.NullInt64
+		var moderated bool
 
-		err = rows.Scan(&bname, &lo, &hi)
+		err = rows.Scan(&bname, &lo, &hi, &moderated)
 		if err != nil {
 			_ = sp.SQLError("list active query rows scan", err)
 			aw.Abort()
 			return
 		}
 
-		if wm != nil && !wm.CheckBytes(bname) {
+		if (wm != nil && !wm.CheckBytes(bname)) ||
+			!cs.CanReadGroup(unsafeBytesToStr(bname)) {
+
 			continue
 		}
 
@@ -534,8 +613,9 @@ func ListActiveGroups(sp *pibase.PSQLIB, aw AbstractResponder, wildmat []byte) {
 			hi = lo // paranoia
 		}
 
-		_, err = fmt.Fprintf(dw, "%s %d %d y\n",
-			bname, uint64(hi.Int64), uint64(lo.Int64))
+		_, err = fmt.Fprintf(dw, "%s %d %d %c\n",
+			bname, uint64(hi.Int64), uint64(lo.Int64),
+			groupStatus(moderated))
 		nntpAbortOnErr(err)
 	}
 	if err = rows.Err(); err != nil {
@@ -547,7 +627,9 @@ func ListActiveGroups(sp *pibase.PSQLIB, aw AbstractResponder, wildmat []byte) {
 	nntpAbortOnErr(dw.Close())
 }
 
-func ListNewsgroups(sp *pibase.PSQLIB, aw AbstractResponder, wildmat []byte) {
+func ListNewsgroups(
+	sp *pibase.PSQLIB, aw AbstractResponder, cs *ConnState, wildmat []byte) {
+
 	// name[tab]description
 
 	var rows *sql.Rows
@@ -593,7 +675,7 @@ func ListNewsgroups(sp *pibase.PSQLIB, aw AbstractResponder, wildmat []byte) {
 			return
 		}
 
-		if wm != nil && !wm.CheckString(bname) {
+		if (wm != nil && !wm.CheckString(bname)) || !cs.CanReadGroup(bname) {
 			continue
 		}
 
@@ -615,6 +697,172 @@ func ListNewsgroups(sp *pibase.PSQLIB, aw AbstractResponder, wildmat []byte) {
 	nntpAbortOnErr(dw.Close())
 }
 
+// groupWildmat prepares wildmat for filtering of group listings.
+// wmgrp is set if wildmat is just single group name,
+// wm is nil if no further filtering is needed.
+func groupWildmat(wildmat []byte) (wm nntp.Wildmat, wmgrp bool) {
+	wmany := emptyWildmat(wildmat)
+	wmgrp = !wmany && nntp.ValidGroupSlice(wildmat)
+
+	if !wmany && !wmgrp {
+		wm = nntp.CompileWildmat(wildmat)
+	}
+	return
+}
+
+func ListActiveTimes(
+	sp *pibase.PSQLIB, aw AbstractResponder, cs *ConnState, wildmat []byte) {
+
+	// name time creator
+	// we don't track who created group, but we're who added it to our node
+
+	var rows *sql.Rows
+	var err error
+
+	wm, wmgrp := groupWildmat(wildmat)
+
+	if !wmgrp {
+		rows, err = sp.StPrep[pibase.St_nntp_listactivetimes_all].Query()
+	} else {
+		rows, err = sp.StPrep[pibase.St_nntp_listactivetimes_one].Query(wildmat)
+	}
+	if err != nil {
+		nntpAbortOnErr(aw.GetResponder().ResInternalError(
+			sp.SQLError("list active.times query", err)))
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			rows.Close()
+		}
+	}()
+
+	creator := sp.Instance
+	if creator == "" {
+		creator = "-"
+	}
+
+	dw, err := aw.OpenDotWriter()
+	nntpAbortOnErr(err)
+
+	for rows.Next() {
+		var bname []byte
+		var added time.Time
+
+		err = rows.Scan(&bname, &added)
+		if err != nil {
+			_ = sp.SQLError("list active.times query rows scan", err)
+			aw.Abort()
+			return
+		}
+
+		if (wm != nil && !wm.CheckBytes(bname)) ||
+			!cs.CanReadGroup(unsafeBytesToStr(bname)) {
+
+			continue
+		}
+
+		_, err = fmt.Fprintf(dw, "%s %d %s\n", bname, added.Unix(), creator)
+		nntpAbortOnErr(err)
+	}
+	if err = rows.Err(); err != nil {
+		_ = sp.SQLError("list active.times query rows iteration", err)
+		aw.Abort()
+		return
+	}
+
+	nntpAbortOnErr(dw.Close())
+}
+
+func ListCounts(
+	sp *pibase.PSQLIB, aw AbstractResponder, cs *ConnState, wildmat []byte) {
+
+	// name hiwm lowm count status
+	// status is same as in LIST ACTIVE
+
+	var rows *sql.Rows
+	var err error
+
+	wm, wmgrp := groupWildmat(wildmat)
+
+	if !wmgrp {
+		rows, err = sp.StPrep[pibase.St_nntp_listcounts_all].Query()
+	} else {
+		rows, err = sp.StPrep[pibase.St_nntp_listcounts_one].Query(wildmat)
+	}
+	if err != nil {
+		nntpAbortOnErr(aw.GetResponder().ResInternalError(
+			sp.SQLError("list counts query", err)))
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			rows.Close()
+		}
+	}()
+
+	dw, err := aw.OpenDotWriter()
+	nntpAbortOnErr(err)
+
+	for rows.Next() {
+		var bname []byte
+		var lo, hi sql.NullInt64
+		var count uint64
+		var moderated bool
+
+		err = rows.Scan(&bname, &lo, &hi, &count, &moderated)
+		if err != nil {
+			_ = sp.SQLError("list counts query rows scan", err)
+			aw.Abort()
+			return
+		}
+
+		if (wm != nil && !wm.CheckBytes(bname)) ||
+			!cs.CanReadGroup(unsafeBytesToStr(bname)) {
+
+			continue
+		}
+
+		if uint64(hi.Int64) < uint64(lo.Int64) {
+			hi = lo // paranoia
+		}
+
+		_, err = fmt.Fprintf(dw, "%s %d %d %d %c\n",
+			bname, uint64(hi.Int64), uint64(lo.Int64), count,
+			groupStatus(moderated))
+		nntpAbortOnErr(err)
+	}
+	if err = rows.Err(); err != nil {
+		_ = sp.SQLError("list counts query rows iteration", err)
+		aw.Abort()
+		return
+	}
+
+	nntpAbortOnErr(dw.Close())
+}
+
+func ListDistribPats(sp *pibase.PSQLIB, aw AbstractResponder, cs *ConnState) {
+	// we don't make use of Distribution header so there's nothing to suggest
+	dw, err := aw.OpenDotWriter()
+	nntpAbortOnErr(err)
+	nntpAbortOnErr(dw.Close())
+}
+
+func ListHeaders(
+	sp *pibase.PSQLIB, aw AbstractResponder, cs *ConnState,
+	form nntp.ListHeadersForm) {
+
+	// any header can be queried in all forms;
+	// :bytes and :lines metadata aren't supported yet
+	dw, err := aw.OpenDotWriter()
+	nntpAbortOnErr(err)
+	_, err = fmt.Fprintf(dw, ":\n")
+	nntpAbortOnErr(err)
+	nntpAbortOnErr(dw.Close())
+}
+
 var headerReplacer = strings.NewReplacer(
 	"\t", " ",
 	"\r", string(unicode.ReplacementChar),
@@ -627,7 +875,8 @@ func safeHeader(s string) string {
 }
 
 func printOver(
-	sp *pibase.PSQLIB, w io.Writer, bpid postID, msgid TCoreMsgIDStr,
+	sp *pibase.PSQLIB, cs *ConnState, w io.Writer,
+	bpid postID, msgid TCoreMsgIDStr,
 	hsubject, hfrom, hdate, hrefs string,
 	bnames []string, bpids []int64) {
 
@@ -654,6 +903,10 @@ func printOver(
 		safeHeader(hsubject), safeHeader(hfrom), safeHeader(hdate), msgid,
 		safeHeader(hrefs), "", "", sp.Instance)
 	for i := range bnames {
+		if !cs.CanReadGroup(bnames[i]) {
+			// don't reveal hidden groups
+			continue
+		}
 		fmt.Fprintf(w, " %s:%d", bnames[i], bpids[i])
 	}
 	fmt.Fprintf(w, "\n")
@@ -700,7 +953,7 @@ func GetOverByMsgID(
 		nntpAbortOnErr(w.ResInternalError(sp.SQLError("overview query", err)))
 		return true
 	}
-	if isbanned {
+	if isbanned || !canReadAnyGroup(cs, bnames) {
 		// this kind of signaling so far
 		return false
 	}
@@ -710,7 +963,7 @@ func GetOverByMsgID(
 
 	nntpAbortOnErr(w.ResOverviewInformationFollows())
 	dw := w.DotWriter()
-	printOver(sp, dw, artnumInGroups(cs, bids, bpids), smsgid,
+	printOver(sp, cs, dw, artnumInGroups(cs, bids, bpids), smsgid,
 		hsubject.String, hfrom.String, hdate.String, hrefs.String,
 		bnames, bpids)
 	dw.Close()
@@ -777,7 +1030,7 @@ func GetOverByRange(
 			dw = w.DotWriter()
 		}
 
-		printOver(sp, dw, cbpid, msgid,
+		printOver(sp, cs, dw, cbpid, msgid,
 			hsubject.String, hfrom.String, hdate.String, hrefs.String,
 			bnames, bpids)
 	}
@@ -845,7 +1098,7 @@ func GetOverByCurr(sp *pibase.PSQLIB, w Responder, cs *ConnState) bool {
 
 	nntpAbortOnErr(w.ResOverviewInformationFollows())
 	dw := w.DotWriter()
-	printOver(sp, dw, gs.bpid, msgid,
+	printOver(sp, cs, dw, gs.bpid, msgid,
 		hsubject.String, hfrom.String, hdate.String, hrefs.String,
 		bnames, bpids)
 	nntpAbortOnErr(dw.Close())
@@ -929,6 +1182,14 @@ func CommonGetHdrByMsgID(
 		// this kind of signaling so far
 		return false
 	}
+	readable, err := canReadMsgID(sp, cs, sid)
+	if err != nil {
+		nntpAbortOnErr(w.ResInternalError(err))
+		return true
+	}
+	if !readable {
+		return false
+	}
 
 	if rfc {
 		nntpAbortOnErr(w.ResHdrFollow())
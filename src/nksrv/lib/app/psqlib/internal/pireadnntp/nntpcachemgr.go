@@ -64,6 +64,13 @@ func nntpObtainItemByMsgID(
 		// we could signal this in some other way later maybe
 		return errNotExist
 	}
+	readable, err := canReadMsgID(sp, cs, msgid)
+	if err != nil {
+		return err
+	}
+	if !readable {
+		return errNotExist
+	}
 
 	// this kind of query should never modify current article ID
 
@@ -0,0 +1,94 @@
+package pireadnntp
+
+import (
+	"database/sql"
+	"io"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+)
+
+// for pushing articles to peers
+type pushCopyer struct {
+	begin func() (io.Writer, error)
+	w     io.Writer
+}
+
+func (c *pushCopyer) CopyFrom(
+	src io.Reader, objid string, objinfo interface{}) (
+	written int64, err error) {
+
+	buf := make([]byte, 32*1024)
+
+	var nr, nw int
+	var er, ew error
+
+	// initialise if we successfuly read something
+	if c.w == nil {
+		nr, er = src.Read(buf)
+		if nr <= 0 && er != nil {
+			// abort on error before initialisation if no data - avoid begining empty incomplete message
+			err = er
+			return
+		}
+		c.w, err = c.begin()
+		if err != nil {
+			return
+		}
+	}
+
+	for {
+		if nr > 0 {
+			nw, ew = c.w.Write(buf[:nr])
+			if nw > 0 {
+				written += int64(nw)
+			}
+			if ew != nil {
+				err = ew
+				break
+			}
+			if nr != nw {
+				err = io.ErrShortWrite
+				break
+			}
+		}
+		if er != nil {
+			if er != io.EOF {
+				// read EOF isn't error :>
+				err = er
+			}
+			break
+		}
+		nr, er = src.Read(buf)
+	}
+
+	return
+}
+
+// CopyArticleForPush copies article in the form suitable for DotWriter.
+// begin is called right before first write.
+func CopyArticleForPush(
+	sp *pibase.PSQLIB, msgid TCoreMsgIDStr,
+	begin func() (io.Writer, error)) (exists bool, err error) {
+
+	var p_bid boardID
+	var p_bpid postID
+	var p_gpid postID
+	var p_isbanned bool
+
+	err = sp.StPrep[pibase.St_nntp_article_num_by_msgid].
+		QueryRow(string(msgid), 0).
+		Scan(&p_bid, &p_bpid, &p_gpid, &p_isbanned)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, sp.SQLError("posts row query scan", err)
+	}
+	if p_isbanned {
+		return false, nil
+	}
+
+	nii := nntpidinfo{bpid: p_bpid, gpid: p_gpid}
+	err = sp.NNTPCE.ObtainItem(&pushCopyer{begin: begin}, string(msgid), nii)
+	return true, err
+}
@@ -0,0 +1,173 @@
+package pireadweb
+
+import (
+	"database/sql"
+	"net/http"
+
+	xtypes "github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+
+	"nksrv/lib/app/base/ftypes"
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibaseweb"
+	ib0 "nksrv/lib/app/webib0"
+)
+
+// archive pages list catalog-like entries, so can fit more than board pages
+const archivePageSize = 100
+
+func GetThreadArchive(
+	sp *pibase.PSQLIB, page *ib0.IBThreadArchive,
+	board string, num uint32) (error, int) {
+
+	rows, err := sp.StPrep[pibase.St_web_thread_archive].
+		Query(board, num, archivePageSize)
+	if err != nil {
+		return sp.SQLError("web_thread_archive query", err),
+			http.StatusInternalServerError
+	}
+
+	var x_bid boardID
+	var x_bpid postID
+
+	for rows.Next() {
+		var (
+			// xb
+			bid       boardID
+			bdesc     string
+			battrib_j xtypes.JSONText
+			a_count   uint64
+			// xt
+			t_id      sql.NullInt64
+			t_name    sql.NullString
+			t_p_count sql.NullInt64
+			t_f_count sql.NullInt64
+			t_bump    pq.NullTime
+			t_arch    sql.NullInt64
+			// xbp
+			b_p_id sql.NullInt64
+			// xp
+			pdate     pq.NullTime
+			p_f_count sql.NullInt64
+			author    sql.NullString
+			trip      sql.NullString
+			title     sql.NullString
+			message   []byte
+			// xf
+			f_id       sql.NullInt64
+			fname      sql.NullString
+			ftype      sql.NullString
+			thumb      sql.NullString
+			thumbcfg_j xtypes.JSONText
+		)
+
+		err = rows.Scan(
+			&bid, &bdesc, &battrib_j, &a_count,
+
+			&t_id, &t_name, &t_p_count, &t_f_count, &t_bump, &t_arch,
+
+			&b_p_id,
+
+			&pdate, &p_f_count, &author, &trip, &title, &message,
+
+			&f_id, &fname, &ftype, &thumb, &thumbcfg_j)
+
+		if err != nil {
+			rows.Close()
+			return sp.SQLError("web_thread_archive query rows scan", err),
+				http.StatusInternalServerError
+		}
+
+		if x_bid != bid {
+			battrs := pibaseweb.DefaultBoardAttributes
+
+			err = battrib_j.Unmarshal(&battrs)
+			if err != nil {
+				rows.Close()
+				return sp.SQLError(
+						"web_thread_archive board attr json unmarshal", err),
+					http.StatusInternalServerError
+			}
+
+			page.Board = ib0.IBBoardInfo{
+				BNum:        bid,
+				Name:        board,
+				Description: bdesc,
+				Info:        battrs.Info,
+			}
+
+			page.Number = num
+			page.Available = uint32(
+				(a_count + archivePageSize - 1) / archivePageSize)
+			if page.Available <= 0 {
+				page.Available = 1
+			}
+
+			x_bid = bid
+			x_bpid = 0
+		}
+
+		if x_bpid != postID(b_p_id.Int64) {
+
+			var t ib0.IBThreadArchiveThread
+
+			t.Order = uint64(t_arch.Int64)
+			t.Num = uint64(b_p_id.Int64)
+			t.ID = t_name.String
+			if t_p_count.Int64 > 0 {
+				// OP itself not included
+				t.TotalReplies = t_p_count.Int64 - 1
+			}
+			// OP files not counted
+			t.TotalFiles = t_f_count.Int64 - p_f_count.Int64
+			t.BumpDate = t_bump.Time.Unix()
+			t.Subject = title.String
+			t.Message = message
+
+			if f_id.Int64 != 0 {
+				if ft := ftypes.StringToFType(ftype.String); ft.Normal() {
+					ta := pibaseweb.DefaultThumbAttributes
+
+					err = thumbcfg_j.Unmarshal(&ta)
+					if err != nil {
+						rows.Close()
+						return sp.SQLError(
+								"web_thread_archive thumbcfg json unmarshal", err),
+							http.StatusInternalServerError
+					}
+
+					t.Thumb.ID = thumb.String
+					t.Thumb.Width = ta.Width
+					t.Thumb.Height = ta.Height
+
+					t.Thumb = ensureThumb(sp, t.Thumb, fname.String, ftype.String)
+
+					goto thumbnailed
+				}
+			}
+			// fallback if not found thumbnail above
+			t.Thumb = ensureThumb(sp, t.Thumb, "", "")
+		thumbnailed:
+			// thumbnail done at this point
+
+			page.Threads = append(page.Threads, t)
+
+			x_bpid = postID(b_p_id.Int64)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return sp.SQLError(
+				"web_thread_archive query rows iteration", err),
+			http.StatusInternalServerError
+	}
+
+	if x_bid == 0 {
+		return pibase.ErrNoSuchBoard, http.StatusNotFound
+	}
+	if num >= page.Available {
+		return pibaseweb.ErrNoSuchPage, http.StatusNotFound
+	}
+
+	return nil, 0
+}
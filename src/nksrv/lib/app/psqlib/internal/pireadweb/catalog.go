@@ -37,6 +37,8 @@ func GetThreadCatalog(
 			t_p_count sql.NullInt64
 			t_f_count sql.NullInt64
 			t_bump    pq.NullTime
+			t_locked  sql.NullBool
+			t_sticky  sql.NullBool
 			// xbp
 			b_p_id sql.NullInt64
 			// xp
@@ -58,6 +60,7 @@ func GetThreadCatalog(
 			&bid, &bdesc, &battrib_j,
 
 			&t_id, &t_name, &t_p_count, &t_f_count, &t_bump,
+			&t_locked, &t_sticky,
 
 			&b_p_id,
 
@@ -106,6 +109,8 @@ func GetThreadCatalog(
 			// OP files not counted
 			t.TotalFiles = t_f_count.Int64 - p_f_count.Int64
 			t.BumpDate = t_bump.Time.Unix()
+			t.Locked = t_locked.Bool
+			t.Sticky = t_sticky.Bool
 			t.Subject = title.String
 			t.Message = message
 
@@ -0,0 +1,79 @@
+package pireadweb
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"nksrv/lib/app/psqlib/internal/pibase"
+	"nksrv/lib/app/psqlib/internal/pibaseweb"
+	ib0 "nksrv/lib/app/webib0"
+)
+
+const modLogPageSize = 50
+
+func GetModLogPage(
+	sp *pibase.PSQLIB, page *ib0.IBModLogPage,
+	board, mod string, num uint32) (error, int) {
+
+	// mod pubkeys are stored uppercase
+	mod = strings.ToUpper(mod)
+
+	page.Number = num
+	page.Board = board
+	page.Mod = mod
+
+	// fetch one more to know whether there's next page
+	rows, err := sp.StPrep[pibase.St_web_modlog].Query(
+		sql.NullString{String: board, Valid: board != ""},
+		sql.NullString{String: mod, Valid: mod != ""},
+		uint64(num)*modLogPageSize,
+		modLogPageSize+1)
+	if err != nil {
+		return sp.SQLError("web_modlog query", err),
+			http.StatusInternalServerError
+	}
+
+	for rows.Next() {
+		var (
+			e                    ib0.IBModLogEntry
+			date                 time.Time
+			modkey, msgid, bname sql.NullString
+		)
+
+		err = rows.Scan(
+			&e.ID, &date, &modkey, &msgid, &bname,
+			&e.Command, pq.Array(&e.Targets), &e.Applied, &e.Reason)
+		if err != nil {
+			rows.Close()
+			return sp.SQLError("web_modlog query rows scan", err),
+				http.StatusInternalServerError
+		}
+
+		if len(page.Entries) == modLogPageSize {
+			page.HasNext = true
+			continue
+		}
+
+		e.Date = date.Unix()
+		e.Mod = modkey.String
+		e.MsgID = msgid.String
+		e.Board = bname.String
+
+		page.Entries = append(page.Entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return sp.SQLError("web_modlog query rows iteration", err),
+			http.StatusInternalServerError
+	}
+
+	if len(page.Entries) == 0 && num > 0 {
+		return pibaseweb.ErrNoSuchPage, http.StatusNotFound
+	}
+
+	return nil, 0
+}
@@ -0,0 +1,69 @@
+package pireadweb
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	xtypes "github.com/jmoiron/sqlx/types"
+
+	"nksrv/lib/app/mailib"
+	"nksrv/lib/app/psqlib/internal/pibase"
+	ib0 "nksrv/lib/app/webib0"
+)
+
+// how much of queue is listed at once
+const modQueueListMax = 500
+
+func GetModQueue(
+	sp *pibase.PSQLIB, q *ib0.IBModQueue, board string) (error, int) {
+
+	q.Board = board
+
+	rows, err := sp.StPrep[pibase.St_web_modqueue].Query(
+		sql.NullString{String: board, Valid: board != ""},
+		0, modQueueListMax)
+	if err != nil {
+		return sp.SQLError("web_modqueue query", err),
+			http.StatusInternalServerError
+	}
+
+	for rows.Next() {
+		var (
+			e     ib0.IBModQueueEntry
+			tname sql.NullString
+			date  time.Time
+			jpi   xtypes.JSONText
+			pi    mailib.PostInfo
+		)
+
+		err = rows.Scan(&e.MsgID, &e.Board, &tname, &date, &jpi)
+		if err != nil {
+			rows.Close()
+			return sp.SQLError("web_modqueue query rows scan", err),
+				http.StatusInternalServerError
+		}
+
+		if err = jpi.Unmarshal(&pi); err != nil {
+			rows.Close()
+			return err, http.StatusInternalServerError
+		}
+
+		e.Thread = tname.String
+		e.Date = date.Unix()
+		e.Subject = pi.MI.Title
+		e.Name = pi.MI.Author
+		e.Trip = pi.MI.Trip
+		e.Message = pi.MI.Message
+		e.Files = pi.FC
+
+		q.Entries = append(q.Entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return sp.SQLError("web_modqueue query rows iteration", err),
+			http.StatusInternalServerError
+	}
+
+	return nil, 0
+}
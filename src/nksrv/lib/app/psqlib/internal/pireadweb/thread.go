@@ -43,6 +43,9 @@ func GetThread(
 			t_name    sql.NullString
 			t_p_count sql.NullInt64
 			t_f_count sql.NullInt64
+			t_arch    sql.NullInt64
+			t_locked  sql.NullBool
+			t_sticky  sql.NullBool
 			// xto
 			t_pos sql.NullInt64
 			// xbp
@@ -73,7 +76,8 @@ func GetThread(
 		err = rows.Scan(
 			&bid, &bdesc, &battrib_j, &threads_per_page, &t_count,
 
-			&t_id, &t_name, &t_p_count, &t_f_count,
+			&t_id, &t_name, &t_p_count, &t_f_count, &t_arch,
+			&t_locked, &t_sticky,
 
 			&t_pos,
 
@@ -128,6 +132,10 @@ func GetThread(
 
 			page.ThreadStats.NumFiles = t_f_count.Int64
 
+			page.Archived = t_arch.Valid
+			page.Locked = t_locked.Bool
+			page.Sticky = t_sticky.Bool
+
 			if threads_per_page > 0 {
 				page.ThreadStats.PageNum = uint32(
 					uint64(t_pos.Int64-1) / uint64(threads_per_page))
@@ -272,6 +280,8 @@ func GetThreadListPage(
 			t_name    sql.NullString
 			t_p_count sql.NullInt64
 			t_f_count sql.NullInt64
+			t_locked  sql.NullBool
+			t_sticky  sql.NullBool
 			// xbp
 			b_p_id         sql.NullInt64
 			p_name         sql.NullString
@@ -300,7 +310,7 @@ func GetThreadListPage(
 		err = rows.Scan(
 			&bid, &bdesc, &battrib_j, &threads_per_page, &t_count,
 
-			&t_id, &t_name, &t_p_count, &t_f_count,
+			&t_id, &t_name, &t_p_count, &t_f_count, &t_locked, &t_sticky,
 
 			&b_p_id, &p_name, &b_p_activ_refs,
 
@@ -365,6 +375,8 @@ func GetThreadListPage(
 				t.SkippedReplies = t_p_count.Int64 - 1
 			}
 			t.SkippedFiles = t_f_count.Int64
+			t.Locked = t_locked.Bool
+			t.Sticky = t_sticky.Bool
 
 			page.Threads = append(page.Threads, t)
 
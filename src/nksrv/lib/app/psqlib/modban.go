@@ -0,0 +1,46 @@
+package psqlib
+
+import (
+	. "nksrv/lib/utils/logx"
+	mm "nksrv/lib/utils/minimail"
+
+	"nksrv/lib/app/psqlib/internal/pimod"
+)
+
+func (sp *PSQLIB) checkMsgIDs(msgids []string) ([]mm.TFullMsgIDStr, bool) {
+	fmsgids := make([]mm.TFullMsgIDStr, len(msgids))
+	for i, s := range msgids {
+		fmsgids[i] = mm.TFullMsgIDStr(s)
+		if !mm.ValidMessageIDStr(fmsgids[i]) {
+			sp.Log.LogPrintf(ERROR, "invalid msgid %q", s)
+			return nil, false
+		}
+	}
+	return fmsgids, true
+}
+
+// DemoBanByMsgID deletes msgids, or bans them if reason is set.
+// If board is set, only that board is affected.
+func (sp *PSQLIB) DemoBanByMsgID(msgids []string, board, reason string) {
+	fmsgids, ok := sp.checkMsgIDs(msgids)
+	if !ok {
+		return
+	}
+	err := pimod.BanMsgIDs(&sp.PSQLIB, fmsgids, board, reason)
+	if err != nil {
+		sp.Log.LogPrintf(ERROR, "%v", err)
+	}
+}
+
+// DemoUnbanByMsgID lifts global bans of msgids,
+// or, if board is set, bans in that board.
+func (sp *PSQLIB) DemoUnbanByMsgID(msgids []string, board string) {
+	fmsgids, ok := sp.checkMsgIDs(msgids)
+	if !ok {
+		return
+	}
+	err := pimod.UnbanMsgIDs(&sp.PSQLIB, fmsgids, board)
+	if err != nil {
+		sp.Log.LogPrintf(ERROR, "%v", err)
+	}
+}
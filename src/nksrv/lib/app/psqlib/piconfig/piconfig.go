@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"nksrv/lib/app/base/altthumber"
+	"nksrv/lib/app/base/ctlmsg"
 	"nksrv/lib/app/base/psql"
 	"nksrv/lib/app/base/webcaptcha"
 	"nksrv/lib/app/psqlib/internal/pibase"
@@ -14,6 +15,7 @@ import (
 	"nksrv/lib/app/psqlib/internal/pigpolicy"
 	"nksrv/lib/app/psqlib/internal/pireadnntp"
 	"nksrv/lib/mail/form"
+	"nksrv/lib/nntp/nntppostlockmgr"
 	"nksrv/lib/thumbnailer"
 	"nksrv/lib/thumbnailer/nilthm"
 	"nksrv/lib/utils/fs/cacheengine"
@@ -25,6 +27,7 @@ type Config struct {
 	DB             *psql.PSQL
 	Logger         *LoggerX
 	NodeName       string
+	PathAliases    []string // other path identities of this node
 	WebFrontendKey string
 	SrcCfg         *fstore.Config
 	ThmCfg         *fstore.Config
@@ -39,6 +42,11 @@ type Config struct {
 	NGPAnyPuller   string
 	NGPAnyServer   string
 	InstanceName   string
+	ControlTrust   []ctlmsg.TrustEntry
+	// accept NNTP replies to archived threads
+	ArchivedNNTPReplies bool
+	// article transfer locks, nil for nntppostlockmgr.DefaultConfig
+	PostLocks *nntppostlockmgr.Config
 }
 
 var stOnce sync.Once
@@ -103,6 +111,7 @@ func ConfigPSQLIB(p *pibase.PSQLIB, cfg Config) (err error) {
 	p.FFO = pibase.FormFileOpener{&p.Src}
 
 	p.Instance = nonEmptyStrOrPanic(cfg.NodeName)
+	p.PathIdentities = append([]string{p.Instance}, cfg.PathAliases...)
 	if cfg.WebFrontendKey != "" {
 		seed, e := hex.DecodeString(cfg.WebFrontendKey)
 		if e != nil {
@@ -118,6 +127,12 @@ func ConfigPSQLIB(p *pibase.PSQLIB, cfg Config) (err error) {
 
 	p.MaxArticleBodySize = (2 << 30) - 1 // TODO config
 
+	plcfg := nntppostlockmgr.DefaultConfig
+	if cfg.PostLocks != nil {
+		plcfg = *cfg.PostLocks
+	}
+	p.PostLocks = nntppostlockmgr.NewPostLockMgr(plcfg)
+
 	p.WebCaptcha = cfg.WebCaptcha
 	p.TextPostParamFunc = pibaseweb.MakePostParamFunc(cfg.WebCaptcha)
 
@@ -133,6 +148,11 @@ func ConfigPSQLIB(p *pibase.PSQLIB, cfg Config) (err error) {
 	if err != nil {
 		return
 	}
+	p.ControlTrust, err = ctlmsg.MakeTrustList(cfg.ControlTrust)
+	if err != nil {
+		return
+	}
+	p.ArchivedNNTPReplies = cfg.ArchivedNNTPReplies
 
 	return
 }
@@ -13,7 +13,7 @@ import (
 	"nksrv/lib/utils/sqlbucket"
 )
 
-const currDbVersion = "demo8"
+const currDbVersion = "demo15"
 
 func (sp *PSQLIB) InitDB() (err error) {
 
@@ -31,7 +31,7 @@ func (sp *PSQLIB) InitDB() (err error) {
 	}()
 
 	initfs := [...]string{
-		"", "_jobstate", "_puller",
+		"", "_jobstate", "_puller", "_pusher",
 		"_triggers",
 		"_triggers_banlist",
 		"_triggers_boards",
@@ -245,6 +245,20 @@ func (s *PullerDB) IsArticleWanted(
 	wanted bool, wdata interface{}, err error) {
 
 	cmsgid := cutMsgID(fmsgid)
+
+	// check if pull filter rejected it before
+	var rejected bool
+	err = s.sp.StPrep[pibase.St_puller_reject_check].
+		QueryRow(s.id, string(cmsgid)).
+		Scan(&rejected)
+	if err != nil {
+		err = s.sp.SQLError("puller_reject_check query scan", err)
+		return
+	}
+	if rejected {
+		return
+	}
+
 	// check if we already have it
 	// XXX
 	i, err := s.sp.checkArticleForPush(cmsgid)
@@ -271,6 +285,29 @@ func (s *PullerDB) IsArticleWanted(
 	return
 }
 
+// RejectArticle remembers that pull filter rejected article,
+// so that IsArticleWanted won't want it again.
+func (s *PullerDB) RejectArticle(fmsgid TFullMsgIDStr, reason string) error {
+	_, e := s.sp.StPrep[pibase.St_puller_reject_add].
+		Exec(s.id, string(cutMsgID(fmsgid)), reason)
+	if e != nil {
+		return s.sp.SQLError("puller_reject_add query execution", e)
+	}
+	return nil
+}
+
+// SetPullRules records rules pull filter uses.
+// If they changed, articles rejected before are forgotten,
+// as new rules may want them.
+func (s *PullerDB) SetPullRules(rules string) error {
+	_, e := s.sp.StPrep[pibase.St_puller_set_rules].
+		Exec(s.id, sql.NullString{String: rules, Valid: rules != ""})
+	if e != nil {
+		return s.sp.SQLError("puller_set_rules query execution", e)
+	}
+	return nil
+}
+
 func isGlobalCtl(group string) bool {
 	return group == "ctl"
 }
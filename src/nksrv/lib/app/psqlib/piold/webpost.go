@@ -135,6 +135,8 @@ func (sp *PSQLIB) commonNewPost(
 	}
 	rInfo.PostID = pInfo.ID
 	rInfo.MessageID = pInfo.MessageID
+	rInfo.CancelKey = ctx.cancelKey
+	rInfo.Queued = ctx.queued
 	return
 }
 
@@ -165,7 +167,8 @@ func (sp *PSQLIB) addNewBoard(
 		threads_per_page,
 		max_active_pages,
 		max_pages,
-		cfg_t_bump_limit
+		cfg_t_bump_limit,
+		moderated
 	)
 VALUES
 	(
@@ -176,7 +179,8 @@ VALUES
 		$4,
 		$5,
 		$6,
-		$7
+		$7,
+		$8
 	)
 ON CONFLICT
 	DO NOTHING
@@ -188,7 +192,7 @@ RETURNING
 		QueryRow(
 			q, bi.Name, bi.NewsGroup, bi.Description,
 			bi.ThreadsPerPage, bi.MaxActivePages, bi.MaxPages,
-			defaultThreadOptions.BumpLimit).
+			defaultThreadOptions.BumpLimit, bi.Moderated).
 		Scan(&bid)
 
 	if e != nil {
@@ -242,10 +246,11 @@ SET
 	bdesc = $2,
 	threads_per_page = $3,
 	max_active_pages = $4,
-	max_pages = $5
+	max_pages = $5,
+	moderated = $6
 WHERE bname = $1`
 	res, e := sp.db.DB.Exec(q, bi.Name, bi.Description,
-		bi.ThreadsPerPage, bi.MaxActivePages, bi.MaxPages)
+		bi.ThreadsPerPage, bi.MaxActivePages, bi.MaxPages, bi.Moderated)
 	if e != nil {
 		err = sp.SQLError("board update query row scan", e)
 		return
@@ -2,8 +2,10 @@ package psqlib
 
 import (
 	"database/sql"
+	"net/http"
 
 	"nksrv/lib/app/psqlib/internal/pibase"
+	ib0 "nksrv/lib/app/webib0"
 	. "nksrv/lib/utils/logx"
 
 	xtypes "github.com/jmoiron/sqlx/types"
@@ -32,7 +34,7 @@ func (ctx *postWebContext) getPrePostInfo(
 
 		err = ctx.sp.maybeTxStmt(tx, st_web_prepost_newthread).
 			QueryRow(btr.board).
-			Scan(&dbi.bid, &jbPL, &jbXL)
+			Scan(&dbi.bid, &jbPL, &jbXL, &dbi.moderated)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				err = webNotFound(errNoSuchBoard)
@@ -56,7 +58,8 @@ func (ctx *postWebContext) getPrePostInfo(
 
 		err = sp.maybeTxStmt(tx, st_web_prepost_newpost).
 			QueryRow(btr.board, btr.thread).
-			Scan(&dbi.bid, &jbPL, &jbXL, &dbi.tid, &jtRL, &dbi.ref, &dbi.opdate)
+			Scan(&dbi.bid, &jbPL, &jbXL, &dbi.tid, &jtRL, &dbi.ref, &dbi.opdate,
+				&dbi.archived, &dbi.locked, &dbi.moderated)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				err = webNotFound(errNoSuchBoard)
@@ -78,6 +81,17 @@ func (ctx *postWebContext) getPrePostInfo(
 			return
 		}
 
+		if dbi.archived.Bool {
+			err = &ib0.WebPostError{
+				Err: pibase.ErrThreadArchived, Code: http.StatusForbidden}
+			return
+		}
+		if dbi.locked.Bool {
+			err = &ib0.WebPostError{
+				Err: pibase.ErrThreadLocked, Code: http.StatusForbidden}
+			return
+		}
+
 		rInfo.ThreadID = thread
 
 		dbi.postLimits = defaultReplySubmissionLimits
@@ -17,7 +17,8 @@ func (p *PSQLIB) SupportsIHave() bool  { return true }
 func (p *PSQLIB) SupportsPost() bool   { return true }
 func (p *PSQLIB) SupportsStream() bool { return true }
 
-func (p *PSQLIB) SupportsXListen() bool { return false }
+// XLISTEN events come from PushNotifier
+func (p *PSQLIB) SupportsXListen() bool { return p.PushNotifier != nil }
 
 // ARTICLE/HEAD/BODY/STAT by MsgID
 func (sp *PSQLIB) GetArticleFullByMsgID(
@@ -107,18 +108,42 @@ func (sp *PSQLIB) SelectPrevArticle(w Responder, cs *ConnState) {
 
 // listings
 func (sp *PSQLIB) ListNewNews(
-	aw AbstractResponder, wildmat []byte, qt time.Time) {
+	aw AbstractResponder, cs *ConnState, wildmat []byte, qt time.Time) {
 
-	return pireadnntp.ListNewNews(&sp.PSQLIB, aw, wildmat, qt)
+	return pireadnntp.ListNewNews(&sp.PSQLIB, aw, cs, wildmat, qt)
 }
-func (sp *PSQLIB) ListNewGroups(aw AbstractResponder, qt time.Time) {
-	return pireadnntp.ListNewGroups(&sp.PSQLIB, aw, qt)
+func (sp *PSQLIB) ListNewGroups(
+	aw AbstractResponder, cs *ConnState, qt time.Time) {
+
+	return pireadnntp.ListNewGroups(&sp.PSQLIB, aw, cs, qt)
+}
+func (sp *PSQLIB) ListActiveGroups(
+	aw AbstractResponder, cs *ConnState, wildmat []byte) {
+
+	return pireadnntp.ListActiveGroups(&sp.PSQLIB, aw, cs, wildmat)
 }
-func (sp *PSQLIB) ListActiveGroups(aw AbstractResponder, wildmat []byte) {
-	return pireadnntp.ListActiveGroups(&sp.PSQLIB, aw, wildmat)
+func (sp *PSQLIB) ListNewsgroups(
+	aw AbstractResponder, cs *ConnState, wildmat []byte) {
+
+	return pireadnntp.ListNewsgroups(&sp.PSQLIB, aw, cs, wildmat)
 }
-func (sp *PSQLIB) ListNewsgroups(aw AbstractResponder, wildmat []byte) {
-	return pireadnntp.ListNewsgroups(&sp.PSQLIB, aw, wildmat)
+func (sp *PSQLIB) ListActiveTimes(
+	aw AbstractResponder, cs *ConnState, wildmat []byte) {
+
+	return pireadnntp.ListActiveTimes(&sp.PSQLIB, aw, cs, wildmat)
+}
+func (sp *PSQLIB) ListCounts(
+	aw AbstractResponder, cs *ConnState, wildmat []byte) {
+
+	return pireadnntp.ListCounts(&sp.PSQLIB, aw, cs, wildmat)
+}
+func (sp *PSQLIB) ListDistribPats(aw AbstractResponder, cs *ConnState) {
+	return pireadnntp.ListDistribPats(&sp.PSQLIB, aw, cs)
+}
+func (sp *PSQLIB) ListHeaders(
+	aw AbstractResponder, cs *ConnState, form nntp.ListHeadersForm) {
+
+	return pireadnntp.ListHeaders(&sp.PSQLIB, aw, cs, form)
 }
 
 // over stuff
@@ -158,10 +183,10 @@ func (sp *PSQLIB) GetHdrByCurr(w Responder, cs *ConnState, hdr []byte) bool {
 	return sp.CommonGetHdrByCurr(&sp.PSQLIB, w, cs, hdr, true)
 }
 func (sp *PSQLIB) GetXHdrByMsgID(
-	w Responder, hdr []byte, msgid TCoreMsgID) bool {
+	w Responder, cs *ConnState, hdr []byte, msgid TCoreMsgID) bool {
 
 	return pireadnntp.CommonGetHdrByMsgID(
-		&sp.PSQLIB, w, nil, hdr, msgid, false)
+		&sp.PSQLIB, w, cs, hdr, msgid, false)
 }
 func (sp *PSQLIB) GetXHdrByRange(
 	w Responder, cs *ConnState, hdr []byte, rmin, rmax int64) bool {
@@ -0,0 +1,18 @@
+package psqlib
+
+import (
+	"nksrv/lib/app/psqlib/internal/pipushnntp"
+	"nksrv/lib/nntp"
+)
+
+func (sp *PSQLIB) NewPusherDB(name string) (nntp.PusherDatabase, error) {
+	db, err := pipushnntp.NewPusherDB(&sp.PSQLIB, name)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (sp *PSQLIB) ClearPusherDBs() error {
+	return pipushnntp.ClearPusherDBs(&sp.PSQLIB)
+}
@@ -0,0 +1,14 @@
+package psqlib
+
+import (
+	"net/http"
+
+	"nksrv/lib/app/psqlib/internal/pipostweb"
+)
+
+func (sp *PSQLIB) IBCancelPost(
+	w http.ResponseWriter, r *http.Request,
+	board, post, password, ckey string) (err error) {
+
+	return pipostweb.CancelPost(&sp.PSQLIB, board, post, password, ckey)
+}
@@ -0,0 +1,34 @@
+package psqlib
+
+import (
+	"net/http"
+
+	"nksrv/lib/app/psqlib/internal/pipostweb"
+	"nksrv/lib/app/psqlib/internal/pireadweb"
+	ib0 "nksrv/lib/app/webib0"
+)
+
+func (sp *PSQLIB) IBGetModQueue(
+	w http.ResponseWriter, r *http.Request, q *ib0.IBModQueue, board string) (
+	err error) {
+
+	err, code := pireadweb.GetModQueue(&sp.PSQLIB, q, board)
+	if err != nil {
+		return &ib0.WebPostError{Err: err, Code: code}
+	}
+	return nil
+}
+
+func (sp *PSQLIB) IBApproveQueuedPost(
+	w http.ResponseWriter, r *http.Request, board, msgid string) (
+	err error) {
+
+	return pipostweb.QueueAction(&sp.PSQLIB, board, msgid, true)
+}
+
+func (sp *PSQLIB) IBRejectQueuedPost(
+	w http.ResponseWriter, r *http.Request, board, msgid string) (
+	err error) {
+
+	return pipostweb.QueueAction(&sp.PSQLIB, board, msgid, false)
+}
@@ -3,6 +3,7 @@ package apiproxy
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -64,3 +65,25 @@ func (p APIProxy) IBGetOverboardCatalog(ocp *ib0.IBOverboardCatalog) (error, int
 func (p APIProxy) IBGetThreadCatalog(tcp *ib0.IBThreadCatalog, b string) (error, int) {
 	return p.call("/boards/"+b+"/catalog", tcp)
 }
+
+func (p APIProxy) IBGetThreadArchive(tap *ib0.IBThreadArchive, b string, pn uint32) (error, int) {
+	return p.call("/boards/"+b+"/archive/pages/"+strconv.FormatUint(uint64(pn), 10), tap)
+}
+
+func modLogQuery(b, m string) string {
+	q := url.Values{}
+	if b != "" {
+		q.Set("board", b)
+	}
+	if m != "" {
+		q.Set("mod", m)
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return "?" + q.Encode()
+}
+
+func (p APIProxy) IBGetModLogPage(mlp *ib0.IBModLogPage, b, m string, pn uint32) (error, int) {
+	return p.call("/modlog/pages/"+strconv.FormatUint(uint64(pn), 10)+modLogQuery(b, m), mlp)
+}
@@ -105,6 +105,23 @@ func (a *JSONAPIIB) IBGetThreadListPage(
 		page)
 }
 
+func (a *JSONAPIIB) IBGetModLogPage(
+	page *webib0.IBModLogPage, board, mod string, num uint32) (error, int) {
+
+	q := url.Values{}
+	if board != "" {
+		q.Set("board", board)
+	}
+	if mod != "" {
+		q.Set("mod", mod)
+	}
+	u := a.u + "/modlog/pages/" + strconv.FormatUint(uint64(num), 10)
+	if len(q) != 0 {
+		u += "?" + q.Encode()
+	}
+	return fetchInto(&a.c, u, page)
+}
+
 func (a *JSONAPIIB) IBGetOverboardPage(
 	page *webib0.IBOverboardPage, num uint32) (error, int) {
 
@@ -120,6 +137,16 @@ func (a *JSONAPIIB) IBGetThreadCatalog(
 	return fetchInto(&a.c, a.u+"/boards/"+url.PathEscape(board)+"/catalog", page)
 }
 
+func (a *JSONAPIIB) IBGetThreadArchive(
+	page *webib0.IBThreadArchive, board string, num uint32) (error, int) {
+
+	return fetchInto(
+		&a.c,
+		a.u+"/boards/"+url.PathEscape(board)+"/archive/pages/"+
+			strconv.FormatUint(uint64(num), 10),
+		page)
+}
+
 func (a *JSONAPIIB) IBGetOverboardCatalog(
 	page *webib0.IBOverboardCatalog) (error, int) {
 
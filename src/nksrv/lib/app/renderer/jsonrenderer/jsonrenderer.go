@@ -84,6 +84,19 @@ func (j *JSONRenderer) ServeThreadListPage(
 	e.Encode(&pag)
 }
 
+func (j *JSONRenderer) ServeModLogPage(
+	w http.ResponseWriter, r *http.Request, board, mod string, page uint32) {
+
+	e := j.prepareEncoder(w, 0)
+	var pag ib0.IBModLogPage
+	err, code := j.p.IBGetModLogPage(&pag, board, mod, page)
+	if err != nil {
+		returnError(w, e, err, code)
+		return
+	}
+	e.Encode(&pag)
+}
+
 func (j *JSONRenderer) ServeOverboardPage(
 	w http.ResponseWriter, r *http.Request, page uint32) {
 
@@ -110,6 +123,19 @@ func (j *JSONRenderer) ServeThreadCatalog(
 	e.Encode(&pag)
 }
 
+func (j *JSONRenderer) ServeThreadArchive(
+	w http.ResponseWriter, r *http.Request, board string, page uint32) {
+
+	e := j.prepareEncoder(w, 0)
+	var pag ib0.IBThreadArchive
+	err, code := j.p.IBGetThreadArchive(&pag, board, page)
+	if err != nil {
+		returnError(w, e, err, code)
+		return
+	}
+	e.Encode(&pag)
+}
+
 func (j *JSONRenderer) ServeOverboardCatalog(
 	w http.ResponseWriter, r *http.Request) {
 
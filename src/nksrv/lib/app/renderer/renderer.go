@@ -14,8 +14,11 @@ type Renderer interface {
 		w http.ResponseWriter, r *http.Request, board string, page uint32)
 	ServeOverboardPage(w http.ResponseWriter, r *http.Request, page uint32)
 	ServeThreadCatalog(w http.ResponseWriter, r *http.Request, board string)
+	ServeThreadArchive(w http.ResponseWriter, r *http.Request, board string, page uint32)
 	ServeOverboardCatalog(w http.ResponseWriter, r *http.Request)
 	ServeThread(w http.ResponseWriter, r *http.Request, board, thread string)
+	ServeModLogPage(
+		w http.ResponseWriter, r *http.Request, board, mod string, page uint32)
 
 	DressNewBoardResult(
 		w http.ResponseWriter, bname string, err error, code int)
@@ -48,6 +48,14 @@ func (RendererStatic) ServeThreadCatalog(w http.ResponseWriter, r *http.Request,
 	doServe(w, r, "c-"+board+".html")
 }
 
+func (RendererStatic) ServeThreadArchive(w http.ResponseWriter, r *http.Request, board string, page uint32) {
+	doServe(w, r, "a-"+board+"-"+strconv.Itoa(int(page))+".html")
+}
+
+func (RendererStatic) ServeModLogPage(w http.ResponseWriter, r *http.Request, board, mod string, page uint32) {
+	doServe(w, r, "modlog-"+strconv.Itoa(int(page))+".html")
+}
+
 func (RendererStatic) ServeThread(w http.ResponseWriter, r *http.Request, board, thread string) {
 	doServe(w, r, "t-"+board+"-"+thread+".html")
 }
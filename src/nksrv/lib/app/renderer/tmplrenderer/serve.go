@@ -189,6 +189,73 @@ func (tr *TmplRenderer) ServeThreadCatalog(
 	tr.outTmplP(w, ptmplThreadCatalog, 200, l)
 }
 
+func (tr *TmplRenderer) ServeThreadArchive(
+	w http.ResponseWriter, r *http.Request, board string, page uint32) {
+
+	l := &struct {
+		D ib0.IBThreadArchive
+		N *NodeInfo
+		R *TmplRenderer
+	}{
+		N: &tr.ni,
+		R: tr,
+	}
+
+	err, code := tr.p.IBGetThreadArchive(&l.D, board, page)
+	if err != nil {
+		ctx := struct {
+			Code  int
+			Err   error
+			Board string
+			Page  uint32
+		}{
+			code,
+			err,
+			board,
+			page,
+		}
+		tr.outTmplP(w, ptmplThreadArchiveErr, code, ctx)
+		return
+	}
+	setCacheControl(w)
+	tr.outTmplP(w, ptmplThreadArchive, 200, l)
+}
+
+func (tr *TmplRenderer) ServeModLogPage(
+	w http.ResponseWriter, r *http.Request,
+	board, mod string, page uint32) {
+
+	l := &struct {
+		D ib0.IBModLogPage
+		N *NodeInfo
+		R *TmplRenderer
+	}{
+		N: &tr.ni,
+		R: tr,
+	}
+
+	err, code := tr.p.IBGetModLogPage(&l.D, board, mod, page)
+	if err != nil {
+		ctx := struct {
+			Code  int
+			Err   error
+			Board string
+			Mod   string
+			Page  uint32
+		}{
+			code,
+			err,
+			board,
+			mod,
+			page,
+		}
+		tr.outTmplP(w, ptmplModLogPageErr, code, ctx)
+		return
+	}
+	setCacheControl(w)
+	tr.outTmplP(w, ptmplModLogPage, 200, l)
+}
+
 func (tr *TmplRenderer) ServeOverboardCatalog(
 	w http.ResponseWriter, r *http.Request) {
 
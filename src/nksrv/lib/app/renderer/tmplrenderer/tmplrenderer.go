@@ -33,10 +33,14 @@ const (
 	ptmplOverboardPageErr
 	ptmplThreadCatalog
 	ptmplThreadCatalogErr
+	ptmplThreadArchive
+	ptmplThreadArchiveErr
 	ptmplOverboardCatalog
 	ptmplOverboardCatalogErr
 	ptmplThread
 	ptmplThreadErr
+	ptmplModLogPage
+	ptmplModLogPageErr
 
 	ptmplMax
 )
@@ -63,10 +67,14 @@ var pnames = [ptmplMax]string{
 	"overboard_page_err",
 	"thread_catalog",
 	"thread_catalog_err",
+	"thread_archive",
+	"thread_archive_err",
 	"overboard_catalog",
 	"overboard_catalog_err",
 	"thread",
 	"thread_err",
+	"modlog_page",
+	"modlog_page_err",
 }
 var rnames = [rtmplMax]string{
 	"created_board",
@@ -120,5 +120,13 @@ const (
 	SI_puller_unset_group_id
 	SI_puller_load_temp_groups
 
+	// pusher specific
+
+	SI_pusher_register
+	SI_pusher_clear_unused
+	SI_pusher_get_groups
+	SI_pusher_set_group_id
+	SI_pusher_list_articles
+
 	SISize int = iota
 )
@@ -87,11 +87,16 @@ func _() {
 	_ = x[SI_puller_set_group_id-76]
 	_ = x[SI_puller_unset_group_id-77]
 	_ = x[SI_puller_load_temp_groups-78]
+	_ = x[SI_pusher_register-79]
+	_ = x[SI_pusher_clear_unused-80]
+	_ = x[SI_pusher_get_groups-81]
+	_ = x[SI_pusher_set_group_id-82]
+	_ = x[SI_pusher_list_articles-83]
 }
 
-const _StatementIndexEntry_name = "nntp_article_exists_or_banned_by_msgidnntp_article_valid_by_msgidnntp_article_num_by_msgidnntp_article_msgid_by_numnntp_article_get_gpidnntp_selectnntp_select_and_listnntp_nextnntp_lastnntp_newnews_allnntp_newnews_onenntp_newnews_all_groupnntp_newgroupsnntp_listactive_allnntp_listactive_onenntp_over_msgidnntp_over_rangenntp_over_currnntp_hdr_msgid_msgidnntp_hdr_msgid_subjectnntp_hdr_msgid_anynntp_hdr_range_msgidnntp_hdr_range_subjectnntp_hdr_range_anynntp_hdr_curr_msgidnntp_hdr_curr_subjectnntp_hdr_curr_anyweb_listboardsweb_thread_list_pageweb_overboard_pageweb_thread_catalogweb_overboard_catalogweb_threadweb_prepost_newthreadweb_prepost_newpostpost_newthread_sb_nfpost_newthread_mb_nfpost_newthread_sb_sfpost_newthread_mb_sfpost_newthread_sb_mfpost_newthread_mb_mfpost_newreply_sb_nfpost_newreply_mb_nfpost_newreply_sb_sfpost_newreply_mb_sfpost_newreply_sb_mfpost_newreply_mb_mfmod_ref_writemod_ref_find_postmod_update_bpost_activ_refsmod_autoregister_modmod_delete_by_msgidmod_ban_by_msgidmod_bname_topts_by_tidmod_refresh_bump_by_tidmod_set_mod_privmod_set_mod_priv_groupmod_unset_modmod_fetch_and_clear_mod_msgs_startmod_fetch_and_clear_mod_msgs_continuemod_load_filesmod_check_article_for_pushmod_delete_ph_for_pushmod_add_ph_after_pushmod_joblist_modlist_changes_getmod_joblist_modlist_changes_setmod_joblist_modlist_changes_delmod_joblist_refs_deps_recalc_getmod_joblist_refs_deps_recalc_setmod_joblist_refs_deps_recalc_delmod_joblist_refs_recalc_getpuller_get_last_newnewspuller_set_last_newnewspuller_get_last_newsgroupspuller_set_last_newsgroupspuller_get_group_idpuller_set_group_idpuller_unset_group_idpuller_load_temp_groups"
+const _StatementIndexEntry_name = "nntp_article_exists_or_banned_by_msgidnntp_article_valid_by_msgidnntp_article_num_by_msgidnntp_article_msgid_by_numnntp_article_get_gpidnntp_selectnntp_select_and_listnntp_nextnntp_lastnntp_newnews_allnntp_newnews_onenntp_newnews_all_groupnntp_newgroupsnntp_listactive_allnntp_listactive_onenntp_over_msgidnntp_over_rangenntp_over_currnntp_hdr_msgid_msgidnntp_hdr_msgid_subjectnntp_hdr_msgid_anynntp_hdr_range_msgidnntp_hdr_range_subjectnntp_hdr_range_anynntp_hdr_curr_msgidnntp_hdr_curr_subjectnntp_hdr_curr_anyweb_listboardsweb_thread_list_pageweb_overboard_pageweb_thread_catalogweb_overboard_catalogweb_threadweb_prepost_newthreadweb_prepost_newpostpost_newthread_sb_nfpost_newthread_mb_nfpost_newthread_sb_sfpost_newthread_mb_sfpost_newthread_sb_mfpost_newthread_mb_mfpost_newreply_sb_nfpost_newreply_mb_nfpost_newreply_sb_sfpost_newreply_mb_sfpost_newreply_sb_mfpost_newreply_mb_mfmod_ref_writemod_ref_find_postmod_update_bpost_activ_refsmod_autoregister_modmod_delete_by_msgidmod_ban_by_msgidmod_bname_topts_by_tidmod_refresh_bump_by_tidmod_set_mod_privmod_set_mod_priv_groupmod_unset_modmod_fetch_and_clear_mod_msgs_startmod_fetch_and_clear_mod_msgs_continuemod_load_filesmod_check_article_for_pushmod_delete_ph_for_pushmod_add_ph_after_pushmod_joblist_modlist_changes_getmod_joblist_modlist_changes_setmod_joblist_modlist_changes_delmod_joblist_refs_deps_recalc_getmod_joblist_refs_deps_recalc_setmod_joblist_refs_deps_recalc_delmod_joblist_refs_recalc_getpuller_get_last_newnewspuller_set_last_newnewspuller_get_last_newsgroupspuller_set_last_newsgroupspuller_get_group_idpuller_set_group_idpuller_unset_group_idpuller_load_temp_groupspusher_registerpusher_clear_unusedpusher_get_groupspusher_set_group_idpusher_list_articles"
 
-var _StatementIndexEntry_index = [...]uint16{0, 38, 65, 90, 115, 136, 147, 167, 176, 185, 201, 217, 239, 253, 272, 291, 306, 321, 335, 355, 377, 395, 415, 437, 455, 474, 495, 512, 526, 546, 564, 582, 603, 613, 634, 653, 673, 693, 713, 733, 753, 773, 792, 811, 830, 849, 868, 887, 900, 917, 944, 964, 983, 999, 1021, 1044, 1060, 1082, 1095, 1129, 1166, 1180, 1206, 1228, 1249, 1280, 1311, 1342, 1374, 1406, 1438, 1465, 1488, 1511, 1537, 1563, 1582, 1601, 1622, 1645}
+var _StatementIndexEntry_index = [...]uint16{0, 38, 65, 90, 115, 136, 147, 167, 176, 185, 201, 217, 239, 253, 272, 291, 306, 321, 335, 355, 377, 395, 415, 437, 455, 474, 495, 512, 526, 546, 564, 582, 603, 613, 634, 653, 673, 693, 713, 733, 753, 773, 792, 811, 830, 849, 868, 887, 900, 917, 944, 964, 983, 999, 1021, 1044, 1060, 1082, 1095, 1129, 1166, 1180, 1206, 1228, 1249, 1280, 1311, 1342, 1374, 1406, 1438, 1465, 1488, 1511, 1537, 1563, 1582, 1601, 1622, 1645, 1660, 1679, 1696, 1715, 1735}
 
 func (i StatementIndexEntry) String() string {
 	if i < 0 || i >= StatementIndexEntry(len(_StatementIndexEntry_index)-1) {
@@ -41,6 +41,8 @@ CREATE TABLE ib.boards (
 	thread_opts      JSONB, -- options common for all threads. stuff like bump/file limits
 	attrib           JSONB, -- board attributes
 
+	moderated BOOLEAN  DEFAULT FALSE  NOT NULL, -- posts need approval of mod
+
 
 	PRIMARY KEY (b_id)
 );
@@ -67,7 +69,8 @@ CREATE TABLE ib.threads (
 
 	bump TIMESTAMP  WITH TIME ZONE  NOT NULL, -- last bump time. decides position in pages/catalog
 
-	t_arch_order BIGINT  NOT NULL, -- order number within arcive (newest thread goes last, so don't need updating of old indexes)
+	-- order number within archive (newest thread goes last, so don't need updating of old indexes), NULL if not archived
+	t_arch_order BIGINT,
 
 	skip_over BOOLEAN  NOT NULL,            -- if true, do not include in overboard
 	p_count   BIGINT   NOT NULL  DEFAULT 0, -- post count (including OP)
@@ -90,6 +93,7 @@ CREATE TABLE ib.threads (
 CREATE INDEX
 	ON ib.threads (
 		b_id   ASC,
+		COALESCE((thread_opts ->> 'sticky')::BOOLEAN, FALSE) DESC,
 		bump   DESC,
 		b_t_id ASC
 	);
@@ -102,6 +106,14 @@ CREATE INDEX
 	)
 	WHERE
 		skip_over IS NOT TRUE;
+-- for board archive
+CREATE INDEX
+	ON ib.threads (
+		b_id         ASC,
+		t_arch_order ASC
+	)
+	WHERE
+		t_arch_order IS NOT NULL;
 
 
 
@@ -114,6 +126,7 @@ CREATE TABLE ib.gposts (
 	date_sent TIMESTAMP  WITH TIME ZONE,
 	date_recv TIMESTAMP  WITH TIME ZONE,
 	sage      BOOLEAN    NOT NULL        DEFAULT FALSE,
+	expires   TIMESTAMP  WITH TIME ZONE, -- parsed Expires header, if any
 
 	f_count INTEGER  NOT NULL  DEFAULT 0, -- attachment count
 	f_size  BIGINT   NOT NULL  DEFAULT 0, -- total attachment size in storage
@@ -140,6 +153,10 @@ CREATE TABLE ib.gposts (
 	PRIMARY KEY (g_p_id),
 	UNIQUE      (msgid)
 );
+-- expiry
+CREATE INDEX
+	ON ib.gposts (expires)
+	WHERE expires IS NOT NULL;
 
 
 
@@ -4,6 +4,8 @@ CREATE TABLE ib.puller_list (
 	sname    TEXT    COLLATE "C"  NOT NULL,
 	-- nonce, used to clean dead server trackings
 	last_use BIGINT               NOT NULL,
+	-- pull rules puller_rejects were made under
+	rules    TEXT,
 
 	PRIMARY KEY (sid),
 	UNIQUE (sname)
@@ -66,3 +68,23 @@ CREATE INDEX
 
 CREATE INDEX
 	ON ib.puller_group_track (bid);
+
+-- articles pull filter rejected (per-server), not to be fetched again
+CREATE TABLE ib.puller_rejects (
+	sid      BIGINT                    NOT NULL,
+	msgid    TEXT  COLLATE "C"         NOT NULL,
+	date_rej TIMESTAMP  WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	reason   TEXT                      NOT NULL,
+
+
+	PRIMARY KEY (sid,msgid),
+
+	FOREIGN KEY (sid)
+		REFERENCES ib.puller_list
+		ON DELETE CASCADE
+);
+
+CREATE INDEX
+    ON ib.puller_rejects (
+        date_rej
+    );
@@ -0,0 +1,26 @@
+-- audit log of executed mod commands.
+-- deliberately not linked to posts, so it stays after ctl msg is gone
+CREATE TABLE ib.modlog (
+	ml_id     BIGINT GENERATED ALWAYS AS IDENTITY,
+	date_exec TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+	mod_pubkey TEXT  COLLATE "C", -- NULL if done by local admin
+	msgid      TEXT  COLLATE "C", -- ctl msg which issued command (if any)
+	b_name     TEXT  COLLATE "C", -- board command was scoped to, NULL if global
+
+	cmd     TEXT                NOT NULL,
+	targets TEXT  COLLATE "C"   ARRAY,    -- msgids, pubkeys and such
+	applied BOOLEAN             NOT NULL, -- false if rejected
+	reason  TEXT                NOT NULL DEFAULT '', -- why rejected, or reason given by mod
+
+
+	PRIMARY KEY (ml_id)
+);
+
+CREATE INDEX
+	ON ib.modlog (b_name,ml_id)
+	WHERE b_name IS NOT NULL;
+
+CREATE INDEX
+	ON ib.modlog (mod_pubkey,ml_id)
+	WHERE mod_pubkey IS NOT NULL;
@@ -0,0 +1,42 @@
+-- posts to moderated boards awaiting approval.
+-- post is kept in the form it would be inserted in,
+-- its files are already in place and are counted as used.
+CREATE TABLE ib.modqueue (
+	mq_id     BIGINT GENERATED ALWAYS AS IDENTITY,
+	date_recv TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+	b_id   INTEGER NOT NULL, -- board post is for
+	b_t_id BIGINT,           -- thread if reply, NULL if new thread
+
+	msgid TEXT  COLLATE "C"  NOT NULL, -- Message-ID of post
+	pinfo JSONB              NOT NULL, -- post itself
+
+
+	PRIMARY KEY (mq_id),
+	UNIQUE      (msgid),
+
+	FOREIGN KEY (b_id)
+		REFERENCES ib.boards
+		ON DELETE CASCADE,
+	FOREIGN KEY (b_id,b_t_id)
+		REFERENCES ib.threads
+		ON DELETE CASCADE -- thread is gone, reply has nowhere to go
+);
+
+CREATE INDEX
+	ON ib.modqueue (b_id,mq_id);
+
+-- files of queued posts. triggers keep them in files GC counters
+CREATE TABLE ib.modqueue_files (
+	mq_id BIGINT               NOT NULL,
+	fname TEXT    COLLATE "C"  NOT NULL,
+	thumb TEXT    COLLATE "C"  NOT NULL, -- '' if none
+
+
+	FOREIGN KEY (mq_id)
+		REFERENCES ib.modqueue
+		ON DELETE CASCADE
+);
+
+CREATE INDEX
+	ON ib.modqueue_files (mq_id);
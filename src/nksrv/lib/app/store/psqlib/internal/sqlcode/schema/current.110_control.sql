@@ -0,0 +1,34 @@
+-- control messages already acted upon, so they can't be replayed.
+-- they aren't stored in gposts, so this is the only trace of them
+CREATE TABLE ib.control_history (
+	ch_id     BIGINT GENERATED ALWAYS AS IDENTITY,
+	date_recv TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+	msgid TEXT  COLLATE "C", -- Message-ID, if it had one
+	sig   TEXT  COLLATE "C", -- signature, if it was signed
+	-- whole message, kept only for ones posted here so that we push them
+	article TEXT,
+
+
+	PRIMARY KEY (ch_id)
+);
+
+CREATE UNIQUE INDEX
+	ON ib.control_history (msgid)
+	WHERE msgid IS NOT NULL;
+
+CREATE UNIQUE INDEX
+	ON ib.control_history (sig)
+	WHERE sig IS NOT NULL;
+
+CREATE INDEX
+	ON ib.control_history (date_recv);
+
+
+-- server-side secrets, generated when first needed
+CREATE TABLE ib.secrets (
+	s_name TEXT  COLLATE "C" NOT NULL,
+	s_data BYTEA NOT NULL,
+
+	PRIMARY KEY (s_name)
+);
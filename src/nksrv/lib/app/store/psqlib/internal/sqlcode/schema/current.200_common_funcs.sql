@@ -65,7 +65,16 @@ BEGIN
 				1
 		) as xbump
 	WHERE
-		(b_id,b_t_id) = (x_b_id,x_b_t_id);
+		(b_id,b_t_id) = (x_b_id,x_b_t_id) AND
+			-- archived threads stay where they are
+			t_arch_order IS NULL AND
+			-- autosaged threads don't get bumped anymore
+			COALESCE((thread_opts ->> 'autosage')::BOOLEAN, FALSE) = FALSE;
+
+	IF FOUND THEN
+		-- other threads may have fallen off active pages
+		NOTIFY ib0_threads_bumped;
+	END IF;
 
 END;
 $$ LANGUAGE plpgsql;
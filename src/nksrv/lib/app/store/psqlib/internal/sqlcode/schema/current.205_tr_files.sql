@@ -76,6 +76,21 @@ REFERENCING OLD TABLE AS oldrows
 FOR EACH STATEMENT
 EXECUTE PROCEDURE ib.files_after_delete();
 
+-- files of queued posts use the same counters
+CREATE TRIGGER after_insert
+AFTER INSERT
+ON ib.modqueue_files
+REFERENCING NEW TABLE AS newrows
+FOR EACH STATEMENT
+EXECUTE PROCEDURE ib.files_after_insert();
+
+CREATE TRIGGER after_delete
+AFTER DELETE
+ON ib.modqueue_files
+REFERENCING OLD TABLE AS oldrows
+FOR EACH STATEMENT
+EXECUTE PROCEDURE ib.files_after_delete();
+
 
 
 CREATE FUNCTION ib.files_uniq_fname_update_delete() RETURNS TRIGGER
@@ -1,12 +1,22 @@
 -- :name nntp_article_exists_or_banned_by_msgid
 -- input: cmsgid
 -- output: 1
+-- processed control messages count as existing
 SELECT
 	1
 FROM
 	ib.gposts
 WHERE
 	msgid = $1
+UNION ALL
+SELECT
+	1
+FROM
+	ib.control_history
+WHERE
+	msgid = $1
+LIMIT
+	1
 
 -- :name nntp_article_valid_by_msgid
 -- input: cmsgid
@@ -244,7 +254,8 @@ ORDER BY
 SELECT
 	xb.newsgroup,
 	MIN(xbp.b_p_id) AS lo,
-	MAX(xbp.b_p_id) AS hi
+	MAX(xbp.b_p_id) AS hi,
+	xb.moderated
 FROM
 	ib.boards AS xb
 LEFT JOIN
@@ -265,7 +276,8 @@ ORDER BY
 SELECT
 	xb.newsgroup,
 	MIN(xbp.b_p_id),
-	MAX(xbp.b_p_id)
+	MAX(xbp.b_p_id),
+	xb.moderated
 FROM
 	ib.boards AS xb
 LEFT JOIN
@@ -282,7 +294,70 @@ ORDER BY
 SELECT
 	xb.newsgroup,
 	MIN(xbp.b_p_id),
-	MAX(xbp.b_p_id)
+	MAX(xbp.b_p_id),
+	xb.moderated
+FROM
+	ib.boards AS xb
+LEFT JOIN
+	ib.bposts AS xbp
+USING
+	(b_id)
+WHERE
+	xb.newsgroup = $1
+GROUP BY
+	xb.b_id
+
+
+
+-- :name nntp_listactivetimes_all
+SELECT
+	xb.newsgroup,
+	xb.b_added
+FROM
+	ib.boards AS xb
+WHERE
+	xb.newsgroup IS NOT NULL
+ORDER BY
+	xb.newsgroup COLLATE "und-x-icu"
+
+-- :name nntp_listactivetimes_one
+-- input: {board name}
+SELECT
+	xb.newsgroup,
+	xb.b_added
+FROM
+	ib.boards AS xb
+WHERE
+	xb.newsgroup = $1
+
+-- :name nntp_listcounts_all
+SELECT
+	xb.newsgroup,
+	MIN(xbp.b_p_id),
+	MAX(xbp.b_p_id),
+	COUNT(xbp.b_p_id),
+	xb.moderated
+FROM
+	ib.boards AS xb
+LEFT JOIN
+	ib.bposts AS xbp
+USING
+	(b_id)
+WHERE
+	xb.newsgroup IS NOT NULL
+GROUP BY
+	xb.b_id
+ORDER BY
+	xb.newsgroup COLLATE "und-x-icu"
+
+-- :name nntp_listcounts_one
+-- input: {board name}
+SELECT
+	xb.newsgroup,
+	MIN(xbp.b_p_id),
+	MAX(xbp.b_p_id),
+	COUNT(xbp.b_p_id),
+	xb.moderated
 FROM
 	ib.boards AS xb
 LEFT JOIN
@@ -95,3 +95,48 @@ WHERE
 	xs.sid=$1 AND xs.last_use=$2
 ORDER BY
 	xb.newsgroup COLLATE "und-x-icu"
+
+-- :name puller_reject_add
+INSERT INTO
+	ib.puller_rejects (sid,msgid,reason)
+VALUES
+	($1,$2,$3)
+ON CONFLICT
+	DO NOTHING
+
+-- :name puller_reject_check
+SELECT
+	EXISTS (
+		SELECT
+			1
+		FROM
+			ib.puller_rejects
+		WHERE
+			sid = $1 AND msgid = $2
+	)
+
+-- :name puller_set_rules
+-- input: {sid, rules}
+-- rejects made under different rules are forgotten
+WITH
+	old AS (
+		SELECT
+			rules
+		FROM
+			ib.puller_list
+		WHERE
+			sid = $1
+		FOR UPDATE
+	),
+	upd AS (
+		UPDATE
+			ib.puller_list
+		SET
+			rules = $2
+		WHERE
+			sid = $1
+	)
+DELETE FROM
+	ib.puller_rejects
+WHERE
+	sid = $1 AND (SELECT rules FROM old) IS DISTINCT FROM $2
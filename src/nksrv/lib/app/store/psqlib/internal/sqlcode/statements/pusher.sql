@@ -0,0 +1,132 @@
+-- pusher-related stuff
+
+-- :name pusher_register
+-- input: {name} {nonce}
+INSERT INTO
+	ib.pusher_list AS sl (sname,last_use)
+VALUES
+	($1,$2)
+ON CONFLICT
+	(sname)
+DO
+	UPDATE SET
+		last_use = $2
+	WHERE
+		sl.sname = $1
+RETURNING
+	sid
+
+-- :name pusher_clear_unused
+-- input: {nonce}
+DELETE FROM
+	ib.pusher_list
+WHERE
+	last_use <> $1
+
+-- :name pusher_get_groups
+-- input: {sid}
+SELECT
+	xb.b_id,
+	xb.newsgroup,
+	xb.last_id,
+	st.last_max
+FROM
+	ib.boards AS xb
+LEFT JOIN
+	ib.pusher_group_track AS st
+ON
+	st.sid = $1 AND st.bid = xb.b_id
+WHERE
+	xb.newsgroup IS NOT NULL
+ORDER BY
+	xb.newsgroup COLLATE "und-x-icu"
+
+-- :name pusher_set_group_id
+-- input: {sid} {bid} {last_max}
+INSERT INTO
+	ib.pusher_group_track AS st (sid,bid,last_max)
+VALUES
+	($1,$2,$3)
+ON CONFLICT
+	(sid,bid)
+DO
+	UPDATE SET
+		last_max = $3
+	WHERE
+		st.sid = $1 AND st.bid = $2
+
+-- :name pusher_list_articles
+-- input: {bid} {after} {limit}
+SELECT
+	xbp.b_p_id,
+	xbp.msgid,
+	COALESCE(xp.headers -> 'Path' ->> 0, '')
+FROM
+	ib.bposts AS xbp
+LEFT JOIN
+	ib.gposts AS xp
+USING
+	(g_p_id)
+WHERE
+	xbp.b_id = $1 AND xbp.b_p_id > $2
+ORDER BY
+	xbp.b_p_id ASC
+LIMIT
+	$3
+
+-- :name pusher_get_control
+-- input: {sid}
+SELECT
+	(
+		SELECT
+			MAX(ch_id)
+		FROM
+			ib.control_history
+		WHERE
+			article IS NOT NULL
+	),
+	(
+		SELECT
+			last_max
+		FROM
+			ib.pusher_control_track
+		WHERE
+			sid = $1
+	)
+
+-- :name pusher_set_control_id
+-- input: {sid} {last_max}
+INSERT INTO
+	ib.pusher_control_track AS st (sid,last_max)
+VALUES
+	($1,$2)
+ON CONFLICT
+	(sid)
+DO
+	UPDATE SET
+		last_max = $2
+	WHERE
+		st.sid = $1
+
+-- :name pusher_list_control
+-- input: {after} {limit}
+SELECT
+	ch_id,
+	msgid
+FROM
+	ib.control_history
+WHERE
+	ch_id > $1 AND article IS NOT NULL
+ORDER BY
+	ch_id ASC
+LIMIT
+	$2
+
+-- :name pusher_get_control_article
+-- input: {msgid}
+SELECT
+	article
+FROM
+	ib.control_history
+WHERE
+	msgid = $1 AND article IS NOT NULL
@@ -23,6 +23,11 @@ SELECT
 	xt.b_t_name,
 	xt.p_count,
 	xt.f_count AS xt_f_count,
+	COALESCE(
+		(xt.thread_opts ->> 'locked')::BOOLEAN,
+		(xb.thread_opts ->> 'locked')::BOOLEAN,
+		FALSE) AS t_locked,
+	COALESCE((xt.thread_opts ->> 'sticky')::BOOLEAN, FALSE) AS t_sticky,
 	xbp.b_p_id,
 	xbp.p_name,
 	xbp.activ_refs,
@@ -53,12 +58,15 @@ LEFT JOIN
 			zt.b_t_name,
 			zt.bump,
 			zt.p_count,
-			zt.f_count
+			zt.f_count,
+			zt.thread_opts
 		FROM
 			ib.threads AS zt
 		WHERE
 			zt.b_id = xb.b_id
 		ORDER BY
+			-- sticky threads go first
+			COALESCE((zt.thread_opts ->> 'sticky')::BOOLEAN, FALSE) DESC,
 			zt.bump DESC,
 			zt.b_t_id ASC
 		LIMIT
@@ -326,6 +334,11 @@ SELECT
 	xt.p_count,
 	xt.f_count AS xt_f_count,
 	xt.bump,
+	COALESCE(
+		(xt.thread_opts ->> 'locked')::BOOLEAN,
+		(xb.thread_opts ->> 'locked')::BOOLEAN,
+		FALSE) AS t_locked,
+	COALESCE((xt.thread_opts ->> 'sticky')::BOOLEAN, FALSE) AS t_sticky,
 	xbp.b_p_id,
 	xp.date_sent,
 	xp.f_count AS xp_f_count,
@@ -349,6 +362,7 @@ LEFT JOIN LATERAL
 		WHERE
 			xb.b_id = zt.b_id
 		ORDER BY
+			COALESCE((zt.thread_opts ->> 'sticky')::BOOLEAN, FALSE) DESC,
 			zt.bump DESC,
 			zt.b_t_id ASC
 	) AS xt
@@ -384,6 +398,88 @@ ON
 WHERE
 	xb.b_name = $1
 
+-- :name web_thread_archive
+-- input: {b_name} {page num} {threads per page}
+SELECT
+	xb.b_id,
+	xb.bdesc,
+	xb.attrib,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			ib.threads AS zt
+		WHERE
+			zt.b_id = xb.b_id AND zt.t_arch_order IS NOT NULL
+	) AS a_count,
+	xt.b_t_id,
+	xt.b_t_name,
+	xt.p_count,
+	xt.f_count AS xt_f_count,
+	xt.bump,
+	xt.t_arch_order,
+	xbp.b_p_id,
+	xp.date_sent,
+	xp.f_count AS xp_f_count,
+	xp.author,
+	xp.trip,
+	xp.title,
+	xp.message,
+	xf.f_id,
+	xf.fname,
+	xf.ftype,
+	xf.thumb,
+	xf.thumbcfg
+FROM
+	ib.boards xb
+LEFT JOIN LATERAL
+	(
+		SELECT
+			*
+		FROM
+			ib.threads zt
+		WHERE
+			xb.b_id = zt.b_id AND zt.t_arch_order IS NOT NULL
+		ORDER BY
+			zt.t_arch_order DESC,
+			zt.b_t_id ASC
+		LIMIT
+			$3
+		OFFSET
+			$2 * $3
+	) AS xt
+ON
+	TRUE
+LEFT JOIN
+	ib.bposts xbp
+ON
+	xt.b_id = xbp.b_id AND xt.b_t_id = xbp.b_p_id
+LEFT JOIN
+	ib.gposts xp
+ON
+	xbp.g_p_id = xp.g_p_id
+LEFT JOIN
+	LATERAL (
+		SELECT
+			zf.f_id,
+			zf.fname,
+			zf.ftype,
+			zf.thumb,
+			zf.thumbcfg
+		FROM
+			ib.files AS zf
+		WHERE
+			xp.g_p_id = zf.g_p_id AND zf.ftype != 'msg'
+		ORDER BY
+			zf.f_id
+		LIMIT
+			1
+	) AS xf
+ON
+	TRUE
+WHERE
+	xb.b_name = $1
+
 -- :name web_overboard_catalog
 -- input: {thread_count}
 SELECT
@@ -471,6 +567,12 @@ SELECT
 	xt.b_t_name,
 	xt.p_count,
 	xt.f_count AS xt_f_count,
+	xt.t_arch_order,
+	COALESCE(
+		(xt.thread_opts ->> 'locked')::BOOLEAN,
+		(xb.thread_opts ->> 'locked')::BOOLEAN,
+		FALSE) AS t_locked,
+	COALESCE((xt.thread_opts ->> 'sticky')::BOOLEAN, FALSE) AS t_sticky,
 	xto.t_pos,
 	xbp.b_p_id,
 	xbp.p_name,
@@ -518,6 +620,7 @@ LEFT JOIN
 					b_t_id,
 					row_number() OVER (
 						ORDER BY
+							COALESCE((thread_opts ->> 'sticky')::BOOLEAN, FALSE) DESC,
 							bump DESC,
 							b_t_id ASC
 					) AS t_pos
@@ -568,11 +671,63 @@ WHERE
 	xb.b_name=$1
 
 
+-- :name web_modlog
+-- input: {board name or NULL} {mod pubkey or NULL} {offset} {limit}
+SELECT
+	ml_id,
+	date_exec,
+	mod_pubkey,
+	msgid,
+	b_name,
+	cmd,
+	targets,
+	applied,
+	reason
+FROM
+	ib.modlog
+WHERE
+	($1::TEXT IS NULL OR b_name = $1) AND
+		($2::TEXT IS NULL OR mod_pubkey = $2)
+ORDER BY
+	ml_id DESC
+OFFSET
+	$3
+LIMIT
+	$4
+
+-- :name web_modqueue
+-- input: {board name or NULL} {offset} {limit}
+SELECT
+	mq.msgid,
+	xb.b_name,
+	xt.b_t_name,
+	mq.date_recv,
+	mq.pinfo
+FROM
+	ib.modqueue AS mq
+JOIN
+	ib.boards AS xb
+USING
+	(b_id)
+LEFT JOIN
+	ib.threads AS xt
+USING
+	(b_id,b_t_id)
+WHERE
+	$1::TEXT IS NULL OR xb.b_name = $1
+ORDER BY
+	mq.mq_id
+OFFSET
+	$2
+LIMIT
+	$3
+
 -- :name web_prepost_newthread
 SELECT
 	b_id,
 	post_limits,
-	newthread_limits
+	newthread_limits,
+	moderated
 FROM
 	ib.boards
 WHERE
@@ -586,13 +741,21 @@ SELECT
 	xtp.b_t_id,
 	xtp.reply_limits,
 	xtp.msgid,
-	xtp.date_sent
+	xtp.date_sent,
+	xtp.archived,
+	COALESCE(
+		(xtp.thread_opts ->> 'locked')::BOOLEAN,
+		(xb.thread_opts ->> 'locked')::BOOLEAN,
+		FALSE) AS locked,
+	xb.moderated
 FROM
 	(
 		SELECT
 			b_id,
 			post_limits,
-			reply_limits
+			reply_limits,
+			thread_opts,
+			moderated
 		FROM
 			ib.boards
 		WHERE
@@ -604,8 +767,10 @@ LEFT JOIN LATERAL
 			xt.b_id,
 			xt.b_t_id,
 			xt.reply_limits,
+			xt.thread_opts,
 			xp.msgid,
-			xp.date_sent
+			xp.date_sent,
+			xt.t_arch_order IS NOT NULL AS archived
 		FROM
 			ib.threads xt
 		JOIN
@@ -623,3 +788,38 @@ LEFT JOIN LATERAL
 	) AS xtp
 ON
 	TRUE
+
+
+-- :name web_post_msgid_by_pname
+-- input: {board name, post name}
+-- output: {msgid} {newsgroup or NULL}
+SELECT
+	xbp.msgid,
+	xb.newsgroup
+FROM
+	ib.bposts AS xbp
+JOIN
+	ib.boards AS xb
+ON
+	xbp.b_id = xb.b_id
+WHERE
+	xb.b_name = $1 AND xbp.p_name = $2
+
+
+-- :name web_cancel_secret
+-- input: {new secret, used if there's none yet}
+-- output: {secret}
+WITH
+	ins AS (
+		INSERT INTO
+			ib.secrets (s_name, s_data)
+		VALUES
+			('cancel', $1)
+		ON CONFLICT
+			DO NOTHING
+		RETURNING
+			s_data
+	)
+SELECT s_data FROM ins
+UNION ALL
+SELECT s_data FROM ib.secrets WHERE s_name = 'cancel'
@@ -6,6 +6,8 @@ type IBProvider interface {
 	IBGetThreadListPage(*IBThreadListPage, string, uint32) (error, int)
 	IBGetOverboardPage(*IBOverboardPage, uint32) (error, int)
 	IBGetThreadCatalog(*IBThreadCatalog, string) (error, int)
+	IBGetThreadArchive(*IBThreadArchive, string, uint32) (error, int)
 	IBGetOverboardCatalog(*IBOverboardCatalog) (error, int)
 	IBGetThread(*IBThreadPage, string, string) (error, int)
+	IBGetModLogPage(*IBModLogPage, string, string, uint32) (error, int)
 }
@@ -102,6 +102,8 @@ type IBCommonThread struct {
 	OP      IBPostInfo             `json:"op"`                // OP
 	Replies []IBPostInfo           `json:"replies,omitempty"` // replies
 	Options map[string]interface{} `json:"opts,omitempty"`    // additional stuff
+	Locked  bool                   `json:"locked,omitempty"`  // replies are not accepted
+	Sticky  bool                   `json:"sticky,omitempty"`  // kept on top of board
 }
 
 // thread in thread list page
@@ -152,6 +154,7 @@ type IBThreadPage struct {
 	Board       IBBoardInfo   `json:"board"`              // info about this board
 	HasBackRefs bool          `json:"hasbrefs,omitempty"` // whether backreferences are already calculated
 	ThreadStats IBThreadStats `json:"stats"`
+	Archived    bool          `json:"archived,omitempty"` // archived threads don't accept replies
 
 	IBCommonThread
 }
@@ -165,6 +168,8 @@ type IBThreadCatalogThread struct {
 	BumpDate     int64       `json:"bdate"`    // bump date
 	Subject      string      `json:"subject"`  // subject
 	Message      IBMessage   `json:"msg"`      // message
+	Locked       bool        `json:"locked,omitempty"`
+	Sticky       bool        `json:"sticky,omitempty"`
 }
 
 type IBThreadCatalog struct {
@@ -172,6 +177,39 @@ type IBThreadCatalog struct {
 	Threads []IBThreadCatalogThread `json:"threads,omitempty"` // threads
 }
 
+type IBThreadArchiveThread struct {
+	Order uint64 `json:"order"` // order within archive, later archived ones have bigger
+
+	IBThreadCatalogThread
+}
+
+type IBThreadArchive struct {
+	Board     IBBoardInfo             `json:"board"`             // info about this board
+	Number    uint32                  `json:"pnum"`              // this page num (starting from 0)
+	Available uint32                  `json:"pavail"`            // num of pages
+	Threads   []IBThreadArchiveThread `json:"threads,omitempty"` // threads, latest archived first
+}
+
+type IBModLogEntry struct {
+	ID      uint64   `json:"id"`                // sequential number of entry
+	Date    int64    `json:"date"`              // seconds since unix epoch
+	Mod     string   `json:"mod,omitempty"`     // mod pubkey, empty if local admin
+	MsgID   string   `json:"msgid,omitempty"`   // msgid of control message, if any
+	Board   string   `json:"board,omitempty"`   // board command was scoped to, if any
+	Command string   `json:"cmd"`               // command name
+	Targets []string `json:"targets,omitempty"` // what command acted upon
+	Applied bool     `json:"applied"`           // false if rejected
+	Reason  string   `json:"reason,omitempty"`  // why rejected, or reason given by mod
+}
+
+type IBModLogPage struct {
+	Number  uint32          `json:"pnum"`              // this page num (starting from 0)
+	HasNext bool            `json:"hasnext,omitempty"` // whether there are older entries
+	Board   string          `json:"board,omitempty"`   // board filter, if any
+	Mod     string          `json:"mod,omitempty"`     // mod filter, if any
+	Entries []IBModLogEntry `json:"entries,omitempty"` // entries, latest first
+}
+
 type IBOverboardCatalogThread struct {
 	BNum      uint32 `json:"bn"` // internal board num
 	BoardName string `json:"bname"`
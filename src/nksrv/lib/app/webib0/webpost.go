@@ -12,6 +12,9 @@ type IBPostedInfo struct {
 	ThreadID  string           `json:"thread_id"`
 	PostID    string           `json:"post_id"`
 	MessageID mm.TCoreMsgIDStr `json:"msgid"` // XXX will we actually use this for anything??
+
+	CancelKey string `json:"cancel_key,omitempty"` // allows poster to delete post
+	Queued    bool   `json:"queued,omitempty"`     // post awaits approval of mod
 }
 
 var IBWebFormFileFields = []string{
@@ -26,6 +29,8 @@ const (
 	IBWebFormTextName       = "name"
 	IBWebFormTextMessage    = "message"
 	IBWebFormTextOptions    = "options"
+	IBWebFormTextPassword   = "password"
+	IBWebFormTextCancelKey  = "cancel_key"
 	IBWebFormTextCaptchaKey = "captcha_key"
 	IBWebFormTextCaptchaAns = "captcha_ans"
 )
@@ -37,9 +42,28 @@ type IBNewBoardInfo struct {
 	ThreadsPerPage int32  `json:"threads_per_page,omitempty"` // <= 0 - infinite
 	MaxActivePages int32  `json:"max_active_pages,omitempty"` // <= 0 - all pages are active
 	MaxPages       int32  `json:"max_pages,omitempty"`        // <= 0 - unlimited
+	Moderated      bool   `json:"moderated,omitempty"`        // posts need approval of mod
 	// TODO more fields
 }
 
+// IBModQueueEntry is post awaiting approval of mod.
+type IBModQueueEntry struct {
+	MsgID   string `json:"msgid"`             // Message-ID of post
+	Board   string `json:"board"`             // board post is for
+	Thread  string `json:"thread,omitempty"`  // thread post replies to, empty if new thread
+	Date    int64  `json:"date"`              // when we received it, seconds since unix epoch
+	Subject string `json:"subject,omitempty"` // subject text
+	Name    string `json:"name,omitempty"`    // name of poster
+	Trip    string `json:"trip,omitempty"`    // tripcode
+	Message string `json:"msg,omitempty"`     // message, unformatted
+	Files   int    `json:"files,omitempty"`   // count of attached files
+}
+
+type IBModQueue struct {
+	Board   string            `json:"board,omitempty"`   // board filter, if any
+	Entries []IBModQueueEntry `json:"entries,omitempty"` // entries, oldest first
+}
+
 type WebPostError struct {
 	Err  error
 	Code int
@@ -87,4 +111,27 @@ type IBWebPostProvider interface {
 	IBDeletePost(
 		w http.ResponseWriter, r *http.Request, board, post string) (
 		err error)
+
+	// IBGetModQueue lists posts awaiting approval.
+	// If board is empty, all boards are listed.
+	IBGetModQueue(
+		w http.ResponseWriter, r *http.Request, q *IBModQueue, board string) (
+		err error)
+
+	// IBApproveQueuedPost inserts queued post as if it didn't need approval.
+	IBApproveQueuedPost(
+		w http.ResponseWriter, r *http.Request, board, msgid string) (
+		err error)
+
+	// IBRejectQueuedPost drops queued post.
+	IBRejectQueuedPost(
+		w http.ResponseWriter, r *http.Request, board, msgid string) (
+		err error)
+
+	// IBCancelPost deletes post on behalf of its author.
+	// Either password used when posting
+	// or Cancel-Key given when posting (ckey) is specified.
+	IBCancelPost(
+		w http.ResponseWriter, r *http.Request,
+		board, post, password, ckey string) (err error)
 }
@@ -32,28 +32,78 @@ func ParseUserPriv(s string, up UserPriv) (_ UserPriv, ok bool) {
 	return up, true
 }
 
+// GroupAccess limits which newsgroups can be read or posted to.
+// nil wildmat means no limit.
+// Groups which can't be read are hidden as if they didn't exist.
+type GroupAccess struct {
+	ReadGroups Wildmat
+	PostGroups Wildmat
+}
+
+func (a GroupAccess) CanReadGroup(group string) bool {
+	return a.ReadGroups == nil || a.ReadGroups.CheckString(group)
+}
+
+func (a GroupAccess) CanPostGroup(group string) bool {
+	return a.PostGroups == nil || a.PostGroups.CheckString(group)
+}
+
+// Restricted tells whether any limits are set.
+func (a GroupAccess) Restricted() bool {
+	return a.ReadGroups != nil || a.PostGroups != nil
+}
+
 type UserInfo struct {
 	Name string
 	Serv string
 
 	UserPriv
+	GroupAccess // replaces server's defaults once logged in
 }
 
 func (c *ConnState) setupDefaults(rCfg *NNTPServerRunCfg) {
 	c.UserPriv = rCfg.DefaultPriv
+	c.GroupAccess = rCfg.DefaultGroupAccess
+	c.setRates(rCfg.Rates)
+}
+
+// peerLogin switches limits if ui is peer.
+// It returns false if peer has too many connections already.
+func (c *ConnState) peerLogin(rCfg *NNTPServerRunCfg, ui *UserInfo) bool {
+	if ui.Serv == "" {
+		return true
+	}
+	if !c.srv.switchToPeer(c.conn, ui.Serv, rCfg.Limits.MaxConnsPerPeer) {
+		c.log.LogPrintf(WARN,
+			"peer %q has too many connections, refusing login", ui.Serv)
+		return false
+	}
+	c.setRates(rCfg.PeerRates)
+	c.peerName = ui.Serv
+	return true
+}
+
+func (c *ConnState) setUserGroupAccess(ui *UserInfo) {
+	if ui.ReadGroups != nil {
+		// selected group may be no longer visible
+		c.CurrentGroup = nil
+	}
+	c.GroupAccess = ui.GroupAccess
 }
 
 func (c *ConnState) postTLS(rCfg *NNTPServerRunCfg, tlsConn *tls.Conn) {
 	c.tlsConn = tlsConn
 	c.UserPriv = MergeUserPriv(c.UserPriv, rCfg.TLSPriv) // TLS niceties, if any
 
-	if rCfg.CertFPAutoAuth && !c.authenticated {
+	if rCfg.CertFPAutoAuth && rCfg.CertFPProvider != nil && !c.authenticated {
 		cs := tlsConn.ConnectionState()
 		if len(cs.PeerCertificates) != 0 {
 			ui := rCfg.CertFPProvider.NNTPUserByFingerprint(cs.PeerCertificates[0])
-			if ui != nil {
+			if ui != nil && c.peerLogin(rCfg, ui) {
 				c.authenticated = true
+				c.userName = ui.Name
 				c.UserPriv = MergeUserPriv(c.UserPriv, ui.UserPriv)
+				c.setUserGroupAccess(ui)
 				c.log.LogPrintf(NOTICE,
 					"authenticated using CertFP as name=%q serv=%q", ui.Name, ui.Serv)
 			}
@@ -61,6 +111,18 @@ func (c *ConnState) postTLS(rCfg *NNTPServerRunCfg, tlsConn *tls.Conn) {
 	}
 }
 
+// postProxy applies information received from proxy.
+func (c *ConnState) postProxy(rCfg *NNTPServerRunCfg, pi *ProxyInfo) {
+	c.proxy = pi
+	if c.proxyTLS() {
+		// proxy terminated TLS for us.
+		// we don't get client's certificate, so no CertFP
+		c.UserPriv = MergeUserPriv(c.UserPriv, rCfg.TLSPriv)
+	}
+	c.log.LogPrintf(INFO,
+		"proxied by %s, TLS: %v", pi.ProxyAddr, c.proxyTLS())
+}
+
 func (c *ConnState) advertisePlaintextAuth(rCfg *NNTPServerRunCfg) bool {
 	return rCfg.UserPassProvider != nil &&
 		!c.authenticated &&
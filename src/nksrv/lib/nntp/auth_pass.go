@@ -1,5 +1,7 @@
 package nntp
 
+import "nksrv/lib/utils/scram"
+
 type ClientPassword string
 
 type UserPassProvider interface {
@@ -12,6 +14,13 @@ type UserPassProvider interface {
 	NNTPCheckUserPass(username string, rpass ClientPassword) *UserInfo
 }
 
+// optionally implemented by UserPassProvider, enables SCRAM-SHA-256
+type SCRAMProvider interface {
+	// given username, returns info and SCRAM-SHA-256 credentials
+	// returns nil info if user doesn't exist or can't use SCRAM
+	NNTPUserSCRAMByName(name string) (*UserInfo, *scram.Credentials)
+}
+
 type ActiveLogin struct {
 	ui *UserInfo
 	ch string
@@ -0,0 +1,284 @@
+package nntp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+
+	. "nksrv/lib/utils/logx"
+	"nksrv/lib/utils/scram"
+	"nksrv/lib/utils/text/bufreader"
+)
+
+type saslMech struct {
+	name string
+	// returns 0 if mechanism can be used, otherwise response code
+	check func(c *ConnState, rCfg *NNTPServerRunCfg) int
+	// ir is nil if client didn't supply initial response
+	exchange func(c *ConnState, rCfg *NNTPServerRunCfg, ir []byte)
+}
+
+// ordered by preference
+var saslMechs = [...]saslMech{
+	{
+		name:     "EXTERNAL",
+		check:    saslCheckExternal,
+		exchange: saslExternal,
+	},
+	{
+		name:     scram.MechSHA256,
+		check:    saslCheckSCRAM,
+		exchange: saslSCRAM,
+	},
+	{
+		name:     "PLAIN",
+		check:    saslCheckPlain,
+		exchange: saslPlain,
+	},
+}
+
+func findSASLMech(name string) *saslMech {
+	for i := range saslMechs {
+		if saslMechs[i].name == name {
+			return &saslMechs[i]
+		}
+	}
+	return nil
+}
+
+// list of mechanisms usable right now, for CAPABILITIES
+func (c *ConnState) saslMechanisms(rCfg *NNTPServerRunCfg) (l []string) {
+	if c.authenticated {
+		return
+	}
+	for i := range saslMechs {
+		if saslMechs[i].check(c, rCfg) == 0 {
+			l = append(l, saslMechs[i].name)
+		}
+	}
+	return
+}
+
+func encodeSASLData(b []byte) string {
+	if len(b) == 0 {
+		return "="
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func decodeSASLData(b []byte) ([]byte, error) {
+	if len(b) == 1 && b[0] == '=' {
+		return []byte{}, nil
+	}
+	d := make([]byte, base64.StdEncoding.DecodedLen(len(b)))
+	n, err := base64.StdEncoding.Decode(d, b)
+	return d[:n], err
+}
+
+// sends challenge and reads client's response.
+// if it returns !ok then client was already responded to.
+func (c *ConnState) saslChallenge(ch []byte) (resp []byte, ok bool) {
+	AbortOnErr(c.w.PrintfLine("383 %s", encodeSASLData(ch)))
+
+	i, e := c.r.ReadUntil(c.inbuf[:], '\n')
+	if e != nil {
+		if e != bufreader.ErrDelimNotFound {
+			if e != io.EOF {
+				c.log.LogPrintf(WARN, "error reading SASL response: %v", e)
+			}
+			c.w.Abort()
+		}
+		for e == bufreader.ErrDelimNotFound {
+			_, e = c.r.ReadUntil(c.inbuf[:], '\n')
+		}
+		if e != nil {
+			c.w.Abort()
+		}
+		AbortOnErr(c.w.PrintfLine("504 SASL response too long"))
+		return
+	}
+
+	var line []byte
+	if i > 1 && c.inbuf[i-2] == '\r' {
+		line = c.inbuf[:i-2]
+	} else {
+		line = c.inbuf[:i-1]
+	}
+
+	if len(line) == 1 && line[0] == '*' {
+		AbortOnErr(c.w.PrintfLine("481 authentication cancelled"))
+		return
+	}
+
+	resp, e = decodeSASLData(line)
+	if e != nil {
+		AbortOnErr(c.w.PrintfLine("504 base64 encoding error"))
+		return
+	}
+	return resp, true
+}
+
+func (c *ConnState) peerCertificate() *tls.ConnectionState {
+	if c.tlsConn == nil {
+		return nil
+	}
+	cs := c.tlsConn.ConnectionState()
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	return &cs
+}
+
+func saslCheckExternal(c *ConnState, rCfg *NNTPServerRunCfg) int {
+	if rCfg.CertFPProvider == nil {
+		return 503
+	}
+	if c.peerCertificate() == nil {
+		// if TLS is not started, client could start it and retry
+		if !c.tlsStarted() {
+			return 483
+		}
+		return 503
+	}
+	return 0
+}
+
+func saslExternal(c *ConnState, rCfg *NNTPServerRunCfg, ir []byte) {
+	if ir == nil {
+		var ok bool
+		if ir, ok = c.saslChallenge(nil); !ok {
+			return
+		}
+	}
+
+	cert := c.peerCertificate().PeerCertificates[0]
+
+	var ui *UserInfo
+	if len(ir) == 0 {
+		ui = rCfg.CertFPProvider.NNTPUserByFingerprint(cert)
+	} else {
+		ui = rCfg.CertFPProvider.NNTPUserByAnchor(cert, string(ir))
+	}
+	if ui == nil {
+		AbortOnErr(c.w.PrintfLine("481 authentication failed"))
+		return
+	}
+
+	if c.loginSuccess(ui) {
+		AbortOnErr(c.w.PrintfLine("281 authentication accepted"))
+	}
+}
+
+func saslCheckPlain(c *ConnState, rCfg *NNTPServerRunCfg) int {
+	if rCfg.UserPassProvider == nil {
+		return 503
+	}
+	if !rCfg.UnsafePass && !c.tlsStarted() {
+		return 483
+	}
+	return 0
+}
+
+func saslPlain(c *ConnState, rCfg *NNTPServerRunCfg, ir []byte) {
+	if ir == nil {
+		var ok bool
+		if ir, ok = c.saslChallenge(nil); !ok {
+			return
+		}
+	}
+
+	// authzid NUL authcid NUL passwd
+	f := bytes.Split(ir, []byte{0})
+	if len(f) != 3 || len(f[1]) == 0 {
+		AbortOnErr(c.w.PrintfLine("482 SASL protocol error"))
+		return
+	}
+	// we don't support acting as someone else
+	if len(f[0]) != 0 && !bytes.Equal(f[0], f[1]) {
+		AbortOnErr(c.w.PrintfLine("481 authentication failed"))
+		return
+	}
+
+	ui := rCfg.UserPassProvider.NNTPCheckUserPass(
+		string(f[1]), ClientPassword(f[2]))
+	if ui == nil {
+		AbortOnErr(c.w.PrintfLine("481 authentication failed"))
+		return
+	}
+
+	if c.loginSuccess(ui) {
+		AbortOnErr(c.w.PrintfLine("281 authentication accepted"))
+	}
+}
+
+func saslCheckSCRAM(c *ConnState, rCfg *NNTPServerRunCfg) int {
+	// password doesn't cross the wire so TLS isn't required
+	if _, ok := rCfg.UserPassProvider.(SCRAMProvider); !ok {
+		return 503
+	}
+	return 0
+}
+
+func saslSCRAM(c *ConnState, rCfg *NNTPServerRunCfg, ir []byte) {
+	if ir == nil {
+		var ok bool
+		if ir, ok = c.saslChallenge(nil); !ok {
+			return
+		}
+	}
+
+	var conv scram.ServerConv
+	if err := conv.ReadClientFirst(string(ir)); err != nil {
+		c.log.LogPrintf(DEBUG, "SCRAM client-first error: %v", err)
+		AbortOnErr(c.w.PrintfLine("482 SASL protocol error"))
+		return
+	}
+	if conv.AuthzID != "" && conv.AuthzID != conv.User {
+		AbortOnErr(c.w.PrintfLine("481 authentication failed"))
+		return
+	}
+
+	sp := rCfg.UserPassProvider.(SCRAMProvider)
+	ui, creds := sp.NNTPUserSCRAMByName(conv.User)
+	if ui == nil {
+		if rCfg.UnsafeEarlyUserReject {
+			AbortOnErr(c.w.PrintfLine("481 authentication failed"))
+			return
+		}
+		// proceed with fake credentials to prevent user enumeration
+		fc, err := scram.FakeCredentials()
+		if err != nil {
+			AbortOnErr(c.w.ResInternalError(err))
+			return
+		}
+		creds = &fc
+	}
+
+	snonce, err := scram.NewNonce()
+	if err != nil {
+		AbortOnErr(c.w.ResInternalError(err))
+		return
+	}
+	sf, err := conv.ServerFirst(*creds, snonce)
+	if err != nil {
+		AbortOnErr(c.w.ResInternalError(err))
+		return
+	}
+
+	resp, ok := c.saslChallenge([]byte(sf))
+	if !ok {
+		return
+	}
+
+	fin, err := conv.ReadClientFinal(string(resp))
+	if err != nil || ui == nil {
+		c.log.LogPrintf(DEBUG, "SCRAM client-final error: %v", err)
+		AbortOnErr(c.w.PrintfLine("481 authentication failed"))
+		return
+	}
+
+	if c.loginSuccess(ui) {
+		AbortOnErr(c.w.PrintfLine("283 %s", encodeSASLData([]byte(fin))))
+	}
+}
@@ -0,0 +1,193 @@
+package nntp_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"nksrv/lib/nntp"
+	"nksrv/lib/nntp/nntpcertfpmap"
+	"nksrv/lib/nntp/nntpuserpassmap"
+)
+
+const (
+	saslTestUser = "alice"
+	saslTestPass = "hunter2"
+)
+
+func startSASLTestSrv(t *testing.T) (addr string, stop func()) {
+	lgr := newTestLogger(t)
+	tcert, _ := makeTestCert(t)
+
+	upm := nntpuserpassmap.NewUserPassMap()
+	err := upm.Add(nntp.UserInfo{Name: saslTestUser}, saslTestPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfm := certfpmap.NewCertFPMap()
+
+	rcfg := nntp.DefaultNNTPServerRunCfg
+	rcfg.TLSConfig = &tls.Config{Certificates: []tls.Certificate{tcert}}
+	rcfg.UserPassProvider = upm
+	rcfg.CertFPProvider = &cfm
+	rcfg.UnsafePass = true
+	return startTestSrvCfg(t, lgr, nil, &rcfg)
+}
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func unb64(t *testing.T, s string) string {
+	t.Helper()
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad base64 %q: %v", s, err)
+	}
+	return string(b)
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// scramClientFinal makes SCRAM-SHA-256 client-final-message
+// and returns it along with server-final-message server should answer with.
+func scramClientFinal(
+	t *testing.T, pass, clientFirstBare, serverFirst string) (
+	final, serverFinal string) {
+
+	t.Helper()
+	var nonce, salt string
+	iters := 0
+	for _, f := range strings.Split(serverFirst, ",") {
+		switch {
+		case strings.HasPrefix(f, "r="):
+			nonce = f[2:]
+		case strings.HasPrefix(f, "s="):
+			salt = unb64(t, f[2:])
+		case strings.HasPrefix(f, "i="):
+			iters, _ = strconv.Atoi(f[2:])
+		}
+	}
+	if nonce == "" || salt == "" || iters <= 0 {
+		t.Fatalf("bad server-first %q", serverFirst)
+	}
+
+	withoutProof := "c=" + b64("n,,") + ",r=" + nonce
+	authMsg := clientFirstBare + "," + serverFirst + "," + withoutProof
+
+	salted := pbkdf2.Key([]byte(pass), []byte(salt), iters, sha256.Size, sha256.New)
+	ck := hmacSHA256(salted, "Client Key")
+	sk := sha256.Sum256(ck)
+	proof := hmacSHA256(sk[:], authMsg)
+	for i := range proof {
+		proof[i] ^= ck[i]
+	}
+	ssig := hmacSHA256(hmacSHA256(salted, "Server Key"), authMsg)
+
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof),
+		"v=" + base64.StdEncoding.EncodeToString(ssig)
+}
+
+func TestServerSASL(t *testing.T) {
+	addr, stop := startSASLTestSrv(t)
+	defer stop()
+
+	plain := b64("\x00" + saslTestUser + "\x00" + saslTestPass)
+
+	t.Run("plain initial response", func(t *testing.T) {
+		c := dialRaw(t, addr)
+		defer c.conn.Close()
+		c.expect("AUTHINFO SASL PLAIN "+b64("\x00"+saslTestUser+"\x00nope"), 481)
+		c.expect("AUTHINFO SASL PLAIN "+plain, 281)
+
+		// no second login, by any means
+		c.expect("AUTHINFO SASL PLAIN "+plain, 502)
+		c.expect("AUTHINFO SASL PLAIN", 502)
+		c.expect("AUTHINFO USER "+saslTestUser, 502)
+		c.expect("AUTHINFO PASS "+saslTestPass, 502)
+		caps := c.expectLines("CAPABILITIES", 101)
+		if hasCap(caps, "SASL") {
+			t.Errorf("SASL offered after login: %q", caps)
+		}
+	})
+
+	t.Run("plain challenge", func(t *testing.T) {
+		c := dialRaw(t, addr)
+		defer c.conn.Close()
+		if ch := c.expect("AUTHINFO SASL PLAIN", 383); ch != "=" {
+			t.Errorf("PLAIN challenge %q, want empty", ch)
+		}
+		c.expect(plain, 281)
+	})
+
+	t.Run("scram", func(t *testing.T) {
+		for _, pass := range []string{"wrong", saslTestPass} {
+			c := dialRaw(t, addr)
+			cfb := "n=" + saslTestUser + ",r=fyko+d2lbbFgONRv9qkxdawL"
+			sf := unb64(t, c.expect(
+				"AUTHINFO SASL SCRAM-SHA-256 "+b64("n,,"+cfb), 383))
+			if !strings.HasPrefix(sf, "r=fyko+d2lbbFgONRv9qkxdawL") {
+				t.Errorf("server-first %q doesn't extend our nonce", sf)
+			}
+			cf, wantsf := scramClientFinal(t, pass, cfb, sf)
+			if pass != saslTestPass {
+				c.expect(b64(cf), 481)
+				c.conn.Close()
+				continue
+			}
+			if got := unb64(t, c.expect(b64(cf), 283)); got != wantsf {
+				t.Errorf("server-final %q, want %q", got, wantsf)
+			}
+			c.expect("AUTHINFO SASL PLAIN "+plain, 502)
+			c.conn.Close()
+		}
+	})
+
+	t.Run("external without cert", func(t *testing.T) {
+		c := dialRaw(t, addr)
+		defer c.conn.Close()
+		// could work after STARTTLS
+		c.expect("AUTHINFO SASL EXTERNAL", 483)
+		c.startTLS()
+		caps := c.expectLines("CAPABILITIES", 101)
+		for _, l := range caps {
+			if strings.HasPrefix(l, "SASL ") &&
+				strings.Contains(l, "EXTERNAL") {
+
+				t.Errorf("EXTERNAL offered without client cert: %q", l)
+			}
+		}
+		c.expect("AUTHINFO SASL EXTERNAL", 503)
+		c.expect("AUTHINFO SASL EXTERNAL =", 503)
+		c.expect("AUTHINFO SASL PLAIN "+plain, 281)
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		c := dialRaw(t, addr)
+		defer c.conn.Close()
+		c.expect("AUTHINFO SASL PLAIN", 383)
+		c.expect("*", 481)
+		c.expect("AUTHINFO SASL SCRAM-SHA-256", 383)
+		c.expect("*", 481)
+		// connection is still usable, and not logged in
+		c.expect("AUTHINFO SASL PLAIN "+plain, 281)
+	})
+
+	t.Run("unknown mechanism", func(t *testing.T) {
+		c := dialRaw(t, addr)
+		defer c.conn.Close()
+		c.expect("AUTHINFO SASL DIGEST-MD5", 503)
+		c.expect("AUTHINFO SASL PLAIN !!!", 504)
+		c.expect("AUTHINFO SASL PLAIN "+plain, 281)
+	})
+}
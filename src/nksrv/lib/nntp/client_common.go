@@ -1,9 +1,14 @@
 package nntp
 
 import (
+	"bufio"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	tp "net/textproto"
+	"time"
 
 	. "nksrv/lib/utils/logx"
 	au "nksrv/lib/utils/text/asciiutils"
@@ -12,21 +17,34 @@ import (
 
 var errTooLargeResponse = errors.New("too large response")
 
+// TLSHandshakeTimeout limits how long TLS handshake with server may take,
+// for both STARTTLS and nntps.
+const TLSHandshakeTimeout = 30 * time.Second
+
 type clientState struct {
 	initialResponseUnderstod bool
 	initialResponseAllowPost bool
 
 	badActiveList     bool
 	badNewsgroupsList bool
+	badNewNews        bool
 	badCapabilities   bool
 	badHdr            bool
 	badXHdr           bool
 	badOver           bool
 	badXOver          bool
 
-	capHdr    bool
-	capOver   bool
-	capReader bool
+	capHdr      bool
+	capOver     bool
+	capReader   bool
+	capNewNews  bool
+	capDeflate  bool
+	deflateDone bool
+	capIHave    bool
+	capStream   bool
+	capXListen  bool
+	capStartTLS bool
+	tlsDone     bool
 
 	allowLargeOver bool
 
@@ -49,18 +67,39 @@ func (s *clientState) canXOver() bool {
 	return !s.badXOver
 }
 
+// StartTLSMode tells whether client should upgrade connection with STARTTLS.
+type StartTLSMode int
+
+const (
+	StartTLSOff           StartTLSMode = iota
+	StartTLSOpportunistic              // if server offers it
+	StartTLSRequired                   // fail if server doesn't offer it
+)
+
 type NNTPClient struct {
 	inbuf [512]byte
 	args  [][]byte
 
-	w  *tp.Writer
-	r  *bufreader.BufReader
-	dr *bufreader.DotReader
+	tlsCfg   *tls.Config // used for STARTTLS
+	startTLS StartTLSMode
+
+	conn io.ReadWriter // underlying connection
+	w    *tp.Writer
+	r    *bufreader.BufReader
+	dr   *bufreader.DotReader
 
 	s   clientState
 	log Logger
 }
 
+// abortConn closes underlying connection, so that half-sent stuff
+// can't be mistaken for complete one.
+func (c *NNTPClient) abortConn() {
+	if cl, ok := c.conn.(io.Closer); ok {
+		_ = cl.Close()
+	}
+}
+
 func (c *NNTPClient) openDotReader() *bufreader.DotReader {
 	if c.dr == nil {
 		c.dr = bufreader.NewDotReader(c.r)
@@ -163,3 +202,239 @@ func (c *NNTPClient) handleInitial() error {
 	}
 	return nil
 }
+
+func (c *NNTPClient) readDotLine(dr *bufreader.DotReader) ([]byte, error) {
+	i := 0
+	for {
+		b, e := dr.ReadByte()
+		if e != nil {
+			return c.inbuf[:i], e
+		}
+		if b == '\n' {
+			return c.inbuf[:i], nil
+		}
+		if i >= len(c.inbuf) {
+			return c.inbuf[:i], errTooLargeResponse
+		}
+		c.inbuf[i] = b
+		i++
+	}
+}
+
+func (c *NNTPClient) doCapabilities() (err error, fatal bool) {
+	c.log.LogPrintf(DEBUG, "querying CAPABILITIES")
+	err = c.w.PrintfLine("CAPABILITIES")
+	if err != nil {
+		fatal = true
+		return
+	}
+	code, _, err, fatal := c.readResponse()
+	if err != nil {
+		c.log.LogPrintf(DEBUG, "readResponse() err: %v", err)
+		return
+	}
+	if code != 101 {
+		c.log.LogPrintf(DEBUG, "code: %d", code)
+		c.s.badCapabilities = true
+		return
+	}
+	c.log.LogPrintf(DEBUG, "reading CAPABILITIES")
+	dr := c.openDotReader()
+	defer func() {
+		if err != nil {
+			_, _ = dr.Discard(-1)
+		}
+	}()
+	for {
+		line, e := c.readDotLine(dr)
+		if e != nil {
+			if e == io.EOF {
+				break
+			}
+			err = fmt.Errorf("failed reading list line: %v", e)
+			return
+		}
+		c.log.LogPrintf(DEBUG, "got capability line %q", line)
+		x := parseKeyword(line)
+		capability := unsafeBytesToStr(line[:x])
+		switch capability {
+		case "HDR":
+			c.s.capHdr = true
+		case "OVER":
+			c.s.capOver = true
+		case "READER":
+			c.s.capReader = true
+		case "NEWNEWS":
+			c.s.capNewNews = true
+		case "IHAVE":
+			c.s.capIHave = true
+		case "STREAMING":
+			c.s.capStream = true
+		case "XLISTEN":
+			c.s.capXListen = true
+		case "STARTTLS":
+			c.s.capStartTLS = true
+		case "COMPRESS":
+			c.args, _ = parseResponseArguments(line[x:], -1, c.args[:0])
+			for _, a := range c.args {
+				if au.EqualFoldString(unsafeBytesToStr(a), "DEFLATE") {
+					c.s.capDeflate = true
+				}
+			}
+		case "IMPLEMENTATION":
+			c.args, _ = parseResponseArguments(line[x:], 6, c.args[:0])
+			if len(c.args) != 0 {
+				impl := unsafeBytesToStr(c.args[0])
+				if au.EqualFoldString(impl, "srndv2") {
+					c.log.LogPrintf(INFO, "detected srndv2")
+					// workarounds for some jeff' stuff
+					c.s.workaroundStupidActiveList = true
+					c.s.allowLargeOver = true
+				} else if au.EqualFoldString(impl, "CNTPD") {
+					c.log.LogPrintf(INFO, "detected CNTPD")
+					c.s.allowLargeOver = true
+				}
+			}
+		}
+	}
+	// done
+	c.log.LogPrintf(DEBUG, "done readin CAPABILITIES")
+	return
+}
+
+// SetStartTLS sets up STARTTLS for connections made after this call.
+func (c *NNTPClient) SetStartTLS(cfg *tls.Config, mode StartTLSMode) {
+	c.tlsCfg = cfg
+	c.startTLS = mode
+}
+
+// doStartTLS upgrades connection to TLS if we're set up to and server
+// offers it, and re-queries capabilities afterwards, as they may change.
+// Must be done right after CAPABILITIES, before anything else.
+func (c *NNTPClient) doStartTLS() (err error, fatal bool) {
+	if c.startTLS == StartTLSOff || c.s.tlsDone {
+		return
+	}
+	nc, isnc := c.conn.(net.Conn)
+	if _, istls := c.conn.(*tls.Conn); istls || !isnc {
+		// already TLS (nntps), or something we can't wrap
+		return
+	}
+	if !c.s.capStartTLS {
+		if c.startTLS == StartTLSRequired {
+			return errors.New("server doesn't offer STARTTLS"), true
+		}
+		return
+	}
+
+	c.log.LogPrintf(DEBUG, "starting TLS")
+	err = c.w.PrintfLine("STARTTLS")
+	if err != nil {
+		fatal = true
+		return
+	}
+	code, rest, err, fatal := c.readResponse()
+	if err != nil {
+		return
+	}
+	if code != 382 {
+		err = fmt.Errorf(
+			"bad STARTTLS response %d %q", code, au.TrimWSBytes(rest))
+		fatal = c.startTLS == StartTLSRequired
+		return
+	}
+
+	tc := tls.Client(nc, c.tlsCfg)
+	_ = nc.SetDeadline(time.Now().Add(TLSHandshakeTimeout))
+	if err = tc.Handshake(); err != nil {
+		// connection is in unknown state now
+		return fmt.Errorf("TLS handshake failed: %v", err), true
+	}
+	_ = nc.SetDeadline(time.Time{})
+	c.conn = tc
+	c.w = tp.NewWriter(bufio.NewWriter(tc))
+	c.r = bufreader.NewBufReader(tc)
+	c.dr = nil
+
+	// forget everything learnt over plaintext {RFC 4642 2.2.2}
+	c.s = clientState{tlsDone: true}
+	c.log.LogPrintf(INFO, "TLS started")
+
+	err, fatal = c.doCapabilities()
+	if err != nil && fatal {
+		err = fmt.Errorf("doCapabilities() after STARTTLS failed: %v", err)
+	}
+	return
+}
+
+// doXListen asks server to notify us about new articles in all groups.
+func (c *NNTPClient) doXListen() (err error, fatal bool) {
+	err = c.w.PrintfLine("XLISTEN *")
+	if err != nil {
+		fatal = true
+		return
+	}
+	code, _, err, fatal := c.readResponse()
+	if err != nil {
+		return
+	}
+	if code != 290 {
+		err = fmt.Errorf("bad XLISTEN response code %d", code)
+	}
+	return
+}
+
+// doXWait waits till server tells about new articles.
+// woke is false if wait timed out.
+func (c *NNTPClient) doXWait(timeout time.Duration) (woke bool, err error) {
+	err = c.w.PrintfLine("XWAIT %d", timeout.Milliseconds())
+	if err != nil {
+		return
+	}
+	code, _, err, _ := c.readResponse()
+	if err != nil {
+		return
+	}
+	switch code {
+	case 291:
+		woke = true
+	case 490:
+		// timeout
+	default:
+		err = fmt.Errorf("bad XWAIT response code %d", code)
+	}
+	return
+}
+
+// doCompress negotiates COMPRESS DEFLATE if server advertised it
+func (c *NNTPClient) doCompress() (err error, fatal bool) {
+	if !c.s.capDeflate || c.s.deflateDone {
+		return
+	}
+	err = c.w.PrintfLine("COMPRESS DEFLATE")
+	if err != nil {
+		fatal = true
+		return
+	}
+	code, rest, err, fatal := c.readResponse()
+	if err != nil {
+		return
+	}
+	if code != 206 {
+		// it's not fatal, we can continue without it
+		c.s.capDeflate = false
+		err = fmt.Errorf(
+			"bad COMPRESS response %d %q", code, au.TrimWSBytes(rest))
+		return
+	}
+	if len(c.r.Buffered()) != 0 {
+		// server sent something it shouldn't have, stream is screwed
+		err = errors.New("unexpected data after COMPRESS response")
+		fatal = true
+		return
+	}
+	startDeflate(c.r, c.w.W, c.conn)
+	c.s.deflateDone = true
+	c.log.LogPrintf(DEBUG, "compression activated")
+	return
+}
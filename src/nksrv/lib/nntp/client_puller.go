@@ -8,6 +8,7 @@ import (
 	"math"
 	"net"
 	tp "net/textproto"
+	"strconv"
 	"sync"
 	"time"
 
@@ -26,6 +27,9 @@ type PullerDatabase interface {
 	// MAY make new group, may return id==0 if no info about group before this
 	// if id<0 then no such group currently exists
 	GetGroupID(group []byte) (id int64, err error)
+	// with multiple pull workers, UpdateGroupID, IsArticleWanted,
+	// DoesReferenceExist, RejectArticle and ReadArticle are called
+	// concurrently, though never for the same group at once
 	UpdateGroupID(group string, id uint64) error
 
 	// to keep list of received newsgroups
@@ -40,6 +44,12 @@ type PullerDatabase interface {
 	IsArticleWanted(
 		msgid TFullMsgIDStr, ingroup string) (bool, interface{}, error)
 	DoesReferenceExist(ref TFullMsgIDStr) (bool, error)
+	// remembers that pull filter rejected article,
+	// IsArticleWanted shouldn't want it anymore
+	RejectArticle(msgid TFullMsgIDStr, reason string) error
+	// tells which pull rules are in use, empty if none.
+	// articles rejected under different rules are forgotten
+	SetPullRules(rules string) error
 
 	ReadArticle(
 		r io.Reader,
@@ -48,9 +58,10 @@ type PullerDatabase interface {
 }
 
 type todoArticle struct {
-	id    uint64
-	msgid TFullMsgIDStr
-	ref   TFullMsgIDStr
+	id     uint64
+	msgid  TFullMsgIDStr
+	ref    TFullMsgIDStr
+	reject string // why filter rejected it, if it did
 }
 
 // for HDR/XHDR/OVER/XOVER
@@ -63,14 +74,46 @@ type NNTPPuller struct {
 
 	db       PullerDatabase
 	todoList []todoArticle
+
+	logx    LoggerX
+	workers int // max connections to use, 1 if not set
+
+	d                Dialer
+	network, address string
+
+	inflight *pullInflight // shared between workers, nil if single
+	filter   PullFilter    // may be nil
+
+	// XLISTEN connection, if peer supports it
+	wakeup     chan struct{} // signalled when peer got new articles
+	lmu        sync.Mutex
+	listening  bool
+	lstopped   bool
+	listenConn net.Conn
 }
 
 func NewNNTPPuller(db PullerDatabase, logx LoggerX) *NNTPPuller {
-	c := &NNTPPuller{db: db}
+	c := &NNTPPuller{
+		db:      db,
+		logx:    logx,
+		workers: 1,
+		wakeup:  make(chan struct{}, 1),
+	}
 	c.log = NewLogToX(logx, fmt.Sprintf("nntppuller.%p", c))
 	return c
 }
 
+// SetPullWorkers sets how many connections puller may open to peer.
+// Groups are shared between them, each group is pulled by single
+// connection at a time.
+// Must be called before Run.
+func (c *NNTPPuller) SetPullWorkers(n int) {
+	if n <= 0 {
+		n = 1
+	}
+	c.workers = n
+}
+
 func (c *NNTPPuller) doActiveList() (err error, fatal bool) {
 	err = c.w.PrintfLine("LIST")
 	if err != nil {
@@ -242,104 +285,309 @@ func (c *NNTPPuller) doNewsgroupsList() (err error, fatal bool) {
 	return
 }
 
-func (c *NNTPPuller) doCapabilities() (err error, fatal bool) {
-	c.log.LogPrintf(DEBUG, "querying CAPABILITIES")
-	err = c.w.PrintfLine("CAPABILITIES")
+type Dialer interface {
+	Dial(network, address string) (net.Conn, error)
+}
+
+// connect dials peer and resets connection state.
+func (c *NNTPPuller) connect() (net.Conn, error) {
+	conn, e := c.d.Dial(c.network, c.address)
+	if e != nil {
+		return nil, e
+	}
+
+	c.s = clientState{}
+	c.conn = conn
+	c.w = tp.NewWriter(bufio.NewWriter(conn))
+	c.r = bufreader.NewBufReader(conn)
+	c.dr = nil
+
+	return conn, nil
+}
+
+func (c *NNTPPuller) Run(d Dialer, network, address string) {
+	c.d, c.network, c.address = d, network, address
+	// TODO
+	for {
+		c.log.LogPrintf(DEBUG, "dialing...")
+		conn, e := c.connect()
+		if e != nil {
+			c.log.LogPrintf(WARN, "error dialing: %v", e)
+			c.log.LogPrintf(WARN, "will wait 10 secs")
+			time.Sleep(10 * time.Second)
+			continue
+		}
+
+		c.log.LogPrintf(DEBUG, "scraping...")
+
+		e = c.main()
+
+		conn.Close()
+
+		if e != nil {
+			c.log.LogPrintf(WARN, "puller error: %v", e)
+			c.log.LogPrintf(WARN, "will reconnect after 10 secs")
+			time.Sleep(10 * time.Second)
+		} else {
+			c.log.LogPrintf(WARN, "puller closed, will wait 120 secs")
+			time.Sleep(120 * time.Second)
+		}
+	}
+}
+
+// PullOnce connects to peer and does single scan of its groups.
+// fatal tells that scan couldn't be completed, because peer was
+// unreachable or connection broke; progress of groups pulled before
+// that is kept. Otherwise err reports groups which failed to be pulled.
+func (c *NNTPPuller) PullOnce(
+	d Dialer, network, address string) (err error, fatal bool) {
+
+	return c.pull(d, network, address, false)
+}
+
+// PullNew is like PullOnce, but meant to be used after peer told us
+// it got new articles: if possible, it asks only for articles peer
+// got since last scan, instead of scanning all groups.
+func (c *NNTPPuller) PullNew(
+	d Dialer, network, address string) (err error, fatal bool) {
+
+	return c.pull(d, network, address, true)
+}
+
+func (c *NNTPPuller) pull(
+	d Dialer, network, address string, onlyNew bool) (
+	err error, fatal bool) {
+
+	c.d, c.network, c.address = d, network, address
+
+	c.log.LogPrintf(DEBUG, "dialing...")
+	conn, e := c.connect()
+	if e != nil {
+		return fmt.Errorf("error dialing: %v", e), true
+	}
+	defer conn.Close()
+
+	if e = c.handshake(); e != nil {
+		return e, true
+	}
+
+	c.log.LogPrintf(DEBUG, "scraping...")
+	err, fatal = c.scan(onlyNew)
+	if !fatal && c.s.capXListen {
+		c.startListener()
+	}
+	return
+}
+
+// NEWNEWS date is by peer's clock, and articles may be still in flight
+// when scan starts, so we ask for bit more than since last scan
+const newNewsSlack = 5 * time.Minute
+
+// scan pulls articles we don't have yet.
+// If onlyNew is set, it tries NEWNEWS first.
+func (c *NNTPPuller) scan(onlyNew bool) (err error, fatal bool) {
+	if onlyNew {
+		var done bool
+		err, fatal, done = c.scanNewNews()
+		if done || fatal {
+			return
+		}
+		if err != nil {
+			c.log.LogPrintf(WARN, "NEWNEWS scan failed: %v", err)
+		}
+	}
+
+	start := time.Now()
+	err, fatal = c.groupScanLoop()
+	if err == nil {
+		// all groups are done, so later NEWNEWS can start from there
+		if e := c.db.UpdateLastNewNews(start.Unix()); e != nil {
+			c.log.LogPrintf(WARN, "UpdateLastNewNews() failed: %v", e)
+		}
+	}
+	return
+}
+
+// scanNewNews pulls articles peer got since last scan using NEWNEWS.
+// done is false if that can't be used and all groups need to be scanned.
+func (c *NNTPPuller) scanNewNews() (err error, fatal, done bool) {
+	if !c.s.capNewNews || c.s.badNewNews || c.filter != nil {
+		// pull rules need overview data, which we don't get there
+		return
+	}
+	last, err := c.db.GetLastNewNews()
+	if err != nil {
+		err = fmt.Errorf("GetLastNewNews() failed: %v", err)
+		return
+	}
+	if last <= 0 {
+		// no full scan finished yet
+		return
+	}
+
+	start := time.Now()
+	since := time.Unix(last, 0).Add(-newNewsSlack).UTC()
+	err = c.w.PrintfLine(
+		"NEWNEWS * %s GMT", since.Format("20060102 150405"))
 	if err != nil {
 		fatal = true
 		return
 	}
-	code, _, err, fatal := c.readResponse()
+	code, rest, err, fatal := c.readResponse()
 	if err != nil {
-		c.log.LogPrintf(DEBUG, "readResponse() err: %v", err)
 		return
 	}
-	if code != 101 {
-		c.log.LogPrintf(DEBUG, "code: %d", code)
-		c.s.badCapabilities = true
+	if code != 230 {
+		c.s.badNewNews = true
+		err = fmt.Errorf(
+			"bad response from NEWNEWS %d %q", code, au.TrimWSBytes(rest))
 		return
 	}
-	c.log.LogPrintf(DEBUG, "reading CAPABILITIES")
+
+	var list []TFullMsgIDStr
+	toomany := false
 	dr := c.openDotReader()
-	defer func() {
-		if err != nil {
-			_, _ = dr.Discard(-1)
-		}
-	}()
 	for {
 		line, e := c.readDotLine(dr)
 		if e != nil {
 			if e == io.EOF {
 				break
 			}
-			err = fmt.Errorf("failed reading list line: %v", e)
+			err = fmt.Errorf("failed reading NEWNEWS line: %v", e)
+			fatal = true
 			return
 		}
-		c.log.LogPrintf(DEBUG, "got capability line %q", line)
-		x := parseKeyword(line)
-		capability := unsafeBytesToStr(line[:x])
-		switch capability {
-		case "HDR":
-			c.s.capHdr = true
-		case "OVER":
-			c.s.capOver = true
-		case "READER":
-			c.s.capReader = true
-		case "IMPLEMENTATION":
-			c.args, _ = parseResponseArguments(line[x:], 6, c.args[:0])
-			if len(c.args) != 0 {
-				impl := unsafeBytesToStr(c.args[0])
-				if au.EqualFoldString(impl, "srndv2") {
-					c.log.LogPrintf(INFO, "detected srndv2")
-					// workarounds for some jeff' stuff
-					c.s.workaroundStupidActiveList = true
-					c.s.allowLargeOver = true
-				} else if au.EqualFoldString(impl, "CNTPD") {
-					c.log.LogPrintf(INFO, "detected CNTPD")
-					c.s.allowLargeOver = true
-				}
-			}
+		msgid := au.TrimWSBytes(line)
+		if !ValidMessageID(msgid) {
+			c.log.LogPrintf(WARN, "NEWNEWS: invalid msgid %q", msgid)
+			continue
 		}
+		if len(list) >= maxListSize {
+			// keep reading so that connection stays usable
+			toomany = true
+			continue
+		}
+		list = append(list, TFullMsgIDStr(msgid))
+	}
+	if toomany {
+		c.log.LogPrintf(INFO, "NEWNEWS: too many new articles")
+		return
+	}
+
+	done = true
+	for _, msgid := range list {
+		wanted, wdata, e := c.db.IsArticleWanted(msgid, "")
+		if e != nil {
+			err = fmt.Errorf("IsArticleWanted() failed: %v", e)
+			return
+		}
+		if !wanted {
+			continue
+		}
+		if err = c.w.PrintfLine("ARTICLE %s", msgid); err != nil {
+			fatal = true
+			return
+		}
+		_, err, fatal, _ = c.handleArticleResponse(msgid, "", wdata)
+		if err != nil {
+			return
+		}
+	}
+	c.log.LogPrintf(DEBUG, "NEWNEWS: processed %d articles", len(list))
+
+	if e := c.db.UpdateLastNewNews(start.Unix()); e != nil {
+		c.log.LogPrintf(WARN, "UpdateLastNewNews() failed: %v", e)
 	}
-	// done
-	c.log.LogPrintf(DEBUG, "done readin CAPABILITIES")
 	return
 }
 
-type Dialer interface {
-	Dial(network, address string) (net.Conn, error)
+const (
+	// how long single XWAIT may last, keeps idle connection alive
+	xwaitTimeout = 10 * time.Minute
+	// after wakeup, let more articles arrive before pulling
+	xwaitSettle = 2 * time.Second
+)
+
+// startListener starts holding connection in XWAIT, unless already doing so.
+// When peer tells about new articles, wakeup is signalled,
+// so that scan can be started without waiting for next one.
+func (c *NNTPPuller) startListener() {
+	c.lmu.Lock()
+	defer c.lmu.Unlock()
+
+	if c.listening || c.lstopped {
+		return
+	}
+	c.listening = true
+	go c.runListener(c.newWorker("listen"))
 }
 
-func (c *NNTPPuller) Run(d Dialer, network, address string) {
-	// TODO
-	for {
-		c.log.LogPrintf(DEBUG, "dialing...")
-		conn, e := d.Dial(network, address)
-		if e != nil {
-			c.log.LogPrintf(WARN, "error dialing: %v", e)
-			c.log.LogPrintf(WARN, "will wait 10 secs")
-			time.Sleep(10 * time.Second)
-			continue
-		}
+// StopListening closes XLISTEN connection and doesn't allow new one.
+func (c *NNTPPuller) StopListening() {
+	c.lmu.Lock()
+	defer c.lmu.Unlock()
 
-		c.s = clientState{}
-		c.w = tp.NewWriter(bufio.NewWriter(conn))
-		c.r = bufreader.NewBufReader(conn)
-		c.dr = nil
+	c.lstopped = true
+	if c.listenConn != nil {
+		c.listenConn.Close()
+	}
+}
 
-		c.log.LogPrintf(DEBUG, "scraping...")
+func (c *NNTPPuller) runListener(l *NNTPPuller) {
+	defer func() {
+		c.lmu.Lock()
+		c.listening = false
+		c.listenConn = nil
+		c.lmu.Unlock()
+	}()
 
-		e = c.main()
+	l.log.LogPrintf(DEBUG, "dialing...")
+	conn, e := l.connect()
+	if e != nil {
+		l.log.LogPrintf(WARN, "error dialing: %v", e)
+		return
+	}
+	defer conn.Close()
 
-		conn.Close()
+	c.lmu.Lock()
+	if c.lstopped {
+		c.lmu.Unlock()
+		return
+	}
+	c.listenConn = conn
+	c.lmu.Unlock()
 
+	if e = l.handshake(); e != nil {
+		l.log.LogPrintf(WARN, "listener error: %v", e)
+		return
+	}
+	if !l.s.capXListen {
+		// only listed for reader mode, or changed since
+		l.log.LogPrintf(INFO, "XLISTEN not available")
+		return
+	}
+	if e, _ = l.doXListen(); e != nil {
+		l.log.LogPrintf(WARN, "doXListen failed: %v", e)
+		return
+	}
+
+	l.log.LogPrintf(INFO, "listening for new articles")
+	for {
+		// don't hang forever on connection which silently died
+		_ = conn.SetDeadline(time.Now().Add(xwaitTimeout + time.Minute))
+		woke, e := l.doXWait(xwaitTimeout)
 		if e != nil {
-			c.log.LogPrintf(WARN, "puller error: %v", e)
-			c.log.LogPrintf(WARN, "will reconnect after 10 secs")
-			time.Sleep(10 * time.Second)
-		} else {
-			c.log.LogPrintf(WARN, "puller closed, will wait 120 secs")
-			time.Sleep(120 * time.Second)
+			l.log.LogPrintf(WARN, "doXWait failed: %v", e)
+			return
+		}
+		if woke {
+			l.log.LogPrintf(DEBUG, "peer got new articles")
+			time.Sleep(xwaitSettle)
+			select {
+			case c.wakeup <- struct{}{}:
+			default:
+				// scan already pending
+			}
 		}
 	}
 }
@@ -498,6 +746,7 @@ func (c *NNTPPuller) processTODOList(
 		err error, fatal bool) {
 
 		normalok, err, fatal, _ := c.handleArticleResponse(msgid, group, wdata)
+		c.releaseArticle(msgid)
 		if err != nil {
 			return
 		}
@@ -583,7 +832,15 @@ func (c *NNTPPuller) processTODOList(
 			e      error
 		)
 		if c.todoList[i].msgid != "" {
-			wanted, wdata, e = c.db.IsArticleWanted(c.todoList[i].msgid, group)
+			// claim before checking so that other connection
+			// won't fetch it in the meantime (crossposts)
+			if c.claimArticle(c.todoList[i].msgid) {
+				wanted, wdata, e =
+					c.db.IsArticleWanted(c.todoList[i].msgid, group)
+				if !wanted {
+					c.releaseArticle(c.todoList[i].msgid)
+				}
+			}
 		} else {
 			wanted = true
 		}
@@ -595,6 +852,21 @@ func (c *NNTPPuller) processTODOList(
 			return
 		}
 
+		if wanted && c.todoList[i].reject != "" &&
+			c.todoList[i].msgid != "" {
+			// filter said no based on overview, remember not to retry
+			c.log.LogPrintf(INFO, "TODO list %d %s rejected: %s",
+				c.todoList[i].id, c.todoList[i].msgid, c.todoList[i].reject)
+			e = c.db.RejectArticle(c.todoList[i].msgid, c.todoList[i].reject)
+			if e != nil {
+				// non-serious, filter will reject it again
+				c.log.LogPrintf(WARN,
+					"RejectArticle(%s) fail: %v", c.todoList[i].msgid, e)
+			}
+			c.releaseArticle(c.todoList[i].msgid)
+			wanted = false
+		}
+
 		if !wanted {
 			numunwanted++
 			//c.log.LogPrintf(DEBUG, "TODO list %d %s unwanted",
@@ -679,7 +951,7 @@ func (c *NNTPPuller) processTODOList(
 }
 
 func (c *NNTPPuller) eatHdrOutput(
-	r_begin, r_end int64) (err error) {
+	group string, r_begin, r_end int64) (err error) {
 
 	dr := c.openDotReader()
 	defer func() {
@@ -724,10 +996,21 @@ func (c *NNTPPuller) eatHdrOutput(
 		//c.log.LogPrintf(DEBUG,
 		//	"eatHdrOutput: adding %d %s", id, msgid)
 
+		// not much to filter by, but group could be unwanted
+		reject := ""
+		if c.filter != nil {
+			reject = c.filter.FilterArticle(TFullMsgIDStr(msgid), &PullOverInfo{
+				Bytes:      -1,
+				Lines:      -1,
+				Newsgroups: []string{group},
+			})
+		}
+
 		// add to list to query
 		c.todoList = append(c.todoList, todoArticle{
-			id:    id,
-			msgid: TFullMsgIDStr(msgid),
+			id:     id,
+			msgid:  TFullMsgIDStr(msgid),
+			reject: reject,
 		})
 	}
 
@@ -748,7 +1031,11 @@ func (c *NNTPPuller) eatOverOutput(
 		var id uint64
 		var msgid, ref TFullMsgID
 		var fatal bool
-		id, msgid, ref, err, fatal = c.getOverLineInfo(dr)
+		var oi *PullOverInfo
+		if c.filter != nil {
+			oi = &PullOverInfo{}
+		}
+		id, msgid, ref, err, fatal = c.getOverLineInfo(dr, oi)
 		if err != nil {
 			if err == io.EOF {
 				err = nil
@@ -794,11 +1081,20 @@ func (c *NNTPPuller) eatOverOutput(
 		//c.log.LogPrintf(DEBUG,
 		//	"eatOverOutput: adding %d %q %q", id, msgid, sref)
 
+		reject := ""
+		if oi != nil {
+			if len(oi.Newsgroups) == 0 {
+				oi.Newsgroups = []string{group}
+			}
+			reject = c.filter.FilterArticle(TFullMsgIDStr(msgid), oi)
+		}
+
 		// add to list to query
 		c.todoList = append(c.todoList, todoArticle{
-			id:    id,
-			msgid: TFullMsgIDStr(msgid),
-			ref:   sref,
+			id:     id,
+			msgid:  TFullMsgIDStr(msgid),
+			ref:    sref,
+			reject: reject,
 		})
 	}
 
@@ -827,7 +1123,10 @@ func (c *NNTPPuller) eatGroupSlice(
 
 	hdrD := false
 
-	if c.s.canHdr() {
+	// filter needs more than HDR Message-ID gives
+	tryHdr := c.filter == nil || (!c.s.canOver() && !c.s.canXOver())
+
+	if tryHdr && c.s.canHdr() {
 		err = printHdrLine("HDR")
 		if err != nil {
 			fatal = true
@@ -858,7 +1157,7 @@ func (c *NNTPPuller) eatGroupSlice(
 			c.s.badHdr = true
 		}
 	}
-	if !hdrD && c.s.canXHdr() {
+	if tryHdr && !hdrD && c.s.canXHdr() {
 		err = printHdrLine("XHDR")
 		if err != nil {
 			fatal = true
@@ -891,7 +1190,7 @@ func (c *NNTPPuller) eatGroupSlice(
 	}
 	if hdrD {
 		// parse HDR/XHDR lines
-		err = c.eatHdrOutput(r_begin, r_end)
+		err = c.eatHdrOutput(group, r_begin, r_end)
 		if err != nil {
 			c.log.LogPrintf(WARN, "error parsing HDR output: %v", err)
 			return
@@ -1040,16 +1339,14 @@ func (c *NNTPPuller) eatGroup(
 	return
 }
 
-func (c *NNTPPuller) groupScanLoop() error {
-	var e error
-	var fatal bool
+func (c *NNTPPuller) groupScanLoop() (e error, fatal bool) {
 
 	gotGroupList := false
 	if !gotGroupList && !c.s.badActiveList {
 		e, fatal = c.doActiveList()
 		if e != nil {
 			if fatal {
-				return fmt.Errorf("doActiveList method failed: %v", e)
+				return fmt.Errorf("doActiveList method failed: %v", e), true
 			} else {
 				c.log.LogPrintf(WARN, "doActiveList method failed: %v", e)
 			}
@@ -1061,7 +1358,7 @@ func (c *NNTPPuller) groupScanLoop() error {
 		e, fatal = c.doNewsgroupsList()
 		if e != nil {
 			if fatal {
-				return fmt.Errorf("doNewsgroupsList method failed: %v", e)
+				return fmt.Errorf("doNewsgroupsList method failed: %v", e), true
 			} else {
 				c.log.LogPrintf(WARN, "doNewsgroupsList method failed: %v", e)
 			}
@@ -1070,9 +1367,68 @@ func (c *NNTPPuller) groupScanLoop() error {
 		}
 	}
 	if !gotGroupList {
-		return errors.New("no methods left to get group list")
+		return errors.New("no methods left to get group list"), true
+	}
+
+	groups := c.loadTempGroups()
+	c.db.DoneTempGroups()
+
+	if c.workers > 1 && len(groups) > 1 {
+		return c.pullGroupsParallel(groups)
 	}
+	ch := make(chan tempGroup, len(groups))
+	for _, g := range groups {
+		ch <- g
+	}
+	close(ch)
+	return c.pullGroups(ch)
+}
+
+type tempGroup struct {
+	group  string
+	new_id int64 // -1 if we don't know
+	old_id uint64
+}
+
+// pullInflight tracks articles being fetched by pull workers,
+// so that crossposted article isn't fetched by two connections at once.
+type pullInflight struct {
+	mu sync.Mutex
+	m  map[TFullMsgIDStr]struct{}
+}
+
+func newPullInflight() *pullInflight {
+	return &pullInflight{m: make(map[TFullMsgIDStr]struct{})}
+}
 
+// claimArticle returns false if other connection is fetching msgid.
+func (c *NNTPPuller) claimArticle(msgid TFullMsgIDStr) bool {
+	f := c.inflight
+	if f == nil {
+		return true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, busy := f.m[msgid]; busy {
+		return false
+	}
+	f.m[msgid] = struct{}{}
+	return true
+}
+
+func (c *NNTPPuller) releaseArticle(msgid TFullMsgIDStr) {
+	f := c.inflight
+	if f == nil || msgid == "" {
+		return
+	}
+	f.mu.Lock()
+	delete(f.m, msgid)
+	f.mu.Unlock()
+}
+
+// loadTempGroups loads whole list of groups we got from peer,
+// leaving out ones we've already pulled.
+func (c *NNTPPuller) loadTempGroups() (groups []tempGroup) {
 	c.log.LogPrintf(DEBUG, "puller will load temp groups")
 	for {
 		group, new_id, old_id, e := c.db.LoadTempGroup()
@@ -1103,61 +1459,176 @@ func (c *NNTPPuller) groupScanLoop() error {
 			}
 		}
 
-		var g_id int64
-		var notexists bool
-		g_id, e, notexists, fatal = c.doGroup(group)
-		if e != nil && !notexists {
-			if fatal {
-				return fmt.Errorf("doGroup failed: %v", e)
-			} else {
-				c.log.LogPrintf(WARN, "doGroup failed: %v", e)
-			}
-			// next group, I guess..
-			continue
+		groups = append(groups, tempGroup{group, new_id, old_id})
+	}
+	return
+}
+
+// pullGroup pulls articles of group we don't have yet.
+// fatal means connection can't be used anymore.
+func (c *NNTPPuller) pullGroup(g tempGroup) (err error, fatal bool) {
+	group, new_id, old_id := g.group, g.new_id, g.old_id
+
+	g_id, e, notexists, fatal := c.doGroup(group)
+	if e != nil && !notexists {
+		// if not fatal, next group, I guess..
+		return fmt.Errorf("doGroup failed: %v", e), fatal
+	}
+	if notexists {
+		// weird. ohwell. just ensure we will trigger full rescan if next time happens
+		c.db.UpdateGroupID(group, 0)
+		return nil, false
+	}
+	// in case we had no info about new_id before, or it's higher..
+	if new_id < 0 || g_id > new_id {
+		new_id = g_id
+
+		// maybe we don't need to bother
+		if old_id == uint64(new_id) {
+			// skip this
+			return
 		}
-		if notexists {
-			// weird. ohwell. just ensure we will trigger full rescan if next time happens
-			c.db.UpdateGroupID(group, 0)
-			continue
+		if old_id > uint64(new_id) {
+			// new id is somehow lower
+			// trigger rescan from the start - stuff may be changed
+			old_id = 0
+			// ensure we're aware of difference later on
+			c.db.UpdateGroupID(group, old_id)
 		}
-		// in case we had no info about new_id before, or it's higher..
-		if new_id < 0 || g_id > new_id {
-			new_id = g_id
+	}
+	// redo check
+	if old_id == uint64(new_id) {
+		return
+	}
 
-			// maybe we don't need to bother
-			if old_id == uint64(new_id) {
-				// skip this
-				continue
+	e, fatal = c.eatGroup(group, old_id, uint64(new_id))
+	if e != nil {
+		return fmt.Errorf("eatGroup failed: %v", e), fatal
+	}
+	return
+}
+
+// pullGroups pulls groups from channel until it's closed.
+// Failures of single groups don't stop it, first of them is returned
+// as non-fatal error once channel is drained.
+func (c *NNTPPuller) pullGroups(ch <-chan tempGroup) (err error, fatal bool) {
+	for g := range ch {
+		e, f := c.pullGroup(g)
+		if e != nil {
+			if f {
+				return e, true
 			}
-			if old_id > uint64(new_id) {
-				// new id is somehow lower
-				// trigger rescan from the start - stuff may be changed
-				old_id = 0
-				// ensure we're aware of difference later on
-				c.db.UpdateGroupID(group, old_id)
+			c.log.LogPrintf(WARN, "%v", e)
+			if err == nil {
+				err = e
 			}
 		}
-		// redo check
-		if old_id == uint64(new_id) {
-			continue
-		}
+	}
+	return
+}
 
-		e, fatal = c.eatGroup(group, old_id, uint64(new_id))
-		if e != nil {
-			if fatal {
-				return fmt.Errorf("eatGroup failed: %v", e)
-			} else {
-				c.log.LogPrintf(WARN, "eatGroup failed: %v", e)
+// pullGroupsParallel shares groups between our connection and
+// additional ones opened just for this scan.
+// As each group is pulled by single connection, its progress is
+// tracked the same way as with single connection.
+func (c *NNTPPuller) pullGroupsParallel(
+	groups []tempGroup) (err error, fatal bool) {
+	nextra := c.workers - 1
+	if nextra > len(groups)-1 {
+		nextra = len(groups) - 1
+	}
+
+	c.inflight = newPullInflight()
+	defer func() { c.inflight = nil }()
+
+	ch := make(chan tempGroup)
+	quit := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for _, g := range groups {
+			select {
+			case ch <- g:
+			case <-quit:
+				return
 			}
 		}
+	}()
+
+	var wg sync.WaitGroup
+	var werrMu sync.Mutex
+	var werr error
+	for i := 1; i <= nextra; i++ {
+		w := c.newWorker(strconv.Itoa(i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e := w.runWorker(ch); e != nil {
+				werrMu.Lock()
+				if werr == nil {
+					werr = e
+				}
+				werrMu.Unlock()
+			}
+		}()
 	}
 
-	c.db.DoneTempGroups()
+	c.log.LogPrintf(DEBUG, "pulling %d groups with %d connections",
+		len(groups), nextra+1)
 
-	return nil
+	err, fatal = c.pullGroups(ch)
+	if fatal {
+		// stop handing out groups, others finish ones they're at.
+		// what's left will be pulled on next scan
+		close(quit)
+	}
+	wg.Wait()
+	// failed workers don't make whole scan fail, others did their part
+	if err == nil {
+		err = werr
+	}
+	return
 }
 
-func (c *NNTPPuller) main() error {
+func (c *NNTPPuller) newWorker(name string) *NNTPPuller {
+	w := &NNTPPuller{
+		db:       c.db,
+		logx:     c.logx,
+		workers:  1,
+		d:        c.d,
+		network:  c.network,
+		address:  c.address,
+		inflight: c.inflight,
+	}
+	w.log = NewLogToX(c.logx, fmt.Sprintf("nntppuller.%p.%s", c, name))
+	w.SetStartTLS(c.tlsCfg, c.startTLS)
+	w.filter = c.filter
+	return w
+}
+
+// runWorker pulls groups over additional connection.
+// Failure of it isn't fatal for whole puller, others pick up the rest.
+func (c *NNTPPuller) runWorker(ch <-chan tempGroup) error {
+	c.log.LogPrintf(DEBUG, "dialing...")
+	conn, e := c.connect()
+	if e != nil {
+		c.log.LogPrintf(WARN, "error dialing: %v", e)
+		return fmt.Errorf("worker dial failed: %v", e)
+	}
+	defer conn.Close()
+
+	if e = c.handshake(); e != nil {
+		c.log.LogPrintf(WARN, "puller worker error: %v", e)
+		return e
+	}
+	e, fatal := c.pullGroups(ch)
+	if e != nil && fatal {
+		c.log.LogPrintf(WARN, "puller worker error: %v", e)
+	}
+	return e
+}
+
+// handshake processes initial response and sets connection up.
+func (c *NNTPPuller) handshake() error {
 	var e error
 	var fatal bool
 
@@ -1175,6 +1646,15 @@ func (c *NNTPPuller) main() error {
 		}
 	}
 
+	e, fatal = c.doStartTLS()
+	if e != nil {
+		if fatal {
+			return fmt.Errorf("doStartTLS() failed: %v", e)
+		} else {
+			c.log.LogPrintf(WARN, "doStartTLS() failed: %v", e)
+		}
+	}
+
 	if !c.s.capReader {
 		e = c.w.PrintfLine("MODE READER")
 		if e != nil {
@@ -1204,12 +1684,45 @@ func (c *NNTPPuller) main() error {
 		}
 	}
 
+	e, fatal = c.doCompress()
+	if e != nil {
+		if fatal {
+			return fmt.Errorf("doCompress() failed: %v", e)
+		} else {
+			c.log.LogPrintf(WARN, "doCompress() failed: %v", e)
+		}
+	}
+
+	return nil
+}
+
+func (c *NNTPPuller) main() error {
+	e := c.handshake()
+	if e != nil {
+		return e
+	}
+	if c.s.capXListen {
+		c.startListener()
+	}
+
+	woken := false
 	for {
-		e = c.groupScanLoop()
+		e, fatal := c.scan(woken)
 		if e != nil {
-			return e
+			if fatal {
+				return e
+			}
+			c.log.LogPrintf(WARN, "scan incomplete: %v", e)
+		}
+		c.log.LogPrintf(INFO, "scan done, will wait 90 secs")
+		t := time.NewTimer(90 * time.Second)
+		select {
+		case <-t.C:
+			woken = false
+		case <-c.wakeup:
+			t.Stop()
+			woken = true
+			c.log.LogPrintf(INFO, "peer got new articles, scanning")
 		}
-		c.log.LogPrintf(INFO, "groupScanLoop() done, will wait 90 secs")
-		time.Sleep(90 * time.Second)
 	}
 }
@@ -4,30 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 
 	"nksrv/lib/mail"
 	au "nksrv/lib/utils/text/asciiutils"
 	"nksrv/lib/utils/text/bufreader"
 )
 
-func (c *NNTPPuller) readDotLine(dr *bufreader.DotReader) ([]byte, error) {
-	i := 0
-	for {
-		b, e := dr.ReadByte()
-		if e != nil {
-			return c.inbuf[:i], e
-		}
-		if b == '\n' {
-			return c.inbuf[:i], nil
-		}
-		if i >= len(c.inbuf) {
-			return c.inbuf[:i], errTooLargeResponse
-		}
-		c.inbuf[i] = b
-		i++
-	}
-}
-
 func (c *NNTPPuller) readOnlyNewsgroup(
 	dr *bufreader.DotReader) ([]byte, error) {
 
@@ -119,8 +102,13 @@ func parseListActiveLine(
 	return
 }
 
+// how much of subject or other text overview fields is kept for filtering
+const maxOverStrField = 1024
+
+// getOverLineInfo parses overview line.
+// If oi isn't nil, it's filled with info useful for filtering.
 func (c *NNTPPuller) getOverLineInfo(
-	dr *bufreader.DotReader) (
+	dr *bufreader.DotReader, oi *PullOverInfo) (
 	id uint64, msgid, ref TFullMsgID, err error, fatal bool) {
 
 	i := 0
@@ -179,6 +167,46 @@ func (c *NNTPPuller) getOverLineInfo(
 		}
 	}
 
+	// for fields we don't keep in inbuf, truncated to max
+	eatStrField := func(max int) (field string, err error) {
+		if nomore {
+			return
+		}
+		var b []byte
+		for {
+			ch, e := dr.ReadByte()
+			if e != nil {
+				if e != io.EOF {
+					fatal = true
+				}
+				err = e
+				return
+			}
+			if ch == '\n' {
+				nomore = true
+				break
+			}
+			if ch == '\t' {
+				break
+			}
+			if len(b) < max {
+				b = append(b, ch)
+			}
+		}
+		return string(au.TrimWSBytes(b)), nil
+	}
+	eatNumField := func() (n int64, err error) {
+		f, err := eatStrField(20)
+		if err != nil {
+			return
+		}
+		n, e := strconv.ParseInt(f, 10, 64)
+		if e != nil {
+			n = -1
+		}
+		return
+	}
+
 	defer func() {
 		if !nomore {
 			for {
@@ -215,7 +243,19 @@ func (c *NNTPPuller) getOverLineInfo(
 	id = stoi64(snum)
 	// subject, author, date
 	for xx := 0; xx < 3; xx++ {
-		err = ignoreField()
+		if oi != nil && xx < 2 {
+			var f string
+			f, err = eatStrField(maxOverStrField)
+			// filter rules are written against decoded text
+			f = decodeOverText(f)
+			if xx == 0 {
+				oi.Subject = f
+			} else {
+				oi.From = f
+			}
+		} else {
+			err = ignoreField()
+		}
 		if err != nil {
 			return
 		}
@@ -243,6 +283,28 @@ func (c *NNTPPuller) getOverLineInfo(
 	ref = TFullMsgID(unsafeStrToBytes(
 		string(mail.ExtractFirstValidReference(unsafeBytesToStr(xref)))))
 
+	if oi == nil {
+		return
+	}
+	oi.Bytes, oi.Lines = -1, -1
+	if oi.Bytes, err = eatNumField(); err != nil {
+		return
+	}
+	if oi.Lines, err = eatNumField(); err != nil {
+		return
+	}
+	// optional fields, with header names
+	for !nomore {
+		var f string
+		f, err = eatStrField(maxOverStrField)
+		if err != nil {
+			return
+		}
+		if len(f) > 5 && au.EqualFoldString(f[:5], "Xref:") {
+			oi.Newsgroups = parseXrefGroups(f[5:])
+		}
+	}
+
 	return
 }
 
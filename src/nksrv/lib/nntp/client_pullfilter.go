@@ -0,0 +1,115 @@
+package nntp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"nksrv/lib/mail"
+)
+
+// PullOverInfo is what overview tells about article before it's fetched.
+// Unknown numbers are -1, unknown strings are empty.
+type PullOverInfo struct {
+	Subject    string
+	From       string
+	Bytes      int64
+	Lines      int64
+	Newsgroups []string // from Xref if server included it, else group we pull
+}
+
+// PullFilter decides which articles are worth fetching.
+// FilterArticle returns reason for ones which aren't, empty otherwise.
+// It's called concurrently by pull workers.
+type PullFilter interface {
+	FilterArticle(msgid TFullMsgIDStr, oi *PullOverInfo) (reason string)
+}
+
+// PullRules is PullFilter with simple per-peer rules.
+// Zero values don't limit anything.
+type PullRules struct {
+	MaxBytes int64
+	MaxLines int64
+	// at least one newsgroup article is posted to must match,
+	// crossposts to unwanted groups are still stored in wanted ones
+	Groups Wildmat
+	// reject if any matches
+	RejectSubject []*regexp.Regexp
+	RejectFrom    []*regexp.Regexp
+}
+
+var _ PullFilter = (*PullRules)(nil)
+
+func (r *PullRules) FilterArticle(
+	msgid TFullMsgIDStr, oi *PullOverInfo) string {
+
+	if r.MaxBytes > 0 && oi.Bytes > r.MaxBytes {
+		return fmt.Sprintf("too large (%d bytes)", oi.Bytes)
+	}
+	if r.MaxLines > 0 && oi.Lines > r.MaxLines {
+		return fmt.Sprintf("too large (%d lines)", oi.Lines)
+	}
+	if r.Groups != nil && len(oi.Newsgroups) != 0 &&
+		!r.anyGroupWanted(oi.Newsgroups) {
+
+		return fmt.Sprintf("unwanted newsgroups %q", oi.Newsgroups)
+	}
+	if oi.Subject != "" {
+		for _, re := range r.RejectSubject {
+			if re.MatchString(oi.Subject) {
+				return fmt.Sprintf("subject matches %q", re.String())
+			}
+		}
+	}
+	if oi.From != "" {
+		for _, re := range r.RejectFrom {
+			if re.MatchString(oi.From) {
+				return fmt.Sprintf("from matches %q", re.String())
+			}
+		}
+	}
+	return ""
+}
+
+func (r *PullRules) anyGroupWanted(groups []string) bool {
+	for _, g := range groups {
+		if r.Groups.CheckString(g) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeOverText decodes RFC 2047 encoded-words in overview field.
+// If that fails, field is returned as it was.
+func decodeOverText(s string) string {
+	if strings.Index(s, "=?") < 0 {
+		return s
+	}
+	d, err := mail.DecodeMIMEWordHeader(s)
+	if err != nil {
+		return s
+	}
+	return d
+}
+
+// parseXrefGroups extracts newsgroups from Xref header value,
+// which is "host group:num ...".
+func parseXrefGroups(x string) (groups []string) {
+	f := strings.Fields(x)
+	if len(f) < 2 {
+		return nil
+	}
+	for _, gn := range f[1:] {
+		if i := strings.LastIndexByte(gn, ':'); i > 0 {
+			groups = append(groups, gn[:i])
+		}
+	}
+	return
+}
+
+// SetPullFilter makes puller skip articles filter rejects.
+// Must be called before Run.
+func (c *NNTPPuller) SetPullFilter(f PullFilter) {
+	c.filter = f
+}
@@ -0,0 +1,84 @@
+package nntp
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestPullRules(t *testing.T) {
+	r := &PullRules{
+		MaxBytes:      1000,
+		MaxLines:      50,
+		Groups:        CompileWildmatStr("overchan.*,!overchan.test"),
+		RejectSubject: []*regexp.Regexp{regexp.MustCompile("(?i)buy now")},
+		RejectFrom:    []*regexp.Regexp{regexp.MustCompile(`@spam\.example>?$`)},
+	}
+	for i, tc := range []struct {
+		oi     PullOverInfo
+		reject bool
+	}{
+		{PullOverInfo{Bytes: 500, Lines: 10,
+			Newsgroups: []string{"overchan.a"}}, false},
+		{PullOverInfo{Bytes: -1, Lines: -1,
+			Newsgroups: []string{"overchan.a", "overchan.b"}}, false},
+		{PullOverInfo{Bytes: 1001, Lines: 10,
+			Newsgroups: []string{"overchan.a"}}, true},
+		{PullOverInfo{Bytes: 500, Lines: 51,
+			Newsgroups: []string{"overchan.a"}}, true},
+		// crossposts are wanted if any of groups is
+		{PullOverInfo{Bytes: -1, Lines: -1,
+			Newsgroups: []string{"overchan.a", "overchan.test"}}, false},
+		{PullOverInfo{Bytes: -1, Lines: -1,
+			Newsgroups: []string{"alt.test", "overchan.a"}}, false},
+		{PullOverInfo{Bytes: -1, Lines: -1,
+			Newsgroups: []string{"alt.test", "overchan.test"}}, true},
+		{PullOverInfo{Bytes: -1, Lines: -1,
+			Newsgroups: []string{"alt.test"}}, true},
+		{PullOverInfo{Subject: "BUY NOW cheap", Bytes: -1, Lines: -1,
+			Newsgroups: []string{"overchan.a"}}, true},
+		{PullOverInfo{From: "x <x@spam.example>", Bytes: -1, Lines: -1,
+			Newsgroups: []string{"overchan.a"}}, true},
+		{PullOverInfo{From: "x <x@ham.example>", Bytes: -1, Lines: -1,
+			Newsgroups: []string{"overchan.a"}}, false},
+	} {
+		reason := r.FilterArticle("<a@b>", &tc.oi)
+		if (reason != "") != tc.reject {
+			t.Errorf("%d: got reason %q, want reject %v", i, reason, tc.reject)
+		}
+	}
+}
+
+func TestParseXrefGroups(t *testing.T) {
+	for _, tc := range []struct {
+		x string
+		g []string
+	}{
+		{"", nil},
+		{"host", nil},
+		{"host a.b:1", []string{"a.b"}},
+		{"host a.b:1  c:22 bad", []string{"a.b", "c"}},
+	} {
+		g := parseXrefGroups(tc.x)
+		if !reflect.DeepEqual(g, tc.g) {
+			t.Errorf("parseXrefGroups(%q) = %q, want %q", tc.x, g, tc.g)
+		}
+	}
+}
+
+func TestDecodeOverText(t *testing.T) {
+	for _, tc := range []struct {
+		s, d string
+	}{
+		{"plain subject", "plain subject"},
+		{"=?utf-8?q?BUY_NOW?= cheap", "BUY NOW cheap"},
+		{"=?UTF-8?B?0L/RgNC40LLQtdGC?= <x@spam.example>",
+			"привет <x@spam.example>"},
+		// broken ones are kept as they are
+		{"=?utf-8?b?!!!?=", "=?utf-8?b?!!!?="},
+	} {
+		if d := decodeOverText(tc.s); d != tc.d {
+			t.Errorf("decodeOverText(%q) = %q, want %q", tc.s, d, tc.d)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package nntp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	. "nksrv/lib/utils/logx"
+)
+
+// PullSchedulerCfg controls how often peer is pulled from.
+type PullSchedulerCfg struct {
+	// wait between completed scans
+	Interval time.Duration
+	// wait after first failed scan, doubled on each consecutive failure
+	MinBackoff time.Duration
+	// upper limit of wait after failures
+	MaxBackoff time.Duration
+}
+
+var DefaultPullSchedulerCfg = PullSchedulerCfg{
+	Interval:   90 * time.Second,
+	MinBackoff: 10 * time.Second,
+	MaxBackoff: 30 * time.Minute,
+}
+
+// PullPeerState is snapshot of peer's scheduling state.
+type PullPeerState struct {
+	Running     bool      // scan is in progress
+	LastSuccess time.Time // when last scan without any errors finished
+	LastError   string    // error of last scan, empty if it had none
+	LastErrorAt time.Time
+	Fatal       bool // whether last error aborted scan
+	Failures    int  // consecutive aborted scans
+	NextAttempt time.Time
+}
+
+var errPullSchedClosed = errors.New("pull scheduler closed")
+
+type pullPeer struct {
+	c                *NNTPPuller
+	d                Dialer
+	network, address string
+	cfg              PullSchedulerCfg
+
+	st PullPeerState // protected by PullScheduler.mu
+}
+
+// PullScheduler owns pullers of peers and runs their scans periodically,
+// backing off from peers which fail.
+type PullScheduler struct {
+	log Logger
+
+	mu     sync.Mutex
+	peers  map[string]*pullPeer
+	closed bool
+	quit   chan struct{}
+}
+
+func NewPullScheduler(logx LoggerX) *PullScheduler {
+	s := &PullScheduler{
+		peers: make(map[string]*pullPeer),
+		quit:  make(chan struct{}),
+	}
+	s.log = NewLogToX(logx, fmt.Sprintf("pullsched.%p", s))
+	return s
+}
+
+// Add starts scheduling scans of peer with puller c.
+// First scan is started right away.
+// Zero fields of cfg are taken from DefaultPullSchedulerCfg.
+func (s *PullScheduler) Add(
+	name string, c *NNTPPuller, d Dialer, network, address string,
+	cfg PullSchedulerCfg) error {
+
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultPullSchedulerCfg.Interval
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultPullSchedulerCfg.MinBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultPullSchedulerCfg.MaxBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = cfg.MinBackoff
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errPullSchedClosed
+	}
+	if s.peers[name] != nil {
+		return fmt.Errorf("peer %q already scheduled", name)
+	}
+	p := &pullPeer{
+		c:       c,
+		d:       d,
+		network: network,
+		address: address,
+		cfg:     cfg,
+	}
+	p.st.NextAttempt = time.Now()
+	s.peers[name] = p
+
+	go s.run(name, p)
+	return nil
+}
+
+// States returns current state of all scheduled peers.
+func (s *PullScheduler) States() map[string]PullPeerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := make(map[string]PullPeerState, len(s.peers))
+	for name, p := range s.peers {
+		m[name] = p.st
+	}
+	return m
+}
+
+// Close stops scheduling new scans and closes XLISTEN connections.
+// Scans already in progress aren't interrupted.
+func (s *PullScheduler) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.quit)
+		for _, p := range s.peers {
+			p.c.StopListening()
+		}
+	}
+}
+
+// backoff returns wait after n consecutive failures.
+// Jitter keeps peers which failed together from retrying in lockstep.
+func (cfg *PullSchedulerCfg) backoff(n int) time.Duration {
+	d := cfg.MinBackoff
+	for i := 1; i < n && d < cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > cfg.MaxBackoff {
+		d = cfg.MaxBackoff
+	}
+	// somewhere in [d/2, d]
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// scanDone records outcome of scan which finished at now
+// and returns wait until next one.
+func (st *PullPeerState) scanDone(
+	cfg *PullSchedulerCfg, now time.Time, err error, fatal bool) (
+	next time.Duration) {
+
+	if err != nil {
+		st.LastError = err.Error()
+		st.LastErrorAt = now
+		st.Fatal = fatal
+	} else {
+		st.LastError = ""
+		st.Fatal = false
+	}
+	if fatal {
+		// scan didn't complete, peer is likely unreachable
+		st.Failures++
+		next = cfg.backoff(st.Failures)
+	} else {
+		// some groups may have failed, but others made progress.
+		// that isn't success though
+		if err == nil {
+			st.LastSuccess = now
+		}
+		st.Failures = 0
+		next = cfg.Interval
+	}
+	st.NextAttempt = now.Add(next)
+	return
+}
+
+func (s *PullScheduler) run(name string, p *pullPeer) {
+	for {
+		s.mu.Lock()
+		wait := time.Until(p.st.NextAttempt)
+		var wakeup <-chan struct{}
+		if p.st.Failures == 0 {
+			// peer told us it got new articles (XLISTEN),
+			// don't let that bypass backoff though
+			wakeup = p.c.wakeup
+		}
+		s.mu.Unlock()
+
+		woken := false
+		if wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-wakeup:
+				t.Stop()
+				woken = true
+				s.log.LogPrintf(DEBUG, "peer %q: woken up", name)
+			case <-s.quit:
+				t.Stop()
+				return
+			}
+		} else {
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+		}
+
+		s.mu.Lock()
+		p.st.Running = true
+		s.mu.Unlock()
+
+		s.log.LogPrintf(DEBUG, "peer %q: starting scan", name)
+		var err error
+		var fatal bool
+		if woken {
+			// only what's new, scheduled scans still check everything
+			err, fatal = p.c.PullNew(p.d, p.network, p.address)
+		} else {
+			err, fatal = p.c.PullOnce(p.d, p.network, p.address)
+		}
+		now := time.Now()
+
+		s.mu.Lock()
+		p.st.Running = false
+		next := p.st.scanDone(&p.cfg, now, err, fatal)
+		failures := p.st.Failures
+		s.mu.Unlock()
+
+		if fatal {
+			s.log.LogPrintf(WARN,
+				"peer %q: scan failed (%d in a row): %v; retrying in %v",
+				name, failures, err, next.Round(time.Second))
+		} else if err != nil {
+			s.log.LogPrintf(WARN,
+				"peer %q: scan incomplete: %v; next in %v", name, err, next)
+		} else {
+			s.log.LogPrintf(INFO, "peer %q: scan done, next in %v", name, next)
+		}
+	}
+}
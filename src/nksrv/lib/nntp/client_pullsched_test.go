@@ -0,0 +1,79 @@
+package nntp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPullBackoff(t *testing.T) {
+	cfg := PullSchedulerCfg{
+		MinBackoff: 10 * time.Second,
+		MaxBackoff: 60 * time.Second,
+	}
+	for _, tc := range []struct {
+		n   int
+		max time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, 60 * time.Second},
+		{100, 60 * time.Second},
+	} {
+		for i := 0; i < 100; i++ {
+			d := cfg.backoff(tc.n)
+			if d < tc.max/2 || d > tc.max {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]",
+					tc.n, d, tc.max/2, tc.max)
+			}
+		}
+	}
+}
+
+func TestPullScanDone(t *testing.T) {
+	cfg := PullSchedulerCfg{
+		Interval:   90 * time.Second,
+		MinBackoff: 10 * time.Second,
+		MaxBackoff: 60 * time.Second,
+	}
+	t0 := time.Unix(1000000, 0)
+	var st PullPeerState
+
+	if next := st.scanDone(&cfg, t0, nil, false); next != cfg.Interval {
+		t.Errorf("clean scan: next %v, want %v", next, cfg.Interval)
+	}
+	if !st.LastSuccess.Equal(t0) || st.LastError != "" {
+		t.Errorf("clean scan: state %+v", st)
+	}
+
+	// partial failure isn't success, but doesn't back off either
+	t1 := t0.Add(time.Minute)
+	next := st.scanDone(&cfg, t1, errors.New("group failed"), false)
+	if next != cfg.Interval || st.Failures != 0 || st.Fatal {
+		t.Errorf("partial scan: next %v, state %+v", next, st)
+	}
+	if !st.LastSuccess.Equal(t0) {
+		t.Errorf("partial scan: LastSuccess %v, want %v", st.LastSuccess, t0)
+	}
+	if st.LastError != "group failed" || !st.LastErrorAt.Equal(t1) {
+		t.Errorf("partial scan: error %q at %v", st.LastError, st.LastErrorAt)
+	}
+
+	t2 := t1.Add(time.Minute)
+	next = st.scanDone(&cfg, t2, errors.New("refused"), true)
+	if st.Failures != 1 || !st.Fatal || next > cfg.MinBackoff {
+		t.Errorf("failed scan: next %v, state %+v", next, st)
+	}
+	if !st.LastSuccess.Equal(t0) || !st.NextAttempt.Equal(t2.Add(next)) {
+		t.Errorf("failed scan: state %+v", st)
+	}
+
+	t3 := t2.Add(time.Minute)
+	st.scanDone(&cfg, t3, nil, false)
+	if !st.LastSuccess.Equal(t3) || st.Failures != 0 || st.Fatal ||
+		st.LastError != "" {
+
+		t.Errorf("recovered scan: state %+v", st)
+	}
+}
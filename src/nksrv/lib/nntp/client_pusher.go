@@ -1,91 +1,137 @@
 package nntp
 
-import . "nksrv/lib/utils/logx"
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	tp "net/textproto"
+	"sync"
+	"time"
 
-/*
+	. "nksrv/lib/utils/logx"
+	au "nksrv/lib/utils/text/asciiutils"
+	"nksrv/lib/utils/text/bufreader"
+)
 
-type nntpCopyer interface {
-	Copy(num uint64, msgid CoreMsgIDStr, src io.Reader) (written int64, err error)
-	IsClosed() bool
+type PushGroup struct {
+	Group   string
+	ID      interface{} // database specific
+	Last    uint64      // last article number offered to peer
+	Max     uint64      // current max article number
+	Tracked bool        // whether Last was loaded from database
 }
 
-type ArticlePoster struct {
-	w  *tp.Writer
-	dw io.WriteCloser
+type PushArticle struct {
+	Num   uint64
+	MsgID TCoreMsgIDStr
+	Path  string // value of Path header, may be empty if unknown
 }
 
-var _ nntpCopyer = (*ArticlePoster)(nil)
+type PusherDatabase interface {
+	// list of our groups together with push progress
+	GetPushGroups() ([]PushGroup, error)
+	// persist progress
+	UpdatePushGroupID(g *PushGroup, id uint64) error
+	// articles in group with number above after, in ascending order
+	ListPushArticles(
+		g *PushGroup, after uint64, limit int) ([]PushArticle, error)
+	// WriteArticle copies article to writer obtained from begin.
+	// begin is called only if article exists, right before first write.
+	WriteArticle(
+		msgid TCoreMsgIDStr, begin func() (io.Writer, error)) (
+		exists bool, err error)
+}
 
-func (c *ArticlePoster) Copy(num uint64, msgid CoreMsgIDStr, src io.Reader) (written int64, err error) {
-	buf := make([]byte, 32*1024)
+// max amount of commands pipelined at once.
+// should be small enough that responses fit in socket buffers.
+const pushBatchSize = 64
 
-	var nr, nw int
-	var er, ew error
+// how often to rescan all groups even if we weren't notified
+const pushRescanInterval = 5 * time.Minute
 
-	// initialise if we successfuly read something
-	if c.dw == nil {
-		nr, er = src.Read(buf)
-		if nr <= 0 && er != nil {
-			// abort on error before initialisation if no data - avoid begining empty incomplete message
-			err = er
-			return
-		}
-		c.w.ResArticleFollows(num, msgid)
-		c.dw = c.w.DotWriter()
-	}
+type NNTPPusher struct {
+	NNTPClient
 
-	for {
-		if nr > 0 {
-			nw, ew = c.dw.Write(buf[:nr])
-			if nw > 0 {
-				written += int64(nw)
-			}
-			if ew != nil {
-				err = ew
-				break
-			}
-			if nr != nw {
-				err = io.ErrShortWrite
-				break
-			}
-		}
-		if er != nil {
-			if er != io.EOF {
-				// read EOF isn't error :>
-				err = er
-			}
-			break
-		}
-		nr, er = src.Read(buf)
-	}
+	db PusherDatabase
 
-	if err == nil {
-		err = c.dw.Close()
-		c.dw = nil
-	}
+	// this pusher handles only groups which hash to worker
+	worker   uint32
+	nworkers uint32
 
-	return
+	in  chan<- articleNotif
+	out <-chan articleNotif
+
+	groups    []PushGroup
+	groupsIdx map[string]int
+	loaded    bool // whether we loaded groups at least once
+	streaming bool // whether MODE STREAM succeeded
+	badStream bool // peer advertised streaming but it didn't work
+
+	// returns path identities of peer, may be nil
+	peerPathIDs func() []string
+}
+
+// NewNNTPPusher makes new pusher.
+// worker and nworkers specify which share of groups it handles,
+// it's useful when multiple connections to single peer are used.
+func NewNNTPPusher(
+	db PusherDatabase, logx LoggerX, worker, nworkers int) *NNTPPusher {
+
+	if nworkers <= 0 {
+		nworkers = 1
+	}
+	if worker < 0 || worker >= nworkers {
+		panic("invalid worker number")
+	}
+	in := make(chan articleNotif)
+	out := make(chan articleNotif)
+	c := &NNTPPusher{
+		db:       db,
+		worker:   uint32(worker),
+		nworkers: uint32(nworkers),
+		in:       in,
+		out:      out,
+	}
+	c.log = NewLogToX(logx, fmt.Sprintf("nntppusher.%p", c))
+	go nonBlockArticlePumper(in, out)
+	return c
 }
 
-func (c *fullNNTPCopyer) IsClosed() bool {
-	return c.dw == nil
+// NotifyArticle informs pusher about new article in specified groups.
+// It doesn't block for long.
+func (c *NNTPPusher) NotifyArticle(cmsgid TCoreMsgIDStr, groups []string) {
+	c.in <- articleNotif{cmsgid: cmsgid, groups: groups}
 }
 
-*/
+// SetPeerPathIDs sets function returning path identities of peer.
+// Articles which already passed through any of them aren't offered.
+// It must be called before pusher is started.
+func (c *NNTPPusher) SetPeerPathIDs(f func() []string) {
+	c.peerPathIDs = f
+}
 
-type NNTPPusher struct {
-	NNTPClient
+// seenByPeer tells whether article already passed through peer.
+func (c *NNTPPusher) seenByPeer(a *PushArticle, ids []string) bool {
+	if len(ids) == 0 || a.Path == "" {
+		return false
+	}
+	return PathHasAny(PathIdentities(a.Path), ids)
+}
 
-	// XXX
+func (c *NNTPPusher) handlesGroup(group string) bool {
+	if c.nworkers == 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write(unsafeStrToBytes(group))
+	return h.Sum32()%c.nworkers == c.worker
 }
 
 type articleNotif struct {
-	cmsgid string
-	glpid  uint64
-	ginfo  []struct {
-		group string
-		grpid uint64
-	}
+	cmsgid TCoreMsgIDStr
+	groups []string
 }
 
 func nonBlockArticlePumper(in <-chan articleNotif, out chan<- articleNotif) {
@@ -126,104 +172,579 @@ func nonBlockArticlePumper(in <-chan articleNotif, out chan<- articleNotif) {
 	}
 }
 
+type articleNotifiee interface {
+	NotifyArticle(cmsgid TCoreMsgIDStr, groups []string)
+}
+
+// PushNotifier distributes new article notifications to pushers
+// and to XLISTENing server connections
+type PushNotifier struct {
+	mu      sync.RWMutex
+	pushers map[articleNotifiee]struct{}
+}
+
+func NewPushNotifier() *PushNotifier {
+	return &PushNotifier{pushers: make(map[articleNotifiee]struct{})}
+}
+
+func (n *PushNotifier) add(p articleNotifiee) {
+	n.mu.Lock()
+	n.pushers[p] = struct{}{}
+	n.mu.Unlock()
+}
+
+func (n *PushNotifier) remove(p articleNotifiee) {
+	n.mu.Lock()
+	delete(n.pushers, p)
+	n.mu.Unlock()
+}
+
+func (n *PushNotifier) Add(p *NNTPPusher)    { n.add(p) }
+func (n *PushNotifier) Remove(p *NNTPPusher) { n.remove(p) }
+
+func (n *PushNotifier) NotifyArticle(cmsgid TCoreMsgIDStr, groups []string) {
+	n.mu.RLock()
+	for p := range n.pushers {
+		p.NotifyArticle(cmsgid, groups)
+	}
+	n.mu.RUnlock()
+}
+
 /*
- * planned pipeline:
+ * pipeline:
  * notify func gets called
  * it sends thru 0-sized channel
- * nonBlockArticlePumper receives and sends thru buffered channel
- * sends to worker
+ * nonBlockArticlePumper receives and passes it to worker,
+ * merging multiple notifications into gap if worker is busy
  *
  * worker:
- * initially, checks what groups server can recv
- * clears unsendable-to groups from trackdb
- * adds any new sendable-to groups to trackdb,
- *   initialized to either 0 or {current_max_gpost_num} depending on setting
- * checks for each group if {current_max_gpost_num} > max_gpost_num in trackdb
- * for each such article gets msgid and groups off db, and offers to server
- * for each sent/server didn't need conclusion, writes to db
- * listens on channel and offers to server
- * after every sent/rejected, writes new stats to db
- * if event was nil, does initial check procedure
- *
- * stat writer:
- * should queue and merge writes
- * should disallow write if trackdb is increased more than by one
- * unless there's few pending things
- * or expected tracked ver > actual db tracked ver
- * update algo:
- *   if db_ver >= expected_ver, db_ver := max(new_ver,db_ver)
- * this can fail update if theres mismatch...
- *   but that can be detected, and write held up
- *   until some thread gets notified about gap and fixes it up
- *   note that only single thread should do gap fixing up
- *   that should be guarded by lock
- *
- * determining what groups are sendable-to:
- * we should list current server's groups
- * and also match these with autoadd-wildcards
- * autoadd-wildcards would be obtained by extension
- * or otherwise could be statically set in config
- * static config is sorta ass tbh
- * either way, these would be matched against current grouplist of ours
- * this sendable-to list would need to be periodically rechecked
- * period should be configurable and default to reasonable value (15 mins?)
+ * initially, loads our groups and progress from db
+ * groups we never tracked are initialized to current max on first load,
+ *   and to 0 on later loads (these are new boards)
+ * for each group offers articles above tracked progress,
+ *   using CHECK/TAKETHIS if server supports streaming, IHAVE otherwise
+ * after every batch, writes new progress to db
+ * progress is never moved past article server asked to retry later
+ * if event was gap, rescans all groups
+ * if event concerns groups we don't know about, reloads group list
  *
  * server accept policy:
- * server may either accept only to currently available groups
- * or it may use wildcard expressions to match groups it accepts
- * if server don't specify a way, then whether server can autoaccept
- *   and what it can autoaccept should be configurable
+ * we offer everything and let server reject what it doesn't want,
+ * except articles whose Path already contains peer's path identity
  */
 
-func (c *NNTPPusher) sendArticleToServer(
-	cmsgid string, glpid uint64) (error, bool) {
-	// TODO
-	return nil, false
+func (c *NNTPPusher) loadGroups() error {
+	gl, err := c.db.GetPushGroups()
+	if err != nil {
+		return fmt.Errorf("GetPushGroups() failed: %v", err)
+	}
+	c.groups = c.groups[:0]
+	c.groupsIdx = make(map[string]int)
+	for _, g := range gl {
+		if !c.handlesGroup(g.Group) {
+			continue
+		}
+		if !g.Tracked && !c.loaded {
+			// don't flood peer with our history
+			g.Last = g.Max
+			err = c.db.UpdatePushGroupID(&g, g.Last)
+			if err != nil {
+				return fmt.Errorf("UpdatePushGroupID() failed: %v", err)
+			}
+			g.Tracked = true
+		}
+		c.groupsIdx[g.Group] = len(c.groups)
+		c.groups = append(c.groups, g)
+	}
+	c.loaded = true
+	return nil
+}
+
+func (c *NNTPPusher) doModeStream() (err error, fatal bool) {
+	if !c.s.capStream || c.badStream {
+		return
+	}
+	err = c.w.PrintfLine("MODE STREAM")
+	if err != nil {
+		fatal = true
+		return
+	}
+	code, rest, err, fatal := c.readResponse()
+	if err != nil {
+		return
+	}
+	if code != 203 {
+		c.badStream = true
+		err = fmt.Errorf(
+			"bad mode-stream response %d %q", code, au.TrimWSBytes(rest))
+		return
+	}
+	c.streaming = true
+	return
+}
+
+// writeArticle sends article after header if db has it.
+// If it fails after anything was sent, connection is closed,
+// as terminating dot would make peer take truncated article as whole.
+func (c *NNTPPusher) writeArticle(
+	msgid TCoreMsgIDStr, header func() error) (
+	exists, started bool, err error) {
+
+	var dw io.WriteCloser
+	exists, err = c.db.WriteArticle(msgid, func() (io.Writer, error) {
+		started = true
+		if e := header(); e != nil {
+			return nil, e
+		}
+		dw = c.w.DotWriter()
+		return dw, nil
+	})
+	if err != nil {
+		if started {
+			c.abortConn()
+		}
+		return
+	}
+	if dw != nil {
+		err = dw.Close()
+	}
+	return
 }
 
-func (c *NNTPPusher) pushworker(
-	inev <-chan articleNotif) (err error, fatal bool) {
+var errPushDeferred = errors.New("peer asked to retry later")
+
+// pushes batch using CHECK/TAKETHIS.
+// returns how many leading articles are done.
+func (c *NNTPPusher) streamBatch(
+	arts []PushArticle, peerIDs []string) (done int, err error, fatal bool) {
 
-	// x
-	var ev articleNotif
+	const (
+		stUnknown = iota
+		stWanted
+		stNotWanted
+		stDeferred
+		stSent
+	)
+	st := make([]int, len(arts))
 
-	offer := func() {
-		err = c.w.PrintfLine("IHAVE <%s>", ev.cmsgid)
+	nchecks := 0
+	for i := range arts {
+		if c.seenByPeer(&arts[i], peerIDs) {
+			c.log.LogPrintf(DEBUG,
+				"not offering <%s> as peer is in its path", arts[i].MsgID)
+			st[i] = stNotWanted
+			continue
+		}
+		err = c.w.PrintfLine("CHECK <%s>", arts[i].MsgID)
 		if err != nil {
-			// XXX
-			panic(err)
+			fatal = true
+			return
 		}
-		var code uint
-		code, _, err, fatal = c.readResponse()
+		nchecks++
+	}
+
+	idx := make(map[TCoreMsgIDStr]int, len(arts))
+	for i := range arts {
+		idx[arts[i].MsgID] = i
+	}
+
+	// reads response and finds which article it's about
+	readStreamResponse := func() (code uint, i int, err error, fatal bool) {
+		code, rest, err, fatal := c.readResponse()
 		if err != nil {
-			c.log.LogPrintf(DEBUG, "readResponse() err: %v", err)
 			return
 		}
-		if code == 335 {
-			// server wants
-			err, fatal = c.sendArticleToServer(ev.cmsgid, ev.glpid)
-			if err != nil {
+		c.args, _ = parseResponseArguments(rest, 1, c.args[:0])
+		if len(c.args) == 0 {
+			err = fmt.Errorf(
+				"streaming response %d %q without msgid",
+				code, au.TrimWSBytes(rest))
+			fatal = true
+			return
+		}
+		msgid := c.args[0]
+		if !ValidMessageID(msgid) {
+			err = fmt.Errorf("streaming response with bad msgid %q", msgid)
+			fatal = true
+			return
+		}
+		i, ok := idx[TCoreMsgIDStr(CutMessageID(msgid))]
+		if !ok {
+			err = fmt.Errorf("streaming response with unknown msgid %q", msgid)
+			fatal = true
+			return
+		}
+		return
+	}
+
+	for ; nchecks > 0; nchecks-- {
+		code, i, e, f := readStreamResponse()
+		if e != nil {
+			if code == 500 || code == 501 {
+				// peer doesn't really support streaming
+				c.badStream = true
+				c.streaming = false
+				// we've pipelined, so rest of responses will follow
+				fatal = true
+			}
+			err, fatal = e, fatal || f
+			return
+		}
+		switch code {
+		case 238:
+			st[i] = stWanted
+		case 438:
+			st[i] = stNotWanted
+		case 431:
+			st[i] = stDeferred
+		default:
+			err = fmt.Errorf("unexpected CHECK response %d", code)
+			fatal = true
+			return
+		}
+	}
+
+	nsent := 0
+	for i := range arts {
+		if st[i] != stWanted {
+			continue
+		}
+		var exists, started bool
+		exists, started, err = c.writeArticle(
+			arts[i].MsgID, func() error {
+				return c.w.PrintfLine("TAKETHIS <%s>", arts[i].MsgID)
+			})
+		if err != nil {
+			if started {
+				// stream is in unknown state
+				fatal = true
 				return
 			}
-			code, _, err, fatal = c.readResponse()
+			c.log.LogPrintf(WARN,
+				"failed to obtain article <%s>: %v", arts[i].MsgID, err)
+			err = nil
+			st[i] = stDeferred
+			continue
+		}
+		if !exists {
+			// got deleted in the meantime
+			st[i] = stNotWanted
+			continue
+		}
+		st[i] = stSent
+		nsent++
+	}
+
+	for ; nsent > 0; nsent-- {
+		code, i, e, f := readStreamResponse()
+		if e != nil {
+			err, fatal = e, f
+			return
+		}
+		switch code {
+		case 239:
+			c.log.LogPrintf(DEBUG, "pushed <%s>", arts[i].MsgID)
+		case 439:
+			c.log.LogPrintf(DEBUG, "peer rejected <%s>", arts[i].MsgID)
+		case 400:
+			err = errors.New("peer is not accepting articles anymore")
+			fatal = true
+			return
+		default:
+			err = fmt.Errorf("unexpected TAKETHIS response %d", code)
+			fatal = true
+			return
+		}
+		st[i] = stNotWanted
+	}
+
+	for done < len(arts) && st[done] != stDeferred {
+		done++
+	}
+	if done < len(arts) {
+		err = errPushDeferred
+	}
+	return
+}
+
+// pushes batch using IHAVE.
+// returns how many leading articles are done.
+func (c *NNTPPusher) ihaveBatch(
+	arts []PushArticle, peerIDs []string) (done int, err error, fatal bool) {
+
+	for ; done < len(arts); done++ {
+		a := &arts[done]
+
+		if c.seenByPeer(a, peerIDs) {
+			c.log.LogPrintf(DEBUG,
+				"not offering <%s> as peer is in its path", a.MsgID)
+			continue
+		}
+
+		err = c.w.PrintfLine("IHAVE <%s>", a.MsgID)
+		if err != nil {
+			fatal = true
+			return
+		}
+		var code uint
+		var rest []byte
+		code, rest, err, fatal = c.readResponse()
+		if err != nil {
+			return
+		}
+		switch code {
+		case 335:
+			// peer wants it
+		case 435:
+			// peer doesn't want it
+			continue
+		case 436:
+			err = errPushDeferred
+			return
+		default:
+			err = fmt.Errorf(
+				"bad IHAVE response %d %q", code, au.TrimWSBytes(rest))
+			return
+		}
+
+		var exists, started bool
+		exists, started, err = c.writeArticle(
+			a.MsgID, func() error { return nil })
+		if err != nil {
+			// peer already agreed to take it, and we can't send it.
+			// empty article would make peer reject it for good,
+			// so abort transfer by dropping connection instead
+			if !started {
+				c.log.LogPrintf(WARN,
+					"failed to obtain article <%s>: %v", a.MsgID, err)
+				c.abortConn()
+			}
+			fatal = true
+			return
+		}
+		if !exists {
+			// it got deleted since we offered it.
+			// we have to send something, so send empty article,
+			// peer will reject it
+			err = c.w.DotWriter().Close()
 			if err != nil {
-				c.log.LogPrintf(DEBUG, "readResponse() err: %v", err)
+				fatal = true
 				return
 			}
-			_ = code
-		} else if code == 435 {
-			// server doesn't want
-		} else {
-			// some error
+		}
+
+		code, rest, err, fatal = c.readResponse()
+		if err != nil {
+			return
+		}
+		switch code {
+		case 235:
+			c.log.LogPrintf(DEBUG, "pushed <%s>", a.MsgID)
+		case 436:
+			err = errPushDeferred
+			return
+		case 437:
+			c.log.LogPrintf(DEBUG, "peer rejected <%s>", a.MsgID)
+		default:
+			err = fmt.Errorf(
+				"bad IHAVE transfer response %d %q",
+				code, au.TrimWSBytes(rest))
+			return
 		}
 	}
-	_ = offer
+	return
+}
 
+func (c *NNTPPusher) pushGroup(g *PushGroup) (err error, fatal bool) {
 	for {
-		if ev.cmsgid != "" {
-			// valid event, just try pushing this one
+		var arts []PushArticle
+		arts, err = c.db.ListPushArticles(g, g.Last, pushBatchSize)
+		if err != nil {
+			err = fmt.Errorf("ListPushArticles() failed: %v", err)
+			return
+		}
+		if len(arts) == 0 {
+			return
+		}
+
+		var peerIDs []string
+		if c.peerPathIDs != nil {
+			peerIDs = c.peerPathIDs()
+		}
+
+		var done int
+		if c.streaming {
+			done, err, fatal = c.streamBatch(arts, peerIDs)
 		} else {
-			// gap, figure out how much we need to send
+			done, err, fatal = c.ihaveBatch(arts, peerIDs)
+		}
+
+		if done > 0 {
+			n := arts[done-1].Num
+			if e := c.db.UpdatePushGroupID(g, n); e != nil {
+				c.log.LogPrintf(ERROR, "UpdatePushGroupID() failed: %v", e)
+				if err == nil {
+					err = e
+				}
+				return
+			}
+			g.Last = n
+		}
+
+		if err != nil || len(arts) < pushBatchSize {
+			return
+		}
+	}
+}
+
+// pushes specified groups, or all groups if nil
+func (c *NNTPPusher) pushGroups(groups []string) (err error) {
+	var fatal bool
+
+	pushOne := func(g *PushGroup) bool {
+		err, fatal = c.pushGroup(g)
+		if err != nil {
+			if fatal {
+				return false
+			}
+			if err == errPushDeferred {
+				c.log.LogPrintf(DEBUG, "pushing %q deferred", g.Group)
+			} else {
+				c.log.LogPrintf(WARN, "pushing %q failed: %v", g.Group, err)
+			}
+			err = nil
 		}
+		return true
+	}
+
+	if groups == nil {
+		for i := range c.groups {
+			if !pushOne(&c.groups[i]) {
+				return
+			}
+		}
+		return
+	}
+
+	for _, gn := range groups {
+		if !c.handlesGroup(gn) {
+			continue
+		}
+		i, ok := c.groupsIdx[gn]
+		if !ok {
+			// new group, reload list
+			if err = c.loadGroups(); err != nil {
+				return
+			}
+			if i, ok = c.groupsIdx[gn]; !ok {
+				continue
+			}
+		}
+		if !pushOne(&c.groups[i]) {
+			return
+		}
+	}
+	return
+}
+
+// startSession does initial negotiation on fresh connection
+// and loads groups to push.
+func (c *NNTPPusher) startSession() (err error) {
+	err = c.handleInitial()
+	if err != nil {
+		return
+	}
+
+	var fatal bool
+	err, fatal = c.doCapabilities()
+	if err != nil {
+		if fatal {
+			return fmt.Errorf("doCapabilities() failed: %v", err)
+		}
+		c.log.LogPrintf(WARN, "doCapabilities() failed: %v", err)
+	}
+
+	err, fatal = c.doStartTLS()
+	if err != nil {
+		if fatal {
+			return fmt.Errorf("doStartTLS() failed: %v", err)
+		}
+		c.log.LogPrintf(WARN, "doStartTLS() failed: %v", err)
+	}
+
+	err, fatal = c.doCompress()
+	if err != nil {
+		if fatal {
+			return fmt.Errorf("doCompress() failed: %v", err)
+		}
+		c.log.LogPrintf(WARN, "doCompress() failed: %v", err)
+	}
+
+	c.streaming = false
+	err, fatal = c.doModeStream()
+	if err != nil {
+		if fatal {
+			return fmt.Errorf("doModeStream() failed: %v", err)
+		}
+		c.log.LogPrintf(WARN, "doModeStream() failed: %v", err)
+	}
+
+	return c.loadGroups()
+}
+
+func (c *NNTPPusher) main() (err error) {
+	err = c.startSession()
+	if err != nil {
+		return
+	}
+
+	var groups []string // nil means all
+	t := time.NewTicker(pushRescanInterval)
+	defer t.Stop()
+	for {
+		err = c.pushGroups(groups)
+		if err != nil {
+			return
+		}
+		select {
+		case ev := <-c.out:
+			if ev.cmsgid != "" {
+				groups = ev.groups
+			} else {
+				// gap, we lost some notifications
+				groups = nil
+			}
+		case <-t.C:
+			groups = nil
+		}
+	}
+}
+
+func (c *NNTPPusher) Run(d Dialer, network, address string) {
+	for {
+		c.log.LogPrintf(DEBUG, "dialing...")
+		conn, e := d.Dial(network, address)
+		if e != nil {
+			c.log.LogPrintf(WARN, "error dialing: %v", e)
+			c.log.LogPrintf(WARN, "will wait 10 secs")
+			time.Sleep(10 * time.Second)
+			continue
+		}
+
+		c.s = clientState{}
+		c.conn = conn
+		c.w = tp.NewWriter(bufio.NewWriter(conn))
+		c.r = bufreader.NewBufReader(conn)
+		c.dr = nil
+
+		c.log.LogPrintf(DEBUG, "pushing...")
+
+		e = c.main()
+
+		conn.Close()
+
+		c.log.LogPrintf(WARN, "pusher error: %v", e)
+		c.log.LogPrintf(WARN, "will reconnect after 10 secs")
+		time.Sleep(10 * time.Second)
 	}
 }
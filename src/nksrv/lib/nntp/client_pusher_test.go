@@ -0,0 +1,231 @@
+package nntp_test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"nksrv/lib/nntp"
+	"nksrv/lib/nntp/testsrv"
+)
+
+const pushTestGroup = "test.push"
+
+// testPushDB is in-memory PusherDatabase with single group.
+type testPushDB struct {
+	last    uint64
+	arts    []nntp.PushArticle
+	deleted map[nntp.TCoreMsgIDStr]bool
+	written []nntp.TCoreMsgIDStr
+}
+
+func (db *testPushDB) add(msgid string, path string) {
+	db.arts = append(db.arts, nntp.PushArticle{
+		Num:   uint64(len(db.arts) + 1),
+		MsgID: nntp.TCoreMsgIDStr(msgid),
+		Path:  path,
+	})
+}
+
+func (db *testPushDB) GetPushGroups() ([]nntp.PushGroup, error) {
+	return []nntp.PushGroup{{
+		Group:   pushTestGroup,
+		Last:    db.last,
+		Max:     uint64(len(db.arts)),
+		Tracked: true,
+	}}, nil
+}
+
+func (db *testPushDB) UpdatePushGroupID(g *nntp.PushGroup, id uint64) error {
+	db.last = id
+	return nil
+}
+
+func (db *testPushDB) ListPushArticles(
+	g *nntp.PushGroup, after uint64, limit int) ([]nntp.PushArticle, error) {
+
+	var arts []nntp.PushArticle
+	for _, a := range db.arts {
+		if a.Num > after && len(arts) < limit {
+			arts = append(arts, a)
+		}
+	}
+	return arts, nil
+}
+
+func (db *testPushDB) WriteArticle(
+	msgid nntp.TCoreMsgIDStr, begin func() (io.Writer, error)) (
+	exists bool, err error) {
+
+	if db.deleted[msgid] {
+		return false, nil
+	}
+	w, err := begin()
+	if err != nil {
+		return true, err
+	}
+	db.written = append(db.written, msgid)
+	_, err = fmt.Fprintf(w,
+		"Message-ID: <%s>\nFrom: tester <tester@example.org>\n"+
+			"Newsgroups: %s\nSubject: test\n\ntest body\n",
+		msgid, pushTestGroup)
+	return true, err
+}
+
+// transferLog collects articles testsrv accepted.
+type transferLog struct {
+	mu   sync.Mutex
+	cmds []string
+	ids  []string
+}
+
+func (l *transferLog) add(cmd string, msgid nntp.TCoreMsgIDStr) {
+	l.mu.Lock()
+	l.cmds = append(l.cmds, cmd)
+	l.ids = append(l.ids, string(msgid))
+	l.mu.Unlock()
+}
+
+func (l *transferLog) get() (cmds, ids []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.cmds...), append([]string(nil), l.ids...)
+}
+
+// alreadyThere is message-ID testsrv has in its own data
+const alreadyThere = "nekosnekosnekosnekos@void.neko.test"
+
+func newPushSrv(stream, permit, accept bool, tl *transferLog) *testsrv.TestSrv {
+	return &testsrv.TestSrv{
+		SupportIHave:   true,
+		SupportStream:  stream,
+		TransferPermit: permit,
+		TransferAccept: accept,
+		OnTransfer:     tl.add,
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPusher(t *testing.T) {
+	lgr := newTestLogger(t)
+
+	for _, mode := range []struct {
+		name   string
+		stream bool
+		cmd    string
+	}{
+		{"stream", true, "TAKETHIS"},
+		{"ihave", false, "IHAVE"},
+	} {
+		mode := mode
+		t.Run(mode.name, func(t *testing.T) {
+			push := func(
+				db *testPushDB, permit, accept bool) (
+				cmds, ids []string, err error) {
+
+				tl := &transferLog{}
+				addr, stop := startTestSrv(t, lgr,
+					newPushSrv(mode.stream, permit, accept, tl), nil)
+				defer stop()
+				p := nntp.NewNNTPPusher(db, lgr, 0, 1)
+				p.SetPeerPathIDs(func() []string { return []string{"peer.test"} })
+				err = p.PushOnce(&net.Dialer{}, "tcp4", addr)
+				cmds, ids = tl.get()
+				return
+			}
+
+			db := &testPushDB{
+				deleted: map[nntp.TCoreMsgIDStr]bool{"gone@example.org": true},
+			}
+			db.add("a1@example.org", "")
+			db.add(alreadyThere, "")
+			db.add("gone@example.org", "")
+			db.add("looped@example.org", "other.test!peer.test!not-for-mail")
+			db.add("a2@example.org", "other.test!not-for-mail")
+
+			// peer defers everything, position must stay
+			_, ids, err := push(db, false, true)
+			if err != nil {
+				t.Fatalf("deferred push failed: %v", err)
+			}
+			if len(ids) != 0 || len(db.written) != 0 || db.last != 0 {
+				t.Errorf("deferred push transferred %v, wrote %v, position %d",
+					ids, db.written, db.last)
+			}
+
+			// resumes from where it left off on new connection
+			cmds, ids, err := push(db, true, true)
+			if err != nil {
+				t.Fatalf("push failed: %v", err)
+			}
+			want := []string{"a1@example.org", "a2@example.org"}
+			if !equalStrings(ids, want) {
+				t.Errorf("transferred %v, want %v", ids, want)
+			}
+			for _, cmd := range cmds {
+				if cmd != mode.cmd {
+					t.Errorf("transferred using %s, want %s", cmd, mode.cmd)
+				}
+			}
+			if db.last != 5 {
+				t.Errorf("position %d after push, want 5", db.last)
+			}
+			for _, id := range db.written {
+				if id == alreadyThere || id == "looped@example.org" {
+					t.Errorf("sent %s which peer shouldn't get", id)
+				}
+			}
+
+			// only new articles are offered after reconnect
+			db.add("a3@example.org", "")
+			db.written = nil
+			_, ids, err = push(db, true, true)
+			if err != nil {
+				t.Fatalf("push after reconnect failed: %v", err)
+			}
+			want = []string{"a3@example.org"}
+			if !equalStrings(ids, want) || db.last != 6 {
+				t.Errorf("after reconnect transferred %v position %d, "+
+					"want %v position 6", ids, db.last, want)
+			}
+
+			// rejected articles are done with, not retried
+			db.add("a4@example.org", "")
+			db.written = nil
+			_, ids, err = push(db, true, false)
+			if err != nil {
+				t.Fatalf("rejected push failed: %v", err)
+			}
+			if len(ids) != 0 {
+				t.Errorf("peer rejecting took %v", ids)
+			}
+			if !equalStrings(writtenStrings(db), []string{"a4@example.org"}) {
+				t.Errorf("rejected push wrote %v", db.written)
+			}
+			if db.last != 7 {
+				t.Errorf("position %d after reject, want 7", db.last)
+			}
+		})
+	}
+}
+
+func writtenStrings(db *testPushDB) []string {
+	s := make([]string, len(db.written))
+	for i := range db.written {
+		s[i] = string(db.written[i])
+	}
+	return s
+}
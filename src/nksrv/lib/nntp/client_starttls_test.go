@@ -0,0 +1,87 @@
+package nntp_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"testing"
+
+	"nksrv/lib/nntp"
+	"nksrv/lib/utils/certfp"
+)
+
+// pinnedTLSConfig accepts only server certificate matching pin
+func pinnedTLSConfig(pin certfp.Pin) *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(raw [][]byte, _ [][]*x509.Certificate) error {
+			cert, err := x509.ParseCertificate(raw[0])
+			if err != nil {
+				return err
+			}
+			if !pin.Match(cert) {
+				return errors.New("certificate doesn't match pin")
+			}
+			return nil
+		},
+	}
+}
+
+func TestStartTLS(t *testing.T) {
+	lgr := newTestLogger(t)
+
+	tcert, cert := makeTestCert(t)
+	stcfg := &tls.Config{Certificates: []tls.Certificate{tcert}}
+	tlsAddr, stopTLS := startTestSrv(t, lgr, nil, stcfg)
+	defer stopTLS()
+	plainAddr, stopPlain := startTestSrv(t, lgr, nil, nil)
+	defer stopPlain()
+
+	goodPin := certfp.Pin{
+		Selector:     certfp.SelectorPubKey,
+		MatchingType: certfp.MatchingTypeSHA2_256,
+		Data: certfp.MakeFingerprint(
+			cert, certfp.SelectorPubKey, certfp.MatchingTypeSHA2_256),
+	}
+	badPin := goodPin
+	badPin.Data = make([]byte, len(goodPin.Data))
+
+	for _, tc := range []struct {
+		name    string
+		addr    string
+		mode    nntp.StartTLSMode
+		pin     certfp.Pin
+		tlsDone bool
+		fail    bool
+	}{
+		{"off", tlsAddr, nntp.StartTLSOff, goodPin, false, false},
+		{"try", tlsAddr, nntp.StartTLSOpportunistic, goodPin, true, false},
+		{"require", tlsAddr, nntp.StartTLSRequired, goodPin, true, false},
+		{"try without TLS", plainAddr, nntp.StartTLSOpportunistic, goodPin,
+			false, false},
+		{"require without TLS", plainAddr, nntp.StartTLSRequired, goodPin,
+			false, true},
+		{"try wrong pin", tlsAddr, nntp.StartTLSOpportunistic, badPin,
+			false, true},
+		{"require wrong pin", tlsAddr, nntp.StartTLSRequired, badPin,
+			false, true},
+	} {
+		tlsDone, err, fatal := nntp.StartTLSOnce(
+			&net.Dialer{}, "tcp4", tc.addr, pinnedTLSConfig(tc.pin), tc.mode, lgr)
+		if tc.fail {
+			if err == nil || !fatal {
+				t.Errorf("%s: got err %v fatal %v, want fatal error",
+					tc.name, err, fatal)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if tlsDone != tc.tlsDone {
+			t.Errorf("%s: TLS done %v, want %v", tc.name, tlsDone, tc.tlsDone)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package nntp
+
+// RFC 8054 COMPRESS DEFLATE
+
+import (
+	"bufio"
+	"compress/flate"
+	"io"
+
+	"nksrv/lib/utils/text/bufreader"
+)
+
+// deflateWriter does sync flush after each write.
+// it's meant to be put behind bufio.Writer so flush of that
+// results in all buffered data being sent out.
+type deflateWriter struct {
+	fw *flate.Writer
+}
+
+func (w deflateWriter) Write(b []byte) (n int, err error) {
+	n, err = w.fw.Write(b)
+	if err == nil {
+		err = w.fw.Flush()
+	}
+	return
+}
+
+// deflateReader turns unexpected EOF of underlying connection into EOF,
+// because peers rarely bother finishing deflate stream before closing.
+// truncated dot-terminated data will be still noticed by DotReader.
+type deflateReader struct {
+	fr io.ReadCloser
+}
+
+func (r deflateReader) Read(b []byte) (n int, err error) {
+	n, err = r.fr.Read(b)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return
+}
+
+// startDeflate switches both directions to use raw deflate.
+// u is underlying connection (after TLS, if any).
+// caller must ensure nothing is left buffered in w.
+func startDeflate(
+	r *bufreader.BufReader, w *bufio.Writer, u io.ReadWriter) {
+
+	r.SetReader(deflateReader{flate.NewReader(u)})
+	// error is possible only with invalid level
+	fw, _ := flate.NewWriter(u, flate.DefaultCompression)
+	w.Reset(deflateWriter{fw})
+}
+
+func cmdCompress(c *ConnState, args [][]byte, rest []byte) bool {
+	ToUpperASCII(args[0])
+	if unsafeBytesToStr(args[0]) != "DEFLATE" {
+		AbortOnErr(c.w.PrintfLine("503 compression algorithm not supported"))
+		return true
+	}
+	if c.deflate {
+		AbortOnErr(c.w.PrintfLine("502 compression already active"))
+		return true
+	}
+	if len(c.r.Buffered()) != 0 {
+		// COMPRESS must not be pipelined, and we've already read
+		// data which was supposed to be compressed
+		AbortOnErr(c.w.PrintfLine("501 COMPRESS must not be pipelined"))
+		return false
+	}
+
+	AbortOnErr(c.w.PrintfLine("206 compression active"))
+
+	var u io.ReadWriter = c.conn
+	if c.tlsConn != nil {
+		u = c.tlsConn
+	}
+	startDeflate(c.r, c.w.Writer.W, u)
+	c.deflate = true
+
+	return true
+}
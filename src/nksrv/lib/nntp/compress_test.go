@@ -0,0 +1,225 @@
+package nntp_test
+
+import (
+	"bufio"
+	"compress/flate"
+	"crypto/tls"
+	"io"
+	"net"
+	tp "net/textproto"
+	"strings"
+	"testing"
+
+	"nksrv/lib/nntp"
+)
+
+// rawConn is hand-driven client, for checking server side.
+type rawConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *tp.Reader
+	w    *tp.Writer
+}
+
+func dialRaw(t *testing.T, addr string) *rawConn {
+	conn, err := net.Dial("tcp4", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &rawConn{t: t, conn: conn}
+	c.setRW(conn, conn)
+	c.expect("", 200)
+	return c
+}
+
+// startTLS does STARTTLS, without checking server certificate
+func (c *rawConn) startTLS() *tls.Conn {
+	c.t.Helper()
+	c.expect("STARTTLS", 382)
+	tc := tls.Client(c.conn, &tls.Config{InsecureSkipVerify: true})
+	if err := tc.Handshake(); err != nil {
+		c.t.Fatal(err)
+	}
+	c.setRW(tc, tc)
+	return tc
+}
+
+func (c *rawConn) setRW(r io.Reader, w io.Writer) {
+	c.r = tp.NewReader(bufio.NewReader(r))
+	c.w = tp.NewWriter(bufio.NewWriter(w))
+}
+
+// syncFlusher flushes deflate stream after each write
+type syncFlusher struct{ fw *flate.Writer }
+
+func (w syncFlusher) Write(b []byte) (n int, err error) {
+	if n, err = w.fw.Write(b); err == nil {
+		err = w.fw.Flush()
+	}
+	return
+}
+
+func (c *rawConn) startDeflate(u io.ReadWriter) {
+	fw, _ := flate.NewWriter(u, flate.DefaultCompression)
+	c.setRW(flate.NewReader(u), syncFlusher{fw})
+}
+
+// expect sends cmd, unless it's empty, and checks response code
+func (c *rawConn) expect(cmd string, code int) string {
+	c.t.Helper()
+	if cmd != "" {
+		if err := c.w.PrintfLine("%s", cmd); err != nil {
+			c.t.Fatalf("%s: write failed: %v", cmd, err)
+		}
+	}
+	got, msg, err := c.r.ReadCodeLine(0)
+	if err != nil {
+		c.t.Fatalf("%s: read failed: %v", cmd, err)
+	}
+	if got != code {
+		c.t.Fatalf("%s: got %d %q, want %d", cmd, got, msg, code)
+	}
+	return msg
+}
+
+func (c *rawConn) expectLines(cmd string, code int) []string {
+	c.t.Helper()
+	c.expect(cmd, code)
+	lines, err := c.r.ReadDotLines()
+	if err != nil {
+		c.t.Fatalf("%s: reading lines failed: %v", cmd, err)
+	}
+	return lines
+}
+
+func hasCap(caps []string, name string) bool {
+	for _, l := range caps {
+		if l == name || strings.HasPrefix(l, name+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+const testArticleCmd = "ARTICLE <" + alreadyThere + ">"
+
+func TestServerCompress(t *testing.T) {
+	lgr := newTestLogger(t)
+	tcert, _ := makeTestCert(t)
+	addr, stop := startTestSrv(t, lgr, nil,
+		&tls.Config{Certificates: []tls.Certificate{tcert}})
+	defer stop()
+
+	// reference output, without compression
+	c := dialRaw(t, addr)
+	list := c.expectLines("LIST ACTIVE", 215)
+	art := c.expectLines(testArticleCmd, 220)
+	c.expect("COMPRESS GZIP", 503)
+	c.conn.Close()
+
+	c = dialRaw(t, addr)
+	defer c.conn.Close()
+	caps := c.expectLines("CAPABILITIES", 101)
+	if !hasCap(caps, "COMPRESS") || !hasCap(caps, "STARTTLS") {
+		t.Errorf("COMPRESS or STARTTLS not offered: %q", caps)
+	}
+	c.expect("COMPRESS DEFLATE", 206)
+	c.startDeflate(c.conn)
+
+	caps = c.expectLines("CAPABILITIES", 101)
+	if hasCap(caps, "COMPRESS") || hasCap(caps, "STARTTLS") {
+		t.Errorf("COMPRESS or STARTTLS offered after activation: %q", caps)
+	}
+	c.expect("COMPRESS DEFLATE", 502)
+	// TLS can't go on top of compression
+	c.expect("STARTTLS", 502)
+
+	if got := c.expectLines("LIST ACTIVE", 215); !equalStrings(got, list) {
+		t.Errorf("compressed LIST ACTIVE %q, want %q", got, list)
+	}
+	if got := c.expectLines(testArticleCmd, 220); !equalStrings(got, art) {
+		t.Errorf("compressed ARTICLE %q, want %q", got, art)
+	}
+	c.expect("QUIT", 205)
+}
+
+func TestServerCompressAfterTLS(t *testing.T) {
+	lgr := newTestLogger(t)
+	tcert, _ := makeTestCert(t)
+	addr, stop := startTestSrv(t, lgr, nil,
+		&tls.Config{Certificates: []tls.Certificate{tcert}})
+	defer stop()
+
+	c := dialRaw(t, addr)
+	defer c.conn.Close()
+	list := c.expectLines("LIST ACTIVE", 215)
+
+	tc := c.startTLS()
+
+	caps := c.expectLines("CAPABILITIES", 101)
+	if !hasCap(caps, "COMPRESS") || hasCap(caps, "STARTTLS") {
+		t.Errorf("bad capabilities after STARTTLS: %q", caps)
+	}
+	c.expect("COMPRESS DEFLATE", 206)
+	c.startDeflate(tc)
+
+	if got := c.expectLines("LIST ACTIVE", 215); !equalStrings(got, list) {
+		t.Errorf("LIST ACTIVE over TLS+COMPRESS %q, want %q", got, list)
+	}
+	c.expect("COMPRESS DEFLATE", 502)
+}
+
+func TestClientCompress(t *testing.T) {
+	lgr := newTestLogger(t)
+	tcert, _ := makeTestCert(t)
+	addr, stop := startTestSrv(t, lgr, nil,
+		&tls.Config{Certificates: []tls.Certificate{tcert}})
+	defer stop()
+
+	cmds := []string{"LIST ACTIVE", testArticleCmd}
+	ref, err := nntp.Session(
+		&net.Dialer{}, "tcp4", addr, nil, false, cmds, lgr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.DeflateDone || len(ref.Outputs[0]) == 0 || len(ref.Outputs[1]) == 0 {
+		t.Fatalf("bad reference session: %+v", ref)
+	}
+
+	cmds = append(cmds, "CAPABILITIES", "COMPRESS DEFLATE")
+	for _, tc := range []struct {
+		name string
+		tcfg *tls.Config
+	}{
+		{"plain", nil},
+		{"tls", &tls.Config{InsecureSkipVerify: true}},
+	} {
+		res, err := nntp.Session(
+			&net.Dialer{}, "tcp4", addr, tc.tcfg, true, cmds, lgr)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !res.DeflateDone || res.TLSDone != (tc.tcfg != nil) {
+			t.Errorf("%s: deflate %v tls %v",
+				tc.name, res.DeflateDone, res.TLSDone)
+		}
+		for i := 0; i < 2; i++ {
+			if res.Codes[i] != ref.Codes[i] ||
+				res.Outputs[i] != ref.Outputs[i] {
+
+				t.Errorf("%s: %s over compression gave %d %q, want %d %q",
+					tc.name, cmds[i], res.Codes[i], res.Outputs[i],
+					ref.Codes[i], ref.Outputs[i])
+			}
+		}
+		if strings.Contains(res.Outputs[2], "COMPRESS") {
+			t.Errorf("%s: COMPRESS offered after activation: %q",
+				tc.name, res.Outputs[2])
+		}
+		if res.Codes[3] != 502 {
+			t.Errorf("%s: second COMPRESS got %d, want 502",
+				tc.name, res.Codes[3])
+		}
+	}
+}
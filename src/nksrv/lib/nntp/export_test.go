@@ -0,0 +1,143 @@
+package nntp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	tp "net/textproto"
+
+	. "nksrv/lib/utils/logx"
+	"nksrv/lib/utils/text/bufreader"
+)
+
+// StartTLSOnce connects to server the same way pullers and pushers do,
+// and tells whether connection ended up upgraded to TLS.
+func StartTLSOnce(
+	d Dialer, network, address string,
+	cfg *tls.Config, mode StartTLSMode, logx LoggerX) (
+	tlsDone bool, err error, fatal bool) {
+
+	conn, err := d.Dial(network, address)
+	if err != nil {
+		return false, err, true
+	}
+	c := &NNTPPuller{}
+	c.log = NewLogToX(logx, "starttls-test")
+	c.SetStartTLS(cfg, mode)
+	c.conn = conn
+	c.w = tp.NewWriter(bufio.NewWriter(conn))
+	c.r = bufreader.NewBufReader(conn)
+	defer c.abortConn()
+
+	if err = c.handleInitial(); err != nil {
+		return false, err, true
+	}
+	if err, fatal = c.doCapabilities(); err != nil {
+		return
+	}
+	err, fatal = c.doStartTLS()
+	return c.s.tlsDone, err, fatal
+}
+
+// PushOnce connects to server, pushes whatever is pending once
+// and disconnects, so that tests don't have to stop Run.
+func (c *NNTPPusher) PushOnce(d Dialer, network, address string) error {
+	conn, err := d.Dial(network, address)
+	if err != nil {
+		return err
+	}
+	c.s = clientState{}
+	c.conn = conn
+	c.w = tp.NewWriter(bufio.NewWriter(conn))
+	c.r = bufreader.NewBufReader(conn)
+	c.dr = nil
+	defer c.abortConn()
+
+	if err = c.startSession(); err != nil {
+		return err
+	}
+	return c.pushGroups(nil)
+}
+
+// SessionResult is what Session saw.
+type SessionResult struct {
+	TLSDone     bool
+	DeflateDone bool
+	Codes       []uint   // response code of each command
+	Outputs     []string // dot-terminated output of each command, if any
+}
+
+// responses followed by dot-terminated data
+var multiLineCodes = map[uint]bool{
+	100: true, 101: true, 215: true, 220: true, 221: true,
+	222: true, 224: true, 225: true, 230: true, 231: true,
+}
+
+// Session connects to server, does STARTTLS if cfg isn't nil
+// and COMPRESS DEFLATE if compress is set, the same way pullers
+// and pushers do, and then runs cmds, reading their output
+// through client's DotReader.
+func Session(
+	d Dialer, network, address string, cfg *tls.Config, compress bool,
+	cmds []string, logx LoggerX) (res SessionResult, err error) {
+
+	conn, err := d.Dial(network, address)
+	if err != nil {
+		return
+	}
+	c := &NNTPPuller{}
+	c.log = NewLogToX(logx, "session-test")
+	if cfg != nil {
+		c.SetStartTLS(cfg, StartTLSRequired)
+	}
+	c.conn = conn
+	c.w = tp.NewWriter(bufio.NewWriter(conn))
+	c.r = bufreader.NewBufReader(conn)
+	defer c.abortConn()
+
+	if err = c.handleInitial(); err != nil {
+		return
+	}
+	if err, _ = c.doCapabilities(); err != nil {
+		return
+	}
+	if err, _ = c.doStartTLS(); err != nil {
+		return
+	}
+	res.TLSDone = c.s.tlsDone
+	if compress {
+		if err, _ = c.doCompress(); err != nil {
+			return
+		}
+	}
+	res.DeflateDone = c.s.deflateDone
+
+	for _, cmd := range cmds {
+		if err = c.w.PrintfLine("%s", cmd); err != nil {
+			return
+		}
+		var code uint
+		code, _, err, _ = c.readResponse()
+		if err != nil {
+			return
+		}
+		res.Codes = append(res.Codes, code)
+		var out []byte
+		if multiLineCodes[code] {
+			dr := c.openDotReader()
+			for {
+				line, e := c.readDotLine(dr)
+				if e != nil {
+					if e != io.EOF {
+						err = e
+						return
+					}
+					break
+				}
+				out = append(append(out, line...), '\n')
+			}
+		}
+		res.Outputs = append(res.Outputs, string(out))
+	}
+	return
+}
@@ -0,0 +1,79 @@
+package nntp_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"nksrv/lib/nntp"
+	"nksrv/lib/nntp/testsrv"
+	. "nksrv/lib/utils/logx"
+	fl "nksrv/lib/utils/logx/filelogger"
+)
+
+func newTestLogger(t *testing.T) LoggerX {
+	lgr, err := fl.NewFileLogger(os.Stderr, ERROR, fl.ColorOff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lgr
+}
+
+func makeTestCert(t *testing.T) (tls.Certificate, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, cert
+}
+
+// startTestSrv runs prov, or default testsrv if it's nil,
+// with STARTTLS if tcfg isn't nil.
+func startTestSrv(
+	t *testing.T, lgr LoggerX, prov *testsrv.TestSrv, tcfg *tls.Config) (
+	addr string, stop func()) {
+
+	rcfg := nntp.DefaultNNTPServerRunCfg
+	rcfg.TLSConfig = tcfg
+	return startTestSrvCfg(t, lgr, prov, &rcfg)
+}
+
+// startTestSrvCfg is startTestSrv with full control over server config
+func startTestSrvCfg(
+	t *testing.T, lgr LoggerX, prov *testsrv.TestSrv,
+	rcfg *nntp.NNTPServerRunCfg) (addr string, stop func()) {
+
+	if prov == nil {
+		prov = &testsrv.TestSrv{}
+	}
+	prov.Log = NewLogToX(lgr, "testsrv")
+	srv := nntp.NewNNTPServer(prov, lgr, rcfg)
+	l, err := srv.Listen("tcp4", "127.0.0.1:0", nntp.ListenParam{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	return l.Addr().String(), func() { srv.Close() }
+}
@@ -0,0 +1,268 @@
+package nntp
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	. "nksrv/lib/utils/logx"
+)
+
+// ConnLimits caps amount of connections server accepts.
+// Zero values mean no limit.
+type ConnLimits struct {
+	MaxConns      int // total
+	MaxConnsPerIP int // per source address prefix, until client logs in as peer
+	IPv4Prefix    int // bits of IPv4 address grouped together, 32 if 0
+	IPv6Prefix    int // bits of IPv6 address grouped together, 128 if 0
+
+	MaxConnsPerPeer int // per authenticated peer (UserInfo.Serv)
+}
+
+// RateLimits limits rates of single connection.
+// Rates are per second, zero rate means no limit.
+// Zero burst means rate rounded up (but at least 1).
+// Commands over rate are delayed. Connection is closed only if
+// token is too far away or client keeps going over rate for too long.
+type RateLimits struct {
+	CommandRate  float64
+	CommandBurst int
+	ArticleRate  float64 // POST, IHAVE and TAKETHIS
+	ArticleBurst int
+}
+
+// ServerStats is snapshot of server's connection counters.
+type ServerStats struct {
+	Conns         int            // currently open connections
+	ConnsByPrefix map[string]int // not logged in as peer, by source prefix
+	ConnsByPeer   map[string]int // logged in as peer, by peer name
+	RejectedConns uint64         // refused because of connection limits
+	RateLimited   uint64         // closed because of rate limits
+}
+
+type connEntry struct {
+	prefix string // set if counted per source
+	peer   string // set if counted per peer
+}
+
+// longest single command is delayed waiting for token
+const maxRateWait = 10 * time.Second
+
+// how long client may keep going over rate before it's considered abuse
+const maxRateAbuse = 5 * time.Minute
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	over   time.Time // since when client is going over rate, zero if not
+}
+
+func (b *tokenBucket) reset(rate float64, burst int, now time.Time) {
+	b.rate = rate
+	if burst <= 0 {
+		burst = int(rate)
+		if float64(burst) < rate {
+			burst++
+		}
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	b.burst = float64(burst)
+	b.tokens = b.burst
+	b.last = now
+	b.over = time.Time{}
+}
+
+// take takes token, even if it's not there yet,
+// and returns how long to wait for it.
+// If that's too long, or client is going over rate for too long,
+// nothing is taken and ok is false.
+func (b *tokenBucket) take(now time.Time) (wait time.Duration, ok bool) {
+	if b.rate <= 0 {
+		return 0, true
+	}
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		b.over = time.Time{}
+		return 0, true
+	}
+	if b.over.IsZero() {
+		b.over = now
+	}
+	wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait > maxRateWait || now.Sub(b.over) > maxRateAbuse {
+		return wait, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+// throttle waits for token from bucket.
+// It returns false if client is abusing rate and should be dropped.
+func (c *ConnState) throttle(b *tokenBucket, what string) bool {
+	wait, ok := b.take(time.Now())
+	if !ok {
+		c.srv.countRateLimited()
+		c.log.LogPrintf(WARN, "%s rate limit exceeded", what)
+		return false
+	}
+	if wait > 0 {
+		c.log.LogPrintf(DEBUG, "%s rate limit reached, waiting %v", what, wait)
+		time.Sleep(wait)
+	}
+	return true
+}
+
+func (c *ConnState) setRates(r RateLimits) {
+	now := time.Now()
+	c.cmdBucket.reset(r.CommandRate, r.CommandBurst, now)
+	c.artBucket.reset(r.ArticleRate, r.ArticleBurst, now)
+}
+
+// addrPrefix returns string identifying group of addresses a belongs to.
+func addrPrefix(a net.Addr, v4bits, v6bits int) string {
+	var ip net.IP
+	if ta, ok := a.(*net.TCPAddr); ok {
+		ip = ta.IP
+	} else if h, _, e := net.SplitHostPort(a.String()); e == nil {
+		ip = net.ParseIP(h)
+	}
+	if ip == nil {
+		// not IP, each one is on its own
+		return a.String()
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		if v4bits <= 0 || v4bits > 32 {
+			v4bits = 32
+		}
+		return ip4.Mask(net.CIDRMask(v4bits, 32)).String() +
+			"/" + strconv.Itoa(v4bits)
+	}
+	if v6bits <= 0 || v6bits > 128 {
+		v6bits = 128
+	}
+	return ip.Mask(net.CIDRMask(v6bits, 128)).String() +
+		"/" + strconv.Itoa(v6bits)
+}
+
+// registerConnAndWorker starts tracking new connection.
+// It returns false if connection is over limits, in which case
+// it's not tracked but worker still needs to finish.
+func (s *NNTPServer) registerConnAndWorker(
+	c ConnCW, lim *ConnLimits) bool {
+
+	prefix := addrPrefix(c.RemoteAddr(), lim.IPv4Prefix, lim.IPv6Prefix)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cwg.Add(1)
+
+	if (lim.MaxConns > 0 && len(s.connections) >= lim.MaxConns) ||
+		(lim.MaxConnsPerIP > 0 && s.prefixConns[prefix] >= lim.MaxConnsPerIP) {
+
+		s.rejectedConns++
+		return false
+	}
+
+	s.connections[c] = &connEntry{prefix: prefix}
+	s.prefixConns[prefix]++
+	return true
+}
+
+func (s *NNTPServer) unregisterConn(c ConnCW) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.connections[c]
+	if e == nil {
+		return
+	}
+	delete(s.connections, c)
+	if e.prefix != "" {
+		decCount(s.prefixConns, e.prefix)
+	}
+	if e.peer != "" {
+		decCount(s.peerConns, e.peer)
+	}
+}
+
+func decCount(m map[string]int, k string) {
+	if m[k] > 1 {
+		m[k]--
+	} else {
+		delete(m, k)
+	}
+}
+
+// switchToPeer moves connection from per-source accounting to per-peer one.
+// It returns false if peer has too many connections already.
+func (s *NNTPServer) switchToPeer(c ConnCW, peer string, max int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.connections[c]
+	if e == nil {
+		// shouldn't happen
+		return true
+	}
+	if max > 0 && s.peerConns[peer] >= max {
+		return false
+	}
+	if e.prefix != "" {
+		decCount(s.prefixConns, e.prefix)
+		e.prefix = ""
+	}
+	e.peer = peer
+	s.peerConns[peer]++
+	return true
+}
+
+func (s *NNTPServer) countRateLimited() {
+	s.mu.Lock()
+	s.rateLimited++
+	s.mu.Unlock()
+}
+
+// Stats returns current connection counters.
+func (s *NNTPServer) Stats() (st ServerStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st.Conns = len(s.connections)
+	st.ConnsByPrefix = make(map[string]int, len(s.prefixConns))
+	for k, v := range s.prefixConns {
+		st.ConnsByPrefix[k] = v
+	}
+	st.ConnsByPeer = make(map[string]int, len(s.peerConns))
+	for k, v := range s.peerConns {
+		st.ConnsByPeer[k] = v
+	}
+	st.RejectedConns = s.rejectedConns
+	st.RateLimited = s.rateLimited
+	return
+}
+
+func (s *NNTPServer) rejectConnection(c ConnCW, nntps bool) {
+	defer s.cwg.Done()
+
+	s.log.LogPrintf(WARN,
+		"rejecting %s on %s: too many connections",
+		c.RemoteAddr(), c.LocalAddr())
+
+	// don't bother with TLS handshake, it'd be waste of resources
+	if !nntps {
+		_ = c.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		_, _ = c.Write([]byte("400 too many connections, try again later\r\n"))
+		_ = c.SetLinger(-1)
+	}
+	c.Close()
+}
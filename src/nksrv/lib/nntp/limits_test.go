@@ -0,0 +1,62 @@
+package nntp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var b tokenBucket
+	b.reset(2, 2, now)
+
+	// burst goes through right away
+	for i := 0; i < 2; i++ {
+		if w, ok := b.take(now); !ok || w != 0 {
+			t.Fatalf("take %d = %v, %v; want 0, true", i, w, ok)
+		}
+	}
+	// then we wait for token instead of failing
+	w, ok := b.take(now)
+	if !ok || w != 500*time.Millisecond {
+		t.Fatalf("take = %v, %v; want 500ms, true", w, ok)
+	}
+	// client which waits as told keeps going
+	now = now.Add(w)
+	w, ok = b.take(now)
+	if !ok || w != 500*time.Millisecond {
+		t.Fatalf("take after wait = %v, %v; want 500ms, true", w, ok)
+	}
+	// pause lets bucket refill and forgets about going over rate
+	now = now.Add(time.Minute)
+	if w, ok = b.take(now); !ok || w != 0 || !b.over.IsZero() {
+		t.Fatalf("take after pause = %v, %v; want 0, true", w, ok)
+	}
+
+	// keeping going over rate for too long is abuse
+	b.reset(2, 1, now)
+	b.take(now)
+	for end := now.Add(maxRateAbuse); !now.After(end); now = now.Add(w) {
+		if w, ok = b.take(now); !ok {
+			t.Fatalf("take failed %v before limit", end.Sub(now))
+		}
+	}
+	if _, ok = b.take(now); ok {
+		t.Fatalf("take succeeded after going over rate for %v", maxRateAbuse)
+	}
+
+	// token too far away
+	b.reset(1/(2*maxRateWait.Seconds()), 1, now)
+	b.take(now)
+	if _, ok = b.take(now); ok {
+		t.Fatalf("take succeeded with wait over %v", maxRateWait)
+	}
+
+	// no limit
+	b.reset(0, 0, now)
+	for i := 0; i < 100; i++ {
+		if w, ok := b.take(now); !ok || w != 0 {
+			t.Fatalf("unlimited take = %v, %v", w, ok)
+		}
+	}
+}
@@ -0,0 +1,492 @@
+package nntpfeedcfg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+
+	"nksrv/lib/nntp"
+	"nksrv/lib/utils/certfp"
+	. "nksrv/lib/utils/logx"
+	"nksrv/lib/utils/xdialer"
+)
+
+// FeedDatabase provides per-peer progress tracking.
+// name is peer's name from config.
+type FeedDatabase interface {
+	NewPullerDB(name string) (nntp.PullerDatabase, error)
+	NewPusherDB(name string) (nntp.PusherDatabase, error)
+}
+
+type feedServer struct {
+	srv       *nntp.NNTPServer
+	listeners map[BindCfg]nntp.ListenerCW
+}
+
+// Feed runs servers and peers described by Cfg.
+type Feed struct {
+	log  Logger
+	logx LoggerX
+	prov nntp.NNTPProvider
+	db   FeedDatabase
+	pn   *nntp.PushNotifier
+
+	// path identities peers were seen using, shared by all servers
+	pathIDs *nntp.PeerPathIDs
+
+	mu      sync.Mutex
+	cfg     *Cfg
+	started bool
+	servers map[string]*feedServer
+	peers   map[string]*Peer // peers which were started
+	sched   *nntp.PullScheduler
+	pushers []*nntp.NNTPPusher
+}
+
+// NewFeed makes new feed out of config.
+// pn may be nil if pushers don't need to be notified about new articles.
+func NewFeed(
+	cfg *Cfg, prov nntp.NNTPProvider, db FeedDatabase,
+	pn *nntp.PushNotifier, logx LoggerX) *Feed {
+
+	f := &Feed{
+		logx:    logx,
+		prov:    prov,
+		db:      db,
+		pn:      pn,
+		cfg:     cfg,
+		servers: make(map[string]*feedServer),
+		peers:   make(map[string]*Peer),
+		pathIDs: nntp.NewPeerPathIDs(),
+		sched:   nntp.NewPullScheduler(logx),
+	}
+	f.log = NewLogToX(logx, fmt.Sprintf("nntpfeed.%p", f))
+	return f
+}
+
+// tlsDialer does TLS handshake after connecting
+type tlsDialer struct {
+	d   xdialer.Dialer
+	cfg *tls.Config
+}
+
+func (d tlsDialer) Dial(network, address string) (net.Conn, error) {
+	c, err := d.d.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	cfg := d.cfg
+	if cfg.ServerName == "" {
+		cfg = cfg.Clone()
+		cfg.ServerName, _, _ = net.SplitHostPort(address)
+	}
+	tc := tls.Client(c, cfg)
+	_ = c.SetDeadline(time.Now().Add(nntp.TLSHandshakeTimeout))
+	if err = tc.Handshake(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	_ = c.SetDeadline(time.Time{})
+	return tc, nil
+}
+
+// verifyPins makes sure server certificate matches one of pins.
+func verifyPins(pins []certfp.Pin) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server presented no certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		for _, pin := range pins {
+			if pin.Match(cert) {
+				return nil
+			}
+		}
+		return errors.New("server certificate doesn't match any pin")
+	}
+}
+
+// peerTLSConfig makes TLS config for connections to peer,
+// used for both nntps and STARTTLS.
+// Server certificate is verified against system roots unless
+// pins are set, in which case they're checked instead,
+// as peers often use self-signed certs.
+func peerTLSConfig(p *Peer, host string) *tls.Config {
+	tcfg := &tls.Config{ServerName: host}
+	if h, _, e := net.SplitHostPort(host); e == nil {
+		tcfg.ServerName = h
+	}
+	if p.DialCert != nil {
+		// nksrv peers can recognise us by it (certfp_autoauth)
+		tcfg.Certificates = []tls.Certificate{*p.DialCert}
+	}
+	if len(p.DialPins) != 0 {
+		tcfg.InsecureSkipVerify = true
+		tcfg.VerifyPeerCertificate = verifyPins(p.DialPins)
+	} else if p.DialInsecure {
+		tcfg.InsecureSkipVerify = true
+	}
+	return tcfg
+}
+
+func (f *Feed) warnInsecure(name string, p *Peer) {
+	if p.DialInsecure && len(p.DialPins) == 0 {
+		f.log.LogPrintf(WARN,
+			"peer %q: dial_insecure set, server certificate won't be checked",
+			name)
+	}
+}
+
+func (f *Feed) peerDialer(
+	name string, p *Peer) (
+	d nntp.Dialer, proto, host string, tcfg *tls.Config, err error) {
+
+	xd, proto, host, err := xdialer.XDial(p.DialAddr)
+	if err != nil {
+		return
+	}
+	d = xd
+	if proto == "nntps" || proto == "tls" {
+		proto = "tcp"
+		tcfg = peerTLSConfig(p, host)
+		d = tlsDialer{d: xd, cfg: tcfg}
+		f.warnInsecure(name, p)
+		if p.DialTLS != nntp.StartTLSOff {
+			f.log.LogPrintf(WARN,
+				"peer %q: dial_starttls is useless with nntps, ignoring", name)
+		}
+	} else if p.DialTLS != nntp.StartTLSOff {
+		tcfg = peerTLSConfig(p, host)
+		f.warnInsecure(name, p)
+	} else if p.DialCert != nil || len(p.DialPins) != 0 || p.DialInsecure {
+		f.log.LogPrintf(WARN,
+			"peer %q: dial_cert, dial_pins and dial_insecure "+
+				"are useless without TLS, ignoring",
+			name)
+	}
+	return
+}
+
+// peerPathIDs returns function listing path identities of peer,
+// both configured and seen in articles it sent us.
+func (f *Feed) peerPathIDs(name string) func() []string {
+	return func() []string {
+		f.mu.Lock()
+		cids := f.cfg.PathIDs[name]
+		f.mu.Unlock()
+		sids := f.pathIDs.Get(name)
+		if len(cids) == 0 {
+			return sids
+		}
+		return append(append([]string(nil), cids...), sids...)
+	}
+}
+
+// samePeer tells whether peer settings are equal, ignoring certificate.
+func samePeer(a, b *Peer) bool {
+	x, y := *a, *b
+	x.DialCert, y.DialCert = nil, nil
+	return reflect.DeepEqual(x, y)
+}
+
+func (f *Feed) serve(srv *nntp.NNTPServer, l nntp.ListenerCW) {
+	err := srv.Serve(l)
+	if err != nil {
+		f.log.LogPrintf(ERROR, "Serve on %s returned: %v", l.Addr(), err)
+	}
+}
+
+// apply makes running state match cfg.
+// Everything which can fail is done before anything is changed,
+// so on error running state is left as it was.
+// Peers are registered in DB and get their pull rules recorded
+// only after their dialers were set up successfully.
+// Only exception is listeners which are no longer needed,
+// they're closed first so that new ones could take their addresses,
+// and are reopened on error.
+func (f *Feed) apply(cfg *Cfg) (err error) {
+	type newListener struct {
+		fs *feedServer
+		b  BindCfg
+		l  nntp.ListenerCW
+	}
+	type oldListener struct {
+		fs *feedServer
+		b  BindCfg
+	}
+	var opened []newListener
+	var closed []oldListener
+	defer func() {
+		if err != nil {
+			for _, n := range opened {
+				n.l.Close()
+			}
+			for _, o := range closed {
+				l, e := o.fs.srv.Listen(o.b.Network, o.b.Addr, o.b.ListenParam)
+				if e != nil {
+					f.log.LogPrintf(ERROR,
+						"failed to reopen listener {%s}%s: %v",
+						o.b.Network, o.b.Addr, e)
+					continue
+				}
+				o.fs.listeners[o.b] = l
+				go f.serve(o.fs.srv, l)
+			}
+		}
+	}()
+
+	for name, fs := range f.servers {
+		var keep map[BindCfg]struct{}
+		if sc := cfg.Servers[name]; sc != nil {
+			keep = make(map[BindCfg]struct{}, len(sc.BindCfg))
+			for _, b := range sc.BindCfg {
+				keep[b] = struct{}{}
+			}
+		}
+		for b, l := range fs.listeners {
+			if _, ok := keep[b]; ok {
+				continue
+			}
+			f.log.LogPrintf(NOTICE,
+				"server %q: closing listener {%s}%s", name, b.Network, b.Addr)
+			fs.srv.CloseListener(l)
+			delete(fs.listeners, b)
+			closed = append(closed, oldListener{fs: fs, b: b})
+		}
+	}
+
+	runCfgs := make(map[string]*nntp.NNTPServerRunCfg, len(cfg.Servers))
+	newServers := make(map[string]*feedServer)
+	for name, sc := range cfg.Servers {
+		rcfg := sc.RunCfg
+		rcfg.PeerPathIDs = f.pathIDs
+		rcfg.Notifier = f.pn
+		runCfgs[name] = &rcfg
+
+		fs := f.servers[name]
+		if fs == nil {
+			fs = &feedServer{
+				srv:       nntp.NewNNTPServer(f.prov, f.logx, &rcfg),
+				listeners: make(map[BindCfg]nntp.ListenerCW),
+			}
+			newServers[name] = fs
+		}
+		for _, b := range sc.BindCfg {
+			if _, ok := fs.listeners[b]; ok {
+				// already listening
+				continue
+			}
+			l, e := fs.srv.Listen(b.Network, b.Addr, b.ListenParam)
+			if e != nil {
+				return fmt.Errorf("server %q: %v", name, e)
+			}
+			opened = append(opened, newListener{fs: fs, b: b, l: l})
+		}
+	}
+
+	var pullers []*nntp.NNTPPuller
+	var pushers []*nntp.NNTPPusher
+	type peerRun struct {
+		name        string
+		d           nntp.Dialer
+		proto, host string
+	}
+	type newPeer struct {
+		p    *Peer
+		r    peerRun
+		tcfg *tls.Config
+	}
+	var newPeers []newPeer
+	var pullRuns, pushRuns []peerRun
+	var pullDBs []nntp.PullerDatabase
+	for name, p := range cfg.Peers {
+		if op := f.peers[name]; op != nil {
+			if !samePeer(op, p) {
+				f.log.LogPrintf(WARN,
+					"peer %q: changes will take effect only after restart",
+					name)
+			}
+			continue
+		}
+		d, proto, host, tcfg, e := f.peerDialer(name, p)
+		if e != nil {
+			return fmt.Errorf("peer %q: %v", name, e)
+		}
+		newPeers = append(newPeers, newPeer{
+			p:    p,
+			r:    peerRun{name: name, d: d, proto: proto, host: host},
+			tcfg: tcfg,
+		})
+	}
+	// registering peers in DB only marks them as in use
+	for _, np := range newPeers {
+		name, p := np.r.name, np.p
+		if p.Pull {
+			db, e := f.db.NewPullerDB(name)
+			if e != nil {
+				return fmt.Errorf("peer %q: NewPullerDB: %v", name, e)
+			}
+			c := nntp.NewNNTPPuller(db, f.logx)
+			c.SetPullWorkers(p.PullWorkers)
+			c.SetStartTLS(np.tcfg, p.DialTLS)
+			if p.PullRules != nil {
+				c.SetPullFilter(p.PullRules)
+			}
+			pullers = append(pullers, c)
+			pullRuns = append(pullRuns, np.r)
+			pullDBs = append(pullDBs, db)
+		}
+		if p.Push {
+			db, e := f.db.NewPusherDB(name)
+			if e != nil {
+				return fmt.Errorf("peer %q: NewPusherDB: %v", name, e)
+			}
+			for w := 0; w < p.PushWorkers; w++ {
+				c := nntp.NewNNTPPusher(db, f.logx, w, p.PushWorkers)
+				c.SetPeerPathIDs(f.peerPathIDs(name))
+				c.SetStartTLS(np.tcfg, p.DialTLS)
+				pushers = append(pushers, c)
+				pushRuns = append(pushRuns, np.r)
+			}
+		}
+	}
+	// changed rules make DB forget rejects, so it's done last
+	for i, db := range pullDBs {
+		name := pullRuns[i].name
+		if e := db.SetPullRules(cfg.Peers[name].PullRulesID); e != nil {
+			return fmt.Errorf("peer %q: SetPullRules: %v", name, e)
+		}
+	}
+	for name := range f.peers {
+		if cfg.Peers[name] == nil {
+			f.log.LogPrintf(WARN,
+				"peer %q: removal will take effect only after restart", name)
+		}
+	}
+
+	// everything is set up, apply
+
+	for name, fs := range f.servers {
+		rcfg := runCfgs[name]
+		if rcfg == nil {
+			// server is gone, its listeners were closed already,
+			// but its clients are kept
+			delete(f.servers, name)
+			continue
+		}
+		// new connections will use new settings
+		fs.srv.SetRunCfg(rcfg)
+	}
+	for name, fs := range newServers {
+		f.servers[name] = fs
+	}
+	for _, n := range opened {
+		n.fs.listeners[n.b] = n.l
+		go f.serve(n.fs.srv, n.l)
+	}
+
+	for i, c := range pullers {
+		r := pullRuns[i]
+		f.log.LogPrintf(NOTICE,
+			"starting puller for peer %q with proto(%s) host(%s)",
+			r.name, r.proto, r.host)
+		e := f.sched.Add(
+			r.name, c, r.d, r.proto, r.host, cfg.Peers[r.name].PullSched)
+		if e != nil {
+			// can't happen, new peers aren't scheduled yet
+			f.log.LogPrintf(ERROR, "peer %q: %v", r.name, e)
+		}
+	}
+	for i, c := range pushers {
+		r := pushRuns[i]
+		if f.pn != nil {
+			f.pn.Add(c)
+		}
+		f.log.LogPrintf(NOTICE,
+			"starting pusher for peer %q with proto(%s) host(%s)",
+			r.name, r.proto, r.host)
+		go c.Run(r.d, r.proto, r.host)
+	}
+	f.pushers = append(f.pushers, pushers...)
+	for name, p := range cfg.Peers {
+		if f.peers[name] == nil {
+			f.peers[name] = p
+		}
+	}
+
+	f.cfg = cfg
+	return nil
+}
+
+// Start binds all listeners and starts all peers.
+// If any listener fails to bind, already bound ones are closed.
+func (f *Feed) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.apply(f.cfg)
+	if err == nil {
+		f.started = true
+	}
+	return err
+}
+
+// Reload switches feed to new config.
+// New listeners are opened and removed ones are closed,
+// but already established connections are left alone,
+// keeping privileges they had until they authenticate again.
+// Changes to already running peers need restart.
+// If error is returned, nothing was changed.
+func (f *Feed) Reload(cfg *Cfg) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.started {
+		// will be used once started
+		f.cfg = cfg
+		return nil
+	}
+	return f.apply(cfg)
+}
+
+// Stats returns connection counters of running servers.
+func (f *Feed) Stats() map[string]nntp.ServerStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m := make(map[string]nntp.ServerStats, len(f.servers))
+	for name, fs := range f.servers {
+		m[name] = fs.srv.Stats()
+	}
+	return m
+}
+
+// PullStates returns scheduling state of pulled peers.
+func (f *Feed) PullStates() map[string]nntp.PullPeerState {
+	return f.sched.States()
+}
+
+// Close stops all servers.
+// Peers don't support stopping yet and keep running.
+func (f *Feed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, fs := range f.servers {
+		// Serve may not have registered listener yet, close them ourselves
+		for _, l := range fs.listeners {
+			fs.srv.CloseListener(l)
+		}
+		fs.srv.Close()
+	}
+	f.servers = make(map[string]*feedServer)
+	f.started = false
+}
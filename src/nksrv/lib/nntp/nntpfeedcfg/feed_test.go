@@ -0,0 +1,118 @@
+package nntpfeedcfg
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"nksrv/lib/nntp"
+	"nksrv/lib/utils/certfp"
+	. "nksrv/lib/utils/logx"
+	fl "nksrv/lib/utils/logx/filelogger"
+)
+
+func TestPeerTLSConfig(t *testing.T) {
+	pin, err := certfp.ParsePin(certfp.SelectorPubKey, "sha256:"+
+		"0011223344556677889900112233445566778899001122334455667788990011")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		p        Peer
+		insecure bool
+		pinned   bool
+	}{
+		{"plain", Peer{}, false, false},
+		{"pinned", Peer{DialPins: []certfp.Pin{pin}}, true, true},
+		{"insecure", Peer{DialInsecure: true}, true, false},
+		{"both", Peer{DialPins: []certfp.Pin{pin}, DialInsecure: true}, true, true},
+	}
+	for _, tt := range tests {
+		tcfg := peerTLSConfig(&tt.p, "news.example.org:563")
+		if tcfg.ServerName != "news.example.org" {
+			t.Errorf("%s: ServerName = %q", tt.name, tcfg.ServerName)
+		}
+		if tcfg.InsecureSkipVerify != tt.insecure {
+			t.Errorf("%s: InsecureSkipVerify = %v",
+				tt.name, tcfg.InsecureSkipVerify)
+		}
+		if (tcfg.VerifyPeerCertificate != nil) != tt.pinned {
+			t.Errorf("%s: VerifyPeerCertificate set = %v",
+				tt.name, tcfg.VerifyPeerCertificate != nil)
+		}
+	}
+
+	tcfg := peerTLSConfig(&Peer{}, "news.example.org")
+	if tcfg.ServerName != "news.example.org" {
+		t.Errorf("no port: ServerName = %q", tcfg.ServerName)
+	}
+}
+
+// testFeedDB records which DB side effects happened
+type testFeedDB struct {
+	pullerFail, pusherFail bool
+	registered             []string
+	rules                  []string
+}
+
+type testPullerDB struct {
+	nntp.PullerDatabase
+	f *testFeedDB
+}
+
+func (db testPullerDB) SetPullRules(rules string) error {
+	db.f.rules = append(db.f.rules, rules)
+	return nil
+}
+
+func (f *testFeedDB) NewPullerDB(name string) (nntp.PullerDatabase, error) {
+	if f.pullerFail {
+		return nil, errors.New("no puller for you")
+	}
+	f.registered = append(f.registered, name)
+	return testPullerDB{f: f}, nil
+}
+
+func (f *testFeedDB) NewPusherDB(name string) (nntp.PusherDatabase, error) {
+	if f.pusherFail {
+		return nil, errors.New("no pusher for you")
+	}
+	f.registered = append(f.registered, name)
+	return nil, nil
+}
+
+func TestApplyFailsBeforeDB(t *testing.T) {
+	lgr, err := fl.NewFileLogger(os.Stderr, ERROR, fl.ColorOff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// bad dial address of one peer must stop others from being registered
+	db := &testFeedDB{}
+	f := NewFeed(&Cfg{Peers: map[string]*Peer{
+		"good": {DialAddr: "127.0.0.1:119", Pull: true, PullRulesID: "x"},
+		"bad":  {DialAddr: "nntp://", Pull: true, PullRulesID: "y"},
+	}}, nil, db, nil, lgr)
+	if err = f.Start(); err == nil {
+		t.Fatal("Start with bad dial address succeeded")
+	}
+	if len(db.registered) != 0 || len(db.rules) != 0 {
+		t.Errorf("DB touched: registered %v, rules %v",
+			db.registered, db.rules)
+	}
+
+	// failing pusher registration must not change pull rules
+	db = &testFeedDB{pusherFail: true}
+	f = NewFeed(&Cfg{Peers: map[string]*Peer{
+		"pull": {DialAddr: "127.0.0.1:119", Pull: true, PullRulesID: "x"},
+		"push": {DialAddr: "127.0.0.1:119", Push: true, PushWorkers: 1},
+	}}, nil, db, nil, lgr)
+	if err = f.Start(); err == nil {
+		t.Fatal("Start with failing NewPusherDB succeeded")
+	}
+	if len(db.rules) != 0 {
+		t.Errorf("pull rules set: %v", db.rules)
+	}
+}
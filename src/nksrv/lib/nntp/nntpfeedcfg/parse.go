@@ -4,63 +4,212 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"time"
 
 	"github.com/BurntSushi/toml"
 
 	"nksrv/lib/nntp"
+	certfpmap "nksrv/lib/nntp/nntpcertfpmap"
+	"nksrv/lib/nntp/nntpuserpassmap"
 	"nksrv/lib/utils/certfp"
 )
 
-type parsedUserCfg struct {
-	ui nntp.UserInfo
-	ch string
+type BindCfg struct {
+	Network     string
+	Addr        string
+	ListenParam nntp.ListenParam
 }
 
-type parsedCertFPCfg struct {
-	ui nntp.UserInfo
-	sl certfp.Selector
-	fp string
+type Server struct {
+	BindCfg []BindCfg
+	RunCfg  nntp.NNTPServerRunCfg
 }
 
-type parsedBindCfg struct {
-	network     string
-	addr        string
-	listenParam nntp.ListenParam
+type Peer struct {
+	DialAddr     string
+	DialCert     *tls.Certificate // client certificate, if any
+	DialTLS      nntp.StartTLSMode
+	DialPins     []certfp.Pin // if set, server must match one of them
+	DialInsecure bool         // no pins and no verification against roots
+	Pull         bool
+	PullWorkers  int
+	PullSched    nntp.PullSchedulerCfg
+	PullRules    *nntp.PullRules // nil if there are none
+	PullRulesID  string          // identifies PullRules, empty if none
+	Push         bool
+	PushWorkers  int
 }
 
-type parsedServerParams struct {
-	nntps                 bool
-	cert                  tls.Certificate
-	priv                  nntp.UserPriv
-	tlspriv               nntp.UserPriv
-	unsafePass            bool
-	unsafeEarlyUserReject bool
-	certfpAutoAuth        bool
+// Cfg is parsed feed configuration.
+// It's immutable once made, reloads produce new Cfg.
+type Cfg struct {
+	UserPass nntpuserpassmap.UserPassMap
+	CertFP   *certfpmap.CertFPMap
+	Servers  map[string]*Server
+	Peers    map[string]*Peer
+	PathIDs  map[string][]string // configured path identities of peers
 }
 
-type parsedServer struct {
-	BindCfg []parsedBindCfg
+func loadCert(c PrivCertCfg) (*tls.Certificate, error) {
+	if c.Certificate == "" && c.PrivateKey == "" {
+		return nil, nil
+	}
+	if c.Certificate == "" || c.PrivateKey == "" {
+		return nil, errors.New("both cert and priv must be set")
+	}
+	cert, err := tls.LoadX509KeyPair(c.Certificate, c.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func addCertFP(
+	m *certfpmap.CertFPMap, fc CertFPInnerCfg, ui nntp.UserInfo) error {
+
+	if fc.Cert != "" {
+		return m.Add(certfp.SelectorFull, fc.Cert, ui)
+	} else if fc.PubKey != "" {
+		return m.Add(certfp.SelectorPubKey, fc.PubKey, ui)
+	} else {
+		return errors.New("either cert or pubkey must be set")
+	}
+}
+
+func parsePin(fc CertFPInnerCfg) (certfp.Pin, error) {
+	if fc.Cert != "" {
+		return certfp.ParsePin(certfp.SelectorFull, fc.Cert)
+	} else if fc.PubKey != "" {
+		return certfp.ParsePin(certfp.SelectorPubKey, fc.PubKey)
+	} else {
+		return certfp.Pin{}, errors.New("either cert or pubkey must be set")
+	}
+}
+
+func parseStartTLSMode(s string) (nntp.StartTLSMode, bool) {
+	switch s {
+	case "", "off", "no":
+		return nntp.StartTLSOff, true
+	case "try", "opportunistic":
+		return nntp.StartTLSOpportunistic, true
+	case "require", "required", "yes":
+		return nntp.StartTLSRequired, true
+	default:
+		return 0, false
+	}
+}
+
+func validPathID(id string) bool {
+	if id == "" || id[0] == '.' {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] >= 0x7F || id[i] == '!' {
+			return false
+		}
+	}
+	return true
+}
+
+func compileGroupsWildmat(name, s string) (nntp.Wildmat, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !nntp.ValidWildmatStr(s) {
+		return nil, fmt.Errorf("invalid %s wildmat %q", name, s)
+	}
+	return nntp.CompileWildmatStr(s), nil
+}
+
+func compileRegexps(name string, ss []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, s := range ss {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s regexp %q: %v", name, s, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// pullRulesID identifies rules articles were rejected under.
+func pullRulesID(c PeerInnerCfg) string {
+	return fmt.Sprintf("bytes=%d lines=%d groups=%q subject=%q from=%q",
+		c.PullMaxBytes, c.PullMaxLines, c.PullGroups,
+		c.PullRejectSubject, c.PullRejectFrom)
+}
+
+func parsePullRules(c PeerInnerCfg) (*nntp.PullRules, error) {
+	if c.PullMaxBytes < 0 || c.PullMaxLines < 0 {
+		return nil, errors.New("negative pull limits")
+	}
+	r := &nntp.PullRules{
+		MaxBytes: c.PullMaxBytes,
+		MaxLines: c.PullMaxLines,
+	}
+	var err error
+	r.Groups, err = compileGroupsWildmat("pull_groups", c.PullGroups)
+	if err != nil {
+		return nil, err
+	}
+	r.RejectSubject, err = compileRegexps(
+		"pull_reject_subject", c.PullRejectSubject)
+	if err != nil {
+		return nil, err
+	}
+	r.RejectFrom, err = compileRegexps("pull_reject_from", c.PullRejectFrom)
+	if err != nil {
+		return nil, err
+	}
+	if r.MaxBytes == 0 && r.MaxLines == 0 && r.Groups == nil &&
+		len(r.RejectSubject) == 0 && len(r.RejectFrom) == 0 {
 
-	parsedServerParams
+		return nil, nil
+	}
+	return r, nil
+}
+
+func parseGroupAccess(c GroupAccessCfg) (ga nntp.GroupAccess, err error) {
+	ga.ReadGroups, err = compileGroupsWildmat("read_groups", c.ReadGroups)
+	if err != nil {
+		return
+	}
+	ga.PostGroups, err = compileGroupsWildmat("post_groups", c.PostGroups)
+	return
 }
 
-type parsedCfg struct {
-	users   []parsedUserCfg
-	certfp  []parsedCertFPCfg
-	servers map[string]parsedServer
-	peers   map[string]parsedPeer
-	// TODO
+func parseListen(s string) (b BindCfg) {
+	u, e := url.ParseRequestURI(s)
+	if e == nil {
+		b.Network, b.Addr = u.Scheme, u.Host
+	} else {
+		b.Network, b.Addr = "tcp", s
+	}
+	return
 }
 
-func ParseCfg(cfg string) (err error) {
-	var pcfg parsedCfg
+func ParseCfg(cfg string) (_ *Cfg, err error) {
 	fc := DefaultFeedCfg
 
-	md, err = toml.Decode(cfg, &fc)
+	md, err := toml.Decode(cfg, &fc)
 	if err != nil {
 		return
 	}
 
+	pcfg := &Cfg{
+		UserPass: nntpuserpassmap.NewUserPassMap(),
+		Servers:  make(map[string]*Server),
+		Peers:    make(map[string]*Peer),
+		PathIDs:  make(map[string][]string),
+	}
+	certfps := certfpmap.NewCertFPMap()
+	pcfg.CertFP = &certfps
+	haveUsers, haveCertFP := false, false
+
 	for i := range fc.Users {
 		// default priv
 		fc_user := UserCfg{Priv: fc.UsersPriv}
@@ -71,12 +220,20 @@ func ParseCfg(cfg string) (err error) {
 		}
 		priv, ok := nntp.ParseUserPriv(fc_user.Priv, nntp.UserPriv{})
 		if !ok {
-			return fmt.Errorf("unrecognised priv %q", fc_user.Priv)
+			return nil, fmt.Errorf("unrecognised priv %q", fc_user.Priv)
+		}
+		var ga nntp.GroupAccess
+		ga, err = parseGroupAccess(fc_user.GroupAccessCfg)
+		if err != nil {
+			return nil, fmt.Errorf("user %q: %v", fc_user.Name, err)
+		}
+		err = pcfg.UserPass.Add(
+			nntp.UserInfo{Name: fc_user.Name, UserPriv: priv, GroupAccess: ga},
+			fc_user.Pass)
+		if err != nil {
+			return nil, fmt.Errorf("user %q: %v", fc_user.Name, err)
 		}
-		pcfg.users = append(pcfg.users, parsedUserCfg{
-			ui: nntp.UserInfo{Name: fc_user.Name, UserPriv: priv},
-			ch: fc_user.Pass,
-		})
+		haveUsers = true
 	}
 	for i := range fc.CertFP {
 		// default priv
@@ -88,44 +245,316 @@ func ParseCfg(cfg string) (err error) {
 		}
 		priv, ok := nntp.ParseUserPriv(fc_certfp.Priv, nntp.UserPriv{})
 		if !ok {
-			return fmt.Errorf("unrecognised priv %q", fc_certfp.Priv)
+			return nil, fmt.Errorf("unrecognised priv %q", fc_certfp.Priv)
 		}
-		pcfp := parsedCertFPCfg{
-			ui: nntp.UserInfo{Name: fc_user.Name, UserPriv: priv},
+		var ga nntp.GroupAccess
+		ga, err = parseGroupAccess(fc_certfp.GroupAccessCfg)
+		if err != nil {
+			return nil, fmt.Errorf("certfp %d: %v", i, err)
 		}
-		if fc_certfp.Cert != "" {
-			pcfp.sl = certfp.SelectorFull
-			pcfp.fp = fc_certfp.Cert
-		} else if fc_certfp.PubKey != "" {
-			pcfp.sl = certfp.SelectorPubKey
-			pcfp.fp = fc_certfp.PubKey
-		} else {
-			return errors.New("either cert or pubkey must be set")
+		err = addCertFP(pcfg.CertFP, fc_certfp.CertFPInnerCfg,
+			nntp.UserInfo{
+				Name:        fc_certfp.Name,
+				UserPriv:    priv,
+				GroupAccess: ga,
+			})
+		if err != nil {
+			return nil, fmt.Errorf("certfp %d: %v", i, err)
 		}
-		pcfg.certfp = append(pcfg.certfp, pcfp)
+		haveCertFP = true
 	}
 
-	//parseServAddr := func(listen toml.Primitive) []
-	for i := range fc.Servers {
-		fc_server := ServerCfg{ServerInnerCfg: fc.ServersDefault}
+	for name := range fc.Peers {
+		fc_peer := PeerCfg{PeerInnerCfg: fc.PeersDefault}
 
-		err = md.PrimitiveDecode(fc.Servers[i], &fc_server)
+		err = md.PrimitiveDecode(fc.Peers[name], &fc_peer)
 		if err != nil {
 			return
 		}
+		if !fc_peer.Enabled {
+			continue
+		}
+
+		priv, ok := nntp.ParseUserPriv(fc_peer.ServPriv, nntp.UserPriv{})
+		if !ok {
+			return nil, fmt.Errorf(
+				"peer %q: unrecognised serv_priv %q", name, fc_peer.ServPriv)
+		}
+
+		for _, id := range fc_peer.PathIDs {
+			if !validPathID(id) {
+				return nil, fmt.Errorf(
+					"peer %q: invalid path identity %q", name, id)
+			}
+		}
+		if len(fc_peer.PathIDs) != 0 {
+			pcfg.PathIDs[name] = fc_peer.PathIDs
+		}
 
-		listenstrs := make([]string, 1)
-		err = md.PrimitiveDecode(fc_server.Listen, &listenstrs[0])
+		// defaults for both serv_user and serv_certfp
+		servGroups := GroupAccessCfg{
+			ReadGroups: fc_peer.ServReadGroups,
+			PostGroups: fc_peer.ServPostGroups,
+		}
+		peerGroupAccess := func(c GroupAccessCfg) (nntp.GroupAccess, error) {
+			if c.ReadGroups == "" {
+				c.ReadGroups = servGroups.ReadGroups
+			}
+			if c.PostGroups == "" {
+				c.PostGroups = servGroups.PostGroups
+			}
+			return parseGroupAccess(c)
+		}
+
+		// credentials peer uses to connect to us
+		if fc_peer.ServUser.Pass != "" {
+			upriv := priv
+			if fc_peer.ServUser.Priv != "" {
+				upriv, ok = nntp.ParseUserPriv(
+					fc_peer.ServUser.Priv, nntp.UserPriv{})
+				if !ok {
+					return nil, fmt.Errorf(
+						"peer %q: unrecognised priv %q",
+						name, fc_peer.ServUser.Priv)
+				}
+			}
+			uname := fc_peer.ServUser.Name
+			if uname == "" {
+				uname = name
+			}
+			var ga nntp.GroupAccess
+			ga, err = peerGroupAccess(fc_peer.ServUser.GroupAccessCfg)
+			if err != nil {
+				return nil, fmt.Errorf("peer %q: serv_user: %v", name, err)
+			}
+			err = pcfg.UserPass.Add(
+				nntp.UserInfo{
+					Name:        uname,
+					Serv:        name,
+					UserPriv:    upriv,
+					GroupAccess: ga,
+				},
+				fc_peer.ServUser.Pass)
+			if err != nil {
+				return nil, fmt.Errorf("peer %q: serv_user: %v", name, err)
+			}
+			haveUsers = true
+		}
+		if fc_peer.ServCertFP.Cert != "" || fc_peer.ServCertFP.PubKey != "" {
+			cpriv := priv
+			if fc_peer.ServCertFP.Priv != "" {
+				cpriv, ok = nntp.ParseUserPriv(
+					fc_peer.ServCertFP.Priv, nntp.UserPriv{})
+				if !ok {
+					return nil, fmt.Errorf(
+						"peer %q: unrecognised priv %q",
+						name, fc_peer.ServCertFP.Priv)
+				}
+			}
+			var ga nntp.GroupAccess
+			ga, err = peerGroupAccess(fc_peer.ServCertFP.GroupAccessCfg)
+			if err != nil {
+				return nil, fmt.Errorf("peer %q: serv_certfp: %v", name, err)
+			}
+			err = addCertFP(pcfg.CertFP, fc_peer.ServCertFP.CertFPInnerCfg,
+				nntp.UserInfo{
+					Name:        fc_peer.ServCertFP.Name,
+					Serv:        name,
+					UserPriv:    cpriv,
+					GroupAccess: ga,
+				})
+			if err != nil {
+				return nil, fmt.Errorf("peer %q: serv_certfp: %v", name, err)
+			}
+			haveCertFP = true
+		}
+
+		if !fc_peer.Pull && !fc_peer.Push {
+			// only incoming connections
+			continue
+		}
+		if fc_peer.DialAddr == "" {
+			return nil, fmt.Errorf("peer %q: dial address not specified", name)
+		}
+		if fc_peer.PullWorkers < 0 || fc_peer.PushWorkers < 0 {
+			return nil, fmt.Errorf("peer %q: negative workers count", name)
+		}
+		if fc_peer.PullInterval < 0 || fc_peer.PullMaxBackoff < 0 {
+			return nil, fmt.Errorf("peer %q: negative pull timing", name)
+		}
+		peer := &Peer{
+			DialAddr:    fc_peer.DialAddr,
+			Pull:        fc_peer.Pull,
+			PullWorkers: fc_peer.PullWorkers,
+			PullSched: nntp.PullSchedulerCfg{
+				Interval:   time.Duration(fc_peer.PullInterval) * time.Second,
+				MaxBackoff: time.Duration(fc_peer.PullMaxBackoff) * time.Second,
+			},
+			Push:         fc_peer.Push,
+			PushWorkers:  fc_peer.PushWorkers,
+			DialInsecure: fc_peer.DialInsecure,
+		}
+		if peer.PullWorkers == 0 {
+			peer.PullWorkers = 1
+		}
+		if peer.PushWorkers == 0 {
+			peer.PushWorkers = 1
+		}
+		peer.DialCert, err = loadCert(fc_peer.DialCert)
+		if err != nil {
+			return nil, fmt.Errorf("peer %q: dial_cert: %v", name, err)
+		}
+		peer.DialTLS, ok = parseStartTLSMode(fc_peer.DialStartTLS)
+		if !ok {
+			return nil, fmt.Errorf(
+				"peer %q: unrecognised dial_starttls %q",
+				name, fc_peer.DialStartTLS)
+		}
+		for _, fp := range fc_peer.DialPins {
+			pin, e := parsePin(fp)
+			if e != nil {
+				return nil, fmt.Errorf("peer %q: dial_pins: %v", name, e)
+			}
+			peer.DialPins = append(peer.DialPins, pin)
+		}
+		peer.PullRules, err = parsePullRules(fc_peer.PeerInnerCfg)
+		if err != nil {
+			return nil, fmt.Errorf("peer %q: %v", name, err)
+		}
+		if peer.PullRules != nil {
+			peer.PullRulesID = pullRulesID(fc_peer.PeerInnerCfg)
+		}
+		pcfg.Peers[name] = peer
+	}
+
+	if !haveCertFP {
+		pcfg.CertFP = nil
+	}
+
+	for name := range fc.Servers {
+		fc_server := ServerCfg{ServerCommonCfg: fc.ServersDefault}
+
+		err = md.PrimitiveDecode(fc.Servers[name], &fc_server)
 		if err != nil {
-			err = md.PrimitiveDecode(fc_server.Listen, &listenstrs)
+			return
+		}
+		if !fc_server.Enabled {
+			continue
+		}
+
+		srv := &Server{RunCfg: nntp.DefaultNNTPServerRunCfg}
+
+		var listenstrs []string
+		if md.IsDefined("servers", name, "listen") {
+			listenstrs = make([]string, 1)
+			err = md.PrimitiveDecode(fc_server.Listen, &listenstrs[0])
 			if err != nil {
-				return
+				listenstrs = nil
+				err = md.PrimitiveDecode(fc_server.Listen, &listenstrs)
+				if err != nil {
+					return
+				}
+			}
+		}
+		if len(listenstrs) == 0 {
+			return nil, fmt.Errorf("server %q: no listen addresses", name)
+		}
+		for _, s := range listenstrs {
+			srv.BindCfg = append(srv.BindCfg, parseListen(s))
+		}
+
+		rcfg := &srv.RunCfg
+		var ok bool
+		rcfg.DefaultPriv, ok = nntp.ParseUserPriv(
+			fc_server.Priv, nntp.UserPriv{})
+		if !ok {
+			return nil, fmt.Errorf(
+				"server %q: unrecognised priv %q", name, fc_server.Priv)
+		}
+		rcfg.DefaultGroupAccess, err = parseGroupAccess(
+			fc_server.GroupAccessCfg)
+		if err != nil {
+			return nil, fmt.Errorf("server %q: %v", name, err)
+		}
+		rcfg.TLSPriv, ok = nntp.ParseUserPriv(
+			fc_server.TLSPriv, nntp.UserPriv{})
+		if !ok {
+			return nil, fmt.Errorf(
+				"server %q: unrecognised tls_priv %q", name, fc_server.TLSPriv)
+		}
+
+		cert, e := loadCert(fc_server.TLSCert)
+		if e != nil {
+			return nil, fmt.Errorf("server %q: tls_cert: %v", name, e)
+		}
+		if cert != nil {
+			rcfg.TLSConfig = &tls.Config{
+				Certificates: []tls.Certificate{*cert},
+				// we don't verify them, only match fingerprints
+				ClientAuth: tls.RequestClientCert,
+			}
+		}
+		if fc_server.NNTPS {
+			if rcfg.TLSConfig == nil {
+				return nil, fmt.Errorf(
+					"server %q: tls_nntps requires tls_cert", name)
 			}
+			rcfg.NNTPS = true
 		}
-		//if len(listenstrs) == 0 {
 
+		if haveUsers {
+			rcfg.UserPassProvider = pcfg.UserPass
+		}
+		if pcfg.CertFP != nil {
+			rcfg.CertFPProvider = pcfg.CertFP
+			rcfg.CertFPAutoAuth = fc_server.CertFPAutoAuth
+		}
+		rcfg.UnsafePass = fc_server.UnsafePass
+		rcfg.UnsafeEarlyUserReject = fc_server.UnsafeEarlyUserReject
+
+		rcfg.MOTD = fc_server.MOTD
+		if fc_server.MOTDFile != "" {
+			b, e := ioutil.ReadFile(fc_server.MOTDFile)
+			if e != nil {
+				return nil, fmt.Errorf("server %q: motd_file: %v", name, e)
+			}
+			rcfg.MOTD = string(b)
+		}
+
+		if fc_server.MaxConns < 0 || fc_server.MaxConnsPerIP < 0 ||
+			fc_server.MaxConnsPerPeer < 0 ||
+			fc_server.IPv4Prefix < 0 || fc_server.IPv4Prefix > 32 ||
+			fc_server.IPv6Prefix < 0 || fc_server.IPv6Prefix > 128 {
+
+			return nil, fmt.Errorf("server %q: invalid connection limits", name)
+		}
+		rcfg.Limits = nntp.ConnLimits{
+			MaxConns:        fc_server.MaxConns,
+			MaxConnsPerIP:   fc_server.MaxConnsPerIP,
+			IPv4Prefix:      fc_server.IPv4Prefix,
+			IPv6Prefix:      fc_server.IPv6Prefix,
+			MaxConnsPerPeer: fc_server.MaxConnsPerPeer,
+		}
+		rcfg.Rates = nntp.RateLimits(fc_server.RateLimitCfg)
+		rcfg.PeerRates = nntp.RateLimits(fc_server.PeerRates)
+
+		rcfg.ProxyTrusted, err = nntp.ParseProxyTrusted(fc_server.ProxyTrusted)
+		if err != nil {
+			return nil, fmt.Errorf("server %q: proxy_trusted: %v", name, err)
+		}
+
+		pcfg.Servers[name] = srv
 	}
 
-	// TODO
-	return
+	return pcfg, nil
+}
+
+// ParseCfgFile reads and parses config file.
+// TLS certificates referenced by config are (re-)read too.
+func ParseCfgFile(fn string) (*Cfg, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCfg(string(b))
 }
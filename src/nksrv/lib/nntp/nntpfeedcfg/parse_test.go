@@ -0,0 +1,34 @@
+package nntpfeedcfg
+
+import (
+	"testing"
+
+	"nksrv/lib/nntp"
+)
+
+func TestParseStartTLSMode(t *testing.T) {
+	tests := []struct {
+		s    string
+		mode nntp.StartTLSMode
+		ok   bool
+	}{
+		{"", nntp.StartTLSOff, true},
+		{"off", nntp.StartTLSOff, true},
+		{"no", nntp.StartTLSOff, true},
+		{"try", nntp.StartTLSOpportunistic, true},
+		{"opportunistic", nntp.StartTLSOpportunistic, true},
+		{"require", nntp.StartTLSRequired, true},
+		{"required", nntp.StartTLSRequired, true},
+		{"yes", nntp.StartTLSRequired, true},
+		{"Require", 0, false},
+		{"tls", 0, false},
+		{" try", 0, false},
+	}
+	for _, tt := range tests {
+		mode, ok := parseStartTLSMode(tt.s)
+		if ok != tt.ok || (ok && mode != tt.mode) {
+			t.Errorf("parseStartTLSMode(%q) = %v, %v; want %v, %v",
+				tt.s, mode, ok, tt.mode, tt.ok)
+		}
+	}
+}
@@ -7,8 +7,16 @@ type UserInnerCfg struct {
 	Pass string `toml:"pass"`
 }
 
+// GroupAccessCfg holds wildmats of groups allowed to read and post to.
+// Empty means no limit.
+type GroupAccessCfg struct {
+	ReadGroups string `toml:"read_groups"`
+	PostGroups string `toml:"post_groups"`
+}
+
 type UserCfg struct {
 	UserInnerCfg
+	GroupAccessCfg
 
 	Priv string `toml:"priv"`
 }
@@ -20,6 +28,7 @@ type CertFPInnerCfg struct {
 
 type CertFPCfg struct {
 	CertFPInnerCfg
+	GroupAccessCfg
 
 	Priv string `toml:"priv"`
 	Name string `toml:"name"`
@@ -31,6 +40,8 @@ type PrivCertCfg struct {
 }
 
 type ServerCommonCfg struct {
+	GroupAccessCfg // for unauthenticated clients
+
 	Enabled               bool        `toml:"enabled"`
 	Priv                  string      `toml:"priv"`
 	TLSPriv               string      `toml:"tls_priv"`
@@ -38,13 +49,37 @@ type ServerCommonCfg struct {
 	TLSCert               PrivCertCfg `toml:"tls_cert"`
 	UnsafePass            bool        `toml:"unsafe_pass"`
 	UnsafeEarlyUserReject bool        `toml:"unsafe_early_user_reject"`
+	MOTD                  string      `toml:"motd"`
+	MOTDFile              string      `toml:"motd_file"` // overrides motd, re-read on reload
+
+	// connection limits, 0 means unlimited
+	MaxConns        int `toml:"max_conns"`
+	MaxConnsPerIP   int `toml:"max_conns_per_ip"`
+	IPv4Prefix      int `toml:"ipv4_prefix"`
+	IPv6Prefix      int `toml:"ipv6_prefix"`
+	MaxConnsPerPeer int `toml:"max_conns_per_peer"`
+
+	// per-connection rates (per second), 0 means unlimited
+	RateLimitCfg
+	PeerRates RateLimitCfg `toml:"peer_rates"`
+
+	// addresses or prefixes of proxies which send PROXY header
+	ProxyTrusted []string `toml:"proxy_trusted"`
 	// TODO
 }
 
+type RateLimitCfg struct {
+	CommandRate  float64 `toml:"cmd_rate"`
+	CommandBurst int     `toml:"cmd_burst"`
+	ArticleRate  float64 `toml:"article_rate"`
+	ArticleBurst int     `toml:"article_burst"`
+}
+
 var DefaultServerCommonCfg = ServerCommonCfg{
 	Enabled:        true,
 	Priv:           "rw",
 	CertFPAutoAuth: true,
+	IPv6Prefix:     64,
 }
 
 type ServerCfg struct {
@@ -63,6 +98,29 @@ type PeerInnerCfg struct {
 	Push        bool        `toml:"push"`
 	PushWorkers int         `toml:"push_workers"`
 	ServPriv    string      `toml:"serv_priv"`
+
+	// path identities peer uses in addition to ones it was seen using
+	PathIDs []string `toml:"path_ids"`
+
+	ServReadGroups string `toml:"serv_read_groups"`
+	ServPostGroups string `toml:"serv_post_groups"`
+
+	// seconds between pulls and max wait after failed ones, 0 for default
+	PullInterval   int `toml:"pull_interval"`
+	PullMaxBackoff int `toml:"pull_max_backoff"`
+
+	// "off", "try" or "require"; ignored for nntps
+	DialStartTLS string `toml:"dial_starttls"`
+	// don't verify server certificate when there are no dial_pins
+	DialInsecure bool `toml:"dial_insecure"`
+
+	// pulled articles not passing these (checked against overview)
+	// aren't fetched, and are remembered as rejected
+	PullMaxBytes      int64    `toml:"pull_max_bytes"`
+	PullMaxLines      int64    `toml:"pull_max_lines"`
+	PullGroups        string   `toml:"pull_groups"`         // wildmat
+	PullRejectSubject []string `toml:"pull_reject_subject"` // regexps
+	PullRejectFrom    []string `toml:"pull_reject_from"`    // regexps
 }
 
 var DefaultPeerInnerCfg = PeerInnerCfg{
@@ -78,6 +136,9 @@ type PeerCfg struct {
 	DialUser   UserInnerCfg `toml:"dial_user"` // sharing this would be bad idea
 	ServUser   UserCfg      `toml:"serv_user"`
 	ServCertFP CertFPCfg    `toml:"serv_certfp"`
+
+	// fingerprints of server certificate (or its public key) to accept
+	DialPins []CertFPInnerCfg `toml:"dial_pins"`
 }
 
 type FeedCfg struct {
@@ -97,6 +158,6 @@ type FeedCfg struct {
 var DefaultFeedCfg = FeedCfg{
 	UsersPriv:      "rw",
 	CertFPPriv:     "rw",
-	ServersDefault: DefaultServerInnerCfg,
+	ServersDefault: DefaultServerCommonCfg,
 	PeersDefault:   DefaultPeerInnerCfg,
 }
@@ -7,13 +7,267 @@ package nntppostlockmgr
  * we also need to keep track of posting failures, otherwise timeout could be easily defeated
  * probably best way to handle this would be keeping entry about posting failure and poster, allow that poster to try resending article, but don't lock off other posters
  * but then posters could flood lock manager by sending tons of bad articles; we should keep track of posters who do that and after certain threshold discard all their expired locks and disallow them taking any more locks
- * this stuff should be tracker per-connection or per-poster basis? not decided (what is poster's identity?)
+ * lock ownership is tracked per-connection (owner), failures are tracked per-poster;
+ * poster's identity is decided by caller (authenticated user name or remote address)
  * POST cmd is a bit edge case as it doesn't ask for Message-ID upfront, but there is no real harm of submitting article
  * we SHOULD handle conflicting inserts gracefuly regardless of post locks
  */
 
+import (
+	"sync"
+	"time"
+)
+
 const (
 	stNone = iota
 	stCanceled
 	stPosting
 )
+
+type Config struct {
+	// how long lock can be held before others are allowed to take it
+	LockTimeout time.Duration
+	// how long failed attempts are remembered
+	FailTimeout time.Duration
+	// after this many failures poster can't take locks anymore
+	// (until failures expire); 0 disables this check
+	MaxFailures int
+	// how long LockWait waits for other transfer to finish
+	WaitTimeout time.Duration
+}
+
+var DefaultConfig = Config{
+	LockTimeout: 10 * time.Minute,
+	FailTimeout: 30 * time.Minute,
+	MaxFailures: 32,
+	WaitTimeout: 30 * time.Second,
+}
+
+type lockEntry struct {
+	state   int
+	owner   interface{}
+	poster  string
+	expires time.Time
+	retry   bool // poster already failed this one before
+}
+
+type posterEntry struct {
+	failures int
+	expires  time.Time
+}
+
+// PostLockMgr keeps track of articles currently being transferred.
+// It's safe for concurrent use.
+type PostLockMgr struct {
+	mu      sync.Mutex
+	cfg     Config
+	locks   map[string]*lockEntry
+	posters map[string]*posterEntry
+	// closed and replaced when locks are released
+	released chan struct{}
+
+	nextSweep time.Time
+
+	// for tests
+	now func() time.Time
+}
+
+func NewPostLockMgr(cfg Config) *PostLockMgr {
+	return &PostLockMgr{
+		cfg:     cfg,
+		locks:   make(map[string]*lockEntry),
+		posters: make(map[string]*posterEntry),
+		now:     time.Now,
+
+		released: make(chan struct{}),
+	}
+}
+
+// sweep removes expired entries. must be called with mu held.
+func (m *PostLockMgr) sweep(now time.Time) {
+	if now.Before(m.nextSweep) {
+		return
+	}
+	for k, e := range m.locks {
+		if !now.Before(e.expires) {
+			if e.state == stPosting {
+				// never finished, count as failure
+				m.failLocked(now, e.poster)
+			}
+			delete(m.locks, k)
+		}
+	}
+	for k, p := range m.posters {
+		if !now.Before(p.expires) {
+			delete(m.posters, k)
+		}
+	}
+	m.nextSweep = now.Add(m.cfg.LockTimeout / 4)
+}
+
+func (m *PostLockMgr) failLocked(now time.Time, poster string) {
+	p := m.posters[poster]
+	if p == nil {
+		p = &posterEntry{}
+		m.posters[poster] = p
+	}
+	p.failures++
+	p.expires = now.Add(m.cfg.FailTimeout)
+}
+
+func (m *PostLockMgr) blockedLocked(now time.Time, poster string) bool {
+	if m.cfg.MaxFailures <= 0 {
+		return false
+	}
+	p := m.posters[poster]
+	return p != nil && now.Before(p.expires) && p.failures >= m.cfg.MaxFailures
+}
+
+// dropPosterLocks discards all locks held by given poster.
+// must be called with mu held.
+func (m *PostLockMgr) dropPosterLocks(poster string) {
+	for k, e := range m.locks {
+		if e.poster == poster {
+			delete(m.locks, k)
+		}
+	}
+	m.wakeLocked()
+}
+
+// wakeLocked wakes up LockWait callers. must be called with mu held.
+func (m *PostLockMgr) wakeLocked() {
+	close(m.released)
+	m.released = make(chan struct{})
+}
+
+// busyLocked checks whether msgid is being transferred by someone else.
+func (m *PostLockMgr) busyLocked(
+	now time.Time, msgid string, owner interface{}) bool {
+
+	e := m.locks[msgid]
+	return e != nil && e.state == stPosting &&
+		e.owner != owner && now.Before(e.expires)
+}
+
+// Check returns true if poster should try sending msgid later:
+// either because other connection is transferring it right now,
+// or because poster failed too much.
+func (m *PostLockMgr) Check(
+	msgid string, poster string, owner interface{}) (later bool) {
+
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	return m.busyLocked(now, msgid, owner) || m.blockedLocked(now, poster)
+}
+
+// Lock attempts to take transfer lock of msgid.
+// If it returns true, caller must call Unlock once done.
+func (m *PostLockMgr) Lock(
+	msgid string, poster string, owner interface{}) bool {
+
+	ok, _, _ := m.tryLock(msgid, poster, owner)
+	return ok
+}
+
+// LockWait is like Lock, except that if other connection is transferring
+// msgid, it waits up to WaitTimeout for that to finish.
+// As other transfer may have succeeded, caller should check
+// whether it still needs article once it gets lock.
+func (m *PostLockMgr) LockWait(
+	msgid string, poster string, owner interface{}) bool {
+
+	var timer *time.Timer
+	for {
+		ok, busy, released := m.tryLock(msgid, poster, owner)
+		if ok || !busy || m.cfg.WaitTimeout <= 0 {
+			if timer != nil {
+				timer.Stop()
+			}
+			return ok
+		}
+		if timer == nil {
+			timer = time.NewTimer(m.cfg.WaitTimeout)
+		}
+		select {
+		case <-released:
+		case <-timer.C:
+			ok, _, _ = m.tryLock(msgid, poster, owner)
+			return ok
+		}
+	}
+}
+
+// tryLock attempts to take lock. If it's busy, it returns channel
+// which is closed once some lock is released.
+func (m *PostLockMgr) tryLock(
+	msgid string, poster string, owner interface{}) (
+	ok, busy bool, released <-chan struct{}) {
+
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	if m.blockedLocked(now, poster) {
+		// they can't take any more locks, and whatever they hold is void
+		m.dropPosterLocks(poster)
+		return false, false, nil
+	}
+	if m.busyLocked(now, msgid, owner) {
+		return false, true, m.released
+	}
+
+	e := m.locks[msgid]
+	if e == nil {
+		e = &lockEntry{}
+		m.locks[msgid] = e
+	} else if e.state == stPosting && e.owner != owner {
+		// stale lock of someone else, they failed to finish in time
+		m.failLocked(now, e.poster)
+	}
+	e.retry = e.state == stCanceled && e.poster == poster
+	e.state = stPosting
+	e.owner = owner
+	e.poster = poster
+	e.expires = now.Add(m.cfg.LockTimeout)
+	return true, false, nil
+}
+
+// Unlock releases lock of msgid taken by owner.
+// failed should be true if article was rejected for reasons which are
+// poster's fault; such failures are remembered and counted against poster.
+// Internal errors should be reported with failed=false.
+func (m *PostLockMgr) Unlock(msgid string, owner interface{}, failed bool) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.locks[msgid]
+	if e == nil || e.state != stPosting || e.owner != owner {
+		// lock was dropped or taken over
+		return
+	}
+	m.wakeLocked()
+
+	if !failed {
+		delete(m.locks, msgid)
+		return
+	}
+
+	// keep entry about failure so that repeated failures of
+	// the same article from the same poster aren't counted twice
+	if !e.retry {
+		m.failLocked(now, e.poster)
+	}
+	e.state = stCanceled
+	e.owner = nil
+	e.expires = now.Add(m.cfg.FailTimeout)
+}
@@ -0,0 +1,118 @@
+package nntppostlockmgr
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestMgr(cfg Config) (*PostLockMgr, *time.Time) {
+	m := NewPostLockMgr(cfg)
+	t := time.Unix(1000000, 0)
+	m.now = func() time.Time { return t }
+	return m, &t
+}
+
+func TestConcurrentTransfer(t *testing.T) {
+	m, now := newTestMgr(DefaultConfig)
+	a, b := new(int), new(int)
+
+	if !m.Lock("x@y", "A", a) {
+		t.Fatal("first lock failed")
+	}
+	if !m.Check("x@y", "B", b) {
+		t.Error("check of locked id should say later")
+	}
+	if m.Check("x@y", "A", a) {
+		t.Error("owner's own check shouldn't say later")
+	}
+	if m.Lock("x@y", "B", b) {
+		t.Error("second lock succeeded")
+	}
+
+	// lock times out
+	*now = now.Add(DefaultConfig.LockTimeout)
+	if !m.Lock("x@y", "B", b) {
+		t.Error("lock after timeout failed")
+	}
+	// stale owner can't release it anymore
+	m.Unlock("x@y", a, false)
+	if !m.Check("x@y", "A", a) {
+		t.Error("stale owner released lock")
+	}
+	m.Unlock("x@y", b, false)
+	if m.Check("x@y", "A", a) {
+		t.Error("lock still held after unlock")
+	}
+}
+
+func TestFailures(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.MaxFailures = 2
+	m, now := newTestMgr(cfg)
+	a, b := new(int), new(int)
+
+	// failed article doesn't lock off others
+	m.Lock("1@y", "A", a)
+	m.Unlock("1@y", a, true)
+	if m.Check("1@y", "B", b) {
+		t.Error("failed article still locked")
+	}
+	// retrying same article isn't counted twice
+	m.Lock("1@y", "A", a)
+	m.Unlock("1@y", a, true)
+	if m.Check("2@y", "A", a) {
+		t.Error("poster blocked after retry")
+	}
+
+	m.Lock("2@y", "A", a)
+	m.Unlock("2@y", a, true)
+	if !m.Check("3@y", "A", a) || m.Lock("3@y", "A", a) {
+		t.Error("poster not blocked after reaching threshold")
+	}
+	if m.Check("3@y", "B", b) || !m.Lock("3@y", "B", b) {
+		t.Error("other poster affected")
+	}
+
+	*now = now.Add(cfg.FailTimeout)
+	if !m.Lock("4@y", "A", a) {
+		t.Error("poster still blocked after failures expired")
+	}
+}
+
+func TestLockWait(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.WaitTimeout = 20 * time.Millisecond
+	m, _ := newTestMgr(cfg)
+	a, b := new(int), new(int)
+
+	if !m.Lock("x@y", "A", a) {
+		t.Fatal("first lock failed")
+	}
+	// other transfer doesn't finish in time
+	if m.LockWait("x@y", "B", b) {
+		t.Error("LockWait took lock held by other")
+	}
+
+	// other transfer finishes while waiting
+	cfg.WaitTimeout = time.Minute
+	m, _ = newTestMgr(cfg)
+	m.Lock("x@y", "A", a)
+	done := make(chan bool)
+	go func() { done <- m.LockWait("x@y", "B", b) }()
+	time.Sleep(10 * time.Millisecond)
+	m.Unlock("x@y", a, true)
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("LockWait failed after other transfer failed")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("LockWait didn't wake up")
+	}
+	m.Unlock("x@y", b, false)
+
+	// free lock doesn't wait
+	if !m.LockWait("z@y", "B", b) {
+		t.Error("LockWait of free lock failed")
+	}
+}
@@ -24,6 +24,15 @@ type AbstractResponder interface {
 	Abort()
 }
 
+// ListHeadersForm is HDR form LIST HEADERS asks about.
+type ListHeadersForm int
+
+const (
+	ListHeadersAny   ListHeadersForm = iota // usable with any form
+	ListHeadersMsgID                        // usable with message-id form
+	ListHeadersRange                        // usable with range or no argument form
+)
+
 type NNTPProvider interface {
 	SupportsNewNews() bool
 	SupportsOverByMsgID() bool
@@ -66,13 +75,25 @@ type NNTPProvider interface {
 	// - fail: 422{ResNoPrevArticleInThisGroup}
 	SelectPrevArticle(w Responder, cs *ConnState)
 
+	// listings MUST omit groups cs can't read (cs.CanReadGroup),
+	// and articles which aren't in any group cs can read;
+	// same goes for article lookups by msgid
 	// + 230{ResListOfNewArticlesFollows} ret: list of FullMsgID
-	ListNewNews(aw AbstractResponder, wildmat []byte, qt time.Time) // SupportsNewNews()
+	ListNewNews(aw AbstractResponder, cs *ConnState, wildmat []byte, qt time.Time) // SupportsNewNews()
 	// + 231{ResListOfNewNewsgroupsFollows} ret: list of {name hiwm lowm status}
-	ListNewGroups(aw AbstractResponder, qt time.Time)
+	ListNewGroups(aw AbstractResponder, cs *ConnState, qt time.Time)
 	// + 215{ResListFollows}
-	ListActiveGroups(aw AbstractResponder, wildmat []byte)
-	ListNewsgroups(aw AbstractResponder, wildmat []byte)
+	ListActiveGroups(aw AbstractResponder, cs *ConnState, wildmat []byte)
+	ListNewsgroups(aw AbstractResponder, cs *ConnState, wildmat []byte)
+	// + 215{ResListFollows} ret: list of {name time creator}
+	ListActiveTimes(aw AbstractResponder, cs *ConnState, wildmat []byte)
+	// + 215{ResListFollows} ret: list of {name hiwm lowm count status}
+	ListCounts(aw AbstractResponder, cs *ConnState, wildmat []byte)
+	// + 215{ResListFollows} ret: list of {weight:wildmat:distribution}
+	ListDistribPats(aw AbstractResponder, cs *ConnState)
+	// + 215{ResListFollows} ret: list of headers and metadata items
+	// usable with HDR in given form, ":" meaning any header
+	ListHeaders(aw AbstractResponder, cs *ConnState, form ListHeadersForm) // SupportsHdr()
 
 	// + ok: 224{ResOverviewInformationFollows}
 	// fail:
@@ -95,11 +116,13 @@ type NNTPProvider interface {
 	GetHdrByMsgID(w Responder, cs *ConnState, hdr []byte, msgid TCoreMsgID) bool
 	GetHdrByRange(w Responder, cs *ConnState, hdr []byte, rmin, rmax int64) bool
 	GetHdrByCurr(w Responder, cs *ConnState, hdr []byte) bool
-	GetXHdrByMsgID(w Responder, hdr []byte, msgid TCoreMsgID) bool
+	GetXHdrByMsgID(w Responder, cs *ConnState, hdr []byte, msgid TCoreMsgID) bool
 	GetXHdrByRange(w Responder, cs *ConnState, hdr []byte, rmin, rmax int64) bool
 	GetXHdrByCurr(w Responder, cs *ConnState, hdr []byte) bool
 
 	// ! implementers MUST drain readers or bad things will happen
+	// articles posted to groups cs can't post to (cs.CanPostGroup)
+	// MUST be refused with failure codes
 	// + iok: 340{ResSendArticleToBePosted} ifail: 440{ResPostingNotPermitted[false]}
 	// cok: 240{ResPostingAccepted} cfail: 441{ResPostingFailed}
 	HandlePost(w Responder, cs *ConnState, ro ReaderOpener) bool // SupportsPost()
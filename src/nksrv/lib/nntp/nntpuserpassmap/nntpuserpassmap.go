@@ -4,13 +4,15 @@ import (
 	"errors"
 
 	"nksrv/lib/nntp"
+	"nksrv/lib/utils/scram"
 	upn "nksrv/lib/utils/text/userpassnorm"
 )
 
 type node struct {
 	nntp.UserInfo
 
-	pass string
+	pass  string
+	scram scram.Credentials
 }
 
 type UserPassMap struct {
@@ -26,6 +28,7 @@ func (m UserPassMap) Initialized() bool {
 }
 
 var _ nntp.UserPassProvider = UserPassMap{}
+var _ nntp.SCRAMProvider = UserPassMap{}
 
 func (m UserPassMap) Add(ui nntp.UserInfo, pass string) (err error) {
 	ui.Name, err = upn.NormaliseUser(ui.Name)
@@ -42,9 +45,15 @@ func (m UserPassMap) Add(ui nntp.UserInfo, pass string) (err error) {
 	if ex {
 		return errors.New("duplicate username")
 	}
+	// passwordless users get credentials for empty password
+	sc, err := scram.NewCredentials(pass)
+	if err != nil {
+		return
+	}
 	m.m[ui.Name] = &node{
 		UserInfo: ui,
 		pass:     pass,
+		scram:    sc,
 	}
 	return
 }
@@ -84,3 +93,15 @@ func (m UserPassMap) NNTPCheckUserPass(name string, rpass nntp.ClientPassword) *
 	}
 	return &n.UserInfo
 }
+
+func (m UserPassMap) NNTPUserSCRAMByName(name string) (*nntp.UserInfo, *scram.Credentials) {
+	name, err := upn.NormaliseUser(name)
+	if err != nil {
+		return nil, nil
+	}
+	n := m.m[name]
+	if n == nil {
+		return nil, nil
+	}
+	return &n.UserInfo, &n.scram
+}
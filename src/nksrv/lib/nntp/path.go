@@ -0,0 +1,113 @@
+package nntp
+
+import (
+	"strings"
+	"sync"
+)
+
+// PathIdentities returns path identities mentioned in Path header value,
+// most recent first.
+// Tail entry (usually "not-for-mail") is not included.
+// Identities inside diagnostic entries (RFC 5537 section 3.2.1),
+// such as ".SEEN.example.org" or ".POSTED.example.org", are included.
+func PathIdentities(path string) (ids []string) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return
+	}
+	ents := strings.Split(path, "!")
+	ents = ents[:len(ents)-1] // tail
+	for _, e := range ents {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if e[0] == '.' {
+			var ok bool
+			e, ok = pathDiagIdentity(e)
+			if !ok {
+				continue
+			}
+		}
+		ids = append(ids, e)
+	}
+	return
+}
+
+var pathDiagKeywords = [...]string{".SEEN.", ".MISMATCH.", ".POSTED."}
+
+func pathDiagIdentity(e string) (string, bool) {
+	for _, k := range pathDiagKeywords {
+		if len(e) > len(k) && strings.EqualFold(e[:len(k)], k) {
+			return e[len(k):], true
+		}
+	}
+	return "", false
+}
+
+// PathSender returns identity of node which sent us article,
+// or empty string if Path doesn't tell that.
+func PathSender(path string) string {
+	path = strings.TrimSpace(path)
+	i := strings.IndexByte(path, '!')
+	if i <= 0 || path[0] == '.' {
+		return ""
+	}
+	return strings.TrimSpace(path[:i])
+}
+
+// PathHasAny tells whether any of names is in ids.
+// Comparison is case-insensitive.
+func PathHasAny(ids, names []string) bool {
+	for _, id := range ids {
+		for _, n := range names {
+			if n != "" && strings.EqualFold(id, n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// max amount of path identities remembered per peer
+const maxPeerPathIDs = 8
+
+// PeerPathIDs remembers path identities peers used
+// when sending articles to us.
+type PeerPathIDs struct {
+	mu  sync.RWMutex
+	ids map[string][]string
+}
+
+func NewPeerPathIDs() *PeerPathIDs {
+	return &PeerPathIDs{ids: make(map[string][]string)}
+}
+
+// Add records id for peer.
+// It returns true if id wasn't known before.
+func (p *PeerPathIDs) Add(peer, id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	l := p.ids[peer]
+	for _, x := range l {
+		if strings.EqualFold(x, id) {
+			return false
+		}
+	}
+	if len(l) >= maxPeerPathIDs {
+		// forget oldest
+		l = append(l[:0:0], l[1:]...)
+	}
+	p.ids[peer] = append(l, id)
+	return true
+}
+
+// Get returns path identities recorded for peer.
+// Returned slice must not be modified.
+func (p *PeerPathIDs) Get(peer string) []string {
+	p.mu.RLock()
+	l := p.ids[peer]
+	p.mu.RUnlock()
+	return l
+}
@@ -0,0 +1,62 @@
+package nntp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathIdentities(t *testing.T) {
+	for _, tc := range []struct {
+		path   string
+		ids    []string
+		sender string
+	}{
+		{"", nil, ""},
+		{"not-for-mail", nil, ""},
+		{"a.example!not-for-mail", []string{"a.example"}, "a.example"},
+		{
+			" b.example!.SEEN.c.example!c.example!.POSTED.d.example!not-for-mail ",
+			[]string{"b.example", "c.example", "c.example", "d.example"},
+			"b.example",
+		},
+		{
+			".POSTED!e.example!!f.example!.MISMATCH.g.example!x",
+			[]string{"e.example", "f.example", "g.example"},
+			"",
+		},
+		{"!h.example!x", []string{"h.example"}, ""},
+	} {
+		ids := PathIdentities(tc.path)
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("PathIdentities(%q) = %q, expected %q", tc.path, ids, tc.ids)
+		}
+		if s := PathSender(tc.path); s != tc.sender {
+			t.Errorf("PathSender(%q) = %q, expected %q", tc.path, s, tc.sender)
+		}
+	}
+
+	ids := PathIdentities("a.example!B.Example!not-for-mail")
+	if !PathHasAny(ids, []string{"x", "b.example"}) {
+		t.Errorf("PathHasAny should match case-insensitively")
+	}
+	if PathHasAny(ids, []string{"not-for-mail", ""}) {
+		t.Errorf("PathHasAny shouldn't match tail or empty name")
+	}
+}
+
+func TestPeerPathIDs(t *testing.T) {
+	p := NewPeerPathIDs()
+	if !p.Add("peer", "a") || p.Add("peer", "A") {
+		t.Errorf("unexpected Add results")
+	}
+	for i := 0; i < maxPeerPathIDs; i++ {
+		p.Add("peer", string(rune('b'+i)))
+	}
+	l := p.Get("peer")
+	if len(l) != maxPeerPathIDs || l[0] == "a" {
+		t.Errorf("oldest identity should be forgotten, got %q", l)
+	}
+	if p.Get("other") != nil {
+		t.Errorf("unknown peer should have no identities")
+	}
+}
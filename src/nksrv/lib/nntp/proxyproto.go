@@ -0,0 +1,472 @@
+package nntp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PROXY protocol support.
+// https://www.haproxy.org/download/2.4/doc/proxy-protocol.txt
+
+const DefaultProxyTimeout = 10 * time.Second
+
+// ProxyTLSInfo is TLS information sent by proxy terminating TLS.
+type ProxyTLSInfo struct {
+	ClientSSL      bool // client connected over TLS
+	ClientCertConn bool // client sent certificate in this connection
+	ClientCertSess bool // client sent certificate in this session
+	Verified       bool // proxy verified client's certificate
+
+	Version string
+	CN      string // client certificate's common name
+	Cipher  string
+	SigAlg  string
+	KeyAlg  string
+}
+
+// ProxyInfo describes connection accepted through proxy.
+type ProxyInfo struct {
+	ProxyAddr net.Addr // address proxy connected from
+	Local     bool     // addresses not provided, e.g. proxy's own health check
+
+	// original addresses, nil if not known
+	Source      net.Addr
+	Destination net.Addr
+
+	ALPN      string
+	Authority string // server name requested by client
+	TLS       *ProxyTLSInfo
+}
+
+// ParseProxyTrusted parses list of trusted proxy sources.
+// Each of them is either address or CIDR prefix.
+func ParseProxyTrusted(list []string) (nets []*net.IPNet, err error) {
+	for _, x := range list {
+		if strings.IndexByte(x, '/') >= 0 {
+			var n *net.IPNet
+			_, n, err = net.ParseCIDR(x)
+			if err != nil {
+				return nil, err
+			}
+			nets = append(nets, n)
+			continue
+		}
+		ip := net.ParseIP(x)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address %q", x)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		nets = append(nets, &net.IPNet{
+			IP:   ip,
+			Mask: net.CIDRMask(len(ip)*8, len(ip)*8),
+		})
+	}
+	return
+}
+
+var errProxyHeader = errors.New("invalid PROXY header")
+
+var proxyV2Sig = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyV1MaxLen = 107
+
+	pp2TypeALPN      = 0x01
+	pp2TypeAuthority = 0x02
+	pp2TypeSSL       = 0x20
+	pp2SubtypeSSLVer = 0x21
+	pp2SubtypeSSLCN  = 0x22
+	pp2SubtypeCipher = 0x23
+	pp2SubtypeSigAlg = 0x24
+	pp2SubtypeKeyAlg = 0x25
+
+	pp2ClientSSL      = 0x01
+	pp2ClientCertConn = 0x02
+	pp2ClientCertSess = 0x04
+)
+
+// readProxyHeader reads PROXY header, v1 or v2.
+// It doesn't read anything past header so no buffering is needed.
+func readProxyHeader(r io.Reader) (pi *ProxyInfo, err error) {
+	var b [16]byte
+	// both v1 and v2 headers are at least this long
+	if _, err = io.ReadFull(r, b[:8]); err != nil {
+		return
+	}
+	if bytes.Equal(b[:8], proxyV2Sig[:8]) {
+		if _, err = io.ReadFull(r, b[8:16]); err != nil {
+			return
+		}
+		if !bytes.Equal(b[8:12], proxyV2Sig[8:]) {
+			return nil, errProxyHeader
+		}
+		l := binary.BigEndian.Uint16(b[14:16])
+		rest := make([]byte, l)
+		if _, err = io.ReadFull(r, rest); err != nil {
+			return
+		}
+		return parseProxyV2(b[12], b[13], rest)
+	}
+	if string(b[:6]) != "PROXY " {
+		return nil, errProxyHeader
+	}
+	line := make([]byte, 8, proxyV1MaxLen)
+	copy(line, b[:8])
+	for line[len(line)-1] != '\n' {
+		if len(line) >= proxyV1MaxLen {
+			return nil, errProxyHeader
+		}
+		if _, err = io.ReadFull(r, b[:1]); err != nil {
+			return
+		}
+		line = append(line, b[0])
+	}
+	return parseProxyV1(line)
+}
+
+func parseProxyV1(line []byte) (*ProxyInfo, error) {
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errProxyHeader
+	}
+	f := strings.Split(string(line[:len(line)-2]), " ")
+	if len(f) < 2 || f[0] != "PROXY" {
+		return nil, errProxyHeader
+	}
+	pi := &ProxyInfo{}
+	switch f[1] {
+	case "UNKNOWN":
+		// rest of line should be ignored
+		pi.Local = true
+		return pi, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, errProxyHeader
+	}
+	if len(f) != 6 {
+		return nil, errProxyHeader
+	}
+	src, dst := net.ParseIP(f[2]), net.ParseIP(f[3])
+	if src == nil || dst == nil ||
+		(src.To4() != nil) != (f[1] == "TCP4") ||
+		(dst.To4() != nil) != (f[1] == "TCP4") {
+
+		return nil, errProxyHeader
+	}
+	sp, e1 := parseProxyPort(f[4])
+	dp, e2 := parseProxyPort(f[5])
+	if e1 != nil || e2 != nil {
+		return nil, errProxyHeader
+	}
+	pi.Source = &net.TCPAddr{IP: src, Port: sp}
+	pi.Destination = &net.TCPAddr{IP: dst, Port: dp}
+	return pi, nil
+}
+
+func parseProxyPort(s string) (int, error) {
+	// no leading zeros or signs
+	if s == "" || (len(s) > 1 && s[0] == '0') || s[0] < '0' || s[0] > '9' {
+		return 0, errProxyHeader
+	}
+	p, err := strconv.ParseUint(s, 10, 16)
+	return int(p), err
+}
+
+func parseProxyV2(vercmd, fam byte, b []byte) (*ProxyInfo, error) {
+	if vercmd>>4 != 2 {
+		return nil, errProxyHeader
+	}
+	pi := &ProxyInfo{}
+	switch vercmd & 0x0F {
+	case 0:
+		// LOCAL, addresses must be ignored
+		pi.Local = true
+	case 1:
+		// PROXY
+	default:
+		return nil, errProxyHeader
+	}
+
+	var alen int
+	switch fam {
+	case 0x11, 0x12: // TCP/UDP over IPv4
+		alen = 12
+	case 0x21, 0x22: // TCP/UDP over IPv6
+		alen = 36
+	case 0x31, 0x32: // UNIX stream/dgram
+		alen = 216
+	case 0x00: // UNSPEC
+		alen = 0
+	default:
+		return nil, errProxyHeader
+	}
+	if len(b) < alen {
+		return nil, errProxyHeader
+	}
+	if !pi.Local {
+		switch fam {
+		case 0x11, 0x12, 0x21, 0x22:
+			n := (alen - 4) / 2
+			src := net.IP(append([]byte(nil), b[:n]...))
+			dst := net.IP(append([]byte(nil), b[n:2*n]...))
+			sp := int(binary.BigEndian.Uint16(b[2*n:]))
+			dp := int(binary.BigEndian.Uint16(b[2*n+2:]))
+			if fam&0x0F == 0x01 {
+				pi.Source = &net.TCPAddr{IP: src, Port: sp}
+				pi.Destination = &net.TCPAddr{IP: dst, Port: dp}
+			} else {
+				pi.Source = &net.UDPAddr{IP: src, Port: sp}
+				pi.Destination = &net.UDPAddr{IP: dst, Port: dp}
+			}
+		case 0x31, 0x32:
+			pi.Source = &net.UnixAddr{Name: cString(b[:108]), Net: "unix"}
+			pi.Destination = &net.UnixAddr{Name: cString(b[108:216]), Net: "unix"}
+		default:
+			// UNSPEC, keep using addresses of connection itself
+		}
+	}
+
+	err := parseProxyTLVs(b[alen:], func(t byte, v []byte) error {
+		switch t {
+		case pp2TypeALPN:
+			pi.ALPN = string(v)
+		case pp2TypeAuthority:
+			pi.Authority = string(v)
+		case pp2TypeSSL:
+			if len(v) < 5 {
+				return errProxyHeader
+			}
+			ti := &ProxyTLSInfo{
+				ClientSSL:      v[0]&pp2ClientSSL != 0,
+				ClientCertConn: v[0]&pp2ClientCertConn != 0,
+				ClientCertSess: v[0]&pp2ClientCertSess != 0,
+				Verified:       binary.BigEndian.Uint32(v[1:5]) == 0,
+			}
+			err := parseProxyTLVs(v[5:], func(st byte, sv []byte) error {
+				switch st {
+				case pp2SubtypeSSLVer:
+					ti.Version = string(sv)
+				case pp2SubtypeSSLCN:
+					ti.CN = string(sv)
+				case pp2SubtypeCipher:
+					ti.Cipher = string(sv)
+				case pp2SubtypeSigAlg:
+					ti.SigAlg = string(sv)
+				case pp2SubtypeKeyAlg:
+					ti.KeyAlg = string(sv)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			pi.TLS = ti
+		}
+		// other types aren't interesting for us
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pi, nil
+}
+
+func parseProxyTLVs(b []byte, f func(t byte, v []byte) error) error {
+	for len(b) != 0 {
+		if len(b) < 3 {
+			return errProxyHeader
+		}
+		l := int(binary.BigEndian.Uint16(b[1:3]))
+		if len(b) < 3+l {
+			return errProxyHeader
+		}
+		if err := f(b[0], b[3:3+l]); err != nil {
+			return err
+		}
+		b = b[3+l:]
+	}
+	return nil
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// proxyConn is connection which came through proxy.
+// It reports original addresses.
+type proxyConn struct {
+	ConnCW
+	info *ProxyInfo
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.info.Source != nil {
+		return c.info.Source
+	}
+	return c.ConnCW.RemoteAddr()
+}
+
+func (c *proxyConn) LocalAddr() net.Addr {
+	if c.info.Destination != nil {
+		return c.info.Destination
+	}
+	return c.ConnCW.LocalAddr()
+}
+
+func (c *proxyConn) ProxyInfo() *ProxyInfo {
+	return c.info
+}
+
+type proxyInfoGetter interface {
+	ProxyInfo() *ProxyInfo
+}
+
+// proxyListenerWrapper decodes PROXY headers of connections
+// coming from trusted sources.
+// Connections from other sources are passed as-is.
+// Headers are read in separate goroutines so that slow sources
+// don't hold back accepting of other connections.
+type proxyListenerWrapper struct {
+	ListenerCW
+	trusted func() ([]*net.IPNet, time.Duration)
+	onError func(c ConnCW, err error)
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	ready     chan proxyAcceptResult
+	stopped   chan struct{}
+	err       error // set before stopped is closed
+}
+
+type proxyAcceptResult struct {
+	c   ConnCW
+	err error
+}
+
+var _ ListenerCW = (*proxyListenerWrapper)(nil)
+
+func newProxyListenerWrapper(
+	l ListenerCW, trusted func() ([]*net.IPNet, time.Duration),
+	onError func(c ConnCW, err error)) *proxyListenerWrapper {
+
+	return &proxyListenerWrapper{
+		ListenerCW: l,
+		trusted:    trusted,
+		onError:    onError,
+		ready:      make(chan proxyAcceptResult),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// NewProxyListener wraps l so that connections from trusted sources
+// are expected to start with PROXY header.
+// onError, if not nil, is called before bad connections are closed.
+func NewProxyListener(
+	l ListenerCW, trusted []*net.IPNet, timeout time.Duration,
+	onError func(c ConnCW, err error)) ListenerCW {
+
+	return newProxyListenerWrapper(
+		l,
+		func() ([]*net.IPNet, time.Duration) { return trusted, timeout },
+		onError)
+}
+
+func isTrustedProxy(trusted []*net.IPNet, a net.Addr) bool {
+	ta, ok := a.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ta.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *proxyListenerWrapper) stop(err error) {
+	w.stopOnce.Do(func() {
+		w.err = err
+		close(w.stopped)
+	})
+}
+
+func (w *proxyListenerWrapper) deliver(r proxyAcceptResult) {
+	select {
+	case w.ready <- r:
+	case <-w.stopped:
+		if r.c != nil {
+			r.c.Close()
+		}
+	}
+}
+
+func (w *proxyListenerWrapper) handshake(c ConnCW, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultProxyTimeout
+	}
+	_ = c.SetReadDeadline(time.Now().Add(timeout))
+	pi, err := readProxyHeader(c)
+	_ = c.SetReadDeadline(time.Time{})
+	if err != nil {
+		if w.onError != nil {
+			w.onError(c, err)
+		}
+		c.Close()
+		return
+	}
+	pi.ProxyAddr = c.RemoteAddr()
+	w.deliver(proxyAcceptResult{c: &proxyConn{ConnCW: c, info: pi}})
+}
+
+func (w *proxyListenerWrapper) acceptLoop() {
+	for {
+		c, err := w.ListenerCW.AcceptCW()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				// let caller decide how to handle it
+				w.deliver(proxyAcceptResult{err: err})
+				continue
+			}
+			w.stop(err)
+			return
+		}
+		trusted, timeout := w.trusted()
+		if !isTrustedProxy(trusted, c.RemoteAddr()) {
+			w.deliver(proxyAcceptResult{c: c})
+			continue
+		}
+		go w.handshake(c, timeout)
+	}
+}
+
+func (w *proxyListenerWrapper) AcceptCW() (ConnCW, error) {
+	w.startOnce.Do(func() { go w.acceptLoop() })
+
+	select {
+	case r := <-w.ready:
+		return r.c, r.err
+	case <-w.stopped:
+		return nil, w.err
+	}
+}
+
+func (w *proxyListenerWrapper) Close() error {
+	err := w.ListenerCW.Close()
+	w.stop(errProxyListenerClosed)
+	return err
+}
+
+var errProxyListenerClosed = errors.New("listener closed")
@@ -0,0 +1,124 @@
+package nntp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestProxyV1(t *testing.T) {
+	const rest = "CAPABILITIES\r\n"
+	r := strings.NewReader(
+		"PROXY TCP4 192.0.2.1 198.51.100.2 56324 119\r\n" + rest)
+	pi, err := readProxyHeader(r)
+	if err != nil {
+		t.Fatalf("readProxyHeader err: %v", err)
+	}
+	if pi.Local || pi.Source.String() != "192.0.2.1:56324" ||
+		pi.Destination.String() != "198.51.100.2:119" {
+
+		t.Errorf("unexpected info %+v", pi)
+	}
+	if b, _ := ioutil.ReadAll(r); string(b) != rest {
+		t.Errorf("header reading consumed too much, left %q", b)
+	}
+
+	pi, err = readProxyHeader(strings.NewReader("PROXY UNKNOWN\r\n"))
+	if err != nil || !pi.Local || pi.Source != nil {
+		t.Errorf("UNKNOWN: unexpected info %+v err %v", pi, err)
+	}
+
+	for _, s := range []string{
+		"PROXY TCP4 192.0.2.1 198.51.100.2 56324\r\n",
+		"PROXY TCP6 192.0.2.1 198.51.100.2 56324 119\r\n",
+		"PROXY TCP4 192.0.2.1 198.51.100.2 056324 119\r\n",
+		"PROXY TCP4 192.0.2.1 198.51.100.2 56324 119\n",
+		"PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n",
+		"CAPABILITIES\r\n",
+	} {
+		if _, err = readProxyHeader(strings.NewReader(s)); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func proxyTLV(t byte, v []byte) []byte {
+	b := []byte{t, 0, 0}
+	binary.BigEndian.PutUint16(b[1:], uint16(len(v)))
+	return append(b, v...)
+}
+
+func TestProxyV2(t *testing.T) {
+	var body []byte
+	body = append(body, net.ParseIP("2001:db8::1")...)
+	body = append(body, net.ParseIP("2001:db8::2")...)
+	body = append(body, 0xDC, 0x04, 0x02, 0x33) // 56324, 563
+	ssl := []byte{pp2ClientSSL | pp2ClientCertConn, 0, 0, 0, 0}
+	ssl = append(ssl, proxyTLV(pp2SubtypeSSLVer, []byte("TLSv1.3"))...)
+	ssl = append(ssl, proxyTLV(pp2SubtypeSSLCN, []byte("peer.example"))...)
+	body = append(body, proxyTLV(pp2TypeSSL, ssl)...)
+	body = append(body, proxyTLV(pp2TypeAuthority, []byte("news.example"))...)
+
+	hdr := append([]byte(nil), proxyV2Sig...)
+	hdr = append(hdr, 0x21, 0x21, 0, 0)
+	binary.BigEndian.PutUint16(hdr[14:], uint16(len(body)))
+	hdr = append(hdr, body...)
+
+	const rest = "CAPABILITIES\r\n"
+	r := bytes.NewReader(append(hdr, rest...))
+	pi, err := readProxyHeader(r)
+	if err != nil {
+		t.Fatalf("readProxyHeader err: %v", err)
+	}
+	if pi.Local || pi.Source.String() != "[2001:db8::1]:56324" ||
+		pi.Destination.String() != "[2001:db8::2]:563" ||
+		pi.Authority != "news.example" {
+
+		t.Errorf("unexpected info %+v", pi)
+	}
+	if pi.TLS == nil || !pi.TLS.ClientSSL || !pi.TLS.ClientCertConn ||
+		!pi.TLS.Verified || pi.TLS.Version != "TLSv1.3" ||
+		pi.TLS.CN != "peer.example" {
+
+		t.Errorf("unexpected TLS info %+v", pi.TLS)
+	}
+	if b, _ := ioutil.ReadAll(r); string(b) != rest {
+		t.Errorf("header reading consumed too much, left %q", b)
+	}
+
+	// LOCAL with truncated TLV
+	hdr = append(append([]byte(nil), proxyV2Sig...), 0x20, 0x00, 0, 2, 1, 0)
+	if _, err = readProxyHeader(bytes.NewReader(hdr)); err == nil {
+		t.Errorf("expected error for truncated TLV")
+	}
+	hdr[15] = 0
+	pi, err = readProxyHeader(bytes.NewReader(hdr[:16]))
+	if err != nil || !pi.Local {
+		t.Errorf("LOCAL: unexpected info %+v err %v", pi, err)
+	}
+}
+
+func TestParseProxyTrusted(t *testing.T) {
+	nets, err := ParseProxyTrusted([]string{"127.0.0.1", "::1", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("ParseProxyTrusted err: %v", err)
+	}
+	for a, exp := range map[string]bool{
+		"127.0.0.1": true,
+		"127.0.0.2": false,
+		"::1":       true,
+		"10.1.2.3":  true,
+		"11.0.0.1":  false,
+	} {
+		ta := &net.TCPAddr{IP: net.ParseIP(a)}
+		if isTrustedProxy(nets, ta) != exp {
+			t.Errorf("%s: expected trusted=%v", a, exp)
+		}
+	}
+	if _, err = ParseProxyTrusted([]string{"localhost"}); err == nil {
+		t.Errorf("expected error for hostname")
+	}
+}
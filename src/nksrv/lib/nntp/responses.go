@@ -90,6 +90,11 @@ func (r Responder) ResSendArticleToBePosted() error {
 
 // 4** - temporary errors
 
+// ResServiceUnavailable must be followed by closing connection.
+func (r Responder) ResServiceUnavailable(e error) error {
+	return r.PrintfLine("400 %v, try again later", e)
+}
+
 func (r Responder) ResInternalError(e error) error {
 	if e != nil {
 		return r.PrintfLine("403 internal error: %v", e)
@@ -94,8 +94,9 @@ func authCmdUser(c *ConnState, args [][]byte, rest []byte) bool {
 	}
 	// I don't see issue accepting passwordless users early though
 	if ch == "" && ui != nil {
-		AbortOnErr(c.w.PrintfLine("281 authentication accepted"))
-		c.loginSuccess(ui)
+		if c.loginSuccess(ui) {
+			AbortOnErr(c.w.PrintfLine("281 authentication accepted"))
+		}
 		return true
 	}
 	// otherwise require pass
@@ -136,20 +137,70 @@ func authCmdPass(c *ConnState, args [][]byte, rest []byte) bool {
 		return true
 	}
 
-	AbortOnErr(c.w.PrintfLine("281 authentication accepted"))
-	c.loginSuccess(ol.ui)
+	if c.loginSuccess(ol.ui) {
+		AbortOnErr(c.w.PrintfLine("281 authentication accepted"))
+	}
 	return true
 }
 
-func (c *ConnState) loginSuccess(ui *UserInfo) {
+// loginSuccess sets up state of logged in user.
+// If it returns false, it has already responded and connection is closing.
+func (c *ConnState) loginSuccess(ui *UserInfo) bool {
+	if !c.peerLogin(c.srv.GetRunCfg(), ui) {
+		AbortOnErr(c.w.PrintfLine("502 too many connections for this peer"))
+		c.quit = true
+		return false
+	}
 	c.authenticated = true
+	c.userName = ui.Name
 	c.UserPriv = MergeUserPriv(c.UserPriv, ui.UserPriv)
+	c.setUserGroupAccess(ui)
 	c.log.LogPrintf(NOTICE, "logged in as name=%q serv=%q", ui.Name, ui.Serv)
+	return true
 }
 
 func authCmdSASL(c *ConnState, args [][]byte, rest []byte) bool {
+	ol := c.pullActiveLogin()
+	if ol != nil {
+		AbortOnErr(c.w.PrintfLine(
+			"482 authentication commands issued out of sequence"))
+		return true
+	}
+	if c.authenticated {
+		// do not allow multiple authentications
+		AbortOnErr(c.w.PrintfLine("502 command unavailable"))
+		return true
+	}
+
 	ToUpperASCII(args[0])
-	// TODO
-	AbortOnErr(c.w.PrintfLine("503 AUTHINFO SASL %s unimplemented", args[0]))
+	rCfg := c.srv.GetRunCfg()
+
+	mech := findSASLMech(unsafeBytesToStr(args[0]))
+	if mech == nil {
+		AbortOnErr(c.w.PrintfLine("503 mechanism not recognized"))
+		return true
+	}
+	switch mech.check(c, rCfg) {
+	case 0:
+	case 483:
+		AbortOnErr(c.w.PrintfLine("483 TLS required"))
+		return true
+	default:
+		AbortOnErr(c.w.PrintfLine("503 mechanism not available"))
+		return true
+	}
+
+	// decode initial response now, as args point to buffer we'll reuse
+	var ir []byte
+	if len(args) > 1 {
+		var e error
+		ir, e = decodeSASLData(args[1])
+		if e != nil {
+			AbortOnErr(c.w.PrintfLine("504 base64 encoding error"))
+			return true
+		}
+	}
+
+	mech.exchange(c, rCfg, ir)
 	return true
 }
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	. "nksrv/lib/utils/logx"
@@ -81,6 +82,12 @@ func init() {
 		"STARTTLS": &command{
 			cmdfunc: cmdStartTLS,
 		},
+		"COMPRESS": &command{
+			cmdfunc: cmdCompress,
+			minargs: 1,
+			maxargs: 1,
+			help:    "algorithm - enable compression.",
+		},
 		"DATE": &command{
 			cmdfunc: cmdDate,
 			help:    "- get server's current Coordinated Universal Time.",
@@ -195,10 +202,12 @@ func init() {
 
 		"POST": &command{
 			cmdfunc: cmdPost,
+			article: true,
 			help:    "- perform article posting.",
 		},
 		"IHAVE": &command{
 			cmdfunc: cmdIHave,
+			article: true,
 			minargs: 1,
 			maxargs: 1,
 			help:    "<message-id> - offer and perform article transfer.",
@@ -211,6 +220,7 @@ func init() {
 		},
 		"TAKETHIS": &command{
 			cmdfunc: cmdTakeThis,
+			article: true,
 			minargs: 1,
 			maxargs: 1,
 			help:    "<message-id> - It's dangerous to go alone! Take this.",
@@ -247,6 +257,29 @@ func init() {
 			cmdfunc: listCmdOverviewFmt,
 			help:    "- list metadata fields returned by OVER command.",
 		},
+		"HEADERS": &command{
+			cmdfunc: listCmdHeaders,
+			maxargs: 1,
+			help:    "[MSGID|RANGE] - list headers and metadata items usable with HDR command. `:` means any header.",
+		},
+		"ACTIVE.TIMES": &command{
+			cmdfunc: listCmdActiveTimes,
+			maxargs: 1,
+			help:    "[wildmat] - list newsgroups and their creation times. returns list in format `<name> <time> <creator>`.",
+		},
+		"COUNTS": &command{
+			cmdfunc: listCmdCounts,
+			maxargs: 1,
+			help:    "[wildmat] - same as ACTIVE but also includes article counts. returns list in format `<name> <high watermark> <low watermark> <count> <status>`.",
+		},
+		"DISTRIB.PATS": &command{
+			cmdfunc: listCmdDistribPats,
+			help:    "- list default Distribution header values. returns list in format `<weight>:<wildmat>:<distribution>`.",
+		},
+		"MOTD": &command{
+			cmdfunc: listCmdMOTD,
+			help:    "- print message of the day.",
+		},
 	}
 	listCommandList = sortedCmdMapSlice(listCommandMap)
 
@@ -290,8 +323,18 @@ func cmdCapabilities(c *ConnState, args [][]byte, rest []byte) bool {
 
 	fmt.Fprintf(dw, "VERSION 2\n")
 
+	mechs := c.saslMechanisms(rCfg)
 	if c.advertisePlaintextAuth(rCfg) {
-		fmt.Fprintf(dw, "AUTHINFO USER\n")
+		if len(mechs) != 0 {
+			fmt.Fprintf(dw, "AUTHINFO USER SASL\n")
+		} else {
+			fmt.Fprintf(dw, "AUTHINFO USER\n")
+		}
+	} else if len(mechs) != 0 {
+		fmt.Fprintf(dw, "AUTHINFO SASL\n")
+	}
+	if len(mechs) != 0 {
+		fmt.Fprintf(dw, "SASL %s\n", strings.Join(mechs, " "))
 	}
 
 	if c.AllowReading {
@@ -324,16 +367,33 @@ func cmdCapabilities(c *ConnState, args [][]byte, rest []byte) bool {
 		if c.prov.SupportsHdr() {
 			fmt.Fprintf(dw, "HDR\n")
 		}
+
+		if c.xlistenAvailable() {
+			fmt.Fprintf(dw, "XLISTEN\n")
+		}
 	}
 
 	if c.AllowReading || c.AllowPosting {
-		fmt.Fprintf(dw, "LIST ACTIVE NEWSGROUPS OVERVIEW.FMT\n")
+		fmt.Fprintf(dw, "LIST ACTIVE NEWSGROUPS OVERVIEW.FMT"+
+			" ACTIVE.TIMES COUNTS DISTRIB.PATS")
+		if c.AllowReading && c.prov.SupportsHdr() {
+			fmt.Fprintf(dw, " HEADERS")
+		}
+		if rCfg.MOTD != "" {
+			fmt.Fprintf(dw, " MOTD")
+		}
+		fmt.Fprintf(dw, "\n")
 	}
 
-	if rCfg.TLSConfig != nil && !c.tlsStarted() {
+	// TLS can't be started on top of compression
+	if rCfg.TLSConfig != nil && !c.tlsStarted() && !c.deflate {
 		fmt.Fprintf(dw, "STARTTLS\n")
 	}
 
+	if !c.deflate {
+		fmt.Fprintf(dw, "COMPRESS DEFLATE\n")
+	}
+
 	// XXX maybe include backend identification
 	fmt.Fprintf(dw, "IMPLEMENTATION CNTPD\n")
 
@@ -545,9 +605,13 @@ func cmdStartTLS(c *ConnState, args [][]byte, rest []byte) bool {
 		AbortOnErr(c.w.PrintfLine("502 TLS already activated"))
 		return true
 	}
+	if c.deflate {
+		AbortOnErr(c.w.PrintfLine("502 compression already active"))
+		return true
+	}
 
 	AbortOnErr(c.w.PrintfLine("382 continue with TLS negotiation"))
-	tlsc := tls.Client(c.conn, rcfg.TLSConfig)
+	tlsc := tls.Server(c.conn, rcfg.TLSConfig)
 	err := tlsc.Handshake()
 	if err != nil {
 		c.log.LogPrintf(WARN, "STARTTLS TLS negotiation error: %v", err)
@@ -1,6 +1,9 @@
 package nntp
 
-import "io"
+import (
+	"io"
+	"strings"
+)
 
 type listCmdListOpener struct {
 	Responder
@@ -33,7 +36,7 @@ func listCmdActive(c *ConnState, args [][]byte, rest []byte) bool {
 		return true
 	}
 
-	c.prov.ListActiveGroups(listCmdListOpener{c.w}, wildmat)
+	c.prov.ListActiveGroups(listCmdListOpener{c.w}, c, wildmat)
 
 	return true
 }
@@ -53,11 +56,111 @@ func listCmdNewsgroups(c *ConnState, args [][]byte, rest []byte) bool {
 		return true
 	}
 
-	c.prov.ListNewsgroups(listCmdListOpener{c.w}, wildmat)
+	c.prov.ListNewsgroups(listCmdListOpener{c.w}, c, wildmat)
 
 	return true
 }
 
+func listCmdActiveTimes(c *ConnState, args [][]byte, rest []byte) bool {
+	var wildmat []byte
+	if len(args) != 0 {
+		wildmat = args[0]
+		if !ValidWildmat(wildmat) {
+			AbortOnErr(c.w.PrintfLine("501 invalid wildmat"))
+			return true
+		}
+	}
+
+	if !c.AllowReading && !c.AllowPosting {
+		AbortOnErr(c.w.ResAuthRequired())
+		return true
+	}
+
+	c.prov.ListActiveTimes(listCmdListOpener{c.w}, c, wildmat)
+
+	return true
+}
+
+func listCmdCounts(c *ConnState, args [][]byte, rest []byte) bool {
+	var wildmat []byte
+	if len(args) != 0 {
+		wildmat = args[0]
+		if !ValidWildmat(wildmat) {
+			AbortOnErr(c.w.PrintfLine("501 invalid wildmat"))
+			return true
+		}
+	}
+
+	if !c.AllowReading && !c.AllowPosting {
+		AbortOnErr(c.w.ResAuthRequired())
+		return true
+	}
+
+	c.prov.ListCounts(listCmdListOpener{c.w}, c, wildmat)
+
+	return true
+}
+
+func listCmdDistribPats(c *ConnState, args [][]byte, rest []byte) bool {
+	if !c.AllowReading && !c.AllowPosting {
+		AbortOnErr(c.w.ResAuthRequired())
+		return true
+	}
+
+	c.prov.ListDistribPats(listCmdListOpener{c.w}, c)
+
+	return true
+}
+
+func listCmdHeaders(c *ConnState, args [][]byte, rest []byte) bool {
+	form := ListHeadersAny
+	if len(args) != 0 {
+		ToUpperASCII(args[0])
+		switch unsafeBytesToStr(args[0]) {
+		case "MSGID":
+			form = ListHeadersMsgID
+		case "RANGE":
+			form = ListHeadersRange
+		default:
+			AbortOnErr(c.w.PrintfLine("501 unrecognised argument"))
+			return true
+		}
+	}
+
+	if !c.prov.SupportsHdr() {
+		AbortOnErr(c.w.PrintfLine("503 HDR unimplemented"))
+		return true
+	}
+
+	if !c.AllowReading {
+		AbortOnErr(c.w.ResAuthRequired())
+		return true
+	}
+
+	c.prov.ListHeaders(listCmdListOpener{c.w}, c, form)
+
+	return true
+}
+
+func listCmdMOTD(c *ConnState, args [][]byte, rest []byte) bool {
+	motd := c.srv.GetRunCfg().MOTD
+	if motd == "" {
+		AbortOnErr(c.w.PrintfLine("503 no message of the day"))
+		return true
+	}
+
+	AbortOnErr(c.w.ResListFollows())
+	dw := c.w.DotWriter()
+	_, e := io.WriteString(dw, motd)
+	AbortOnErr(e)
+	if !strings.HasSuffix(motd, "\n") {
+		_, e = io.WriteString(dw, "\n")
+		AbortOnErr(e)
+	}
+	AbortOnErr(dw.Close())
+	return true
+}
+
 type cmdXGTitleOpener struct {
 	Responder
 }
@@ -90,7 +193,7 @@ func cmdXGTitle(c *ConnState, args [][]byte, rest []byte) bool {
 		return true
 	}
 
-	c.prov.ListNewsgroups(cmdXGTitleOpener{c.w}, wildmat)
+	c.prov.ListNewsgroups(cmdXGTitleOpener{c.w}, c, wildmat)
 
 	return true
 }
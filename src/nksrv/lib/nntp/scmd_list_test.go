@@ -0,0 +1,38 @@
+package nntp_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestServerListEmptyGroup(t *testing.T) {
+	lgr := newTestLogger(t)
+	addr, stop := startTestSrv(t, lgr, nil, nil)
+	defer stop()
+
+	c := dialRaw(t, addr)
+	defer c.conn.Close()
+
+	for _, tc := range []struct {
+		cmd  string
+		code int
+		want string
+	}{
+		{"LIST ACTIVE", 215, "nekos.empty 0 1 y"},
+		{"LIST ACTIVE nekos.empty", 215, "nekos.empty 0 1 y"},
+		{"LIST COUNTS", 215, "nekos.empty 0 1 0 y"},
+		{"LIST ACTIVE.TIMES nekos.*", 215, "nekos.empty "},
+		{"NEWGROUPS 19990101 000000 GMT", 231, "nekos.empty 0 1 y"},
+	} {
+		found := false
+		for _, l := range c.expectLines(tc.cmd, tc.code) {
+			if strings.HasPrefix(l, tc.want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: no %q line", tc.cmd, tc.want)
+		}
+	}
+	c.expect("GROUP nekos.empty", 211)
+}
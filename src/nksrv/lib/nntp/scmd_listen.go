@@ -1,6 +1,7 @@
 package nntp
 
 import (
+	"strconv"
 	"sync"
 	"time"
 )
@@ -19,14 +20,53 @@ const (
 )
 
 type nntpListenObj struct {
-	c *ConnState
+	c  *ConnState
+	pn *PushNotifier
 
 	mu sync.Mutex
 	cv sync.Cond
 
 	wstate nntpListenState
-	nawake bool // incase event occured while wasn't waiting
-	werr   bool // did worker erred?
+	nawake bool   // incase event occured while wasn't waiting
+	werr   bool   // did worker erred?
+	gen    uint64 // bumped by every XWAIT, so that stale workers know to quit
+
+	filter Wildmat     // nil means all groups; protected by mu
+	access GroupAccess // snapshot from XLISTEN time; protected by mu
+}
+
+// NotifyArticle is called by PushNotifier when new article arrives.
+func (o *nntpListenObj) NotifyArticle(cmsgid TCoreMsgIDStr, groups []string) {
+	o.mu.Lock()
+	want := false
+	for _, g := range groups {
+		if o.access.CanReadGroup(g) &&
+			(o.filter == nil || o.filter.CheckString(g)) {
+
+			want = true
+			break
+		}
+	}
+	o.mu.Unlock()
+
+	if want {
+		o.awake()
+	}
+}
+
+// stop unregisters listener and makes worker quit without responding,
+// used when connection goes away.
+func (o *nntpListenObj) stop() {
+	o.pn.remove(o)
+
+	o.mu.Lock()
+	if o.wstate == nntpListenWaiting {
+		o.wstate = nntpListenNone
+		o.mu.Unlock()
+		o.cv.Broadcast()
+		return
+	}
+	o.mu.Unlock()
 }
 
 func (o *nntpListenObj) cancel() (die bool) {
@@ -69,12 +109,12 @@ func (o *nntpListenObj) awake() (nomore bool) {
 	return
 }
 
-func (o *nntpListenObj) timeoutfunc() {
+func (o *nntpListenObj) timeoutfunc(gen uint64) {
 
 	sendtimeout := false
 
 	o.mu.Lock()
-	if o.wstate == nntpListenWaiting {
+	if o.wstate == nntpListenWaiting && o.gen == gen {
 		o.wstate = nntpListenTimeout
 		sendtimeout = true
 	}
@@ -92,26 +132,31 @@ func (o *nntpListenObj) timeoutfunc() {
 	}
 }
 
-func (o *nntpListenObj) worker(dur int32) {
+// worker waits for event of XWAIT gen.
+// If that XWAIT got cancelled before worker even started,
+// state may already belong to next XWAIT, which has its own worker.
+func (o *nntpListenObj) worker(dur int32, gen uint64) {
 
 	var t *time.Timer
 	if dur > 0 {
 		t = time.AfterFunc(
-			time.Duration(dur)*time.Millisecond, o.timeoutfunc)
+			time.Duration(dur)*time.Millisecond,
+			func() { o.timeoutfunc(gen) })
 	}
 
 	o.mu.Lock()
-	for o.wstate == nntpListenWaiting {
+	for o.wstate == nntpListenWaiting && o.gen == gen {
 		o.cv.Wait()
 	}
 	st := o.wstate
+	stale := o.gen != gen
 	o.mu.Unlock()
 
 	if t != nil {
 		t.Stop()
 	}
 
-	if st == nntpListenAwaking {
+	if st == nntpListenAwaking && !stale {
 		err := o.c.w.ResXWaitAwake()
 		o.mu.Lock()
 		o.wstate = nntpListenNone
@@ -121,28 +166,108 @@ func (o *nntpListenObj) worker(dur int32) {
 	}
 }
 
+// xlistenAvailable tells whether XLISTEN can be used on connection.
+func (c *ConnState) xlistenAvailable() bool {
+	return c.AllowReading && c.prov.SupportsXListen() &&
+		c.srv.GetRunCfg().Notifier != nil
+}
+
+// cancelWait interrupts XWAIT in progress, if any, before next command.
+// Returns true if connection should be aborted.
+func (c *ConnState) cancelWait() bool {
+	if !c.activeWait {
+		return false
+	}
+	c.activeWait = false
+	return c.listen.cancel()
+}
+
+// stopListening cleans up listener once connection is done.
+func (c *ConnState) stopListening() {
+	if c.listen != nil {
+		c.listen.stop()
+		c.listen = nil
+	}
+}
+
 func cmdXListen(c *ConnState, args [][]byte, rest []byte) bool {
-	if !c.prov.SupportsXListen() || true {
-		AbortOnErr(c.w.PrintfLine("503 XLISTEN unimplemented"))
+	if !c.AllowReading {
+		AbortOnErr(c.w.ResAuthRequired())
+		return true
+	}
+	if !c.xlistenAvailable() {
+		AbortOnErr(c.w.PrintfLine("503 XLISTEN unavailable"))
 		return true
 	}
-	t := unsafeBytesToStr(args[0])
-	if t != "*" {
-		AbortOnErr(c.w.PrintfLine("503 XLISTEN %q unimplemented", t))
+	if len(rest) != 0 {
+		AbortOnErr(c.w.PrintfLine("501 too much parameters"))
 		return true
 	}
+
+	var filter Wildmat
+	if t := unsafeBytesToStr(args[0]); t != "*" {
+		if !ValidWildmat(args[0]) {
+			AbortOnErr(c.w.PrintfLine("501 invalid wildmat"))
+			return true
+		}
+		filter = CompileWildmat(args[0])
+	}
+
 	o := c.listen
 	if o == nil {
 		// allocate new listener
-		o = &nntpListenObj{c: c}
+		o = &nntpListenObj{c: c, pn: c.srv.GetRunCfg().Notifier}
 		o.cv.L = &o.mu
-		// XXX
+		c.listen = o
+		o.pn.add(o)
 	}
-	return false // XXX
+	o.mu.Lock()
+	o.filter = filter
+	o.access = c.GroupAccess
+	o.mu.Unlock()
+
+	AbortOnErr(c.w.ResXListening())
+	return true
 }
 
 func cmdXWait(c *ConnState, args [][]byte, rest []byte) bool {
-	// TODO
-	AbortOnErr(c.w.PrintfLine("503 XLISTEN unimplemented"))
+	o := c.listen
+	if o == nil {
+		AbortOnErr(c.w.PrintfLine("412 XLISTEN first"))
+		return true
+	}
+
+	dur, e := strconv.ParseInt(unsafeBytesToStr(args[0]), 10, 32)
+	if e != nil || dur < -1 {
+		AbortOnErr(c.w.PrintfLine("501 invalid wait duration"))
+		return true
+	}
+
+	o.mu.Lock()
+	if o.nawake {
+		// event already happened
+		o.nawake = false
+		o.mu.Unlock()
+		AbortOnErr(c.w.ResXWaitAwake())
+		return true
+	}
+	if dur == 0 {
+		// just polling
+		o.mu.Unlock()
+		AbortOnErr(c.w.ResXWaitTimeout())
+		return true
+	}
+	o.wstate = nntpListenWaiting
+	o.werr = false
+	o.gen++
+	gen := o.gen
+	o.mu.Unlock()
+	// wake up stale worker, if any, so that it quits
+	o.cv.Broadcast()
+
+	// worker responds once event or timeout happens,
+	// any command sent meanwhile cancels wait
+	c.activeWait = true
+	go o.worker(int32(dur), gen)
 	return true
 }
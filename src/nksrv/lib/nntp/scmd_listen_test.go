@@ -0,0 +1,209 @@
+package nntp
+
+import (
+	"bufio"
+	"bytes"
+	tp "net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer collects responses written by XWAIT workers
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+// lines returns response codes written so far
+func (b *lockedBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var l []string
+	for _, s := range strings.Split(b.b.String(), "\r\n") {
+		if s != "" {
+			l = append(l, s[:3])
+		}
+	}
+	return l
+}
+
+func newTestListener(
+	t *testing.T, filter string) (*ConnState, *PushNotifier, *lockedBuffer) {
+
+	b := &lockedBuffer{}
+	c := &ConnState{w: Responder{tp.NewWriter(bufio.NewWriter(b))}}
+	pn := NewPushNotifier()
+	o := &nntpListenObj{c: c, pn: pn}
+	o.cv.L = &o.mu
+	if filter != "" {
+		o.filter = CompileWildmatStr(filter)
+	}
+	c.listen = o
+	pn.add(o)
+	return c, pn, b
+}
+
+func xwait(c *ConnState, dur string) {
+	cmdXWait(c, [][]byte{[]byte(dur)}, nil)
+}
+
+// waitLines waits till n responses are written
+func waitLines(t *testing.T, b *lockedBuffer, n int) []string {
+	for i := 0; i < 200; i++ {
+		if l := b.lines(); len(l) >= n {
+			return l
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("got responses %q, want %d", b.lines(), n)
+	return nil
+}
+
+func expectLines(t *testing.T, b *lockedBuffer, want ...string) {
+	// give stray workers chance to write something they shouldn't
+	time.Sleep(20 * time.Millisecond)
+	l := b.lines()
+	if strings.Join(l, " ") != strings.Join(want, " ") {
+		t.Fatalf("got responses %q, want %q", l, want)
+	}
+}
+
+func TestXWaitAwake(t *testing.T) {
+	c, pn, b := newTestListener(t, "")
+	xwait(c, "-1")
+	pn.NotifyArticle("a@b", []string{"x"})
+	waitLines(t, b, 1)
+	if c.cancelWait() {
+		t.Fatal("cancelWait wants abort")
+	}
+	// awake already went out, so nothing to cancel
+	expectLines(t, b, "291")
+}
+
+func TestXWaitEventBeforeWait(t *testing.T) {
+	c, pn, b := newTestListener(t, "")
+	pn.NotifyArticle("a@b", []string{"x"})
+	xwait(c, "-1")
+	expectLines(t, b, "291")
+	if c.activeWait {
+		t.Fatal("pending event shouldn't start worker")
+	}
+	// event was consumed
+	xwait(c, "0")
+	expectLines(t, b, "291", "490")
+}
+
+func TestXWaitFilter(t *testing.T) {
+	c, pn, b := newTestListener(t, "a.*")
+	xwait(c, "-1")
+	pn.NotifyArticle("a@b", []string{"b.x"})
+	expectLines(t, b)
+	pn.NotifyArticle("a@b", []string{"b.x", "a.x"})
+	waitLines(t, b, 1)
+	c.cancelWait()
+	expectLines(t, b, "291")
+}
+
+func TestXWaitTimeout(t *testing.T) {
+	c, pn, b := newTestListener(t, "")
+	xwait(c, "10")
+	waitLines(t, b, 1)
+	c.cancelWait()
+	// event after timeout is kept for next XWAIT
+	pn.NotifyArticle("a@b", []string{"x"})
+	expectLines(t, b, "490")
+	xwait(c, "-1")
+	expectLines(t, b, "490", "291")
+}
+
+func TestXWaitCancel(t *testing.T) {
+	c, _, b := newTestListener(t, "")
+	xwait(c, "-1")
+	if c.cancelWait() {
+		t.Fatal("cancelWait wants abort")
+	}
+	if c.cancelWait() {
+		t.Fatal("second cancelWait wants abort")
+	}
+	expectLines(t, b, "491")
+}
+
+func TestXWaitStop(t *testing.T) {
+	c, pn, b := newTestListener(t, "")
+	xwait(c, "10")
+	c.stopListening()
+	pn.NotifyArticle("a@b", []string{"x"})
+	// worker quits without saying anything, connection is gone
+	time.Sleep(30 * time.Millisecond)
+	expectLines(t, b)
+	pn.mu.RLock()
+	n := len(pn.pushers)
+	pn.mu.RUnlock()
+	if n != 0 {
+		t.Fatalf("listener still registered")
+	}
+}
+
+// every XWAIT gets exactly one response, no matter how
+// events, timeouts and cancels race each other
+func TestXWaitRaces(t *testing.T) {
+	c, pn, b := newTestListener(t, "")
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			pn.NotifyArticle("a@b", []string{"x"})
+			time.Sleep(time.Duration(len(b.lines())%3) * time.Millisecond)
+		}
+	}()
+
+	const n = 300
+	for i := 0; i < n; i++ {
+		xwait(c, "1")
+		if i%2 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		if c.cancelWait() {
+			t.Fatal("cancelWait wants abort")
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if l := b.lines(); len(l) != n {
+		t.Fatalf("got %d responses for %d XWAITs", len(l), n)
+	}
+}
+
+// worker of cancelled XWAIT must not act upon XWAIT issued after it
+func TestXWaitStaleWorker(t *testing.T) {
+	c, _, b := newTestListener(t, "")
+
+	const n = 300
+	for i := 0; i < n; i++ {
+		xwait(c, "1")
+		c.cancelWait()
+	}
+	if l := b.lines(); len(l) != n {
+		t.Fatalf("got %d responses for %d XWAITs", len(l), n)
+	}
+	// waits forever, so nothing should be said
+	xwait(c, "-1")
+	expectLines(t, b, b.lines()[:n]...)
+	c.cancelWait()
+}
@@ -91,7 +91,9 @@ func cmdGroup(c *ConnState, args [][]byte, rest []byte) bool {
 		return true
 	}
 
-	if !c.prov.SelectGroup(c.w, c, args[0]) {
+	// hidden groups don't exist as far as client can tell
+	if !c.CanReadGroup(unsafeBytesToStr(args[0])) ||
+		!c.prov.SelectGroup(c.w, c, args[0]) {
 		AbortOnErr(c.w.ResNoSuchNewsgroup())
 	}
 	return true
@@ -127,7 +129,8 @@ func cmdListGroup(c *ConnState, args [][]byte, rest []byte) bool {
 		return true
 	}
 
-	if !c.prov.SelectAndListGroup(c.w, c, group, rmin, rmax) {
+	if (group != nil && !c.CanReadGroup(unsafeBytesToStr(group))) ||
+		!c.prov.SelectAndListGroup(c.w, c, group, rmin, rmax) {
 		AbortOnErr(c.w.ResNoSuchNewsgroup())
 	}
 	return true
@@ -200,7 +203,7 @@ func cmdNewNews(c *ConnState, args [][]byte, rest []byte) bool {
 		return true
 	}
 
-	c.prov.ListNewNews(cmdNewNewsOpener{c.w}, wildmat, qt)
+	c.prov.ListNewNews(cmdNewNewsOpener{c.w}, c, wildmat, qt)
 
 	return true
 }
@@ -236,7 +239,7 @@ func cmdNewGroups(c *ConnState, args [][]byte, rest []byte) bool {
 		return true
 	}
 
-	c.prov.ListNewGroups(cmdNewGroupsOpener{c.w}, qt)
+	c.prov.ListNewGroups(cmdNewGroupsOpener{c.w}, c, qt)
 
 	return true
 }
@@ -323,7 +326,7 @@ func commonCmdHdr(c *ConnState, args [][]byte, hdr bool) {
 				if hdr {
 					ok = c.prov.GetHdrByMsgID(c.w, c, hq, CutMessageID(mid))
 				} else {
-					ok = c.prov.GetXHdrByMsgID(c.w, hq, CutMessageID(mid))
+					ok = c.prov.GetXHdrByMsgID(c.w, c, hq, CutMessageID(mid))
 				}
 			}
 			if !ok {
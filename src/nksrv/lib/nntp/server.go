@@ -34,7 +34,8 @@ type ListenerCW interface {
 
 // config opts easy to swap at run time
 type NNTPServerRunCfg struct {
-	DefaultPriv UserPriv
+	DefaultPriv        UserPriv
+	DefaultGroupAccess GroupAccess
 
 	NNTPS     bool
 	TLSConfig *tls.Config
@@ -46,6 +47,22 @@ type NNTPServerRunCfg struct {
 
 	UnsafePass            bool // plaintext pass without TLS
 	UnsafeEarlyUserReject bool // reject username early - allows enumeration
+
+	MOTD string // message of the day for LIST MOTD, none if empty
+
+	Limits    ConnLimits
+	Rates     RateLimits
+	PeerRates RateLimits // once logged in as peer
+
+	// sources allowed to use PROXY protocol, they must use it
+	ProxyTrusted []*net.IPNet
+	ProxyTimeout time.Duration // DefaultProxyTimeout if 0
+
+	// path identities of peers, shared with outgoing feeds, may be nil
+	PeerPathIDs *PeerPathIDs
+
+	// source of new article events for XLISTEN, may be nil
+	Notifier *PushNotifier
 }
 
 var DefaultNNTPServerRunCfg = NNTPServerRunCfg{
@@ -64,7 +81,12 @@ type NNTPServer struct {
 	wg          sync.WaitGroup
 	cwg         sync.WaitGroup
 	listeners   map[ListenerCW]struct{}
-	connections map[ConnCW]struct{}
+	connections map[ConnCW]*connEntry
+	prefixConns map[string]int
+	peerConns   map[string]int
+
+	rejectedConns uint64
+	rateLimited   uint64
 }
 
 func (s *NNTPServer) GetRunCfg() *NNTPServerRunCfg {
@@ -110,7 +132,9 @@ func NewNNTPServer(
 		prov:        prov,
 		logx:        logx,
 		listeners:   make(map[ListenerCW]struct{}),
-		connections: make(map[ConnCW]struct{}),
+		connections: make(map[ConnCW]*connEntry),
+		prefixConns: make(map[string]int),
+		peerConns:   make(map[string]int),
 	}
 	s.SetRunCfg(runCfg)
 	s.log = NewLogToX(logx, fmt.Sprintf("nntpsrv.%p", s))
@@ -134,31 +158,32 @@ func (s *NNTPServer) tryRegister(l ListenerCW) (bool, error) {
 	return true, nil
 }
 
+// checkClosing tells whether listener was closed on purpose
 func (s *NNTPServer) checkClosing(l ListenerCW) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.closing {
-		return false
-	}
-	delete(s.listeners, l)
-	return true
+	_, registered := s.listeners[l]
+	return s.closing || !registered
 }
 
-func (s *NNTPServer) registerConnAndWorker(c ConnCW) {
+func (s *NNTPServer) unregisterListener(l ListenerCW) {
 	s.mu.Lock()
-	s.connections[c] = struct{}{}
-	s.cwg.Add(1)
+	delete(s.listeners, l)
 	s.mu.Unlock()
 }
 
-func (s *NNTPServer) unregisterConn(c ConnCW) {
+// CloseListener stops accepting connections on single listener.
+// Connections accepted earlier aren't affected.
+func (s *NNTPServer) CloseListener(l ListenerCW) {
 	s.mu.Lock()
-	delete(s.connections, c)
+	delete(s.listeners, l)
 	s.mu.Unlock()
+
+	l.Close()
 }
 
-func (s *NNTPServer) handleConnection(c ConnCW) {
+func (s *NNTPServer) handleConnection(c ConnCW, rcfg *NNTPServerRunCfg) {
 
 	defer s.cwg.Done()
 	defer s.unregisterConn(c)
@@ -170,14 +195,19 @@ func (s *NNTPServer) handleConnection(c ConnCW) {
 		prov: s.prov,
 	}
 
-	rcfg := s.GetRunCfg()
+	cs.log = NewLogToX(
+		s.logx, fmt.Sprintf("nntpsrv.%p.client.%p-%s", s, cs, c.RemoteAddr()))
 
 	cs.setupDefaults(rcfg)
 
+	if pg, ok := c.(proxyInfoGetter); ok {
+		cs.postProxy(rcfg, pg.ProxyInfo())
+	}
+
 	var fc net.Conn
 	if rcfg.NNTPS {
 		// this is TLS server
-		tlsc := tls.Client(c, rcfg.TLSConfig)
+		tlsc := tls.Server(c, rcfg.TLSConfig)
 		err := tlsc.Handshake()
 		if err != nil {
 			s.log.LogPrintf(WARN,
@@ -194,9 +224,6 @@ func (s *NNTPServer) handleConnection(c ConnCW) {
 		fc = c
 	}
 
-	cs.log = NewLogToX(
-		s.logx, fmt.Sprintf("nntpsrv.%p.client.%p-%s", s, cs, c.RemoteAddr()))
-
 	cs.r = bufreader.NewBufReader(fc)
 	cs.w = Responder{tp.NewWriter(bufio.NewWriter(fc))}
 
@@ -215,13 +242,14 @@ func (s *NNTPServer) handleConnection(c ConnCW) {
 	fc.Close()
 }
 
-func (s *NNTPServer) ListenAndServe(
-	network, addr string, listenParam ListenParam) error {
+// Listen sets up TCP listener suitable for use with Serve.
+func (s *NNTPServer) Listen(
+	network, addr string, listenParam ListenParam) (ListenerCW, error) {
 
 	raddr, err := net.ResolveTCPAddr(network, addr)
 	if err != nil {
 		s.log.LogPrintf(ERROR, "failed to resolve {%s}%s: %v", network, addr, err)
-		return err
+		return nil, err
 	}
 	s.log.LogPrintf(INFO, "{%s}%s resolved to %s", network, addr, raddr)
 
@@ -229,16 +257,39 @@ func (s *NNTPServer) ListenAndServe(
 	if err != nil {
 		s.log.LogPrintf(ERROR,
 			"failed to listen on {%s}%s: %v", network, raddr, err)
-		return err
+		return nil, err
 	}
 	s.log.LogPrintf(INFO, "listening on {%s}%s", network, raddr)
 
-	w := tcpListenerWrapper{
+	l := tcpListenerWrapper{
 		TCPListener: tl,
 		keepAlive:   listenParam.KeepAlive,
 	}
 
-	return s.Serve(w)
+	// trusted proxies are part of run cfg so that they can be changed
+	// without reopening listeners
+	return newProxyListenerWrapper(
+		l,
+		func() ([]*net.IPNet, time.Duration) {
+			rcfg := s.GetRunCfg()
+			return rcfg.ProxyTrusted, rcfg.ProxyTimeout
+		},
+		func(c ConnCW, err error) {
+			s.log.LogPrintf(WARN,
+				"closing %s on %s because of PROXY header error: %v",
+				c.RemoteAddr(), c.LocalAddr(), err)
+		}), nil
+}
+
+func (s *NNTPServer) ListenAndServe(
+	network, addr string, listenParam ListenParam) error {
+
+	l, err := s.Listen(network, addr, listenParam)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
 }
 
 func (s *NNTPServer) Serve(l ListenerCW) error {
@@ -247,6 +298,7 @@ func (s *NNTPServer) Serve(l ListenerCW) error {
 		return err
 	}
 	defer s.wg.Done()
+	defer s.unregisterListener(l)
 
 	s.log.LogPrintf(INFO, "accepting connections on %s", l.Addr())
 
@@ -276,11 +328,15 @@ func (s *NNTPServer) Serve(l ListenerCW) error {
 		}
 		s.log.LogPrintf(
 			NOTICE, "accepted %s on %s", c.RemoteAddr(), c.LocalAddr())
+		rcfg := s.GetRunCfg()
 		// track it, we gonna need it when closing,
 		// as Serve() functions may prematurely return and thats OK
-		s.registerConnAndWorker(c)
+		if !s.registerConnAndWorker(c, &rcfg.Limits) {
+			go s.rejectConnection(c, rcfg.NNTPS)
+			continue
+		}
 		// spawn handler
-		go s.handleConnection(c)
+		go s.handleConnection(c, rcfg)
 	}
 }
 
@@ -346,6 +402,8 @@ func (c *ConnState) serveClient() bool {
 		}
 	}()
 
+	defer c.stopListening()
+
 	if c.AllowPosting {
 		AbortOnErr(c.w.PrintfLine("200 welcome! posting allowed."))
 	} else {
@@ -356,6 +414,10 @@ func (c *ConnState) serveClient() bool {
 
 	for {
 		i, e := c.r.ReadUntil(c.inbuf[:], '\n')
+		if (e == nil || e == bufreader.ErrDelimNotFound) && c.cancelWait() {
+			// XWAIT result couldn't be sent
+			return true
+		}
 		if e != nil {
 			if e == bufreader.ErrDelimNotFound {
 				// command line too big to process, drain and signal error
@@ -391,6 +453,11 @@ func (c *ConnState) serveClient() bool {
 
 		c.log.LogPrintf(DEBUG, "got %q", incmd)
 
+		if !c.throttle(&c.cmdBucket, "command") {
+			_ = c.w.PrintfLine("400 too many commands, slow down")
+			return false
+		}
+
 		x := parseKeyword(incmd)
 		cmd, ok := commandMap[string(incmd[:x])]
 		if !ok {
@@ -400,6 +467,11 @@ func (c *ConnState) serveClient() bool {
 		}
 		//c.log.LogPrintf(INFO, "processing command %q", incmd[:x])
 
+		if cmd.article && !c.throttle(&c.artBucket, "article") {
+			_ = c.w.PrintfLine("400 too many articles, slow down")
+			return false
+		}
+
 		args = args[:0] // reuse
 
 		if x >= len(incmd) {
@@ -466,5 +538,8 @@ func (c *ConnState) serveClient() bool {
 			return false
 		}
 	nextcommand:
+		if c.quit {
+			return false
+		}
 	}
 }
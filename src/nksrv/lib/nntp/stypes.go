@@ -2,6 +2,8 @@ package nntp
 
 import (
 	"crypto/tls"
+	"io"
+	"net"
 	tp "net/textproto"
 
 	. "nksrv/lib/utils/logx"
@@ -17,12 +19,45 @@ func (r Responder) Abort() {
 	panic(ErrAbortHandler)
 }
 
+// DotWriter is like textproto's one except that it doesn't
+// emit empty line if nothing was written.
+func (r Responder) DotWriter() io.WriteCloser {
+	return &lazyDotWriter{w: r.Writer}
+}
+
+type lazyDotWriter struct {
+	w  *tp.Writer
+	dw io.WriteCloser
+}
+
+func (d *lazyDotWriter) Write(b []byte) (int, error) {
+	if d.dw == nil {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		d.dw = d.w.DotWriter()
+	}
+	return d.dw.Write(b)
+}
+
+func (d *lazyDotWriter) Close() error {
+	if d.dw != nil {
+		return d.dw.Close()
+	}
+	if _, err := d.w.W.WriteString(".\r\n"); err != nil {
+		return err
+	}
+	return d.w.W.Flush()
+}
+
 type ConnState struct {
 	inbuf [512]byte
 
 	srv     *NNTPServer
 	conn    ConnCW
-	tlsConn *tls.Conn // TLS connection if activated
+	tlsConn *tls.Conn  // TLS connection if activated
+	proxy   *ProxyInfo // set if connection came through PROXY protocol
+	deflate bool       // whether COMPRESS DEFLATE is active
 	r       *bufreader.BufReader
 	dr      *bufreader.DotReader
 	w       Responder
@@ -31,11 +66,19 @@ type ConnState struct {
 	prov          NNTPProvider
 	CurrentGroup  interface{}  // provider-specific
 	UserPriv                   // stuff allowed
+	GroupAccess                // groups allowed
 	authenticated bool         // whether authenticated
+	userName      string       // name of authenticated user, if any
+	peerName      string       // name of peer logged in as, if any
+	peerPathID    string       // path identity peer used last time
 	activeLogin   *ActiveLogin // for AUTHINFO USER
 
 	listen     *nntpListenObj
 	activeWait bool
+
+	cmdBucket tokenBucket
+	artBucket tokenBucket
+	quit      bool // close gracefuly after current command
 }
 
 func (c *ConnState) Cleanup() {
@@ -57,8 +100,88 @@ func (c *ConnState) pullActiveLogin() (l *ActiveLogin) {
 	return
 }
 
+// PosterID returns identity of poster for purposes of
+// tracking their activity across connections.
+// It's name of authenticated user, or remote host if not authenticated.
+func (c *ConnState) PosterID() string {
+	if c.authenticated && c.userName != "" {
+		return "user:" + c.userName
+	}
+	a := c.RemoteAddr().String()
+	if h, _, e := net.SplitHostPort(a); e == nil {
+		a = h
+	}
+	return "addr:" + a
+}
+
+// RemoteAddr returns address of client.
+// For proxied connections it's the original one.
+func (c *ConnState) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// LocalAddr returns address client connected to.
+func (c *ConnState) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// ProxyInfo returns PROXY protocol information, or nil if connection
+// didn't come through proxy.
+func (c *ConnState) ProxyInfo() *ProxyInfo {
+	return c.proxy
+}
+
+// TLSConnectionState returns state of TLS terminated by us.
+// TLS terminated by proxy is described in ProxyInfo.
+func (c *ConnState) TLSConnectionState() (tls.ConnectionState, bool) {
+	if c.tlsConn == nil {
+		return tls.ConnectionState{}, false
+	}
+	return c.tlsConn.ConnectionState(), true
+}
+
+// PeerName returns name of peer client is logged in as,
+// or empty string if it's not peer.
+func (c *ConnState) PeerName() string {
+	return c.peerName
+}
+
+// PeerPathID returns path identity client used in last article it sent.
+func (c *ConnState) PeerPathID() string {
+	return c.peerPathID
+}
+
+// NotePeerPathID records path identity of incoming article sender,
+// so that outgoing feeds to the same peer can avoid offering
+// articles which already passed through it.
+func (c *ConnState) NotePeerPathID(id string) {
+	if id == "" || id == c.peerPathID {
+		return
+	}
+	if c.peerPathID != "" {
+		c.log.LogPrintf(NOTICE,
+			"path identity changed from %q to %q", c.peerPathID, id)
+	}
+	c.peerPathID = id
+	if c.peerName == "" {
+		return
+	}
+	if reg := c.srv.GetRunCfg().PeerPathIDs; reg != nil &&
+		reg.Add(c.peerName, id) {
+
+		c.log.LogPrintf(INFO,
+			"peer %q uses path identity %q", c.peerName, id)
+	}
+}
+
+func (c *ConnState) proxyTLS() bool {
+	return c.proxy != nil && c.proxy.TLS != nil && c.proxy.TLS.ClientSSL
+}
+
+// tlsStarted tells whether connection is encrypted,
+// either by us or by proxy in front of us.
 func (c *ConnState) tlsStarted() bool {
-	return c.tlsConn != nil
+	return c.tlsConn != nil || c.proxyTLS()
 }
 
 type commandFunc func(c *ConnState, args [][]byte, rest []byte) bool
@@ -68,5 +191,6 @@ type command struct {
 	minargs    int
 	maxargs    int
 	allowextra bool
+	article    bool // counts against article rate limit
 	help       string
 }
@@ -75,6 +75,15 @@ type group struct {
 	articlesSort []uint64
 }
 
+// watermarks returns lowest and highest article numbers of group.
+// Empty group has low one above high {RFC 3977 6.1.1.2}.
+func (g *group) watermarks() (lo, hi uint64) {
+	if len(g.articlesSort) == 0 {
+		return 1, 0
+	}
+	return g.articlesSort[0], g.articlesSort[len(g.articlesSort)-1]
+}
+
 type server struct {
 	name       string
 	groups     map[string]*group
@@ -113,10 +122,20 @@ var g1 = group{
 	},
 }
 
+// no nekos there
+var g2 = group{
+	name:     "nekos.empty",
+	info:     "group without nekos",
+	created:  time.Date(2000, 1, 23, 12, 34, 56, 0, time.UTC),
+	status:   'y',
+	articles: map[uint64]*article{},
+}
+
 var s1 = server{
 	name: "void.neko.test",
 	groups: map[string]*group{
 		a1group: &g1,
+		g2.name: &g2,
 	},
 	articles: map[CoreMsgIDStr]*article{},
 }
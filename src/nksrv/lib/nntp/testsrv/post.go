@@ -91,6 +91,9 @@ func (p *TestSrv) HandleIHave(w Responder, cs *ConnState, ro nntp.ReaderOpener,
 	h.B.Discard(-1)
 	h.Close()
 	r.Discard(-1) // ensure
+	if p.OnTransfer != nil {
+		p.OnTransfer("IHAVE", cutMsgID(mid))
+	}
 	nntp.AbortOnErr(w.ResTransferSuccess())
 	return true
 }
@@ -121,6 +124,9 @@ func (p *TestSrv) HandleTakeThis(w Responder, cs *ConnState, r nntp.ArticleReade
 	h.B.Discard(-1)
 	h.Close()
 	r.Discard(-1) // ensure
+	if p.OnTransfer != nil {
+		p.OnTransfer("TAKETHIS", cutMsgID(mid))
+	}
 	nntp.AbortOnErr(w.ResArticleTransferedOK(msgid))
 	return true
 }
@@ -30,6 +30,9 @@ type TestSrv struct {
 	TransferPermit bool
 	TransferAccept bool
 
+	// if set, called for articles accepted by IHAVE or TAKETHIS
+	OnTransfer func(cmd string, msgid CoreMsgIDStr)
+
 	Log Logger
 }
 
@@ -64,7 +67,7 @@ func (p *TestSrv) SupportsXListen() bool { return false }
 func (p *TestSrv) GetArticleFullByMsgID(w Responder, cs *ConnState, msgid CoreMsgID) bool {
 	sid := unsafeCoreMsgIDToStr(msgid)
 	a := s1.articles[sid]
-	if a == nil {
+	if a == nil || !cs.CanReadGroup(a.group) {
 		return false
 	}
 	w.ResArticleFollows(artnumInGroup(cs, a.group, a.number), a.msgid)
@@ -79,7 +82,7 @@ func (p *TestSrv) GetArticleFullByMsgID(w Responder, cs *ConnState, msgid CoreMs
 func (p *TestSrv) GetArticleHeadByMsgID(w Responder, cs *ConnState, msgid CoreMsgID) bool {
 	sid := unsafeCoreMsgIDToStr(msgid)
 	a := s1.articles[sid]
-	if a == nil {
+	if a == nil || !cs.CanReadGroup(a.group) {
 		return false
 	}
 	w.ResHeadFollows(artnumInGroup(cs, a.group, a.number), a.msgid)
@@ -92,7 +95,7 @@ func (p *TestSrv) GetArticleHeadByMsgID(w Responder, cs *ConnState, msgid CoreMs
 func (p *TestSrv) GetArticleBodyByMsgID(w Responder, cs *ConnState, msgid CoreMsgID) bool {
 	sid := unsafeCoreMsgIDToStr(msgid)
 	a := s1.articles[sid]
-	if a == nil {
+	if a == nil || !cs.CanReadGroup(a.group) {
 		return false
 	}
 	w.ResBodyFollows(artnumInGroup(cs, a.group, a.number), a.msgid)
@@ -105,7 +108,7 @@ func (p *TestSrv) GetArticleBodyByMsgID(w Responder, cs *ConnState, msgid CoreMs
 func (p *TestSrv) GetArticleStatByMsgID(w Responder, cs *ConnState, msgid CoreMsgID) bool {
 	sid := unsafeCoreMsgIDToStr(msgid)
 	a := s1.articles[sid]
-	if a == nil {
+	if a == nil || !cs.CanReadGroup(a.group) {
 		return false
 	}
 	w.ResArticleFound(artnumInGroup(cs, a.group, a.number), a.msgid)
@@ -370,7 +373,7 @@ func (p *TestSrv) SelectPrevArticle(w Responder, cs *ConnState) {
 	w.ResNoPrevArticleInThisGroup()
 }
 
-func (p *TestSrv) ListNewGroups(r AbstractResponder, qt time.Time) {
+func (p *TestSrv) ListNewGroups(r AbstractResponder, cs *ConnState, qt time.Time) {
 	w, e := r.OpenDotWriter()
 	if e != nil {
 		panic(nntp.ErrAbortHandler)
@@ -379,8 +382,8 @@ func (p *TestSrv) ListNewGroups(r AbstractResponder, qt time.Time) {
 
 	for _, gn := range s1.groupsSort {
 		g := s1.groups[gn]
-		if !qt.After(g.created) {
-			lo, hi := g.articlesSort[0], g.articlesSort[len(g.articlesSort)-1]
+		if !qt.After(g.created) && cs.CanReadGroup(gn) {
+			lo, hi := g.watermarks()
 			fmt.Fprintf(w, "%s %d %d %c\n", gn, hi, lo, g.status)
 		}
 	}
@@ -390,7 +393,7 @@ func emptyWildmat(w []byte) bool {
 	return len(w) == 0 || (len(w) == 1 && w[0] == '*')
 }
 
-func (p *TestSrv) ListNewNews(r AbstractResponder, wildmat []byte, qt time.Time) {
+func (p *TestSrv) ListNewNews(r AbstractResponder, cs *ConnState, wildmat []byte, qt time.Time) {
 	w, e := r.OpenDotWriter()
 	if e != nil {
 		panic(nntp.ErrAbortHandler)
@@ -411,13 +414,13 @@ func (p *TestSrv) ListNewNews(r AbstractResponder, wildmat []byte, qt time.Time)
 	}
 
 	for id, a := range s1.articles {
-		if !qt.After(a.posted) && chk(a.group) {
+		if !qt.After(a.posted) && chk(a.group) && cs.CanReadGroup(a.group) {
 			fmt.Fprintf(w, "<%s>\n", id)
 		}
 	}
 }
 
-func (p *TestSrv) ListActiveGroups(r AbstractResponder, wildmat []byte) {
+func (p *TestSrv) ListActiveGroups(r AbstractResponder, cs *ConnState, wildmat []byte) {
 	w, e := r.OpenDotWriter()
 	if e != nil {
 		panic(nntp.ErrAbortHandler)
@@ -438,15 +441,15 @@ func (p *TestSrv) ListActiveGroups(r AbstractResponder, wildmat []byte) {
 	}
 
 	for _, gn := range s1.groupsSort {
-		if chk(gn) {
+		if chk(gn) && cs.CanReadGroup(gn) {
 			g := s1.groups[gn]
-			lo, hi := g.articlesSort[0], g.articlesSort[len(g.articlesSort)-1]
+			lo, hi := g.watermarks()
 			fmt.Fprintf(w, "%s %d %d %c\n", gn, hi, lo, g.status)
 		}
 	}
 }
 
-func (p *TestSrv) ListNewsgroups(r AbstractResponder, wildmat []byte) {
+func (p *TestSrv) ListNewsgroups(r AbstractResponder, cs *ConnState, wildmat []byte) {
 	w, e := r.OpenDotWriter()
 	if e != nil {
 		panic(nntp.ErrAbortHandler)
@@ -467,13 +470,92 @@ func (p *TestSrv) ListNewsgroups(r AbstractResponder, wildmat []byte) {
 	}
 
 	for _, gn := range s1.groupsSort {
-		if chk(gn) {
+		if chk(gn) && cs.CanReadGroup(gn) {
 			g := s1.groups[gn]
 			fmt.Fprintf(w, "%s\t%s\n", gn, g.info)
 		}
 	}
 }
 
+func groupChecker(wildmat []byte) func(string) bool {
+	if emptyWildmat(wildmat) {
+		return func(g string) bool { return true }
+	}
+	if nntp.ValidGroupSlice(wildmat) {
+		sw := string(wildmat)
+		return func(g string) bool { return g == sw }
+	}
+	wm := nntp.CompileWildmat(wildmat)
+	return func(g string) bool { return wm.CheckString(g) }
+}
+
+func (p *TestSrv) ListActiveTimes(r AbstractResponder, cs *ConnState, wildmat []byte) {
+	w, e := r.OpenDotWriter()
+	if e != nil {
+		panic(nntp.ErrAbortHandler)
+	}
+	defer w.Close()
+
+	chk := groupChecker(wildmat)
+	for _, gn := range s1.groupsSort {
+		if chk(gn) && cs.CanReadGroup(gn) {
+			g := s1.groups[gn]
+			fmt.Fprintf(w, "%s %d testsrv\n", gn, g.created.Unix())
+		}
+	}
+}
+
+func (p *TestSrv) ListCounts(r AbstractResponder, cs *ConnState, wildmat []byte) {
+	w, e := r.OpenDotWriter()
+	if e != nil {
+		panic(nntp.ErrAbortHandler)
+	}
+	defer w.Close()
+
+	chk := groupChecker(wildmat)
+	for _, gn := range s1.groupsSort {
+		if chk(gn) && cs.CanReadGroup(gn) {
+			g := s1.groups[gn]
+			lo, hi := g.watermarks()
+			fmt.Fprintf(w, "%s %d %d %d %c\n",
+				gn, hi, lo, len(g.articlesSort), g.status)
+		}
+	}
+}
+
+func (p *TestSrv) ListDistribPats(r AbstractResponder, cs *ConnState) {
+	w, e := r.OpenDotWriter()
+	if e != nil {
+		panic(nntp.ErrAbortHandler)
+	}
+	w.Close()
+}
+
+var hdrList = []byte(`Subject
+From
+Date
+Message-ID
+References
+Xref
+To
+Cc
+Bytes
+Lines
+:bytes
+:lines
+`)
+
+func (p *TestSrv) ListHeaders(r AbstractResponder, cs *ConnState, form nntp.ListHeadersForm) {
+	w, e := r.OpenDotWriter()
+	if e != nil {
+		panic(nntp.ErrAbortHandler)
+	}
+	defer w.Close()
+
+	// same set for all forms
+	w.Write(hdrList)
+}
+
 func printOver(w io.Writer, num uint64, a *article) {
 	fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\tXref: %s\n", num,
 		a.over.subject, a.over.from, a.over.date, a.over.msgid,
@@ -489,7 +571,7 @@ func printOver(w io.Writer, num uint64, a *article) {
 func (p *TestSrv) GetOverByMsgID(w Responder, cs *ConnState, msgid CoreMsgID) bool {
 	sid := unsafeCoreMsgIDToStr(msgid)
 	a := s1.articles[sid]
-	if a == nil {
+	if a == nil || !cs.CanReadGroup(a.group) {
 		return false
 	}
 	/*
@@ -556,7 +638,7 @@ func (p *TestSrv) GetOverByCurr(w Responder, cs *ConnState) bool {
 func (p *TestSrv) commonGetHdrByMsgID(w Responder, cs *ConnState, hdr []byte, msgid CoreMsgID, rfc bool) bool {
 	sid := unsafeCoreMsgIDToStr(msgid)
 	a := s1.articles[sid]
-	if a == nil {
+	if a == nil || !cs.CanReadGroup(a.group) {
 		return false
 	}
 	h, supported := a.over.GetByHdr(hdr)
@@ -644,8 +726,8 @@ func (p *TestSrv) GetHdrByRange(w Responder, cs *ConnState, hdr []byte, rmin, rm
 func (p *TestSrv) GetHdrByCurr(w Responder, cs *ConnState, hdr []byte) bool {
 	return p.commonGetHdrByCurr(w, cs, hdr, true)
 }
-func (p *TestSrv) GetXHdrByMsgID(w Responder, hdr []byte, msgid CoreMsgID) bool {
-	return p.commonGetHdrByMsgID(w, nil, hdr, msgid, false)
+func (p *TestSrv) GetXHdrByMsgID(w Responder, cs *ConnState, hdr []byte, msgid CoreMsgID) bool {
+	return p.commonGetHdrByMsgID(w, cs, hdr, msgid, false)
 }
 func (p *TestSrv) GetXHdrByRange(w Responder, cs *ConnState, hdr []byte, rmin, rmax int64) bool {
 	return p.commonGetHdrByRange(w, cs, hdr, rmin, rmax, false)
@@ -1,6 +1,7 @@
 package certfp
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"crypto/sha512"
 	"crypto/x509"
@@ -253,3 +254,23 @@ func MakeFingerprint(
 func FingerprintString(mt MatchingType, data []byte) string {
 	return MatchingTypeStr[mt] + ":" + hex.EncodeToString(data)
 }
+
+// Pin is fingerprint certificate is expected to have.
+type Pin struct {
+	Selector     Selector
+	MatchingType MatchingType
+	Data         []byte
+}
+
+// ParsePin parses fingerprint in the same form as ParseCertFP,
+// to be matched against part of certificate specified by selector.
+func ParsePin(selector Selector, s string) (p Pin, err error) {
+	p.Selector = selector
+	p.MatchingType, p.Data, err = ParseCertFP(s)
+	return
+}
+
+// Match tells whether cert has pinned fingerprint.
+func (p Pin) Match(cert *x509.Certificate) bool {
+	return bytes.Equal(MakeFingerprint(cert, p.Selector, p.MatchingType), p.Data)
+}
@@ -0,0 +1,109 @@
+package certfp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func makeTestCert(t *testing.T, name string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestPin(t *testing.T) {
+	cert := makeTestCert(t, "a")
+	other := makeTestCert(t, "b")
+
+	// same key, different certificate
+	reissued := *cert
+	reissued.Raw = other.Raw
+
+	for _, sel := range []Selector{SelectorFull, SelectorPubKey} {
+		for _, mt := range []MatchingType{
+			MatchingTypeSHA2_256, MatchingTypeSHA3_512, MatchingTypeBLAKE3,
+		} {
+			fp := FingerprintString(mt, MakeFingerprint(cert, sel, mt))
+			p, err := ParsePin(sel, fp)
+			if err != nil {
+				t.Fatalf("ParsePin(%d, %q) failed: %v", sel, fp, err)
+			}
+			if p.Selector != sel || p.MatchingType != mt {
+				t.Errorf("ParsePin(%d, %q) = %d %d", sel, fp,
+					p.Selector, p.MatchingType)
+			}
+			if !p.Match(cert) {
+				t.Errorf("pin %q (selector %d) doesn't match its cert", fp, sel)
+			}
+			if p.Match(other) {
+				t.Errorf("pin %q (selector %d) matches other cert", fp, sel)
+			}
+			// only pubkey pins survive certificate renewal with same key
+			if m := p.Match(&reissued); m != (sel == SelectorPubKey) {
+				t.Errorf("pin %q (selector %d) match of reissued cert = %v",
+					fp, sel, m)
+			}
+		}
+	}
+
+	// cert and pubkey fingerprints differ
+	fp := FingerprintString(MatchingTypeSHA2_256,
+		MakeFingerprint(cert, SelectorFull, MatchingTypeSHA2_256))
+	p, err := ParsePin(SelectorPubKey, fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Match(cert) {
+		t.Errorf("cert fingerprint matched as pubkey pin")
+	}
+
+	for _, s := range []string{
+		"",
+		"abcd",
+		"sha256:abcd",
+		"nope:" + fp[len("sha256:"):],
+		"sha256:zz" + fp[len("sha256:zz"):],
+	} {
+		if _, err := ParsePin(SelectorFull, s); err == nil {
+			t.Errorf("ParsePin(%q) succeeded", s)
+		}
+	}
+	// colons between bytes are allowed
+	hexfp := fp[len("sha256:"):]
+	colfp := "sha256:"
+	for i := 0; i < len(hexfp); i += 2 {
+		if i != 0 {
+			colfp += ":"
+		}
+		colfp += hexfp[i : i+2]
+	}
+	p, err = ParsePin(SelectorFull, colfp)
+	if err != nil {
+		t.Fatalf("ParsePin(%q) failed: %v", colfp, err)
+	}
+	if !p.Match(cert) {
+		t.Errorf("pin %q doesn't match its cert", colfp)
+	}
+}
@@ -0,0 +1,284 @@
+package scram
+
+// SCRAM-SHA-256 (RFC 5802, RFC 7677) server side primitives
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const MechSHA256 = "SCRAM-SHA-256"
+
+// RFC 7677 recommends at least that
+const DefaultIterations = 4096
+
+const saltSize = 16
+const nonceSize = 18
+
+var (
+	errBadMessage      = errors.New("malformed SCRAM message")
+	errChannelBinding  = errors.New("channel binding not supported")
+	errNonceMismatch   = errors.New("nonce mismatch")
+	errGS2Mismatch     = errors.New("channel binding data mismatch")
+	errBadProof        = errors.New("proof verification failed")
+	errBadIterations   = errors.New("bad iteration count")
+	errUnexpectedState = errors.New("unexpected conversation state")
+)
+
+// Credentials is what server needs to store to verify client
+type Credentials struct {
+	Salt      []byte
+	Iters     int
+	StoredKey []byte
+	ServerKey []byte
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// MakeCredentials derives credentials from already normalised password
+func MakeCredentials(pass string, salt []byte, iters int) Credentials {
+	salted := pbkdf2.Key([]byte(pass), salt, iters, sha256.Size, sha256.New)
+	ck := hmacSHA256(salted, "Client Key")
+	sk := sha256.Sum256(ck)
+	return Credentials{
+		Salt:      salt,
+		Iters:     iters,
+		StoredKey: sk[:],
+		ServerKey: hmacSHA256(salted, "Server Key"),
+	}
+}
+
+// NewCredentials derives credentials using random salt
+func NewCredentials(pass string) (_ Credentials, err error) {
+	salt := make([]byte, saltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return
+	}
+	return MakeCredentials(pass, salt, DefaultIterations), nil
+}
+
+// FakeCredentials returns credentials nobody can pass,
+// for use with nonexistent users, so that they look same as real ones
+func FakeCredentials() (_ Credentials, err error) {
+	salt := make([]byte, saltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return
+	}
+	return Credentials{Salt: salt, Iters: DefaultIterations}, nil
+}
+
+func NewNonce() (string, error) {
+	b := make([]byte, nonceSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(b), nil
+}
+
+// decodes saslname, see RFC 5802 section 5.1
+func decodeSASLName(s string) (string, error) {
+	if strings.IndexByte(s, '=') < 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '=' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", errBadMessage
+		}
+		switch s[i+1 : i+3] {
+		case "2C":
+			b.WriteByte(',')
+		case "3D":
+			b.WriteByte('=')
+		default:
+			return "", errBadMessage
+		}
+		i += 2
+	}
+	return b.String(), nil
+}
+
+func validNonce(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		// printable except ","
+		if s[i] < 0x21 || s[i] > 0x7E || s[i] == ',' {
+			return false
+		}
+	}
+	return true
+}
+
+type convState int
+
+const (
+	stStart convState = iota
+	stSentFirst
+	stDone
+)
+
+// ServerConv holds state of single server side conversation
+type ServerConv struct {
+	state convState
+
+	gs2Header       string
+	clientFirstBare string
+	serverFirst     string
+	nonce           string
+
+	creds Credentials
+
+	User    string
+	AuthzID string
+}
+
+// ReadClientFirst processes client-first-message.
+// After this User and AuthzID fields are filled in.
+func (s *ServerConv) ReadClientFirst(msg string) (err error) {
+	if s.state != stStart {
+		return errUnexpectedState
+	}
+	// gs2-cbind-flag "," [authzid] ","
+	if len(msg) < 3 {
+		return errBadMessage
+	}
+	switch msg[0] {
+	case 'n', 'y':
+		if msg[1] != ',' {
+			return errBadMessage
+		}
+	case 'p':
+		return errChannelBinding
+	default:
+		return errBadMessage
+	}
+	i := strings.IndexByte(msg[2:], ',')
+	if i < 0 {
+		return errBadMessage
+	}
+	i += 2
+	if i > 2 {
+		if !strings.HasPrefix(msg[2:i], "a=") {
+			return errBadMessage
+		}
+		s.AuthzID, err = decodeSASLName(msg[4:i])
+		if err != nil {
+			return
+		}
+	}
+	s.gs2Header = msg[:i+1]
+	s.clientFirstBare = msg[i+1:]
+
+	// "n=" saslname "," "r=" c-nonce ["," extensions]
+	f := strings.Split(s.clientFirstBare, ",")
+	if len(f) < 2 || !strings.HasPrefix(f[0], "n=") ||
+		!strings.HasPrefix(f[1], "r=") {
+
+		return errBadMessage
+	}
+	s.User, err = decodeSASLName(f[0][2:])
+	if err != nil {
+		return
+	}
+	if s.User == "" || !validNonce(f[1][2:]) {
+		return errBadMessage
+	}
+	s.nonce = f[1][2:]
+	return nil
+}
+
+// ServerFirst produces server-first-message.
+// snonce must be random and unpredictable, NewNonce() is fine for that.
+func (s *ServerConv) ServerFirst(
+	creds Credentials, snonce string) (_ string, err error) {
+
+	if s.state != stStart || s.clientFirstBare == "" {
+		return "", errUnexpectedState
+	}
+	if creds.Iters <= 0 {
+		return "", errBadIterations
+	}
+	if !validNonce(snonce) {
+		return "", errBadMessage
+	}
+	s.creds = creds
+	s.nonce += snonce
+	s.serverFirst = "r=" + s.nonce +
+		",s=" + base64.StdEncoding.EncodeToString(creds.Salt) +
+		",i=" + strconv.Itoa(creds.Iters)
+	s.state = stSentFirst
+	return s.serverFirst, nil
+}
+
+// ReadClientFinal verifies client-final-message.
+// On success returns server-final-message which should be sent to client.
+func (s *ServerConv) ReadClientFinal(msg string) (_ string, err error) {
+	if s.state != stSentFirst {
+		return "", errUnexpectedState
+	}
+	s.state = stDone
+
+	// "c=" base64 "," "r=" nonce ["," extensions] "," "p=" base64
+	pi := strings.LastIndex(msg, ",p=")
+	if pi < 0 {
+		return "", errBadMessage
+	}
+	withoutProof := msg[:pi]
+	proof, err := base64.StdEncoding.DecodeString(msg[pi+3:])
+	if err != nil || len(proof) != sha256.Size {
+		return "", errBadMessage
+	}
+	f := strings.Split(withoutProof, ",")
+	if len(f) < 2 || !strings.HasPrefix(f[0], "c=") ||
+		!strings.HasPrefix(f[1], "r=") {
+
+		return "", errBadMessage
+	}
+	cb, err := base64.StdEncoding.DecodeString(f[0][2:])
+	if err != nil {
+		return "", errBadMessage
+	}
+	if string(cb) != s.gs2Header {
+		return "", errGS2Mismatch
+	}
+	if f[1][2:] != s.nonce {
+		return "", errNonceMismatch
+	}
+
+	if len(s.creds.StoredKey) == 0 {
+		// fake credentials
+		return "", errBadProof
+	}
+
+	authMsg := s.clientFirstBare + "," + s.serverFirst + "," + withoutProof
+	csig := hmacSHA256(s.creds.StoredKey, authMsg)
+	ck := make([]byte, len(proof))
+	for i := range proof {
+		ck[i] = proof[i] ^ csig[i]
+	}
+	sk := sha256.Sum256(ck)
+	if subtle.ConstantTimeCompare(sk[:], s.creds.StoredKey) != 1 {
+		return "", errBadProof
+	}
+
+	ssig := hmacSHA256(s.creds.ServerKey, authMsg)
+	return "v=" + base64.StdEncoding.EncodeToString(ssig), nil
+}
@@ -0,0 +1,91 @@
+package scram
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+// RFC 7677 section 3
+func TestRFC7677(t *testing.T) {
+	salt, _ := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	creds := MakeCredentials("pencil", salt, 4096)
+
+	var s ServerConv
+	err := s.ReadClientFirst("n,,n=user,r=rOprNGfwEbeRWgbNEkqO")
+	if err != nil {
+		t.Fatalf("ReadClientFirst err: %v", err)
+	}
+	if s.User != "user" || s.AuthzID != "" {
+		t.Errorf("unexpected user %q authzid %q", s.User, s.AuthzID)
+	}
+
+	sf, err := s.ServerFirst(creds, "%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0")
+	if err != nil {
+		t.Fatalf("ServerFirst err: %v", err)
+	}
+	const expsf = "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0," +
+		"s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+	if sf != expsf {
+		t.Errorf("expected server-first %q got %q", expsf, sf)
+	}
+
+	fin, err := s.ReadClientFinal(
+		"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0," +
+			"p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=")
+	if err != nil {
+		t.Fatalf("ReadClientFinal err: %v", err)
+	}
+	const expfin = "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="
+	if fin != expfin {
+		t.Errorf("expected server-final %q got %q", expfin, fin)
+	}
+}
+
+func TestBadProof(t *testing.T) {
+	salt, _ := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	creds := MakeCredentials("pen", salt, 4096)
+
+	var s ServerConv
+	if err := s.ReadClientFirst("n,,n=user,r=rOprNGfwEbeRWgbNEkqO"); err != nil {
+		t.Fatalf("ReadClientFirst err: %v", err)
+	}
+	if _, err := s.ServerFirst(creds, "%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0"); err != nil {
+		t.Fatalf("ServerFirst err: %v", err)
+	}
+	_, err := s.ReadClientFinal(
+		"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0," +
+			"p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=")
+	if err == nil {
+		t.Errorf("expected failure with wrong password")
+	}
+}
+
+func TestClientFirstParse(t *testing.T) {
+	var tests = [...]struct {
+		msg   string
+		user  string
+		authz string
+		ok    bool
+	}{
+		{"n,,n=user,r=abc", "user", "", true},
+		{"y,a=adm=2Cin,n=u=3Dx,r=abc", "u=x", "adm,in", true},
+		{"p=tls-unique,,n=user,r=abc", "", "", false},
+		{"n,,r=abc", "", "", false},
+		{"n,,n=user,r=", "", "", false},
+		{"n,,n=us=ZZ,r=abc", "", "", false},
+	}
+	for i := range tests {
+		var s ServerConv
+		err := s.ReadClientFirst(tests[i].msg)
+		if (err == nil) != tests[i].ok {
+			t.Errorf("%d expected ok=%v got err %v", i, tests[i].ok, err)
+			continue
+		}
+		if err == nil &&
+			(s.User != tests[i].user || s.AuthzID != tests[i].authz) {
+
+			t.Errorf("%d expected %q %q got %q %q",
+				i, tests[i].user, tests[i].authz, s.User, s.AuthzID)
+		}
+	}
+}